@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"ypeskov/budget-go/internal/config"
 	"ypeskov/budget-go/internal/constants"
 	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/queue"
 
 	"github.com/hibiken/asynq"
 )
@@ -26,6 +29,11 @@ func main() {
 	ex := fmt.Sprintf("%d %d * * *", cfg.ExchangeRatesMinute, cfg.ExchangeRatesHour)
 	db := fmt.Sprintf("%d %d * * *", cfg.DBBackupMinute, cfg.DBBackupHour)
 	bud := fmt.Sprintf("%d %d * * *", cfg.BudgetsProcMinute, cfg.BudgetsProcHour)
+	recon := fmt.Sprintf("%d %d * * *", cfg.BudgetsReconMinute, cfg.BudgetsReconHour)
+	goals := fmt.Sprintf("%d %d * * *", cfg.GoalsRecalcMinute, cfg.GoalsRecalcHour)
+	insights := fmt.Sprintf("%d %d * * *", cfg.InsightsMinute, cfg.InsightsHour)
+	weeklyDigest := fmt.Sprintf("%d %d * * %d", cfg.DigestMinute, cfg.DigestHour, cfg.DigestWeekday)
+	monthlyDigest := fmt.Sprintf("%d %d 1 * *", cfg.DigestMinute, cfg.DigestHour)
 
 	if _, err := sch.Register(ex, asynq.NewTask(constants.TaskExchangeRatesDaily, nil)); err != nil {
 		logger.Fatal(err.Error())
@@ -45,7 +53,70 @@ func main() {
 		logger.Info("Scheduled task to run at cron", "task", constants.TaskBudgetsDailyProcessing, "cron", bud)
 	}
 
+	if _, err := sch.Register(recon, asynq.NewTask(constants.TaskBudgetsReconciliation, nil)); err != nil {
+		logger.Fatal(err.Error())
+	} else {
+		logger.Info("Scheduled task to run at cron", "task", constants.TaskBudgetsReconciliation, "cron", recon)
+	}
+
+	if _, err := sch.Register(goals, asynq.NewTask(constants.TaskGoalsDailyRecalc, nil)); err != nil {
+		logger.Fatal(err.Error())
+	} else {
+		logger.Info("Scheduled task to run at cron", "task", constants.TaskGoalsDailyRecalc, "cron", goals)
+	}
+
+	if _, err := sch.Register(insights, asynq.NewTask(constants.TaskInsightsNightly, nil)); err != nil {
+		logger.Fatal(err.Error())
+	} else {
+		logger.Info("Scheduled task to run at cron", "task", constants.TaskInsightsNightly, "cron", insights)
+	}
+
+	digests := map[string]string{models.DigestWeekly: weeklyDigest, models.DigestMonthly: monthlyDigest}
+	for _, frequency := range []string{models.DigestWeekly, models.DigestMonthly} {
+		payload, err := json.Marshal(queue.DigestSchedulePayload{Frequency: frequency})
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		if _, err := sch.Register(digests[frequency], asynq.NewTask(constants.TaskDigestsSchedule, payload),
+			asynq.MaxRetry(queue.DigestScheduleMaxRetry)); err != nil {
+			logger.Fatal(err.Error())
+		} else {
+			logger.Info("Scheduled task to run at cron", "task", constants.TaskDigestsSchedule, "frequency", frequency, "cron", digests[frequency])
+		}
+	}
+
+	enqueueExchangeRatesCatchUp(cfg, loc)
+
 	if err := sch.Run(); err != nil {
 		logger.Fatal(err.Error())
 	}
 }
+
+// enqueueExchangeRatesCatchUp queues a backfill of the days up to the last scheduled exchange rates
+// update, so daily updates missed while the scheduler was down are fetched. The worker only fetches
+// the days without stored rates and notifies the admins when it found any.
+func enqueueExchangeRatesCatchUp(cfg *config.Config, loc *time.Location) {
+	if cfg.ExchangeRatesCatchUpDays <= 0 {
+		return
+	}
+
+	now := time.Now().In(loc)
+	lastRun := time.Date(now.Year(), now.Month(), now.Day(), cfg.ExchangeRatesHour, cfg.ExchangeRatesMinute, 0, 0, loc)
+	if lastRun.After(now) {
+		lastRun = lastRun.AddDate(0, 0, -1)
+	}
+
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: cfg.RedisAddr})
+	defer client.Close()
+
+	payload := queue.ExchangeRatesBackfillPayload{
+		StartDate: lastRun.AddDate(0, 0, 1-cfg.ExchangeRatesCatchUpDays).Format(time.DateOnly),
+		EndDate:   lastRun.Format(time.DateOnly),
+	}
+	if err := queue.NewQueueService(client).EnqueueExchangeRatesBackfill(payload); err != nil {
+		logger.Error("Failed to queue exchange rates catch-up", "error", err)
+		return
+	}
+	logger.Info("Queued exchange rates catch-up", "task", constants.TaskExchangeRatesBackfill,
+		"startDate", payload.StartDate, "endDate", payload.EndDate)
+}
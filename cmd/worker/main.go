@@ -34,9 +34,17 @@ func main() {
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(constants.TaskEmailSend, h.HandleEmailSend)
 	mux.HandleFunc(constants.TaskSendActivationEmail, h.HandleSendActivationEmail)
+	mux.HandleFunc(constants.TaskSendBudgetAlert, h.HandleSendBudgetAlert)
 	mux.HandleFunc(constants.TaskExchangeRatesDaily, h.HandleExchangeRatesDaily)
+	mux.HandleFunc(constants.TaskExchangeRatesBackfill, h.HandleExchangeRatesBackfill)
 	mux.HandleFunc(constants.TaskDBBackupDaily, h.HandleDBBackupDaily)
 	mux.HandleFunc(constants.TaskBudgetsDailyProcessing, h.HandleBudgetsDailyProcessing)
+	mux.HandleFunc(constants.TaskBudgetsReconciliation, h.HandleBudgetsReconciliation)
+	mux.HandleFunc(constants.TaskGoalsDailyRecalc, h.HandleGoalsDailyRecalculation)
+	mux.HandleFunc(constants.TaskDigestsSchedule, h.HandleScheduleDigests)
+	mux.HandleFunc(constants.TaskSendDigest, h.HandleSendDigest)
+	mux.HandleFunc(constants.TaskInsightsNightly, h.HandleInsightsNightlyAnalysis)
+	mux.HandleFunc(constants.TaskSendInsights, h.HandleSendInsights)
 
 	// Run blocks and processes jobs until the process receives a shutdown signal
 	if err := srv.Run(mux); err != nil {
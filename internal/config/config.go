@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 	"github.com/joho/godotenv"
@@ -34,6 +35,18 @@ type Config struct {
 	DBBackupMinute      int `env:"DAILY_DB_BACKUP_MINUTE" envDefault:"0"`
 	BudgetsProcHour     int `env:"DAILY_BUDGETS_PROCESSING_HOUR" envDefault:"2"`
 	BudgetsProcMinute   int `env:"DAILY_BUDGETS_PROCESSING_MINUTE" envDefault:"0"`
+	BudgetsReconHour    int `env:"DAILY_BUDGETS_RECONCILIATION_HOUR" envDefault:"2"`
+	BudgetsReconMinute  int `env:"DAILY_BUDGETS_RECONCILIATION_MINUTE" envDefault:"30"`
+	GoalsRecalcHour     int `env:"DAILY_GOALS_RECALCULATION_HOUR" envDefault:"3"`
+	GoalsRecalcMinute   int `env:"DAILY_GOALS_RECALCULATION_MINUTE" envDefault:"30"`
+	InsightsHour        int `env:"DAILY_INSIGHTS_ANALYSIS_HOUR" envDefault:"4"`
+	InsightsMinute      int `env:"DAILY_INSIGHTS_ANALYSIS_MINUTE" envDefault:"30"`
+
+	// Email digests go out at DigestHour:DigestMinute, weekly ones on DigestWeekday (0 is Sunday)
+	// for the past week and monthly ones on the 1st for the past month
+	DigestHour    int `env:"EMAIL_DIGEST_HOUR" envDefault:"8"`
+	DigestMinute  int `env:"EMAIL_DIGEST_MINUTE" envDefault:"0"`
+	DigestWeekday int `env:"EMAIL_DIGEST_WEEKDAY" envDefault:"1"`
 
 	// Database backup settings
 	Environment string `env:"ENV" envDefault:"prod"`
@@ -53,6 +66,17 @@ type Config struct {
 	CurrencyBeaconAPIKey     string `env:"CURRENCYBEACON_API_KEY" envDefault:""`
 	CurrencyBeaconAPIVersion string `env:"CURRENCYBEACON_API_VERSION" envDefault:"v1"`
 
+	// Exchange rate providers are tried in this order until one has the rates of a date,
+	// known providers are currencybeacon, ecb and file
+	ExchangeRateProvidersRaw string `env:"EXCHANGE_RATE_PROVIDERS" envDefault:"currencybeacon,ecb"`
+	ECBRatesURL              string `env:"ECB_RATES_URL" envDefault:"https://www.ecb.europa.eu/stats/eurofxref"`
+	// ExchangeRatesFile is the .json or .csv file of the file provider
+	ExchangeRatesFile string `env:"EXCHANGE_RATES_FILE" envDefault:""`
+	// Backfills wait ExchangeRatesBackfillDelay between provider requests, the scheduler catches up
+	// on the last ExchangeRatesCatchUpDays days when it starts
+	ExchangeRatesBackfillDelay time.Duration `env:"EXCHANGE_RATES_BACKFILL_DELAY" envDefault:"1s"`
+	ExchangeRatesCatchUpDays   int           `env:"EXCHANGE_RATES_CATCHUP_DAYS" envDefault:"30"`
+
 	// Container detection
 	RunningInContainer bool `env:"RUNNING_IN_CONTAINER" envDefault:"false"`
 
@@ -3,7 +3,15 @@ package constants
 const (
 	TaskEmailSend              = "email:send"
 	TaskExchangeRatesDaily     = "exchange_rates:daily_update"
+	TaskExchangeRatesBackfill  = "exchange_rates:backfill"
 	TaskDBBackupDaily          = "db:backup"
 	TaskBudgetsDailyProcessing = "budgets:daily_processing"
+	TaskBudgetsReconciliation  = "budgets:reconciliation"
 	TaskSendActivationEmail    = "email:send_activation"
+	TaskSendBudgetAlert        = "email:send_budget_alert"
+	TaskGoalsDailyRecalc       = "goals:daily_recalculation"
+	TaskDigestsSchedule        = "digests:schedule"
+	TaskSendDigest             = "email:send_digest"
+	TaskInsightsNightly        = "insights:nightly_analysis"
+	TaskSendInsights           = "email:send_insights"
 )
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/shopspring/decimal"
 	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/money"
 )
 
 type AccountDTO struct {
@@ -24,42 +25,38 @@ type AccountDTO struct {
 	CreatedAt             string           `json:"createdAt" db:"created_at"`
 	UpdateAt              string           `json:"updatedAt" db:"updated_at"`
 	BalanceInBaseCurrency *decimal.Decimal `json:"balanceInBaseCurrency" db:"balance_in_base_currency"`
+	BaseCurrencyCode      *string          `json:"baseCurrencyCode" db:"-"`
 
 	Currency    models.Currency    `json:"currency" db:"currency"`
 	AccountType models.AccountType `json:"accountType" db:"account_type"`
 }
 
 func (a *AccountDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.MarshalMoney(money.Numbers))
+}
+
+// MarshalMoney writes the amounts in the currency of the account and the balance in the base currency
+// in the base currency
+func (a AccountDTO) MarshalMoney(e money.Encoder) interface{} {
 	type Alias AccountDTO
-	var initialBalance *float64
-	if a.InitialBalance != nil {
-		val, _ := a.InitialBalance.Float64()
-		initialBalance = &val
-	}
-	var creditLimit *float64
-	if a.CreditLimit != nil {
-		val, _ := a.CreditLimit.Float64()
-		creditLimit = &val
-	}
-	var balanceInBaseCurrency *float64
-	if a.BalanceInBaseCurrency != nil {
-		val, _ := a.BalanceInBaseCurrency.Float64()
-		balanceInBaseCurrency = &val
+	var baseCurrencyCode string
+	if a.BaseCurrencyCode != nil {
+		baseCurrencyCode = *a.BaseCurrencyCode
 	}
 
-	return json.Marshal(&struct {
-		Balance               float64  `json:"balance"`
-		InitialBalance        *float64 `json:"initialBalance"`
-		CreditLimit           *float64 `json:"creditLimit"`
-		BalanceInBaseCurrency *float64 `json:"balanceInBaseCurrency"`
+	return &struct {
+		Balance               interface{} `json:"balance"`
+		InitialBalance        interface{} `json:"initialBalance"`
+		CreditLimit           interface{} `json:"creditLimit"`
+		BalanceInBaseCurrency interface{} `json:"balanceInBaseCurrency"`
 		*Alias
 	}{
-		Balance:               a.Balance.InexactFloat64(),
-		InitialBalance:        initialBalance,
-		CreditLimit:           creditLimit,
-		BalanceInBaseCurrency: balanceInBaseCurrency,
-		Alias:                 (*Alias)(a),
-	})
+		Balance:               e.Amount(a.Balance, a.Currency.Code),
+		InitialBalance:        e.AmountOf(a.InitialBalance, a.Currency.Code),
+		CreditLimit:           e.AmountOf(a.CreditLimit, a.Currency.Code),
+		BalanceInBaseCurrency: e.AmountOf(a.BalanceInBaseCurrency, baseCurrencyCode),
+		Alias:                 (*Alias)(&a),
+	}
 }
 
 // AccountTypeDTO has been consolidated with models.AccountType
@@ -4,50 +4,202 @@ import (
 	"github.com/shopspring/decimal"
 	"time"
 	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/money"
 	"ypeskov/budget-go/internal/utils"
 )
 
 type CreateBudgetDTO struct {
-	Name         string            `json:"name" validate:"required"`
-	CurrencyID   int               `json:"currencyId" validate:"required"`
-	TargetAmount decimal.Decimal   `json:"targetAmount" validate:"required"`
-	Period       string            `json:"period" validate:"required"`
-	Repeat       bool              `json:"repeat"`
-	StartDate    *utils.CustomDate `json:"startDate" validate:"required"`
-	EndDate      *utils.CustomDate `json:"endDate" validate:"required"`
-	Categories   []int             `json:"categories"`
-	Comment      *string           `json:"comment"`
+	Name                  string            `json:"name" validate:"required"`
+	CurrencyID            int               `json:"currencyId" validate:"required"`
+	TargetAmount          decimal.Decimal   `json:"targetAmount" validate:"required"`
+	Period                string            `json:"period" validate:"required"`
+	Repeat                bool              `json:"repeat"`
+	StartDate             *utils.CustomDate `json:"startDate" validate:"required"`
+	EndDate               *utils.CustomDate `json:"endDate" validate:"required"`
+	Categories            []int             `json:"categories"`
+	ExcludedCategories    []int             `json:"excludedCategories"`
+	Accounts              []int             `json:"accounts"`
+	IncludedTags          []string          `json:"includedTags"`
+	ExcludedTags          []string          `json:"excludedTags"`
+	AnchorDay             *int              `json:"anchorDay"`
+	AnchorLastBusinessDay bool              `json:"anchorLastBusinessDay"`
+	WeekStartDay          *int              `json:"weekStartDay"`
+	ForecastCurve         string            `json:"forecastCurve"`
+	AlertThresholds       []int             `json:"alertThresholds"`
+	AlertOverBudget       bool              `json:"alertOverBudget"`
+	Comment               *string           `json:"comment"`
 }
 
 type UpdateBudgetDTO struct {
-	ID           int               `json:"id" validate:"required"`
-	Name         string            `json:"name" validate:"required"`
-	CurrencyID   int               `json:"currencyId" validate:"required"`
-	TargetAmount decimal.Decimal   `json:"targetAmount" validate:"required"`
-	Period       string            `json:"period" validate:"required"`
-	Repeat       bool              `json:"repeat"`
-	StartDate    *utils.CustomDate `json:"startDate" validate:"required"`
-	EndDate      *utils.CustomDate `json:"endDate" validate:"required"`
-	Categories   []int             `json:"categories"`
-	Comment      *string           `json:"comment"`
+	ID                    int               `json:"id" validate:"required"`
+	Name                  string            `json:"name" validate:"required"`
+	CurrencyID            int               `json:"currencyId" validate:"required"`
+	TargetAmount          decimal.Decimal   `json:"targetAmount" validate:"required"`
+	Period                string            `json:"period" validate:"required"`
+	Repeat                bool              `json:"repeat"`
+	StartDate             *utils.CustomDate `json:"startDate" validate:"required"`
+	EndDate               *utils.CustomDate `json:"endDate" validate:"required"`
+	Categories            []int             `json:"categories"`
+	ExcludedCategories    []int             `json:"excludedCategories"`
+	Accounts              []int             `json:"accounts"`
+	IncludedTags          []string          `json:"includedTags"`
+	ExcludedTags          []string          `json:"excludedTags"`
+	AnchorDay             *int              `json:"anchorDay"`
+	AnchorLastBusinessDay bool              `json:"anchorLastBusinessDay"`
+	WeekStartDay          *int              `json:"weekStartDay"`
+	ForecastCurve         string            `json:"forecastCurve"`
+	AlertThresholds       []int             `json:"alertThresholds"`
+	AlertOverBudget       bool              `json:"alertOverBudget"`
+	Comment               *string           `json:"comment"`
 }
 
 type BudgetResponseDTO struct {
-	ID                 int             `json:"id"`
-	Name               string          `json:"name"`
-	CurrencyID         int             `json:"currencyId"`
-	TargetAmount       decimal.Decimal `json:"targetAmount"`
-	CollectedAmount    decimal.Decimal `json:"collectedAmount"`
-	Period             string          `json:"period"`
-	Repeat             bool            `json:"repeat"`
-	StartDate          *time.Time      `json:"startDate"`
-	EndDate            *time.Time      `json:"endDate"`
-	IncludedCategories string          `json:"includedCategories"`
-	Comment            *string         `json:"comment"`
-	IsArchived         bool            `json:"isArchived"`
-	Currency           models.Currency `json:"currency"`
+	ID                    int                `json:"id"`
+	SeriesID              int                `json:"seriesId"`
+	Name                  string             `json:"name"`
+	CurrencyID            int                `json:"currencyId"`
+	TargetAmount          decimal.Decimal    `json:"targetAmount"`
+	CollectedAmount       decimal.Decimal    `json:"collectedAmount"`
+	Period                string             `json:"period"`
+	Repeat                bool               `json:"repeat"`
+	StartDate             *time.Time         `json:"startDate"`
+	EndDate               *time.Time         `json:"endDate"`
+	IncludedCategories    string             `json:"includedCategories"`
+	ExcludedCategories    []int              `json:"excludedCategories"`
+	Accounts              []int              `json:"accounts"`
+	IncludedTags          []string           `json:"includedTags"`
+	ExcludedTags          []string           `json:"excludedTags"`
+	AnchorDay             *int               `json:"anchorDay"`
+	AnchorLastBusinessDay bool               `json:"anchorLastBusinessDay"`
+	WeekStartDay          *int               `json:"weekStartDay"`
+	ForecastCurve         string             `json:"forecastCurve"`
+	AlertThresholds       []int              `json:"alertThresholds"`
+	AlertOverBudget       bool               `json:"alertOverBudget"`
+	Comment               *string            `json:"comment"`
+	IsArchived            bool               `json:"isArchived"`
+	Currency              models.Currency    `json:"currency"`
+	Forecast              *BudgetForecastDTO `json:"forecast"`
+}
+
+// MarshalMoney writes the amounts and the forecast in the currency of the budget
+func (b BudgetResponseDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias BudgetResponseDTO
+	var forecast interface{}
+	if b.Forecast != nil {
+		forecast = b.Forecast.marshalMoney(e, b.Currency.Code)
+	}
+
+	return &struct {
+		TargetAmount    interface{} `json:"targetAmount"`
+		CollectedAmount interface{} `json:"collectedAmount"`
+		Forecast        interface{} `json:"forecast"`
+		*Alias
+	}{
+		TargetAmount:    e.Amount(b.TargetAmount, b.Currency.Code),
+		CollectedAmount: e.Amount(b.CollectedAmount, b.Currency.Code),
+		Forecast:        forecast,
+		Alias:           (*Alias)(&b),
+	}
+}
+
+// BudgetForecastDTO describes the spending pace of an active budget period
+type BudgetForecastDTO struct {
+	Curve              string          `json:"curve"`
+	ExpectedToDate     decimal.Decimal `json:"expectedToDate"`
+	ProjectedTotal     decimal.Decimal `json:"projectedTotal"`
+	SafeDailyAllowance decimal.Decimal `json:"safeDailyAllowance"`
+	DaysElapsed        int             `json:"daysElapsed"`
+	DaysRemaining      int             `json:"daysRemaining"`
+	Status             string          `json:"status"`
+}
+
+func (f BudgetForecastDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias BudgetForecastDTO
+	return &struct {
+		ExpectedToDate     interface{} `json:"expectedToDate"`
+		ProjectedTotal     interface{} `json:"projectedTotal"`
+		SafeDailyAllowance interface{} `json:"safeDailyAllowance"`
+		*Alias
+	}{
+		ExpectedToDate:     e.Amount(f.ExpectedToDate, currencyCode),
+		ProjectedTotal:     e.Amount(f.ProjectedTotal, currencyCode),
+		SafeDailyAllowance: e.Amount(f.SafeDailyAllowance, currencyCode),
+		Alias:              (*Alias)(&f),
+	}
 }
 
 type BudgetListFilters struct {
 	Include string `query:"include"`
 }
+
+// BudgetHistoryPeriodDTO represents a single period of a budget series
+type BudgetHistoryPeriodDTO struct {
+	BudgetID     int             `json:"budgetId"`
+	StartDate    *time.Time      `json:"startDate"`
+	EndDate      *time.Time      `json:"endDate"`
+	TargetAmount decimal.Decimal `json:"targetAmount"`
+	ActualAmount decimal.Decimal `json:"actualAmount"`
+	Difference   decimal.Decimal `json:"difference"`
+	PercentUsed  decimal.Decimal `json:"percentUsed"`
+}
+
+func (p BudgetHistoryPeriodDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias BudgetHistoryPeriodDTO
+	return &struct {
+		TargetAmount interface{} `json:"targetAmount"`
+		ActualAmount interface{} `json:"actualAmount"`
+		Difference   interface{} `json:"difference"`
+		*Alias
+	}{
+		TargetAmount: e.Amount(p.TargetAmount, currencyCode),
+		ActualAmount: e.Amount(p.ActualAmount, currencyCode),
+		Difference:   e.Amount(p.Difference, currencyCode),
+		Alias:        (*Alias)(&p),
+	}
+}
+
+// BudgetHistoryDTO represents target against actual for past periods of a budget series
+type BudgetHistoryDTO struct {
+	SeriesID      int                      `json:"seriesId"`
+	Name          string                   `json:"name"`
+	Period        string                   `json:"period"`
+	Currency      models.Currency          `json:"currency"`
+	Periods       []BudgetHistoryPeriodDTO `json:"periods"`
+	CurrentPeriod *BudgetHistoryPeriodDTO  `json:"currentPeriod"`
+	AverageTarget decimal.Decimal          `json:"averageTarget"`
+	AverageActual decimal.Decimal          `json:"averageActual"`
+	Trend         string                   `json:"trend"`
+	TrendSlope    decimal.Decimal          `json:"trendSlope"`
+}
+
+// MarshalMoney writes the amounts of the periods in the currency of the budget
+func (h BudgetHistoryDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias BudgetHistoryDTO
+	var periods []interface{}
+	if h.Periods != nil {
+		periods = make([]interface{}, len(h.Periods))
+		for i, period := range h.Periods {
+			periods[i] = period.marshalMoney(e, h.Currency.Code)
+		}
+	}
+	var currentPeriod interface{}
+	if h.CurrentPeriod != nil {
+		currentPeriod = h.CurrentPeriod.marshalMoney(e, h.Currency.Code)
+	}
+
+	return &struct {
+		Periods       []interface{} `json:"periods"`
+		CurrentPeriod interface{}   `json:"currentPeriod"`
+		AverageTarget interface{}   `json:"averageTarget"`
+		AverageActual interface{}   `json:"averageActual"`
+		TrendSlope    interface{}   `json:"trendSlope"`
+		*Alias
+	}{
+		Periods:       periods,
+		CurrentPeriod: currentPeriod,
+		AverageTarget: e.Amount(h.AverageTarget, h.Currency.Code),
+		AverageActual: e.Amount(h.AverageActual, h.Currency.Code),
+		TrendSlope:    e.Amount(h.TrendSlope, h.Currency.Code),
+		Alias:         (*Alias)(&h),
+	}
+}
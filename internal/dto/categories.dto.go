@@ -1,5 +1,7 @@
 package dto
 
+import "ypeskov/budget-go/internal/models"
+
 type CategoryDTO struct {
 	ID         *int    `json:"id" db:"id"`
 	Name       *string `json:"name" db:"name"`
@@ -10,13 +12,46 @@ type CategoryDTO struct {
 	IsDeleted  *bool   `json:"isDeleted" db:"is_deleted"`
 	CreatedAt  *string `json:"createdAt" db:"created_at"`
 	UpdatedAt  *string `json:"updatedAt" db:"updated_at"`
+	// Color and Icon are the values in effect, InheritsColor and InheritsIcon tell they come from the parent or defaults
+	Color         string `json:"color" db:"-"`
+	Icon          string `json:"icon" db:"-"`
+	InheritsColor bool   `json:"inheritsColor" db:"-"`
+	InheritsIcon  bool   `json:"inheritsIcon" db:"-"`
 }
 
 type CreateCategoryDTO struct {
-	Name      string `json:"name"`
-	IsIncome  bool   `json:"isIncome"`
-	ParentID  *int   `json:"parentId"`
-	IsDeleted bool   `json:"isDeleted"`
+	Name      string  `json:"name"`
+	IsIncome  bool    `json:"isIncome"`
+	ParentID  *int    `json:"parentId"`
+	IsDeleted bool    `json:"isDeleted"`
+	Color     *string `json:"color"`
+	Icon      *string `json:"icon"`
+}
+
+type UpdateCategoryDTO struct {
+	Name     string  `json:"name"`
+	ParentID *int    `json:"parentId"`
+	Color    *string `json:"color"` // nil inherits the parent color
+	Icon     *string `json:"icon"`  // nil inherits the parent icon
+}
+
+type MergeCategoryDTO struct {
+	TargetID int `json:"targetId"`
+}
+
+// CategoriesExportVersion is the version of the category export format
+const CategoriesExportVersion = 1
+
+// CategoriesExportDTO is the document produced by the category export and accepted by the import
+type CategoriesExportDTO struct {
+	Version    int                   `json:"version"`
+	Categories []models.CategoryNode `json:"categories"`
+}
+
+type CategoryTreeResultDTO struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Deleted  int `json:"deleted"`
 }
 
 type CategoryDetailDTO struct {
@@ -1,4 +1,76 @@
 package dto
 
+import (
+	"ypeskov/budget-go/internal/money"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
 // CurrencyDTO has been consolidated with models.Currency
 // Use models.Currency directly for all currency operations
+
+// CurrencyConversionDTO is an amount converted with the latest rates on or before Date, RateDate is
+// the date of the rates that were used
+type CurrencyConversionDTO struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Amount   decimal.Decimal `json:"amount"`
+	Result   decimal.Decimal `json:"result"`
+	Rate     decimal.Decimal `json:"rate"`
+	Date     string          `json:"date"`
+	RateDate string          `json:"rateDate"`
+}
+
+func (c CurrencyConversionDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias CurrencyConversionDTO
+	return &struct {
+		Amount interface{} `json:"amount"`
+		Result interface{} `json:"result"`
+		*Alias
+	}{
+		Amount: e.Amount(c.Amount, c.From),
+		Result: e.Amount(c.Result, c.To),
+		Alias:  (*Alias)(&c),
+	}
+}
+
+// RateHistoryDTO is the daily rates of the symbols against the base currency
+type RateHistoryDTO struct {
+	Base      string                `json:"base"`
+	StartDate string                `json:"startDate"`
+	EndDate   string                `json:"endDate"`
+	Points    []RateHistoryPointDTO `json:"points"`
+}
+
+// RateHistoryPointDTO has the rates of a day, ActualDate is the date of the stored rates used for it,
+// an earlier one for days without rates like weekends. Symbols without a rate on the day are left out.
+type RateHistoryPointDTO struct {
+	Date       string                     `json:"date"`
+	ActualDate string                     `json:"actualDate"`
+	Rates      map[string]decimal.Decimal `json:"rates"`
+}
+
+// CurrencyInputDTO defines a private currency or asset of a user. The code cannot be changed later,
+// DecimalPlaces defaults to the minor unit of the code, 2 for codes that are not ISO 4217 ones.
+type CurrencyInputDTO struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	DecimalPlaces *int   `json:"decimalPlaces"`
+}
+
+// UserExchangeRateInputDTO is a rate of a pair for a day, the amount of To one unit of From buys
+type UserExchangeRateInputDTO struct {
+	Date utils.CustomDate `json:"date"`
+	From string           `json:"from"`
+	To   string           `json:"to"`
+	Rate decimal.Decimal  `json:"rate"`
+}
+
+type UserExchangeRateDTO struct {
+	ID   int             `json:"id"`
+	Date string          `json:"date"`
+	From string          `json:"from"`
+	To   string          `json:"to"`
+	Rate decimal.Decimal `json:"rate"`
+}
@@ -0,0 +1,102 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+	"ypeskov/budget-go/internal/money"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// CustomReportFilterDTO selects the transactions of a custom report, empty fields don't filter.
+// Both dates are inclusive and required to run the report, saved definitions may leave them out
+// and get them when they are run.
+type CustomReportFilterDTO struct {
+	StartDate  *utils.CustomDate `json:"startDate"`
+	EndDate    *utils.CustomDate `json:"endDate"`
+	Accounts   []int             `json:"accounts"`
+	Categories []int             `json:"categories"` // subcategories of the listed categories are included
+	Type       string            `json:"type"`       // income, expense or empty for both
+	Currencies []string          `json:"currencies"` // account currency codes
+}
+
+// CustomReportInputDTO defines a custom report: the transactions to aggregate, one or two dimensions
+// to group them by and the measure to compute per group
+type CustomReportInputDTO struct {
+	Filter  CustomReportFilterDTO `json:"filter"`
+	GroupBy []string              `json:"groupBy"`
+	Measure string                `json:"measure"`
+}
+
+// CustomReportGroupDTO is a value of a group-by dimension
+type CustomReportGroupDTO struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// CustomReportOutputDTO is a pivot table: Values[i][j] is the measure of the transactions in row i and column j,
+// null when there are none. With a single dimension Columns is empty and every row has one value.
+// Totals are computed from the transactions, so the total of averages is the average of all transactions.
+type CustomReportOutputDTO struct {
+	GroupBy      []string               `json:"groupBy"`
+	Measure      string                 `json:"measure"`
+	CurrencyCode string                 `json:"currencyCode"`
+	Rows         []CustomReportGroupDTO `json:"rows"`
+	Columns      []CustomReportGroupDTO `json:"columns"`
+	Values       [][]*decimal.Decimal   `json:"values"`
+	RowTotals    []decimal.Decimal      `json:"rowTotals"`
+	ColumnTotals []decimal.Decimal      `json:"columnTotals"`
+	Total        decimal.Decimal        `json:"total"`
+	Count        int                    `json:"count"`
+	// ValueCurrency is the currency of the values in the precise money formats, "-" for counts that are not money
+	ValueCurrency string `json:"-"`
+}
+
+func (r CustomReportOutputDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.MarshalMoney(money.Numbers))
+}
+
+func (r CustomReportOutputDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias CustomReportOutputDTO
+	var values [][]interface{}
+	if r.Values != nil {
+		values = make([][]interface{}, len(r.Values))
+		for i, row := range r.Values {
+			if row == nil {
+				continue
+			}
+			values[i] = make([]interface{}, len(row))
+			for j, value := range row {
+				values[i][j] = e.AmountOf(value, r.ValueCurrency)
+			}
+		}
+	}
+
+	return &struct {
+		Values       [][]interface{} `json:"values"`
+		RowTotals    []interface{}   `json:"rowTotals"`
+		ColumnTotals []interface{}   `json:"columnTotals"`
+		Total        interface{}     `json:"total"`
+		*Alias
+	}{
+		Values:       values,
+		RowTotals:    e.Amounts(r.RowTotals, r.ValueCurrency),
+		ColumnTotals: e.Amounts(r.ColumnTotals, r.ValueCurrency),
+		Total:        e.Amount(r.Total, r.ValueCurrency),
+		Alias:        (*Alias)(&r),
+	}
+}
+
+type SaveReportDefinitionDTO struct {
+	Name       string               `json:"name" validate:"required"`
+	Definition CustomReportInputDTO `json:"definition"`
+}
+
+type ReportDefinitionDTO struct {
+	ID         int                  `json:"id"`
+	Name       string               `json:"name"`
+	Definition CustomReportInputDTO `json:"definition"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	UpdatedAt  time.Time            `json:"updatedAt"`
+}
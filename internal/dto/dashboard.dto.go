@@ -0,0 +1,175 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+	"ypeskov/budget-go/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// DashboardDTO holds the key figures of the home screen, amounts are in the base currency
+type DashboardDTO struct {
+	CurrencyCode      string               `json:"currencyCode"`
+	GeneratedAt       time.Time            `json:"generatedAt"`
+	NetWorth          DashboardNetWorthDTO `json:"netWorth"`
+	MonthToDate       DashboardMonthDTO    `json:"monthToDate"`
+	Budgets           DashboardBudgetsDTO  `json:"budgets"`
+	Runway            DashboardRunwayDTO   `json:"runway"`
+	CreditUtilization DashboardCreditDTO   `json:"creditUtilization"`
+	UpcomingCharges   []UpcomingChargeDTO  `json:"upcomingCharges"`
+}
+
+func (d DashboardDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.MarshalMoney(money.Numbers))
+}
+
+// MarshalMoney writes the amounts in the base currency of the dashboard, upcoming charges have their
+// amount in the currency of the account
+func (d DashboardDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias DashboardDTO
+	var charges []interface{}
+	if d.UpcomingCharges != nil {
+		charges = make([]interface{}, len(d.UpcomingCharges))
+		for i, charge := range d.UpcomingCharges {
+			charges[i] = charge.marshalMoney(e, d.CurrencyCode)
+		}
+	}
+
+	return &struct {
+		NetWorth          interface{}   `json:"netWorth"`
+		MonthToDate       interface{}   `json:"monthToDate"`
+		Runway            interface{}   `json:"runway"`
+		CreditUtilization interface{}   `json:"creditUtilization"`
+		UpcomingCharges   []interface{} `json:"upcomingCharges"`
+		*Alias
+	}{
+		NetWorth:          d.NetWorth.marshalMoney(e, d.CurrencyCode),
+		MonthToDate:       d.MonthToDate.marshalMoney(e, d.CurrencyCode),
+		Runway:            d.Runway.marshalMoney(e, d.CurrencyCode),
+		CreditUtilization: d.CreditUtilization.marshalMoney(e, d.CurrencyCode),
+		UpcomingCharges:   charges,
+		Alias:             (*Alias)(&d),
+	}
+}
+
+type DashboardNetWorthDTO struct {
+	Total       decimal.Decimal `json:"total"`
+	Assets      decimal.Decimal `json:"assets"`
+	Liabilities decimal.Decimal `json:"liabilities"`
+}
+
+func (n DashboardNetWorthDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	return &struct {
+		Total       interface{} `json:"total"`
+		Assets      interface{} `json:"assets"`
+		Liabilities interface{} `json:"liabilities"`
+	}{
+		Total:       e.Amount(n.Total, currencyCode),
+		Assets:      e.Amount(n.Assets, currencyCode),
+		Liabilities: e.Amount(n.Liabilities, currencyCode),
+	}
+}
+
+// DashboardMonthDTO is the income and expenses from the start of the month, the savings rate
+// is the share of income not spent in percent and nil without income
+type DashboardMonthDTO struct {
+	StartDate   time.Time       `json:"startDate"`
+	EndDate     time.Time       `json:"endDate"`
+	Income      decimal.Decimal `json:"income"`
+	Expenses    decimal.Decimal `json:"expenses"`
+	Net         decimal.Decimal `json:"net"`
+	SavingsRate *float64        `json:"savingsRate"`
+}
+
+func (m DashboardMonthDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias DashboardMonthDTO
+	return &struct {
+		Income   interface{} `json:"income"`
+		Expenses interface{} `json:"expenses"`
+		Net      interface{} `json:"net"`
+		*Alias
+	}{
+		Income:   e.Amount(m.Income, currencyCode),
+		Expenses: e.Amount(m.Expenses, currencyCode),
+		Net:      e.Amount(m.Net, currencyCode),
+		Alias:    (*Alias)(&m),
+	}
+}
+
+// DashboardBudgetsDTO counts the active budgets by the status of their forecast
+type DashboardBudgetsDTO struct {
+	Total   int `json:"total"`
+	OnTrack int `json:"onTrack"`
+	AtRisk  int `json:"atRisk"`
+	Over    int `json:"over"`
+}
+
+// DashboardRunwayDTO is how many months the liquid assets cover at the average monthly expenses
+// of the last full months, Months is nil without expenses
+type DashboardRunwayDTO struct {
+	LiquidAssets           decimal.Decimal `json:"liquidAssets"`
+	AverageMonthlyExpenses decimal.Decimal `json:"averageMonthlyExpenses"`
+	MonthsAveraged         int             `json:"monthsAveraged"`
+	Months                 *float64        `json:"months"`
+}
+
+func (r DashboardRunwayDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias DashboardRunwayDTO
+	return &struct {
+		LiquidAssets           interface{} `json:"liquidAssets"`
+		AverageMonthlyExpenses interface{} `json:"averageMonthlyExpenses"`
+		*Alias
+	}{
+		LiquidAssets:           e.Amount(r.LiquidAssets, currencyCode),
+		AverageMonthlyExpenses: e.Amount(r.AverageMonthlyExpenses, currencyCode),
+		Alias:                  (*Alias)(&r),
+	}
+}
+
+// DashboardCreditDTO is the debt on credit accounts against their limits, Percent is nil without limits
+type DashboardCreditDTO struct {
+	Used    decimal.Decimal `json:"used"`
+	Limit   decimal.Decimal `json:"limit"`
+	Percent *float64        `json:"percent"`
+}
+
+func (c DashboardCreditDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias DashboardCreditDTO
+	return &struct {
+		Used  interface{} `json:"used"`
+		Limit interface{} `json:"limit"`
+		*Alias
+	}{
+		Used:  e.Amount(c.Used, currencyCode),
+		Limit: e.Amount(c.Limit, currencyCode),
+		Alias: (*Alias)(&c),
+	}
+}
+
+// UpcomingChargeDTO is the expected next charge of a recurring payee, Amount is in the account currency
+// and BaseAmount in the base currency of the dashboard
+type UpcomingChargeDTO struct {
+	Payee        string          `json:"payee"`
+	CategoryID   *int            `json:"categoryId"`
+	CategoryName *string         `json:"categoryName"`
+	AccountID    int             `json:"accountId"`
+	Amount       decimal.Decimal `json:"amount"`
+	CurrencyCode string          `json:"currencyCode"`
+	BaseAmount   decimal.Decimal `json:"baseAmount"`
+	ExpectedDate time.Time       `json:"expectedDate"`
+	IntervalDays int             `json:"intervalDays"`
+}
+
+func (u UpcomingChargeDTO) marshalMoney(e money.Encoder, baseCurrencyCode string) interface{} {
+	type Alias UpcomingChargeDTO
+	return &struct {
+		Amount     interface{} `json:"amount"`
+		BaseAmount interface{} `json:"baseAmount"`
+		*Alias
+	}{
+		Amount:     e.Amount(u.Amount, u.CurrencyCode),
+		BaseAmount: e.Amount(u.BaseAmount, baseCurrencyCode),
+		Alias:      (*Alias)(&u),
+	}
+}
@@ -0,0 +1,91 @@
+package dto
+
+import (
+	"ypeskov/budget-go/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+type EnvelopeAssignDTO struct {
+	Month      string          `json:"month" validate:"required"` // YYYY-MM
+	CategoryID int             `json:"categoryId"`
+	Amount     decimal.Decimal `json:"amount" validate:"required"`
+}
+
+type EnvelopeMoveDTO struct {
+	Month          string          `json:"month" validate:"required"` // YYYY-MM
+	FromCategoryID int             `json:"fromCategoryId" validate:"required"`
+	ToCategoryID   int             `json:"toCategoryId" validate:"required"`
+	Amount         decimal.Decimal `json:"amount" validate:"required"`
+}
+
+type EnvelopeDTO struct {
+	CategoryID  int             `json:"categoryId"`
+	Name        string          `json:"name"`
+	ParentID    *int            `json:"parentId"`
+	CarriedOver decimal.Decimal `json:"carriedOver"`
+	Assigned    decimal.Decimal `json:"assigned"`
+	Spent       decimal.Decimal `json:"spent"`
+	Available   decimal.Decimal `json:"available"`
+	Children    []EnvelopeDTO   `json:"children"`
+}
+
+func (d EnvelopeDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias EnvelopeDTO
+	return &struct {
+		CarriedOver interface{}   `json:"carriedOver"`
+		Assigned    interface{}   `json:"assigned"`
+		Spent       interface{}   `json:"spent"`
+		Available   interface{}   `json:"available"`
+		Children    []interface{} `json:"children"`
+		*Alias
+	}{
+		CarriedOver: e.Amount(d.CarriedOver, currencyCode),
+		Assigned:    e.Amount(d.Assigned, currencyCode),
+		Spent:       e.Amount(d.Spent, currencyCode),
+		Available:   e.Amount(d.Available, currencyCode),
+		Children:    envelopes(e, d.Children, currencyCode),
+		Alias:       (*Alias)(&d),
+	}
+}
+
+func envelopes(e money.Encoder, items []EnvelopeDTO, currencyCode string) []interface{} {
+	if items == nil {
+		return nil
+	}
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item.marshalMoney(e, currencyCode)
+	}
+	return values
+}
+
+type EnvelopeMonthDTO struct {
+	Month         string          `json:"month"`
+	Currency      string          `json:"currency"`
+	Income        decimal.Decimal `json:"income"`
+	TotalAssigned decimal.Decimal `json:"totalAssigned"`
+	TotalSpent    decimal.Decimal `json:"totalSpent"`
+	ToBeBudgeted  decimal.Decimal `json:"toBeBudgeted"`
+	Envelopes     []EnvelopeDTO   `json:"envelopes"`
+}
+
+// MarshalMoney writes the amounts of the month and of the envelopes in the currency of the month
+func (m EnvelopeMonthDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias EnvelopeMonthDTO
+	return &struct {
+		Income        interface{}   `json:"income"`
+		TotalAssigned interface{}   `json:"totalAssigned"`
+		TotalSpent    interface{}   `json:"totalSpent"`
+		ToBeBudgeted  interface{}   `json:"toBeBudgeted"`
+		Envelopes     []interface{} `json:"envelopes"`
+		*Alias
+	}{
+		Income:        e.Amount(m.Income, m.Currency),
+		TotalAssigned: e.Amount(m.TotalAssigned, m.Currency),
+		TotalSpent:    e.Amount(m.TotalSpent, m.Currency),
+		ToBeBudgeted:  e.Amount(m.ToBeBudgeted, m.Currency),
+		Envelopes:     envelopes(e, m.Envelopes, m.Currency),
+		Alias:         (*Alias)(&m),
+	}
+}
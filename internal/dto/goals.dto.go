@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"time"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/money"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+type CreateGoalDTO struct {
+	Name           string            `json:"name" validate:"required"`
+	TargetAmount   decimal.Decimal   `json:"targetAmount" validate:"required"`
+	CurrencyID     int               `json:"currencyId" validate:"required"`
+	TargetDate     *utils.CustomDate `json:"targetDate"`
+	ProgressSource string            `json:"progressSource"`
+	AccountIDs     []int             `json:"accountIds"`
+	Comment        *string           `json:"comment"`
+}
+
+type UpdateGoalDTO struct {
+	ID             int               `json:"id"`
+	Name           string            `json:"name" validate:"required"`
+	TargetAmount   decimal.Decimal   `json:"targetAmount" validate:"required"`
+	CurrencyID     int               `json:"currencyId" validate:"required"`
+	TargetDate     *utils.CustomDate `json:"targetDate"`
+	ProgressSource string            `json:"progressSource"`
+	AccountIDs     []int             `json:"accountIds"`
+	Comment        *string           `json:"comment"`
+	IsArchived     bool              `json:"isArchived"`
+}
+
+type GoalContributionDTO struct {
+	TransactionID int `json:"transactionId" validate:"required"`
+}
+
+type GoalResponseDTO struct {
+	ID                          int                       `json:"id"`
+	Name                        string                    `json:"name"`
+	TargetAmount                decimal.Decimal           `json:"targetAmount"`
+	CurrentAmount               decimal.Decimal           `json:"currentAmount"`
+	RemainingAmount             decimal.Decimal           `json:"remainingAmount"`
+	ProgressPercent             decimal.Decimal           `json:"progressPercent"`
+	TargetDate                  *time.Time                `json:"targetDate"`
+	ProgressSource              string                    `json:"progressSource"`
+	RequiredMonthlyContribution *decimal.Decimal          `json:"requiredMonthlyContribution"`
+	RecalculatedAt              *time.Time                `json:"recalculatedAt"`
+	AccountIDs                  []int                     `json:"accountIds"`
+	Contributions               []models.GoalContribution `json:"contributions"`
+	Comment                     *string                   `json:"comment"`
+	IsArchived                  bool                      `json:"isArchived"`
+	Currency                    models.Currency           `json:"currency"`
+}
+
+// MarshalMoney writes the amounts in the currency of the goal and the contributions in the currencies
+// of their accounts
+func (g GoalResponseDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias GoalResponseDTO
+	return &struct {
+		TargetAmount                interface{} `json:"targetAmount"`
+		CurrentAmount               interface{} `json:"currentAmount"`
+		RemainingAmount             interface{} `json:"remainingAmount"`
+		RequiredMonthlyContribution interface{} `json:"requiredMonthlyContribution"`
+		Contributions               interface{} `json:"contributions"`
+		*Alias
+	}{
+		TargetAmount:                e.Amount(g.TargetAmount, g.Currency.Code),
+		CurrentAmount:               e.Amount(g.CurrentAmount, g.Currency.Code),
+		RemainingAmount:             e.Amount(g.RemainingAmount, g.Currency.Code),
+		RequiredMonthlyContribution: e.AmountOf(g.RequiredMonthlyContribution, g.Currency.Code),
+		Contributions:               e.Value(g.Contributions),
+		Alias:                       (*Alias)(&g),
+	}
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+	"ypeskov/budget-go/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// InsightResponseDTO is an insight of the spending analysis, the amount is in the base currency
+type InsightResponseDTO struct {
+	ID            int              `json:"id"`
+	Type          string           `json:"type"`
+	Severity      string           `json:"severity"`
+	Title         string           `json:"title"`
+	Message       string           `json:"message"`
+	CategoryID    *int             `json:"categoryId"`
+	TransactionID *int             `json:"transactionId"`
+	Amount        *decimal.Decimal `json:"amount"`
+	CurrencyCode  string           `json:"currencyCode"`
+	Details       json.RawMessage  `json:"details"`
+	DetectedAt    time.Time        `json:"detectedAt"`
+	IsDismissed   bool             `json:"isDismissed"`
+	DismissedAt   *time.Time       `json:"dismissedAt"`
+}
+
+func (i InsightResponseDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias InsightResponseDTO
+	return &struct {
+		Amount interface{} `json:"amount"`
+		*Alias
+	}{
+		Amount: e.AmountOf(i.Amount, i.CurrencyCode),
+		Alias:  (*Alias)(&i),
+	}
+}
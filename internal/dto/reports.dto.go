@@ -1,22 +1,49 @@
 package dto
 
 import (
+	"encoding/json"
+	"time"
+
+	"ypeskov/budget-go/internal/money"
 	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
 )
 
 // CashFlowReportInputDTO represents input for cash flow report
 type CashFlowReportInputDTO struct {
-	StartDate *utils.CustomDate `json:"startDate"`
-	EndDate   *utils.CustomDate `json:"endDate"`
-	Period    string            `json:"period" binding:"required"`
+	StartDate             *utils.CustomDate `json:"startDate"`
+	EndDate               *utils.CustomDate `json:"endDate"`
+	Period                string            `json:"period" binding:"required"`
+	AnchorDay             *int              `json:"anchorDay"`
+	AnchorLastBusinessDay bool              `json:"anchorLastBusinessDay"`
+	WeekStartDay          *int              `json:"weekStartDay"`
 }
 
-// CashFlowReportOutputDTO represents cash flow report output
+// CashFlowReportOutputDTO represents cash flow report output, amounts are in the base currency
 type CashFlowReportOutputDTO struct {
-	Currency      string             `json:"currency"`
-	TotalIncome   map[string]float64 `json:"totalIncome"`
-	TotalExpenses map[string]float64 `json:"totalExpenses"`
-	NetFlow       map[string]float64 `json:"netFlow"`
+	Currency      string                     `json:"currency"`
+	TotalIncome   map[string]decimal.Decimal `json:"totalIncome"`
+	TotalExpenses map[string]decimal.Decimal `json:"totalExpenses"`
+	NetFlow       map[string]decimal.Decimal `json:"netFlow"`
+}
+
+func (c *CashFlowReportOutputDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.MarshalMoney(money.Numbers))
+}
+
+func (c CashFlowReportOutputDTO) MarshalMoney(e money.Encoder) interface{} {
+	return &struct {
+		Currency      string                 `json:"currency"`
+		TotalIncome   map[string]interface{} `json:"totalIncome"`
+		TotalExpenses map[string]interface{} `json:"totalExpenses"`
+		NetFlow       map[string]interface{} `json:"netFlow"`
+	}{
+		Currency:      c.Currency,
+		TotalIncome:   e.AmountMap(c.TotalIncome, c.Currency),
+		TotalExpenses: e.AmountMap(c.TotalExpenses, c.Currency),
+		NetFlow:       e.AmountMap(c.NetFlow, c.Currency),
+	}
 }
 
 // BalanceReportInputDTO represents input for balance report
@@ -27,13 +54,30 @@ type BalanceReportInputDTO struct {
 
 // BalanceReportOutputDTO represents balance report output
 type BalanceReportOutputDTO struct {
-	AccountID           int     `json:"accountId" db:"account_id"`
-	AccountName         string  `json:"accountName" db:"account_name"`
-	CurrencyCode        string  `json:"currencyCode" db:"currency_code"`
-	Balance             float64 `json:"balance" db:"balance"`
-	BaseCurrencyBalance float64 `json:"baseCurrencyBalance" db:"base_currency_balance"`
-	BaseCurrencyCode    string  `json:"baseCurrencyCode" db:"base_currency_code"`
-	ReportDate          string  `json:"reportDate" db:"report_date"`
+	AccountID           int             `json:"accountId" db:"account_id"`
+	AccountName         string          `json:"accountName" db:"account_name"`
+	CurrencyCode        string          `json:"currencyCode" db:"currency_code"`
+	Balance             decimal.Decimal `json:"balance" db:"balance"`
+	BaseCurrencyBalance decimal.Decimal `json:"baseCurrencyBalance" db:"base_currency_balance"`
+	BaseCurrencyCode    string          `json:"baseCurrencyCode" db:"base_currency_code"`
+	ReportDate          string          `json:"reportDate" db:"report_date"`
+}
+
+func (b *BalanceReportOutputDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.MarshalMoney(money.Numbers))
+}
+
+func (b BalanceReportOutputDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias BalanceReportOutputDTO
+	return &struct {
+		Balance             interface{} `json:"balance"`
+		BaseCurrencyBalance interface{} `json:"baseCurrencyBalance"`
+		*Alias
+	}{
+		Balance:             e.Amount(b.Balance, b.CurrencyCode),
+		BaseCurrencyBalance: e.Amount(b.BaseCurrencyBalance, b.BaseCurrencyCode),
+		Alias:               (*Alias)(&b),
+	}
 }
 
 // ExpensesReportInputDTO represents input for expenses report
@@ -46,20 +90,116 @@ type ExpensesReportInputDTO struct {
 
 // ExpensesReportOutputItemDTO represents an item in expenses report
 type ExpensesReportOutputItemDTO struct {
-	ID            int     `json:"id" db:"id"`
-	Name          string  `json:"name" db:"name"`
-	ParentID      *int    `json:"parentId" db:"parent_id"`
-	ParentName    *string `json:"parentName" db:"parent_name"`
-	TotalExpenses float64 `json:"totalExpenses" db:"total_expenses"`
-	CurrencyCode  *string `json:"currencyCode" db:"currency_code"`
-	IsParent      bool    `json:"isParent" db:"is_parent"`
+	ID            int             `json:"id" db:"id"`
+	Name          string          `json:"name" db:"name"`
+	ParentID      *int            `json:"parentId" db:"parent_id"`
+	ParentName    *string         `json:"parentName" db:"parent_name"`
+	TotalExpenses decimal.Decimal `json:"totalExpenses" db:"total_expenses"`
+	CurrencyCode  *string         `json:"currencyCode" db:"currency_code"`
+	IsParent      bool            `json:"isParent" db:"is_parent"`
+	Color         string          `json:"color" db:"-"`
+	Icon          string          `json:"icon" db:"-"`
 }
 
-// ExpensesDiagramDataDTO represents data for expenses diagram
+func (i ExpensesReportOutputItemDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.MarshalMoney(money.Numbers))
+}
+
+func (i ExpensesReportOutputItemDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias ExpensesReportOutputItemDTO
+	return &struct {
+		TotalExpenses interface{} `json:"totalExpenses"`
+		*Alias
+	}{
+		TotalExpenses: e.Amount(i.TotalExpenses, stringValue(i.CurrencyCode)),
+		Alias:         (*Alias)(&i),
+	}
+}
+
+// stringValue returns the string or an empty one for nil
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// IncomeReportInputDTO represents input for income report, it has the same fields as the expenses report input
+type IncomeReportInputDTO = ExpensesReportInputDTO
+
+// IncomeReportOutputItemDTO represents an item in income report
+type IncomeReportOutputItemDTO struct {
+	ID           int             `json:"id"`
+	Name         string          `json:"name"`
+	ParentID     *int            `json:"parentId"`
+	ParentName   *string         `json:"parentName"`
+	TotalIncome  decimal.Decimal `json:"totalIncome"`
+	CurrencyCode *string         `json:"currencyCode"`
+	IsParent     bool            `json:"isParent"`
+	Color        string          `json:"color"`
+	Icon         string          `json:"icon"`
+}
+
+func (i IncomeReportOutputItemDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.MarshalMoney(money.Numbers))
+}
+
+func (i IncomeReportOutputItemDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias IncomeReportOutputItemDTO
+	return &struct {
+		TotalIncome interface{} `json:"totalIncome"`
+		*Alias
+	}{
+		TotalIncome: e.Amount(i.TotalIncome, stringValue(i.CurrencyCode)),
+		Alias:       (*Alias)(&i),
+	}
+}
+
+// IncomeVsExpensesInputDTO represents input for the income vs. expenses by category report
+type IncomeVsExpensesInputDTO struct {
+	StartDate utils.CustomDate `json:"startDate" binding:"required"`
+	EndDate   utils.CustomDate `json:"endDate" binding:"required"`
+}
+
+// IncomeVsExpensesOutputDTO represents income and expenses of a period aggregated by top-level category
+type IncomeVsExpensesOutputDTO struct {
+	CurrencyCode  string                     `json:"currencyCode"`
+	TotalIncome   decimal.Decimal            `json:"totalIncome"`
+	TotalExpenses decimal.Decimal            `json:"totalExpenses"`
+	Net           decimal.Decimal            `json:"net"`
+	Income        []AggregatedDiagramItemDTO `json:"income"`
+	Expenses      []AggregatedDiagramItemDTO `json:"expenses"`
+}
+
+func (r IncomeVsExpensesOutputDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.MarshalMoney(money.Numbers))
+}
+
+func (r IncomeVsExpensesOutputDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias IncomeVsExpensesOutputDTO
+	return &struct {
+		TotalIncome   interface{}   `json:"totalIncome"`
+		TotalExpenses interface{}   `json:"totalExpenses"`
+		Net           interface{}   `json:"net"`
+		Income        []interface{} `json:"income"`
+		Expenses      []interface{} `json:"expenses"`
+		*Alias
+	}{
+		TotalIncome:   e.Amount(r.TotalIncome, r.CurrencyCode),
+		TotalExpenses: e.Amount(r.TotalExpenses, r.CurrencyCode),
+		Net:           e.Amount(r.Net, r.CurrencyCode),
+		Income:        aggregatedItems(e, r.Income, r.CurrencyCode),
+		Expenses:      aggregatedItems(e, r.Expenses, r.CurrencyCode),
+		Alias:         (*Alias)(&r),
+	}
+}
+
+// ExpensesDiagramDataDTO represents data for expenses diagram, the amount is in the base currency
 type ExpensesDiagramDataDTO struct {
-	CategoryName string  `json:"categoryName"`
-	Amount       float64 `json:"amount"`
-	Color        string  `json:"color"`
+	CategoryName string          `json:"categoryName"`
+	Amount       decimal.Decimal `json:"amount"`
+	Color        string          `json:"color"`
+	Icon         string          `json:"icon"`
 }
 
 // ChartImageDTO represents the response for chart generation
@@ -67,9 +207,238 @@ type ChartImageDTO struct {
 	Image string `json:"image"`
 }
 
+// ReportTransactionDTO is a transaction with its amount converted to the base currency
+type ReportTransactionDTO struct {
+	ID               int             `json:"id"`
+	DateTime         time.Time       `json:"dateTime"`
+	Label            string          `json:"label"`
+	CategoryName     string          `json:"categoryName"`
+	IsIncome         bool            `json:"isIncome"`
+	Amount           decimal.Decimal `json:"amount"`
+	CurrencyCode     string          `json:"currencyCode"`
+	BaseAmount       decimal.Decimal `json:"baseAmount"`
+	BaseCurrencyCode string          `json:"baseCurrencyCode"`
+}
+
+func (t ReportTransactionDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.MarshalMoney(money.Numbers))
+}
+
+func (t ReportTransactionDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias ReportTransactionDTO
+	return &struct {
+		Amount     interface{} `json:"amount"`
+		BaseAmount interface{} `json:"baseAmount"`
+		*Alias
+	}{
+		Amount:     e.Amount(t.Amount, t.CurrencyCode),
+		BaseAmount: e.Amount(t.BaseAmount, t.BaseCurrencyCode),
+		Alias:      (*Alias)(&t),
+	}
+}
+
+// BalanceHistoryPointDTO is the balance at the end of BalanceDate
+type BalanceHistoryPointDTO struct {
+	BalanceDate utils.CustomDate `json:"balanceDate"`
+	Balance     decimal.Decimal  `json:"balance"`
+}
+
+func (b *BalanceHistoryPointDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.marshalMoney(money.Numbers, ""))
+}
+
+func (b BalanceHistoryPointDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	return &struct {
+		BalanceDate utils.CustomDate `json:"balanceDate"`
+		Balance     interface{}      `json:"balance"`
+	}{
+		BalanceDate: b.BalanceDate,
+		Balance:     e.Amount(b.Balance, currencyCode),
+	}
+}
+
+// BalanceHistoryDTO is the balance of one account or, without AccountID, the net worth over time
+type BalanceHistoryDTO struct {
+	AccountID    *int                     `json:"accountId"`
+	Name         string                   `json:"name"`
+	CurrencyCode string                   `json:"currencyCode"`
+	Points       []BalanceHistoryPointDTO `json:"points"`
+}
+
+// MarshalMoney writes the balances in the currency of the history
+func (h BalanceHistoryDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias BalanceHistoryDTO
+	var points []interface{}
+	if h.Points != nil {
+		points = make([]interface{}, len(h.Points))
+		for i, point := range h.Points {
+			points[i] = point.marshalMoney(e, h.CurrencyCode)
+		}
+	}
+
+	return &struct {
+		Points []interface{} `json:"points"`
+		*Alias
+	}{
+		Points: points,
+		Alias:  (*Alias)(&h),
+	}
+}
+
 // AggregatedDiagramItemDTO represents aggregated parent-category data for diagrams
 type AggregatedDiagramItemDTO struct {
-	CategoryID int     `json:"category_id"`
-	Label      string  `json:"label"`
-	Amount     float64 `json:"amount"`
+	CategoryID int             `json:"category_id"`
+	Label      string          `json:"label"`
+	Amount     decimal.Decimal `json:"amount"`
+	Color      string          `json:"color"`
+	Icon       string          `json:"icon"`
+}
+
+func (a AggregatedDiagramItemDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.MarshalMoney(money.Numbers))
+}
+
+// MarshalMoney writes the amount without a currency, the diagram endpoints return the items on their own
+// and they are in the base currency of the user
+func (a AggregatedDiagramItemDTO) MarshalMoney(e money.Encoder) interface{} {
+	return a.marshalMoney(e, "")
+}
+
+func (a AggregatedDiagramItemDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias AggregatedDiagramItemDTO
+	return &struct {
+		Amount interface{} `json:"amount"`
+		*Alias
+	}{
+		Amount: e.Amount(a.Amount, currencyCode),
+		Alias:  (*Alias)(&a),
+	}
+}
+
+func aggregatedItems(e money.Encoder, items []AggregatedDiagramItemDTO, currencyCode string) []interface{} {
+	if items == nil {
+		return nil
+	}
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item.marshalMoney(e, currencyCode)
+	}
+	return values
+}
+
+// ComparePeriodDTO is a date range of the comparison report, both dates are inclusive
+type ComparePeriodDTO struct {
+	StartDate utils.CustomDate `json:"startDate"`
+	EndDate   utils.CustomDate `json:"endDate"`
+}
+
+// CompareReportInputDTO represents input for the period comparison report. Either Periods lists the ranges
+// to compare, or Period and Count generate the Count consecutive periods ending with the one containing EndDate.
+type CompareReportInputDTO struct {
+	Periods               []ComparePeriodDTO `json:"periods"`
+	Period                string             `json:"period"`
+	Count                 int                `json:"count"`
+	EndDate               *utils.CustomDate  `json:"endDate"`
+	AnchorDay             *int               `json:"anchorDay"`
+	AnchorLastBusinessDay bool               `json:"anchorLastBusinessDay"`
+	WeekStartDay          *int               `json:"weekStartDay"`
+	// CompareTo is "previous" (default) to compare every period with the one before it,
+	// or "first" to compare every period with the first one
+	CompareTo           string `json:"compareTo"`
+	IsIncome            bool   `json:"isIncome"`
+	Categories          []int  `json:"categories"`
+	HideEmptyCategories bool   `json:"hideEmptyCategories"`
+}
+
+// CompareChangeDTO is the change of a category amount against the compared period.
+// Percent is nil when the compared amount is zero.
+type CompareChangeDTO struct {
+	Absolute decimal.Decimal `json:"absolute"`
+	Percent  *float64        `json:"percent"`
+}
+
+func (c CompareChangeDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias CompareChangeDTO
+	return &struct {
+		Absolute interface{} `json:"absolute"`
+		*Alias
+	}{
+		Absolute: e.Amount(c.Absolute, currencyCode),
+		Alias:    (*Alias)(&c),
+	}
+}
+
+func compareChanges(e money.Encoder, changes []CompareChangeDTO, currencyCode string) []interface{} {
+	if changes == nil {
+		return nil
+	}
+	values := make([]interface{}, len(changes))
+	for i, change := range changes {
+		values[i] = change.marshalMoney(e, currencyCode)
+	}
+	return values
+}
+
+// CompareCategoryItemDTO holds the amounts of a category in every period. Amounts of top-level categories
+// include their subcategories. Changes[i] compares period i+1 with the period it is compared to.
+type CompareCategoryItemDTO struct {
+	ID       int                `json:"id"`
+	Name     string             `json:"name"`
+	ParentID *int               `json:"parentId"`
+	IsParent bool               `json:"isParent"`
+	Color    string             `json:"color"`
+	Icon     string             `json:"icon"`
+	Amounts  []decimal.Decimal  `json:"amounts"`
+	Changes  []CompareChangeDTO `json:"changes"`
+}
+
+func (c CompareCategoryItemDTO) marshalMoney(e money.Encoder, currencyCode string) interface{} {
+	type Alias CompareCategoryItemDTO
+	return &struct {
+		Amounts []interface{} `json:"amounts"`
+		Changes []interface{} `json:"changes"`
+		*Alias
+	}{
+		Amounts: e.Amounts(c.Amounts, currencyCode),
+		Changes: compareChanges(e, c.Changes, currencyCode),
+		Alias:   (*Alias)(&c),
+	}
+}
+
+// CompareReportOutputDTO represents the period comparison report
+type CompareReportOutputDTO struct {
+	CurrencyCode string                   `json:"currencyCode"`
+	IsIncome     bool                     `json:"isIncome"`
+	CompareTo    string                   `json:"compareTo"`
+	Periods      []ComparePeriodDTO       `json:"periods"`
+	Totals       []decimal.Decimal        `json:"totals"`
+	TotalChanges []CompareChangeDTO       `json:"totalChanges"`
+	Categories   []CompareCategoryItemDTO `json:"categories"`
+}
+
+func (r CompareReportOutputDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.MarshalMoney(money.Numbers))
+}
+
+func (r CompareReportOutputDTO) MarshalMoney(e money.Encoder) interface{} {
+	type Alias CompareReportOutputDTO
+	var categories []interface{}
+	if r.Categories != nil {
+		categories = make([]interface{}, len(r.Categories))
+		for i, category := range r.Categories {
+			categories[i] = category.marshalMoney(e, r.CurrencyCode)
+		}
+	}
+
+	return &struct {
+		Totals       []interface{} `json:"totals"`
+		TotalChanges []interface{} `json:"totalChanges"`
+		Categories   []interface{} `json:"categories"`
+		*Alias
+	}{
+		Totals:       e.Amounts(r.Totals, r.CurrencyCode),
+		TotalChanges: compareChanges(e, r.TotalChanges, r.CurrencyCode),
+		Categories:   categories,
+		Alias:        (*Alias)(&r),
+	}
 }
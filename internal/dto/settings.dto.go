@@ -4,5 +4,7 @@ package dto
 // Use models.Currency directly for all currency operations
 
 type UpdateSettingsDTO struct {
-	Language string `json:"language" validate:"required"`
+	Language      string  `json:"language" validate:"required"`
+	EmailDigest   *string `json:"emailDigest"`   // off, weekly or monthly, unchanged when omitted
+	InsightEmails *string `json:"insightEmails"` // off or the lowest severity emailed, unchanged when omitted
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/money"
 	"ypeskov/budget-go/internal/utils"
 
 	"github.com/shopspring/decimal"
@@ -34,6 +35,7 @@ type CreateTransactionDTO struct {
 	DateTime        *time.Time       `json:"dateTime"`
 	IsTransfer      bool             `json:"isTransfer"`
 	IsIncome        bool             `json:"isIncome"`
+	Tags            []string         `json:"tags"`
 }
 
 func (c *CreateTransactionDTO) UnmarshalJSON(data []byte) error {
@@ -101,6 +103,7 @@ type PutTransactionDTO struct {
 	IsTransfer      bool             `json:"isTransfer"`
 	IsIncome        bool             `json:"isIncome"`
 	IsTemplate      *bool            `json:"isTemplate"`
+	Tags            []string         `json:"tags"` // nil keeps the tags unchanged
 }
 
 func (p *PutTransactionDTO) UnmarshalJSON(data []byte) error {
@@ -165,31 +168,33 @@ type ResponseTransactionDTO struct {
 }
 
 func (r *ResponseTransactionDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.MarshalMoney(money.Numbers))
+}
+
+// MarshalMoney writes the amount and the new balance in the currency of the account and the base amounts
+// in the base currency
+func (r ResponseTransactionDTO) MarshalMoney(e money.Encoder) interface{} {
 	type Alias ResponseTransactionDTO
-	var newBalance *float64
-	if r.NewBalance != nil {
-		val, _ := r.NewBalance.Float64()
-		newBalance = &val
-	}
-	var baseCurrencyAmount *float64
-	if r.BaseCurrencyAmount != nil {
-		val, _ := r.BaseCurrencyAmount.Float64()
-		baseCurrencyAmount = &val
+	var baseCurrencyCode string
+	if r.BaseCurrencyCode != nil {
+		baseCurrencyCode = *r.BaseCurrencyCode
 	}
 
-	return json.Marshal(&struct {
-		Amount                float64  `json:"amount"`
-		NewBalance            *float64 `json:"newBalance"`
-		BaseCurrencyAmount    *float64 `json:"baseCurrencyAmount"`
-		BalanceInBaseCurrency float64  `json:"balanceInBaseCurrency"`
+	return &struct {
+		Amount                interface{} `json:"amount"`
+		NewBalance            interface{} `json:"newBalance"`
+		BaseCurrencyAmount    interface{} `json:"baseCurrencyAmount"`
+		BalanceInBaseCurrency interface{} `json:"balanceInBaseCurrency"`
+		Account               interface{} `json:"account"`
 		*Alias
 	}{
-		Amount:                r.Amount.InexactFloat64(),
-		NewBalance:            newBalance,
-		BaseCurrencyAmount:    baseCurrencyAmount,
-		BalanceInBaseCurrency: r.BalanceInBaseCurrency.InexactFloat64(),
-		Alias:                 (*Alias)(r),
-	})
+		Amount:                e.Amount(r.Amount, r.Account.Currency.Code),
+		NewBalance:            e.AmountOf(r.NewBalance, r.Account.Currency.Code),
+		BaseCurrencyAmount:    e.AmountOf(r.BaseCurrencyAmount, baseCurrencyCode),
+		BalanceInBaseCurrency: e.Amount(r.BalanceInBaseCurrency, baseCurrencyCode),
+		Account:               r.Account.MarshalMoney(e),
+		Alias:                 (*Alias)(&r),
+	}
 }
 
 type TransactionDetailDTO struct {
@@ -214,29 +219,41 @@ type TransactionDetailDTO struct {
 	Category            CategoryDetailDTO       `json:"category"`
 	LinkedTransactionID *int                    `json:"linkedTransactionId"`
 	LinkedTransaction   *TransactionDetailDTO   `json:"linkedTransaction,omitempty"`
+	Tags                []string                `json:"tags"`
 }
 
 func (t *TransactionDetailDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.MarshalMoney(money.Numbers))
+}
+
+// MarshalMoney writes the amount and the new balance in the currency of the account, the target amount of
+// a transfer in the currency of the target account and the base amount in the base currency
+func (t TransactionDetailDTO) MarshalMoney(e money.Encoder) interface{} {
 	type Alias TransactionDetailDTO
-	var targetAmount *float64
-	if t.TargetAmount != nil {
-		val, _ := t.TargetAmount.Float64()
-		targetAmount = &val
+	var targetCurrencyCode string
+	var linkedTransaction interface{}
+	if t.LinkedTransaction != nil {
+		targetCurrencyCode = t.LinkedTransaction.Account.Currency.Code
+		linkedTransaction = t.LinkedTransaction.MarshalMoney(e)
 	}
 
-	return json.Marshal(&struct {
-		Amount             float64  `json:"amount"`
-		TargetAmount       *float64 `json:"targetAmount"`
-		BaseCurrencyAmount float64  `json:"baseCurrencyAmount"`
-		NewBalance         float64  `json:"newBalance"`
+	return &struct {
+		Amount             interface{} `json:"amount"`
+		TargetAmount       interface{} `json:"targetAmount"`
+		BaseCurrencyAmount interface{} `json:"baseCurrencyAmount"`
+		NewBalance         interface{} `json:"newBalance"`
+		Account            interface{} `json:"account"`
+		LinkedTransaction  interface{} `json:"linkedTransaction,omitempty"`
 		*Alias
 	}{
-		Amount:             t.Amount.InexactFloat64(),
-		TargetAmount:       targetAmount,
-		BaseCurrencyAmount: t.BaseCurrencyAmount.InexactFloat64(),
-		NewBalance:         t.NewBalance.InexactFloat64(),
-		Alias:              (*Alias)(t),
-	})
+		Amount:             e.Amount(t.Amount, t.Account.Currency.Code),
+		TargetAmount:       e.AmountOf(t.TargetAmount, targetCurrencyCode),
+		BaseCurrencyAmount: e.Amount(t.BaseCurrencyAmount, t.BaseCurrencyCode),
+		NewBalance:         e.Amount(t.NewBalance, t.Account.Currency.Code),
+		Account:            t.Account.MarshalMoney(e),
+		LinkedTransaction:  linkedTransaction,
+		Alias:              (*Alias)(&t),
+	}
 }
 
 // =============================================================================
@@ -261,24 +278,31 @@ type AccountDetailDTO struct {
 	IsDeleted             bool                 `json:"isDeleted"`
 	IsArchived            bool                 `json:"isArchived"`
 	BalanceInBaseCurrency decimal.Decimal      `json:"balanceInBaseCurrency"`
+	BaseCurrencyCode      string               `json:"baseCurrencyCode"`
 	ArchivedAt            *string              `json:"archivedAt"`
 }
 
 func (a *AccountDetailDTO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.MarshalMoney(money.Numbers))
+}
+
+// MarshalMoney writes the amounts in the currency of the account and the balance in the base currency
+// in the base currency
+func (a AccountDetailDTO) MarshalMoney(e money.Encoder) interface{} {
 	type Alias AccountDetailDTO
-	return json.Marshal(&struct {
-		InitialBalance        float64 `json:"initialBalance"`
-		Balance               float64 `json:"balance"`
-		CreditLimit           float64 `json:"creditLimit"`
-		BalanceInBaseCurrency float64 `json:"balanceInBaseCurrency"`
+	return &struct {
+		InitialBalance        interface{} `json:"initialBalance"`
+		Balance               interface{} `json:"balance"`
+		CreditLimit           interface{} `json:"creditLimit"`
+		BalanceInBaseCurrency interface{} `json:"balanceInBaseCurrency"`
 		*Alias
 	}{
-		InitialBalance:        a.InitialBalance.InexactFloat64(),
-		Balance:               a.Balance.InexactFloat64(),
-		CreditLimit:           a.CreditLimit.InexactFloat64(),
-		BalanceInBaseCurrency: a.BalanceInBaseCurrency.InexactFloat64(),
-		Alias:                 (*Alias)(a),
-	})
+		InitialBalance:        e.Amount(a.InitialBalance, a.Currency.Code),
+		Balance:               e.Amount(a.Balance, a.Currency.Code),
+		CreditLimit:           e.Amount(a.CreditLimit, a.Currency.Code),
+		BalanceInBaseCurrency: e.Amount(a.BalanceInBaseCurrency, a.BaseCurrencyCode),
+		Alias:                 (*Alias)(&a),
+	}
 }
 
 type AccountTypeDetailDTO struct {
@@ -10,6 +10,7 @@ type UserRegisterRequestDTO struct {
 	Password  string `json:"password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	Language  string `json:"language"` // language of the default categories, the default language when empty
 }
 
 type UserRegisterResponseDTO struct {
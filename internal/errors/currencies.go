@@ -0,0 +1,10 @@
+package errors
+
+import "errors"
+
+var (
+	ErrCurrencyNotFound         = errors.New("currency not found")
+	ErrCurrencyCodeTaken        = errors.New("currency code is already used")
+	ErrCurrencyInUse            = errors.New("currency is used by accounts")
+	ErrUserExchangeRateNotFound = errors.New("exchange rate not found")
+)
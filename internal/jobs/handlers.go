@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"ypeskov/budget-go/internal/queue"
@@ -48,6 +49,46 @@ func (h *Handlers) HandleExchangeRatesDaily(ctx context.Context, t *asynq.Task)
 	return nil
 }
 
+func (h *Handlers) HandleExchangeRatesBackfill(ctx context.Context, t *asynq.Task) error {
+	var p queue.ExchangeRatesBackfillPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		logger.Error("Failed to unmarshal exchange rates backfill payload", "error", err)
+		return err
+	}
+
+	startDate, err := time.Parse(time.DateOnly, p.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid backfill start date %q: %w", p.StartDate, asynq.SkipRetry)
+	}
+	endDate, err := time.Parse(time.DateOnly, p.EndDate)
+	if err != nil {
+		return fmt.Errorf("invalid backfill end date %q: %w", p.EndDate, asynq.SkipRetry)
+	}
+
+	logger.Info("Exchange rates backfill task started", "startDate", p.StartDate, "endDate", p.EndDate)
+
+	result, err := h.SM.ExchangeRatesService.BackfillExchangeRates(startDate, endDate)
+	if result == nil {
+		logger.Error("Exchange rates backfill failed", "error", err)
+		return err
+	}
+
+	// Admins hear about every gap that was filled or still failed, days like weekends that
+	// have no rates of their own are found by every backfill and are not worth an email
+	if len(result.Fetched) > 0 || len(result.Failed) > 0 {
+		if notifyErr := h.SM.EmailService.SendExchangeRatesBackfillNotification(result); notifyErr != nil {
+			logger.Error("Failed to send exchange rates backfill notification email", "error", notifyErr)
+		}
+	}
+	if err != nil {
+		logger.Error("Exchange rates backfill finished with failed dates", "failed", len(result.Failed), "error", err)
+		return err
+	}
+
+	logger.Info("Exchange rates backfill task completed successfully", "missing", len(result.Missing))
+	return nil
+}
+
 func (h *Handlers) HandleDBBackupDaily(ctx context.Context, t *asynq.Task) error {
 	logger.Info("Starting database backup task")
 
@@ -81,6 +122,30 @@ func (h *Handlers) HandleBudgetsDailyProcessing(ctx context.Context, t *asynq.Ta
 	return nil
 }
 
+func (h *Handlers) HandleBudgetsReconciliation(ctx context.Context, t *asynq.Task) error {
+	logger.Info("Starting budgets reconciliation task")
+	repaired, err := h.SM.BudgetsService.ReconcileCollectedAmounts()
+	if err != nil {
+		logger.Error("Budgets reconciliation failed", "error", err)
+		return err
+	}
+
+	logger.Info("Budgets reconciliation task completed successfully", "repaired", repaired)
+	return nil
+}
+
+func (h *Handlers) HandleGoalsDailyRecalculation(ctx context.Context, t *asynq.Task) error {
+	logger.Info("Starting goals daily recalculation task")
+	recalculated, err := h.SM.GoalsService.RecalculateAllGoals()
+	if err != nil {
+		logger.Error("Goals daily recalculation failed", "error", err)
+		return err
+	}
+
+	logger.Info("Goals daily recalculation task completed successfully", "recalculated", recalculated)
+	return nil
+}
+
 func (h *Handlers) HandleSendActivationEmail(ctx context.Context, t *asynq.Task) error {
 	var p queue.ActivationEmailPayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
@@ -99,3 +164,115 @@ func (h *Handlers) HandleSendActivationEmail(ctx context.Context, t *asynq.Task)
 	logger.Info("Activation email sent successfully", "email", p.UserEmail)
 	return nil
 }
+
+func (h *Handlers) HandleSendBudgetAlert(ctx context.Context, t *asynq.Task) error {
+	var p queue.BudgetAlertPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		logger.Error("Failed to unmarshal budget alert payload", "error", err)
+		return err
+	}
+
+	logger.Info("Sending budget alert email", "email", p.UserEmail, "budgetID", p.BudgetID, "threshold", p.Threshold)
+
+	err := h.SM.EmailService.SendBudgetAlert(p)
+	if err != nil {
+		logger.Error("Failed to send budget alert email", "error", err)
+		return err
+	}
+
+	logger.Info("Budget alert email sent successfully", "email", p.UserEmail, "budgetID", p.BudgetID)
+	return nil
+}
+
+// HandleScheduleDigests fans the digests of one frequency out into a task per opted-in user
+func (h *Handlers) HandleScheduleDigests(ctx context.Context, t *asynq.Task) error {
+	var p queue.DigestSchedulePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		logger.Error("Failed to unmarshal digest schedule payload", "error", err)
+		return err
+	}
+
+	logger.Info("Starting digests scheduling task", "frequency", p.Frequency)
+
+	startDate, endDate, err := h.SM.DigestService.DigestPeriod(p.Frequency, time.Now())
+	if err != nil {
+		logger.Error("Digests scheduling failed", "error", err)
+		return err
+	}
+
+	userIDs, err := h.SM.UserSettingsService.GetDigestUserIDs(p.Frequency)
+	if err != nil {
+		logger.Error("Digests scheduling failed", "error", err)
+		return err
+	}
+
+	// Keep going when a user fails, the retry of this task skips digests that are already queued or sent
+	var failed int
+	for _, userID := range userIDs {
+		err = h.SM.QueueService.EnqueueDigest(queue.DigestPayload{
+			UserID:    userID,
+			Frequency: p.Frequency,
+			StartDate: startDate.Format(time.DateOnly),
+			EndDate:   endDate.Format(time.DateOnly),
+		})
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to queue %d of %d digests", failed, len(userIDs))
+	}
+
+	logger.Info("Digests scheduling task completed successfully", "frequency", p.Frequency, "users", len(userIDs))
+	return nil
+}
+
+func (h *Handlers) HandleSendDigest(ctx context.Context, t *asynq.Task) error {
+	var p queue.DigestPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		logger.Error("Failed to unmarshal digest payload", "error", err)
+		return err
+	}
+
+	logger.Info("Sending digest email", "userID", p.UserID, "frequency", p.Frequency)
+
+	err := h.SM.DigestService.SendDigest(p)
+	if err != nil {
+		logger.Error("Failed to send digest email", "userID", p.UserID, "error", err)
+		return err
+	}
+
+	logger.Info("Digest email task completed successfully", "userID", p.UserID)
+	return nil
+}
+
+func (h *Handlers) HandleInsightsNightlyAnalysis(ctx context.Context, t *asynq.Task) error {
+	logger.Info("Starting insights nightly analysis task")
+	created, err := h.SM.InsightsService.AnalyzeAllUsers()
+	if err != nil {
+		logger.Error("Insights nightly analysis failed", "error", err)
+		return err
+	}
+
+	logger.Info("Insights nightly analysis task completed successfully", "created", created)
+	return nil
+}
+
+func (h *Handlers) HandleSendInsights(ctx context.Context, t *asynq.Task) error {
+	var p queue.InsightsEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		logger.Error("Failed to unmarshal insights email payload", "error", err)
+		return err
+	}
+
+	logger.Info("Sending insights email", "email", p.UserEmail, "insights", len(p.Insights))
+
+	err := h.SM.EmailService.SendInsights(p)
+	if err != nil {
+		logger.Error("Failed to send insights email", "error", err)
+		return err
+	}
+
+	logger.Info("Insights email sent successfully", "email", p.UserEmail)
+	return nil
+}
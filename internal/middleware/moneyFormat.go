@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"ypeskov/budget-go/internal/money"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderMoneyFormat selects the money format of a request and reports the one used in the response
+	HeaderMoneyFormat = "X-Money-Format"
+	// HeaderAPIVersion "2" selects the string money format unless X-Money-Format is given
+	HeaderAPIVersion = "X-API-Version"
+
+	moneyFormatKey = "moneyFormat"
+)
+
+// MoneyFormatMiddleware negotiates how money amounts are written into the JSON responses of the request.
+// Clients that send neither header keep getting numbers.
+func MoneyFormatMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header
+			requested := header.Get(HeaderMoneyFormat)
+			if requested == "" {
+				switch version := strings.TrimSpace(header.Get(HeaderAPIVersion)); version {
+				case "", "1":
+				case "2":
+					requested = string(money.FormatString)
+				default:
+					return c.JSON(http.StatusBadRequest, map[string]string{
+						"error": "invalid API version: " + version + ". Allowed values are 1 and 2",
+					})
+				}
+			}
+
+			format, err := money.ParseFormat(requested)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			c.Set(moneyFormatKey, format)
+			c.Response().Header().Add(echo.HeaderVary, HeaderMoneyFormat)
+			c.Response().Header().Add(echo.HeaderVary, HeaderAPIVersion)
+			c.Response().Header().Set(HeaderMoneyFormat, string(format))
+			return next(c)
+		}
+	}
+}
+
+// MoneyFormat returns the money format negotiated for the request
+func MoneyFormat(c echo.Context) money.Format {
+	if format, ok := c.Get(moneyFormatKey).(money.Format); ok {
+		return format
+	}
+	return money.FormatNumber
+}
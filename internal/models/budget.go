@@ -9,17 +9,19 @@ import (
 type PeriodEnum string
 
 const (
-	PeriodDaily   PeriodEnum = "DAILY"
-	PeriodWeekly  PeriodEnum = "WEEKLY"
-	PeriodMonthly PeriodEnum = "MONTHLY"
-	PeriodYearly  PeriodEnum = "YEARLY"
-	PeriodCustom  PeriodEnum = "CUSTOM"
+	PeriodDaily     PeriodEnum = "DAILY"
+	PeriodWeekly    PeriodEnum = "WEEKLY"
+	PeriodMonthly   PeriodEnum = "MONTHLY"
+	PeriodQuarterly PeriodEnum = "QUARTERLY"
+	PeriodYearly    PeriodEnum = "YEARLY"
+	PeriodCustom    PeriodEnum = "CUSTOM"
 )
 
 // ValidatePeriod checks if the given string is a valid period (accepts both upper and lowercase)
 func ValidatePeriod(period string) bool {
 	switch strings.ToUpper(period) {
-	case string(PeriodDaily), string(PeriodWeekly), string(PeriodMonthly), string(PeriodYearly), string(PeriodCustom):
+	case string(PeriodDaily), string(PeriodWeekly), string(PeriodMonthly), string(PeriodQuarterly),
+		string(PeriodYearly), string(PeriodCustom):
 		return true
 	default:
 		return false
@@ -39,25 +41,84 @@ func FormatPeriodForAPI(period string) string {
 // GetValidPeriods returns all valid period values (lowercase for API compatibility)
 func GetValidPeriods() []string {
 	return []string{
-		"daily", "weekly", "monthly", "yearly", "custom",
+		"daily", "weekly", "monthly", "quarterly", "yearly", "custom",
+	}
+}
+
+// BudgetAlertOverBudget is the threshold value used to record over-budget alerts,
+// as percentage thresholds are always positive
+const BudgetAlertOverBudget = 0
+
+// MaxBudgetAlertThreshold is the highest percentage accepted as an alert threshold
+const MaxBudgetAlertThreshold = 1000
+
+// Spending trend of a budget series across its past periods
+const (
+	BudgetTrendIncreasing   = "increasing"
+	BudgetTrendDecreasing   = "decreasing"
+	BudgetTrendStable       = "stable"
+	BudgetTrendInsufficient = "insufficient_data"
+)
+
+// Curves used to forecast spending within a budget period
+const (
+	ForecastCurveLinear     = "LINEAR"
+	ForecastCurveHistorical = "HISTORICAL"
+)
+
+// Forecast status of an active budget
+const (
+	BudgetStatusOnTrack = "on_track"
+	BudgetStatusAtRisk  = "at_risk"
+	BudgetStatusOver    = "over"
+)
+
+// ValidateForecastCurve checks if the given string is a valid forecast curve (case-insensitive)
+func ValidateForecastCurve(curve string) bool {
+	switch strings.ToUpper(curve) {
+	case ForecastCurveLinear, ForecastCurveHistorical:
+		return true
+	default:
+		return false
 	}
 }
 
 type Budget struct {
-	ID                 *int            `json:"id" db:"id"`
-	UserID             int             `json:"userId" db:"user_id"`
-	Name               string          `json:"name" db:"name"`
-	CurrencyID         int             `json:"currencyId" db:"currency_id"`
-	TargetAmount       decimal.Decimal `json:"targetAmount" db:"target_amount"`
-	CollectedAmount    decimal.Decimal `json:"collectedAmount" db:"collected_amount"`
-	Period             string          `json:"period" db:"period"`
-	Repeat             bool            `json:"repeat" db:"repeat"`
-	StartDate          *time.Time      `json:"startDate" db:"start_date"`
-	EndDate            *time.Time      `json:"endDate" db:"end_date"`
-	IncludedCategories *string         `json:"includedCategories" db:"included_categories"`
-	Comment            *string         `json:"comment" db:"comment"`
-	IsDeleted          bool            `json:"isDeleted" db:"is_deleted"`
-	IsArchived         bool            `json:"isArchived" db:"is_archived"`
-	CreatedAt          *time.Time      `json:"createdAt" db:"created_at"`
-	UpdatedAt          *time.Time      `json:"updatedAt" db:"updated_at"`
+	ID                    *int            `json:"id" db:"id"`
+	SeriesID              *int            `json:"seriesId" db:"series_id"`
+	UserID                int             `json:"userId" db:"user_id"`
+	Name                  string          `json:"name" db:"name"`
+	CurrencyID            int             `json:"currencyId" db:"currency_id"`
+	TargetAmount          decimal.Decimal `json:"targetAmount" db:"target_amount"`
+	CollectedAmount       decimal.Decimal `json:"collectedAmount" db:"collected_amount"`
+	Period                string          `json:"period" db:"period"`
+	Repeat                bool            `json:"repeat" db:"repeat"`
+	StartDate             *time.Time      `json:"startDate" db:"start_date"`
+	EndDate               *time.Time      `json:"endDate" db:"end_date"`
+	AnchorDay             *int            `json:"anchorDay" db:"anchor_day"`
+	AnchorLastBusinessDay bool            `json:"anchorLastBusinessDay" db:"anchor_last_business_day"`
+	WeekStartDay          *int            `json:"weekStartDay" db:"week_start_day"`
+	ForecastCurve         string          `json:"forecastCurve" db:"forecast_curve"`
+	AlertThresholds       *string         `json:"alertThresholds" db:"alert_thresholds"`
+	AlertOverBudget       bool            `json:"alertOverBudget" db:"alert_over_budget"`
+	Comment               *string         `json:"comment" db:"comment"`
+	IsDeleted             bool            `json:"isDeleted" db:"is_deleted"`
+	IsArchived            bool            `json:"isArchived" db:"is_archived"`
+	CreatedAt             *time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt             *time.Time      `json:"updatedAt" db:"updated_at"`
+	Filter                BudgetFilter    `json:"filter" db:"-"`
+}
+
+// BudgetFilter limits which expense transactions count towards a budget
+type BudgetFilter struct {
+	// IncludedCategories count together with all their subcategories, including ones created later
+	IncludedCategories []int `json:"includedCategories"`
+	// ExcludedCategories are subcategories left out even though their parent is included
+	ExcludedCategories []int `json:"excludedCategories"`
+	// Accounts limits the budget to transactions of these accounts, empty means all accounts
+	Accounts []int `json:"accounts"`
+	// IncludedTags requires a transaction to have at least one of the tags, empty means any transaction
+	IncludedTags []string `json:"includedTags"`
+	// ExcludedTags leaves out transactions having any of the tags
+	ExcludedTags []string `json:"excludedTags"`
 }
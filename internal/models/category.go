@@ -1,15 +1,96 @@
 package models
 
+import (
+	"regexp"
+	"strings"
+)
+
+// CategoryPalette is the default color set for top-level categories without a chosen color
+var CategoryPalette = []string{
+	"#FF6384", "#36A2EB", "#FFCE56", "#4BC0C0", "#9966FF", "#FF9F40", "#8BC34A", "#E91E63", "#00ACC1", "#795548",
+}
+
+// CategoryOtherColor is used for the combined "Other" slice of charts
+const CategoryOtherColor = "#C9CBCF"
+
+const MaxCategoryIconLength = 50
+
+var (
+	categoryColorPattern = regexp.MustCompile(`^#[0-9A-F]{6}$`)
+	categoryIconPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
 type UserCategory struct {
-	ID         *int    `db:"id"`
-	Name       *string `db:"name"`
-	ParentID   *int    `db:"parent_id"`
-	ParentName *string `db:"parent_name"`
-	IsIncome   *bool   `db:"is_income"`
-	UserID     *int    `db:"user_id"`
-	IsDeleted  *bool   `db:"is_deleted"`
-	CreatedAt  *string `db:"created_at"`
-	UpdatedAt  *string `db:"updated_at"`
+	ID          *int    `db:"id"`
+	Name        *string `db:"name"`
+	ParentID    *int    `db:"parent_id"`
+	ParentName  *string `db:"parent_name"`
+	IsIncome    *bool   `db:"is_income"`
+	UserID      *int    `db:"user_id"`
+	IsDeleted   *bool   `db:"is_deleted"`
+	CreatedAt   *string `db:"created_at"`
+	UpdatedAt   *string `db:"updated_at"`
+	Color       *string `db:"color"`
+	Icon        *string `db:"icon"`
+	ParentColor *string `db:"parent_color"`
+	ParentIcon  *string `db:"parent_icon"`
+}
+
+// EffectiveColor returns the own color, the parent color or a stable palette color of the top-level category
+func (c UserCategory) EffectiveColor() string {
+	id := 0
+	if c.ID != nil {
+		id = *c.ID
+	}
+	return CategoryColor(id, c.ParentID, c.Color, c.ParentColor)
+}
+
+// EffectiveIcon returns the own icon or the parent icon, empty when neither is set
+func (c UserCategory) EffectiveIcon() string {
+	return CategoryIcon(c.Icon, c.ParentIcon)
+}
+
+// CategoryColor resolves the color shown for a category
+func CategoryColor(id int, parentID *int, color *string, parentColor *string) string {
+	if color != nil && *color != "" {
+		return *color
+	}
+	if parentID != nil {
+		if parentColor != nil && *parentColor != "" {
+			return *parentColor
+		}
+		id = *parentID
+	}
+	return CategoryPalette[id%len(CategoryPalette)]
+}
+
+// CategoryIcon resolves the icon shown for a category
+func CategoryIcon(icon *string, parentIcon *string) string {
+	if icon != nil && *icon != "" {
+		return *icon
+	}
+	if parentIcon != nil {
+		return *parentIcon
+	}
+	return ""
+}
+
+// NormalizeCategoryColor uppercases a #RRGGBB color, empty means no own color
+func NormalizeCategoryColor(color *string) (*string, bool) {
+	if color == nil || strings.TrimSpace(*color) == "" {
+		return nil, true
+	}
+	normalized := strings.ToUpper(strings.TrimSpace(*color))
+	return &normalized, categoryColorPattern.MatchString(normalized)
+}
+
+// NormalizeCategoryIcon lowercases an icon key, empty means no own icon
+func NormalizeCategoryIcon(icon *string) (*string, bool) {
+	if icon == nil || strings.TrimSpace(*icon) == "" {
+		return nil, true
+	}
+	normalized := strings.ToLower(strings.TrimSpace(*icon))
+	return &normalized, len(normalized) <= MaxCategoryIconLength && categoryIconPattern.MatchString(normalized)
 }
 
 type GroupedCategory struct {
@@ -17,5 +98,25 @@ type GroupedCategory struct {
 	Name     string            `json:"name"`
 	ParentID *int              `json:"parentId"`
 	IsIncome bool              `json:"isIncome"`
+	Color    string            `json:"color"`
+	Icon     string            `json:"icon"`
 	Children []GroupedCategory `json:"children"`
 }
+
+type DefaultCategory struct {
+	ID           int    `db:"id"`
+	Name         string `db:"name"`
+	ParentID     *int   `db:"parent_id"`
+	IsIncome     bool   `db:"is_income"`
+	LanguageCode string `db:"language_code"`
+}
+
+// CategoryNode is a category with its subcategories detached from any user.
+// Default category sets and exported category trees are built of them.
+type CategoryNode struct {
+	Name     string         `json:"name"`
+	IsIncome bool           `json:"isIncome"`
+	Color    *string        `json:"color,omitempty"`
+	Icon     *string        `json:"icon,omitempty"`
+	Children []CategoryNode `json:"children,omitempty"`
+}
@@ -3,28 +3,46 @@ package models
 import (
 	"encoding/json"
 	"time"
+
+	"ypeskov/budget-go/internal/money"
 )
 
 const DefaultCurrency = "USD"
 
 type Currency struct {
-	ID        int       `db:"id"`
-	Code      string    `db:"code"`
-	Name      string    `db:"name"`
-	IsDeleted bool      `db:"is_deleted"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID   int    `db:"id"`
+	Code string `db:"code"`
+	Name string `db:"name"`
+	// UserID is set for private currencies and assets of a user, nil for the common ones
+	UserID *int `db:"user_id"`
+	// DecimalPlaces overrides the minor unit of the code, nil keeps the ISO 4217 one
+	DecimalPlaces *int      `db:"decimal_places"`
+	IsDeleted     bool      `db:"is_deleted"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// Scale returns the number of decimal places of amounts in the currency
+func (c *Currency) Scale() int32 {
+	if c.DecimalPlaces != nil {
+		return int32(*c.DecimalPlaces)
+	}
+	return money.Scale(c.Code)
 }
 
 // MarshalJSON customizes JSON output to exclude internal metadata fields
 func (c *Currency) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		ID   int    `json:"id"`
-		Code string `json:"code"`
-		Name string `json:"name"`
+		ID            int    `json:"id"`
+		Code          string `json:"code"`
+		Name          string `json:"name"`
+		DecimalPlaces int32  `json:"decimalPlaces"`
+		IsPrivate     bool   `json:"isPrivate"`
 	}{
-		ID:   c.ID,
-		Code: c.Code,
-		Name: c.Name,
+		ID:            c.ID,
+		Code:          c.Code,
+		Name:          c.Name,
+		DecimalPlaces: c.Scale(),
+		IsPrivate:     c.UserID != nil,
 	})
 }
@@ -0,0 +1,20 @@
+package models
+
+// SettingEmailDigest is the user settings key of the email digest frequency
+const SettingEmailDigest = "emailDigest"
+
+// Email digest frequencies, users without the setting get no digest
+const (
+	DigestOff     = "off"
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
+// ValidateDigestFrequency checks if the given string is a valid email digest frequency
+func ValidateDigestFrequency(frequency string) bool {
+	switch frequency {
+	case DigestOff, DigestWeekly, DigestMonthly:
+		return true
+	}
+	return false
+}
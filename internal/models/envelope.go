@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// EnvelopeAssignment is the money assigned to an expense category envelope for a month
+type EnvelopeAssignment struct {
+	ID         *int            `json:"id" db:"id"`
+	UserID     int             `json:"userId" db:"user_id"`
+	CategoryID int             `json:"categoryId" db:"category_id"`
+	Month      time.Time       `json:"month" db:"month"`
+	Amount     decimal.Decimal `json:"amount" db:"amount"`
+	CreatedAt  *time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt  *time.Time      `json:"updatedAt" db:"updated_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+type GoalProgressSource string
+
+const (
+	// GoalProgressFromAccounts computes progress from the balances of linked accounts
+	GoalProgressFromAccounts GoalProgressSource = "ACCOUNTS"
+	// GoalProgressFromContributions computes progress from transactions tagged as contributions
+	GoalProgressFromContributions GoalProgressSource = "CONTRIBUTIONS"
+)
+
+// ValidateGoalProgressSource checks if the given string is a valid progress source (case-insensitive)
+func ValidateGoalProgressSource(source string) bool {
+	switch GoalProgressSource(strings.ToUpper(source)) {
+	case GoalProgressFromAccounts, GoalProgressFromContributions:
+		return true
+	default:
+		return false
+	}
+}
+
+type Goal struct {
+	ID                          *int             `json:"id" db:"id"`
+	UserID                      int              `json:"userId" db:"user_id"`
+	Name                        string           `json:"name" db:"name"`
+	TargetAmount                decimal.Decimal  `json:"targetAmount" db:"target_amount"`
+	CurrencyID                  int              `json:"currencyId" db:"currency_id"`
+	TargetDate                  *time.Time       `json:"targetDate" db:"target_date"`
+	ProgressSource              string           `json:"progressSource" db:"progress_source"`
+	CurrentAmount               decimal.Decimal  `json:"currentAmount" db:"current_amount"`
+	RequiredMonthlyContribution *decimal.Decimal `json:"requiredMonthlyContribution" db:"required_monthly_contribution"`
+	RecalculatedAt              *time.Time       `json:"recalculatedAt" db:"recalculated_at"`
+	Comment                     *string          `json:"comment" db:"comment"`
+	IsArchived                  bool             `json:"isArchived" db:"is_archived"`
+	IsDeleted                   bool             `json:"isDeleted" db:"is_deleted"`
+	CreatedAt                   *time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt                   *time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// GoalContribution is a transaction tagged as a contribution to a goal
+type GoalContribution struct {
+	GoalID        int             `json:"goalId" db:"goal_id"`
+	TransactionID int             `json:"transactionId" db:"transaction_id"`
+	Amount        decimal.Decimal `json:"amount" db:"amount"`
+	CurrencyCode  string          `json:"currencyCode" db:"currency_code"`
+	DateTime      time.Time       `json:"dateTime" db:"date_time"`
+	Label         string          `json:"label" db:"label"`
+}
+
+func (c GoalContribution) MarshalMoney(e money.Encoder) interface{} {
+	type Alias GoalContribution
+	return &struct {
+		Amount interface{} `json:"amount"`
+		*Alias
+	}{
+		Amount: e.Amount(c.Amount, c.CurrencyCode),
+		Alias:  (*Alias)(&c),
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Types of insights found by the spending analysis
+const (
+	// InsightCategorySpike is a category spending several times its rolling average
+	InsightCategorySpike = "categorySpike"
+	// InsightNewPayee is a large first payment to a payee
+	InsightNewPayee = "newPayee"
+	// InsightPriceIncrease is a recurring charge that became more expensive
+	InsightPriceIncrease = "priceIncrease"
+	// InsightDuplicateCharge is a charge repeated on the same account shortly after the first one
+	InsightDuplicateCharge = "duplicateCharge"
+)
+
+// Insight severities from the least to the most important
+const (
+	InsightSeverityInfo     = "info"
+	InsightSeverityWarning  = "warning"
+	InsightSeverityCritical = "critical"
+)
+
+// SettingInsightEmails is the user settings key of the lowest insight severity sent by email,
+// users without the setting get no insight emails
+const SettingInsightEmails = "insightEmails"
+
+// InsightEmailsOff turns insight emails off
+const InsightEmailsOff = "off"
+
+// InsightSeverityRank orders severities, unknown severities rank below info
+func InsightSeverityRank(severity string) int {
+	switch severity {
+	case InsightSeverityInfo:
+		return 1
+	case InsightSeverityWarning:
+		return 2
+	case InsightSeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// ValidateInsightEmails checks if the given string is a valid insight emails setting
+func ValidateInsightEmails(value string) bool {
+	return value == InsightEmailsOff || InsightSeverityRank(value) > 0
+}
+
+type Insight struct {
+	ID            *int             `json:"id" db:"id"`
+	UserID        int              `json:"userId" db:"user_id"`
+	Type          string           `json:"type" db:"type"`
+	Severity      string           `json:"severity" db:"severity"`
+	Title         string           `json:"title" db:"title"`
+	Message       string           `json:"message" db:"message"`
+	Fingerprint   string           `json:"fingerprint" db:"fingerprint"`
+	CategoryID    *int             `json:"categoryId" db:"category_id"`
+	TransactionID *int             `json:"transactionId" db:"transaction_id"`
+	Amount        *decimal.Decimal `json:"amount" db:"amount"`
+	Details       json.RawMessage  `json:"details" db:"details"`
+	DetectedAt    time.Time        `json:"detectedAt" db:"detected_at"`
+	IsDismissed   bool             `json:"isDismissed" db:"is_dismissed"`
+	DismissedAt   *time.Time       `json:"dismissedAt" db:"dismissed_at"`
+	CreatedAt     time.Time        `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time        `json:"updatedAt" db:"updated_at"`
+}
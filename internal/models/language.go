@@ -2,6 +2,9 @@ package models
 
 import "time"
 
+// DefaultLanguage is used for users without a language setting and for languages without own defaults
+const DefaultLanguage = "en"
+
 type Language struct {
 	ID        int       `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name"`
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ReportDefinition is a saved custom report, Definition holds the report input as JSON
+type ReportDefinition struct {
+	ID         *int            `json:"id" db:"id"`
+	UserID     int             `json:"userId" db:"user_id"`
+	Name       string          `json:"name" db:"name"`
+	Definition json.RawMessage `json:"definition" db:"definition"`
+	IsDeleted  bool            `json:"isDeleted" db:"is_deleted"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updatedAt" db:"updated_at"`
+}
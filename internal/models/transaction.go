@@ -22,6 +22,7 @@ type Transaction struct {
 	IsDeleted           bool             `db:"is_deleted"`
 	CreatedAt           *time.Time       `db:"created_at"`
 	UpdatedAt           *time.Time       `db:"updated_at"`
+	Tags                []string         `db:"-"`
 }
 
 // NullableTransaction is used for LEFT JOINs where all fields can be NULL
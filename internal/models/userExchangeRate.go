@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// UserExchangeRate is a rate entered by a user for a day, CurrencyTo amount one unit of CurrencyFrom buys.
+// It takes precedence over the provider rates in the conversions of the user.
+type UserExchangeRate struct {
+	ID           int             `db:"id"`
+	UserID       int             `db:"user_id"`
+	RateDate     time.Time       `db:"rate_date"`
+	CurrencyFrom string          `db:"currency_from"`
+	CurrencyTo   string          `db:"currency_to"`
+	Rate         decimal.Decimal `db:"rate"`
+	CreatedAt    time.Time       `db:"created_at"`
+	UpdatedAt    time.Time       `db:"updated_at"`
+}
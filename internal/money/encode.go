@@ -0,0 +1,159 @@
+package money
+
+import (
+	"reflect"
+
+	"github.com/shopspring/decimal"
+)
+
+// Marshaler is implemented by types with amounts. MarshalMoney returns the value encoding/json writes for
+// the type, with its amounts converted by the encoder of the response:
+//
+//	func (r Report) MarshalMoney(e money.Encoder) interface{} {
+//		type Alias Report
+//		return &struct {
+//			Total interface{} `json:"total"`
+//			*Alias
+//		}{
+//			Total: e.Amount(r.Total, r.CurrencyCode),
+//			Alias: (*Alias)(&r),
+//		}
+//	}
+//
+// Types that write their amounts as JSON numbers for older clients use the same value in MarshalJSON:
+//
+//	func (r Report) MarshalJSON() ([]byte, error) {
+//		return json.Marshal(r.MarshalMoney(money.Numbers))
+//	}
+type Marshaler interface {
+	MarshalMoney(e Encoder) interface{}
+}
+
+// Encoder converts amounts to the JSON values of a format
+type Encoder struct {
+	Format Format
+	// Scales are the minor unit digits of currencies whose scale differs from Scale, like the private
+	// currencies of a user
+	Scales map[string]int32
+}
+
+// Numbers writes amounts as JSON numbers, which is FormatNumber
+var Numbers = Encoder{Format: FormatNumber}
+
+// Amount returns the JSON value of an amount in the currency. A currency of "-" marks values that are money
+// or not depending on the response, like the measures of a report, they are written like Plain.
+func (e Encoder) Amount(amount decimal.Decimal, currencyCode string) interface{} {
+	if currencyCode == "-" {
+		return e.Plain(amount)
+	}
+	if scale, ok := e.Scales[currencyCode]; ok && e.Format == FormatMinor {
+		return ToMinorWithScale(amount, currencyCode, scale)
+	}
+	return Value(amount, currencyCode, e.Format)
+}
+
+// AmountOf is Amount of an optional amount, nil is written as null
+func (e Encoder) AmountOf(amount *decimal.Decimal, currencyCode string) interface{} {
+	if amount == nil {
+		return nil
+	}
+	return e.Amount(*amount, currencyCode)
+}
+
+// Amounts returns the JSON values of amounts in the currency, nil is written as null
+func (e Encoder) Amounts(amounts []decimal.Decimal, currencyCode string) []interface{} {
+	if amounts == nil {
+		return nil
+	}
+	values := make([]interface{}, len(amounts))
+	for i, amount := range amounts {
+		values[i] = e.Amount(amount, currencyCode)
+	}
+	return values
+}
+
+// AmountMap returns the JSON values of amounts in the currency under the same keys, nil is written as null
+func (e Encoder) AmountMap(amounts map[string]decimal.Decimal, currencyCode string) map[string]interface{} {
+	if amounts == nil {
+		return nil
+	}
+	values := make(map[string]interface{}, len(amounts))
+	for key, amount := range amounts {
+		values[key] = e.Amount(amount, currencyCode)
+	}
+	return values
+}
+
+// Plain returns the JSON value of a decimal that is not money, like a rate or a percentage. It is a number
+// in FormatNumber and an exact string in the precise formats.
+func (e Encoder) Plain(value decimal.Decimal) interface{} {
+	if e.Format.IsPrecise() {
+		return value.String()
+	}
+	return value.InexactFloat64()
+}
+
+// Value returns v with the Marshalers in it replaced by their MarshalMoney, also inside slices, arrays and maps
+// with string keys, so whole responses like lists of DTOs can be handed to encoding/json. Other values are
+// returned as they are.
+func (e Encoder) Value(v interface{}) interface{} {
+	return e.value(reflect.ValueOf(v))
+}
+
+func (e Encoder) value(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return e.value(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v.Interface()
+		}
+		if marshaler, ok := v.Interface().(Marshaler); ok {
+			return marshaler.MarshalMoney(e)
+		}
+		switch v.Elem().Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return e.value(v.Elem())
+		}
+		return v.Interface()
+	}
+
+	if marshaler, ok := v.Interface().(Marshaler); ok {
+		return marshaler.MarshalMoney(e)
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = e.value(v.Index(i))
+		}
+		return values
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = e.value(iter.Value())
+		}
+		return values
+	}
+
+	// Elements of slices are addressable, encoding/json calls the MarshalJSON of their pointers
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
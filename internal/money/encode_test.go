@@ -0,0 +1,216 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type testAmount struct {
+	Amount       decimal.Decimal  `json:"amount"`
+	Fee          *decimal.Decimal `json:"fee,omitempty"`
+	Rate         decimal.Decimal  `json:"rate"`
+	CurrencyCode string           `json:"currencyCode"`
+}
+
+func (a testAmount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.MarshalMoney(Numbers))
+}
+
+func (a testAmount) MarshalMoney(e Encoder) interface{} {
+	type Alias testAmount
+	return &struct {
+		Amount interface{} `json:"amount"`
+		Fee    interface{} `json:"fee,omitempty"`
+		Rate   interface{} `json:"rate"`
+		*Alias
+	}{
+		Amount: e.Amount(a.Amount, a.CurrencyCode),
+		Fee:    e.AmountOf(a.Fee, a.CurrencyCode),
+		Rate:   e.Plain(a.Rate),
+		Alias:  (*Alias)(&a),
+	}
+}
+
+// testAmountFloat is testAmount as older clients get it
+type testAmountFloat struct {
+	Amount       float64  `json:"amount"`
+	Fee          *float64 `json:"fee,omitempty"`
+	Rate         float64  `json:"rate"`
+	CurrencyCode string   `json:"currencyCode"`
+}
+
+type testTotals struct {
+	CurrencyCode string                     `json:"currencyCode"`
+	Totals       map[string]decimal.Decimal `json:"totals"`
+	Months       []decimal.Decimal          `json:"months"`
+}
+
+func (t testTotals) MarshalMoney(e Encoder) interface{} {
+	type Alias testTotals
+	return &struct {
+		Totals map[string]interface{} `json:"totals"`
+		Months []interface{}          `json:"months"`
+		*Alias
+	}{
+		Totals: e.AmountMap(t.Totals, t.CurrencyCode),
+		Months: e.Amounts(t.Months, t.CurrencyCode),
+		Alias:  (*Alias)(&t),
+	}
+}
+
+// testLabel has a MarshalJSON of its pointer, like models.Currency
+type testLabel struct {
+	Name string
+}
+
+func (l *testLabel) MarshalJSON() ([]byte, error) {
+	return json.Marshal("label " + l.Name)
+}
+
+func mustDecimal(t *testing.T, value string) decimal.Decimal {
+	t.Helper()
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		t.Fatalf("invalid decimal %q: %v", value, err)
+	}
+	return amount
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return string(data)
+}
+
+func TestNumbersMatchesFloats(t *testing.T) {
+	fee := mustDecimal(t, "0.1")
+	feeFloat := 0.1
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		floats interface{}
+	}{
+		{
+			name:   "amount, plain value and omitted fee",
+			value:  testAmount{Amount: mustDecimal(t, "1234.56"), Rate: mustDecimal(t, "0.123456789"), CurrencyCode: "USD"},
+			floats: testAmountFloat{Amount: 1234.56, Rate: 0.123456789, CurrencyCode: "USD"},
+		},
+		{
+			name:   "pointer amount",
+			value:  &testAmount{Amount: mustDecimal(t, "-0.01"), Fee: &fee, CurrencyCode: "EUR"},
+			floats: &testAmountFloat{Amount: -0.01, Fee: &feeFloat, CurrencyCode: "EUR"},
+		},
+		{
+			name:   "list",
+			value:  []testAmount{{Amount: mustDecimal(t, "3.3"), CurrencyCode: "JPY"}},
+			floats: []testAmountFloat{{Amount: 3.3, CurrencyCode: "JPY"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := mustMarshal(t, tt.value), mustMarshal(t, tt.floats); got != want {
+				t.Errorf("json.Marshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestEncoderValue(t *testing.T) {
+	fee := mustDecimal(t, "0.005")
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		format Format
+		scales map[string]int32
+		want   string
+	}{
+		{
+			name:   "string keeps every digit",
+			value:  testAmount{Amount: mustDecimal(t, "0.30000000000000004"), Rate: mustDecimal(t, "1.5"), CurrencyCode: "USD"},
+			format: FormatString,
+			want:   `{"amount":"0.30000000000000004","rate":"1.5","currencyCode":"USD"}`,
+		},
+		{
+			name:   "string of a large amount",
+			value:  &testAmount{Amount: mustDecimal(t, "12345678901234567.89"), Fee: &fee, CurrencyCode: "USD"},
+			format: FormatString,
+			want:   `{"amount":"12345678901234567.89","fee":"0.005","rate":"0","currencyCode":"USD"}`,
+		},
+		{
+			name:   "minor rounds half away from zero",
+			value:  testAmount{Amount: mustDecimal(t, "-12.345"), Fee: &fee, Rate: mustDecimal(t, "0.25"), CurrencyCode: "USD"},
+			format: FormatMinor,
+			want: `{"amount":{"amount":-1235,"currency":"USD","scale":2},"fee":{"amount":1,"currency":"USD","scale":2},` +
+				`"rate":"0.25","currencyCode":"USD"}`,
+		},
+		{
+			name:   "minor of currencies without cents and with mills",
+			value:  []testAmount{{Amount: mustDecimal(t, "1500"), CurrencyCode: "JPY"}, {Amount: mustDecimal(t, "1.2345"), CurrencyCode: "KWD"}},
+			format: FormatMinor,
+			want: `[{"amount":{"amount":1500,"currency":"JPY","scale":0},"rate":"0","currencyCode":"JPY"},` +
+				`{"amount":{"amount":1235,"currency":"KWD","scale":3},"rate":"0","currencyCode":"KWD"}]`,
+		},
+		{
+			name:   "minor with a private currency scale",
+			value:  testAmount{Amount: mustDecimal(t, "0.12345678"), CurrencyCode: "SAT"},
+			format: FormatMinor,
+			scales: map[string]int32{"SAT": 8},
+			want:   `{"amount":{"amount":12345678,"currency":"SAT","scale":8},"rate":"0","currencyCode":"SAT"}`,
+		},
+		{
+			name: "maps and lists of amounts",
+			value: testTotals{
+				CurrencyCode: "EUR",
+				Totals:       map[string]decimal.Decimal{"z": mustDecimal(t, "1.1"), "a": mustDecimal(t, "2")},
+				Months:       []decimal.Decimal{mustDecimal(t, "10")},
+			},
+			format: FormatMinor,
+			want: `{"totals":{"a":{"amount":200,"currency":"EUR","scale":2},"z":{"amount":110,"currency":"EUR","scale":2}},` +
+				`"months":[{"amount":1000,"currency":"EUR","scale":2}],"currencyCode":"EUR"}`,
+		},
+		{
+			name:   "minor without a currency uses the default scale",
+			value:  testAmount{Amount: mustDecimal(t, "3.456")},
+			format: FormatMinor,
+			want:   `{"amount":{"amount":346,"scale":2},"rate":"0","currencyCode":""}`,
+		},
+		{
+			name:   "currency of \"-\" marks a plain value",
+			value:  testAmount{Amount: mustDecimal(t, "42"), CurrencyCode: "-"},
+			format: FormatMinor,
+			want:   `{"amount":"42","rate":"0","currencyCode":"-"}`,
+		},
+		{
+			name: "responses built of maps and lists",
+			value: map[string]interface{}{
+				"items": []*testAmount{{Amount: mustDecimal(t, "1"), CurrencyCode: "USD"}, nil},
+				"count": 1,
+			},
+			format: FormatString,
+			want:   `{"count":1,"items":[{"amount":"1","rate":"0","currencyCode":"USD"},null]}`,
+		},
+		{
+			name:   "values without amounts keep their MarshalJSON",
+			value:  []testLabel{{Name: "a"}},
+			format: FormatString,
+			want:   `["label a"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Encoder{Format: tt.format, Scales: tt.scales}
+			if got := mustMarshal(t, e.Value(tt.value)); got != tt.want {
+				t.Errorf("Value() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
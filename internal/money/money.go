@@ -0,0 +1,96 @@
+// Package money renders decimal amounts of API responses in the format a client negotiated.
+//
+// Older clients get amounts as JSON numbers (float64), which is what the MarshalJSON methods of the
+// models and DTOs produce. Clients that ask for a precise format get amounts as exact decimal strings
+// or as integer minor units with the currency, see Marshaler and Encoder.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format is how money amounts are written into JSON responses
+type Format string
+
+const (
+	// FormatNumber writes amounts as JSON numbers, it is the default so existing clients keep working
+	FormatNumber Format = "number"
+	// FormatString writes amounts as exact decimal strings, e.g. "1234.56"
+	FormatString Format = "string"
+	// FormatMinor writes amounts as objects with the amount in minor units, e.g. {"amount":123456,"currency":"USD","scale":2}
+	FormatMinor Format = "minor"
+)
+
+// ParseFormat validates a format requested by a client, empty means FormatNumber
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return FormatNumber, nil
+	case FormatNumber, FormatString, FormatMinor:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid money format: %s. Allowed values are number, string and minor", value)
+	}
+}
+
+// IsPrecise reports whether the format keeps amounts exact
+func (f Format) IsPrecise() bool {
+	return f == FormatString || f == FormatMinor
+}
+
+// Minor is an amount in minor units of its currency, Amount is Scale digits shifted, 12.34 USD is 1234 with scale 2
+type Minor struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency,omitempty"`
+	Scale    int32       `json:"scale"`
+}
+
+// DefaultScale is the number of minor unit digits of currencies not listed in currencyScales
+const DefaultScale int32 = 2
+
+// currencyScales lists ISO 4217 currencies whose minor unit is not a hundredth
+var currencyScales = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Scale returns the number of minor unit digits of the currency
+func Scale(currencyCode string) int32 {
+	if scale, ok := currencyScales[strings.ToUpper(currencyCode)]; ok {
+		return scale
+	}
+	return DefaultScale
+}
+
+// ToMinor converts the amount to minor units of the currency, digits beyond the minor unit are rounded half away from zero
+func ToMinor(amount decimal.Decimal, currencyCode string) Minor {
+	return ToMinorWithScale(amount, currencyCode, Scale(currencyCode))
+}
+
+// ToMinorWithScale converts the amount to minor units of a currency with the given number of decimal places
+func ToMinorWithScale(amount decimal.Decimal, currencyCode string, scale int32) Minor {
+	return Minor{
+		Amount:   json.Number(amount.Shift(scale).Round(0).String()),
+		Currency: currencyCode,
+		Scale:    scale,
+	}
+}
+
+// Value returns the JSON value of the amount in the format. Without a currency FormatMinor
+// uses the default scale and leaves the currency out.
+func Value(amount decimal.Decimal, currencyCode string, format Format) interface{} {
+	switch format {
+	case FormatString:
+		return amount.String()
+	case FormatMinor:
+		return ToMinor(amount, currencyCode)
+	default:
+		return amount.InexactFloat64()
+	}
+}
@@ -2,7 +2,10 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 	"ypeskov/budget-go/internal/constants"
 
 	"github.com/hibiken/asynq"
@@ -15,10 +18,69 @@ type ActivationEmailPayload struct {
 	Token     string `json:"token"`
 }
 
+type BudgetAlertPayload struct {
+	UserEmail       string `json:"userEmail"`
+	UserName        string `json:"userName"`
+	BudgetID        int    `json:"budgetId"`
+	BudgetName      string `json:"budgetName"`
+	Threshold       int    `json:"threshold"`
+	OverBudget      bool   `json:"overBudget"`
+	TargetAmount    string `json:"targetAmount"`
+	CollectedAmount string `json:"collectedAmount"`
+	CurrencyCode    string `json:"currencyCode"`
+	PeriodStart     string `json:"periodStart"`
+	PeriodEnd       string `json:"periodEnd"`
+}
+
+const (
+	// DigestScheduleMaxRetry bounds the retries of a digests scheduling run, they are over in well under an hour
+	DigestScheduleMaxRetry = 5
+	// DigestRetention is how long a sent digest task is kept after completion, longer than the retries of
+	// the scheduling run that queued it
+	DigestRetention = 3 * 24 * time.Hour
+)
+
+// DigestSchedulePayload starts sending the digests of one frequency to all users who opted in
+type DigestSchedulePayload struct {
+	Frequency string `json:"frequency"`
+}
+
+// DigestPayload is the digest of one user. The period is fixed when the digests are scheduled,
+// so a retried task still reports the same period.
+type DigestPayload struct {
+	UserID    int    `json:"userId"`
+	Frequency string `json:"frequency"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+// InsightsEmailPayload lists the new insights of a user found by the nightly analysis
+type InsightsEmailPayload struct {
+	UserEmail string             `json:"userEmail"`
+	UserName  string             `json:"userName"`
+	Insights  []InsightEmailItem `json:"insights"`
+}
+
+type InsightEmailItem struct {
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+}
+
+// ExchangeRatesBackfillPayload is the range of days checked for missing exchange rates
+type ExchangeRatesBackfillPayload struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
 type QueueService interface {
 	EnqueueActivationEmail(userEmail, userName, token string) error
+	EnqueueBudgetAlert(payload BudgetAlertPayload) error
+	EnqueueDigest(payload DigestPayload) error
+	EnqueueInsightsEmail(payload InsightsEmailPayload) error
 	EnqueueDBBackup() error
 	EnqueueExchangeRatesUpdate() error
+	EnqueueExchangeRatesBackfill(payload ExchangeRatesBackfillPayload) error
 }
 
 type QueueServiceInstance struct {
@@ -63,6 +125,62 @@ func (qs *QueueServiceInstance) EnqueueActivationEmail(userEmail, userName, toke
 	return nil
 }
 
+func (qs *QueueServiceInstance) EnqueueBudgetAlert(payload BudgetAlertPayload) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Error marshaling budget alert payload", "error", err)
+		return err
+	}
+
+	_, err = qs.asynqClient.Enqueue(asynq.NewTask(constants.TaskSendBudgetAlert, payloadBytes), asynq.Queue("emails"))
+	if err != nil {
+		logger.Error("Error queuing budget alert task", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (qs *QueueServiceInstance) EnqueueDigest(payload DigestPayload) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Error marshaling digest payload", "error", err)
+		return err
+	}
+
+	// The task ID keeps a retried scheduling run from queueing a digest again. Sent digests are retained
+	// past the retries of the scheduling task, so their IDs still conflict and nobody gets a second email.
+	taskID := fmt.Sprintf("digest:%d:%s:%s", payload.UserID, payload.Frequency, payload.StartDate)
+	_, err = qs.asynqClient.Enqueue(asynq.NewTask(constants.TaskSendDigest, payloadBytes), asynq.Queue("emails"),
+		asynq.TaskID(taskID), asynq.Retention(DigestRetention))
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		logger.Debug("Digest already queued", "userID", payload.UserID, "frequency", payload.Frequency, "startDate", payload.StartDate)
+		return nil
+	}
+	if err != nil {
+		logger.Error("Error queuing digest task", "userID", payload.UserID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (qs *QueueServiceInstance) EnqueueInsightsEmail(payload InsightsEmailPayload) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Error marshaling insights email payload", "error", err)
+		return err
+	}
+
+	_, err = qs.asynqClient.Enqueue(asynq.NewTask(constants.TaskSendInsights, payloadBytes), asynq.Queue("emails"))
+	if err != nil {
+		logger.Error("Error queuing insights email task", "error", err)
+		return err
+	}
+
+	return nil
+}
+
 func (qs *QueueServiceInstance) EnqueueDBBackup() error {
 	_, err := qs.asynqClient.Enqueue(asynq.NewTask(constants.TaskDBBackupDaily, nil), asynq.Queue("default"))
 	if err != nil {
@@ -79,4 +197,26 @@ func (qs *QueueServiceInstance) EnqueueExchangeRatesUpdate() error {
 		return err
 	}
 	return nil
+}
+
+func (qs *QueueServiceInstance) EnqueueExchangeRatesBackfill(payload ExchangeRatesBackfillPayload) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Error marshaling exchange rates backfill payload", "error", err)
+		return err
+	}
+
+	// The task ID keeps repeated requests for a range from fetching it twice while it is queued
+	taskID := fmt.Sprintf("exchange_rates_backfill:%s:%s", payload.StartDate, payload.EndDate)
+	_, err = qs.asynqClient.Enqueue(asynq.NewTask(constants.TaskExchangeRatesBackfill, payloadBytes),
+		asynq.Queue("default"), asynq.TaskID(taskID), asynq.MaxRetry(5))
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		logger.Debug("Exchange rates backfill already queued", "startDate", payload.StartDate, "endDate", payload.EndDate)
+		return nil
+	}
+	if err != nil {
+		logger.Error("Error queuing exchange rates backfill task", "error", err)
+		return err
+	}
+	return nil
 }
\ No newline at end of file
@@ -19,11 +19,27 @@ type Repository interface {
 	ArchiveBudget(budgetID int, userID int) error
 	GetBudgetsWithCurrency(userID int, include string) ([]BudgetWithCurrency, error)
 	UpdateBudgetCollectedAmount(budgetID int, amount decimal.Decimal) error
+	// AddBudgetCollectedAmount atomically adds delta (may be negative) to the budget collected amount
+	AddBudgetCollectedAmount(budgetID int, delta decimal.Decimal) error
+	// GetBudgetsForReconciliation returns running (non-archived) budgets of all users
+	GetBudgetsForReconciliation() ([]models.Budget, error)
 	GetOutdatedBudgets() ([]models.Budget, error)
 	GetUserCategoriesForBudget(userID int, categoryIDs []int) ([]int, error)
 	// GetActiveBudgetsByCategoryAndDate returns budgets for a user whose period covers the given date
-	// and include the given category ID in their included_categories list. Includes archived budgets.
+	// and whose categories cover the given category ID (directly or through a parent). Includes archived budgets.
 	GetActiveBudgetsByCategoryAndDate(userID int, categoryID int, date time.Time) ([]models.Budget, error)
+	// GetBudgetSeries returns all periods of a budget series ordered by start date
+	GetBudgetSeries(seriesID int, userID int) ([]models.Budget, error)
+	// SetBudgetFilter replaces the categories, accounts and tags of the budget
+	SetBudgetFilter(budgetID int, filter models.BudgetFilter) error
+	// GetBudgetCategoryIDs returns the categories counting towards the budget: included categories
+	// with all their subcategories, except excluded ones
+	GetBudgetCategoryIDs(budgetID int) ([]int, error)
+	// GetUserAccountsForBudget returns the IDs of the given accounts that belong to the user
+	GetUserAccountsForBudget(userID int, accountIDs []int) ([]int, error)
+	// MarkBudgetAlertSent records that a threshold alert was sent for the budget period.
+	// Returns false if the alert was already recorded for this period.
+	MarkBudgetAlertSent(budgetID int, threshold int, periodStart time.Time) (bool, error)
 }
 
 type RepositoryInstance struct{}
@@ -42,13 +58,16 @@ func NewBudgetsRepository(dbInstance *sqlx.DB) Repository {
 
 func (r *RepositoryInstance) CreateBudget(budget models.Budget) (*models.Budget, error) {
 	const createBudgetQuery = `
-INSERT INTO budgets (user_id, name, currency_id, target_amount, collected_amount, period, repeat, 
-                     start_date, end_date, included_categories, comment, is_deleted, is_archived, 
-                     created_at, updated_at)
-VALUES (:user_id, :name, :currency_id, :target_amount, :collected_amount, :period, :repeat, 
-        :start_date, :end_date, :included_categories, :comment, :is_deleted, :is_archived, 
-        :created_at, :updated_at)
-RETURNING id
+WITH new_budget AS (SELECT nextval(pg_get_serial_sequence('budgets', 'id')) AS id)
+INSERT INTO budgets (id, series_id, user_id, name, currency_id, target_amount, collected_amount, period, repeat, 
+                     start_date, end_date, anchor_day, anchor_last_business_day, week_start_day, forecast_curve,
+                     alert_thresholds, alert_over_budget, comment, is_deleted, is_archived, created_at, updated_at)
+SELECT new_budget.id, COALESCE(:series_id, new_budget.id), :user_id, :name, :currency_id, :target_amount,
+       :collected_amount, :period, :repeat, :start_date, :end_date, :anchor_day,
+       :anchor_last_business_day, :week_start_day, :forecast_curve, :alert_thresholds, :alert_over_budget, :comment,
+       :is_deleted, :is_archived, :created_at, :updated_at
+FROM new_budget
+RETURNING id, series_id
 `
 
 	stmt, err := db.PrepareNamed(createBudgetQuery)
@@ -57,13 +76,17 @@ RETURNING id
 	}
 	defer stmt.Close()
 
-	var id int
-	err = stmt.Get(&id, budget)
+	var created struct {
+		ID       int `db:"id"`
+		SeriesID int `db:"series_id"`
+	}
+	err = stmt.Get(&created, budget)
 	if err != nil {
 		return nil, err
 	}
 
-	budget.ID = &id
+	budget.ID = &created.ID
+	budget.SeriesID = &created.SeriesID
 	return &budget, nil
 }
 
@@ -78,7 +101,12 @@ UPDATE budgets SET
     repeat = :repeat,
     start_date = :start_date,
     end_date = :end_date,
-    included_categories = :included_categories,
+    anchor_day = :anchor_day,
+    anchor_last_business_day = :anchor_last_business_day,
+    week_start_day = :week_start_day,
+    forecast_curve = :forecast_curve,
+    alert_thresholds = :alert_thresholds,
+    alert_over_budget = :alert_over_budget,
     comment = :comment,
     updated_at = :updated_at
 WHERE id = :id AND user_id = :user_id
@@ -90,9 +118,9 @@ WHERE id = :id AND user_id = :user_id
 
 func (r *RepositoryInstance) GetBudgetByID(budgetID int, userID int) (*models.Budget, error) {
 	const getBudgetQuery = `
-SELECT id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
-       start_date, end_date, included_categories, comment, is_deleted, is_archived,
-       created_at, updated_at
+SELECT id, series_id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
+       start_date, end_date, anchor_day, anchor_last_business_day, week_start_day,
+       forecast_curve, alert_thresholds, alert_over_budget, comment, is_deleted, is_archived, created_at, updated_at
 FROM budgets 
 WHERE id = $1 AND user_id = $2 AND is_deleted = false
 `
@@ -103,14 +131,20 @@ WHERE id = $1 AND user_id = $2 AND is_deleted = false
 		return nil, err
 	}
 
+	filters, err := getBudgetFilters([]int{budgetID})
+	if err != nil {
+		return nil, err
+	}
+	budget.Filter = filters[budgetID]
+
 	return &budget, nil
 }
 
 func (r *RepositoryInstance) GetUserBudgets(userID int, include string) ([]models.Budget, error) {
 	baseQuery := `
-SELECT id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
-       start_date, end_date, included_categories, comment, is_deleted, is_archived,
-       created_at, updated_at
+SELECT id, series_id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
+       start_date, end_date, anchor_day, anchor_last_business_day, week_start_day,
+       forecast_curve, alert_thresholds, alert_over_budget, comment, is_deleted, is_archived, created_at, updated_at
 FROM budgets 
 WHERE user_id = $1 AND is_deleted = false
 `
@@ -135,13 +169,18 @@ WHERE user_id = $1 AND is_deleted = false
 		return nil, err
 	}
 
+	if err = attachBudgetFilters(budgets); err != nil {
+		return nil, err
+	}
+
 	return budgets, nil
 }
 
 func (r *RepositoryInstance) GetBudgetsWithCurrency(userID int, include string) ([]BudgetWithCurrency, error) {
 	baseQuery := `
-SELECT b.id, b.user_id, b.name, b.currency_id, b.target_amount, b.collected_amount, 
-       b.period, b.repeat, b.start_date, b.end_date, b.included_categories, b.comment, 
+SELECT b.id, b.series_id, b.user_id, b.name, b.currency_id, b.target_amount, b.collected_amount, 
+       b.period, b.repeat, b.start_date, b.end_date, b.anchor_day,
+       b.anchor_last_business_day, b.week_start_day, b.forecast_curve, b.alert_thresholds, b.alert_over_budget, b.comment, 
        b.is_deleted, b.is_archived, b.created_at, b.updated_at,
        c.id as "currency.id", c.code as "currency.code", c.name as "currency.name"
 FROM budgets b
@@ -169,6 +208,18 @@ WHERE b.user_id = $1 AND b.is_deleted = false
 		return nil, err
 	}
 
+	budgetIDs := make([]int, len(budgets))
+	for i, budget := range budgets {
+		budgetIDs[i] = *budget.ID
+	}
+	filters, err := getBudgetFilters(budgetIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range budgets {
+		budgets[i].Filter = filters[*budgets[i].ID]
+	}
+
 	return budgets, nil
 }
 
@@ -228,11 +279,42 @@ WHERE id = $2
 	return err
 }
 
+func (r *RepositoryInstance) AddBudgetCollectedAmount(budgetID int, delta decimal.Decimal) error {
+	const addAmountQuery = `
+UPDATE budgets SET collected_amount = collected_amount + $1, updated_at = NOW()
+WHERE id = $2
+`
+
+	_, err := db.Exec(addAmountQuery, delta, budgetID)
+	return err
+}
+
+func (r *RepositoryInstance) GetBudgetsForReconciliation() ([]models.Budget, error) {
+	const q = `
+SELECT id, series_id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
+       start_date, end_date, anchor_day, anchor_last_business_day, week_start_day,
+       forecast_curve, alert_thresholds, alert_over_budget, comment, is_deleted, is_archived, created_at, updated_at
+FROM budgets
+WHERE is_archived = false AND is_deleted = false
+ORDER BY user_id, id
+`
+
+	var budgets []models.Budget
+	if err := db.Select(&budgets, q); err != nil {
+		return nil, err
+	}
+	if err := attachBudgetFilters(budgets); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
 func (r *RepositoryInstance) GetOutdatedBudgets() ([]models.Budget, error) {
 	const getOutdatedQuery = `
-SELECT id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
-       start_date, end_date, included_categories, comment, is_deleted, is_archived,
-       created_at, updated_at
+SELECT id, series_id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
+       start_date, end_date, anchor_day, anchor_last_business_day, week_start_day,
+       forecast_curve, alert_thresholds, alert_over_budget, comment, is_deleted, is_archived, created_at, updated_at
 FROM budgets 
 WHERE end_date < NOW() AND is_archived = false AND is_deleted = false
 `
@@ -243,6 +325,10 @@ WHERE end_date < NOW() AND is_archived = false AND is_deleted = false
 		return nil, err
 	}
 
+	if err = attachBudgetFilters(budgets); err != nil {
+		return nil, err
+	}
+
 	return budgets, nil
 }
 
@@ -275,26 +361,291 @@ WHERE user_id = $1 AND id IN (%s) AND is_deleted = false
 	return validCategories, nil
 }
 
-// GetActiveBudgetsByCategoryAndDate returns budgets whose period covers the given date that include categoryID.
+func (r *RepositoryInstance) GetUserAccountsForBudget(userID int, accountIDs []int) ([]int, error) {
+	if len(accountIDs) == 0 {
+		return []int{}, nil
+	}
+
+	// Create placeholders for the IN clause
+	placeholders := make([]string, len(accountIDs))
+	args := make([]interface{}, len(accountIDs)+1)
+	args[0] = userID
+
+	for i, id := range accountIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args[i+1] = id
+	}
+
+	query := fmt.Sprintf(`
+SELECT id FROM accounts 
+WHERE user_id = $1 AND id IN (%s) AND is_deleted = false
+`, strings.Join(placeholders, ","))
+
+	var validAccounts []int
+	err := db.Select(&validAccounts, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return validAccounts, nil
+}
+
+// GetActiveBudgetsByCategoryAndDate returns budgets whose period covers the given date and whose categories cover categoryID.
 // Archived budgets are included; deleted budgets are excluded.
+// Period windows are stored already aligned to the budget anchors (payday, week start, last business day),
+// so [start_date, end_date) is the exact window for any period type.
 func (r *RepositoryInstance) GetActiveBudgetsByCategoryAndDate(userID int, categoryID int, date time.Time) ([]models.Budget, error) {
-	// included_categories is stored as comma-separated string of ints
-	// Use string_to_array to convert to int[] and check membership with ANY()
+	// The category counts when it or any of its ancestors is included, unless it or an ancestor is excluded
 	const q = `
-SELECT id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
-       start_date, end_date, included_categories, comment, is_deleted, is_archived,
-       created_at, updated_at
-FROM budgets
-WHERE user_id = $1
-  AND is_deleted = false
-  AND start_date <= $2
-  AND end_date > $2
-  AND $3 = ANY(string_to_array(NULLIF(included_categories,''), ',')::int[])
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id, 0 AS depth FROM user_categories WHERE id = $3
+    UNION ALL
+    SELECT uc.id, uc.parent_id, a.depth + 1
+    FROM user_categories uc
+    JOIN ancestors a ON uc.id = a.parent_id
+    WHERE a.depth < 10
+)
+SELECT b.id, b.series_id, b.user_id, b.name, b.currency_id, b.target_amount, b.collected_amount, b.period, b.repeat,
+       b.start_date, b.end_date, b.anchor_day, b.anchor_last_business_day, b.week_start_day, b.forecast_curve,
+       b.alert_thresholds, b.alert_over_budget, b.comment, b.is_deleted, b.is_archived, b.created_at, b.updated_at
+FROM budgets b
+WHERE b.user_id = $1
+  AND b.is_deleted = false
+  AND b.start_date <= $2
+  AND b.end_date > $2
+  AND EXISTS (
+      SELECT 1 FROM budget_categories bc JOIN ancestors a ON a.id = bc.category_id
+      WHERE bc.budget_id = b.id AND bc.is_excluded = false
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM budget_categories bc JOIN ancestors a ON a.id = bc.category_id
+      WHERE bc.budget_id = b.id AND bc.is_excluded = true
+  )
 `
 
 	var budgets []models.Budget
 	if err := db.Select(&budgets, q, userID, date, categoryID); err != nil {
 		return nil, err
 	}
+	if err := attachBudgetFilters(budgets); err != nil {
+		return nil, err
+	}
 	return budgets, nil
 }
+
+// GetBudgetSeries returns all non-deleted budgets (periods) that belong to the series, oldest first.
+func (r *RepositoryInstance) GetBudgetSeries(seriesID int, userID int) ([]models.Budget, error) {
+	const q = `
+SELECT id, series_id, user_id, name, currency_id, target_amount, collected_amount, period, repeat,
+       start_date, end_date, anchor_day, anchor_last_business_day, week_start_day,
+       forecast_curve, alert_thresholds, alert_over_budget, comment, is_deleted, is_archived, created_at, updated_at
+FROM budgets
+WHERE series_id = $1 AND user_id = $2 AND is_deleted = false
+ORDER BY start_date ASC
+`
+
+	var budgets []models.Budget
+	if err := db.Select(&budgets, q, seriesID, userID); err != nil {
+		return nil, err
+	}
+	if err := attachBudgetFilters(budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// MarkBudgetAlertSent inserts the alert record and reports whether it was newly created.
+// The unique constraint on (budget_id, threshold, period_start) guarantees each alert fires once per period.
+func (r *RepositoryInstance) MarkBudgetAlertSent(budgetID int, threshold int, periodStart time.Time) (bool, error) {
+	const q = `
+INSERT INTO budget_alerts_sent (budget_id, threshold, period_start)
+VALUES ($1, $2, $3)
+ON CONFLICT ON CONSTRAINT unique_budget_alert_period DO NOTHING
+`
+
+	result, err := db.Exec(q, budgetID, threshold, periodStart)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark alert %d as sent for budget %d: %w", threshold, budgetID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// SetBudgetFilter replaces categories, accounts and tags of the budget in one transaction
+func (r *RepositoryInstance) SetBudgetFilter(budgetID int, filter models.BudgetFilter) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"budget_categories", "budget_accounts", "budget_tags"} {
+		if _, err = tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE budget_id = $1`, table), budgetID); err != nil {
+			return fmt.Errorf("failed to clear %s of budget %d: %w", table, budgetID, err)
+		}
+	}
+
+	const insertCategoryQuery = `INSERT INTO budget_categories (budget_id, category_id, is_excluded) VALUES ($1, $2, $3)`
+	for _, categoryID := range filter.IncludedCategories {
+		if _, err = tx.Exec(insertCategoryQuery, budgetID, categoryID, false); err != nil {
+			return fmt.Errorf("failed to add category %d to budget %d: %w", categoryID, budgetID, err)
+		}
+	}
+	for _, categoryID := range filter.ExcludedCategories {
+		if _, err = tx.Exec(insertCategoryQuery, budgetID, categoryID, true); err != nil {
+			return fmt.Errorf("failed to exclude category %d from budget %d: %w", categoryID, budgetID, err)
+		}
+	}
+
+	for _, accountID := range filter.Accounts {
+		if _, err = tx.Exec(`INSERT INTO budget_accounts (budget_id, account_id) VALUES ($1, $2)`, budgetID, accountID); err != nil {
+			return fmt.Errorf("failed to add account %d to budget %d: %w", accountID, budgetID, err)
+		}
+	}
+
+	const insertTagQuery = `INSERT INTO budget_tags (budget_id, tag, is_excluded) VALUES ($1, $2, $3)`
+	for _, tag := range filter.IncludedTags {
+		if _, err = tx.Exec(insertTagQuery, budgetID, tag, false); err != nil {
+			return fmt.Errorf("failed to add tag %s to budget %d: %w", tag, budgetID, err)
+		}
+	}
+	for _, tag := range filter.ExcludedTags {
+		if _, err = tx.Exec(insertTagQuery, budgetID, tag, true); err != nil {
+			return fmt.Errorf("failed to exclude tag %s from budget %d: %w", tag, budgetID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBudgetCategoryIDs walks down from the included categories, an excluded category cuts off its whole subtree
+func (r *RepositoryInstance) GetBudgetCategoryIDs(budgetID int) ([]int, error) {
+	const q = `
+WITH RECURSIVE included AS (
+    SELECT bc.category_id AS id, 0 AS depth
+    FROM budget_categories bc
+    WHERE bc.budget_id = $1 AND bc.is_excluded = false
+    UNION ALL
+    SELECT uc.id, i.depth + 1
+    FROM user_categories uc
+    JOIN included i ON uc.parent_id = i.id
+    WHERE i.depth < 10 AND uc.is_deleted = false
+)
+SELECT DISTINCT id FROM included
+WHERE id NOT IN (SELECT category_id FROM budget_categories WHERE budget_id = $1 AND is_excluded = true)
+ORDER BY id
+`
+
+	var categoryIDs []int
+	if err := db.Select(&categoryIDs, q, budgetID); err != nil {
+		return nil, fmt.Errorf("failed to get categories of budget %d: %w", budgetID, err)
+	}
+
+	return categoryIDs, nil
+}
+
+// attachBudgetFilters loads categories, accounts and tags of the budgets
+func attachBudgetFilters(budgets []models.Budget) error {
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	budgetIDs := make([]int, len(budgets))
+	for i, budget := range budgets {
+		budgetIDs[i] = *budget.ID
+	}
+
+	filters, err := getBudgetFilters(budgetIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range budgets {
+		budgets[i].Filter = filters[*budgets[i].ID]
+	}
+	return nil
+}
+
+func getBudgetFilters(budgetIDs []int) (map[int]models.BudgetFilter, error) {
+	filters := make(map[int]models.BudgetFilter, len(budgetIDs))
+	if len(budgetIDs) == 0 {
+		return filters, nil
+	}
+	for _, id := range budgetIDs {
+		filters[id] = models.BudgetFilter{
+			IncludedCategories: []int{},
+			ExcludedCategories: []int{},
+			Accounts:           []int{},
+			IncludedTags:       []string{},
+			ExcludedTags:       []string{},
+		}
+	}
+
+	// Create placeholders for the IN clause
+	placeholders := make([]string, len(budgetIDs))
+	args := make([]interface{}, len(budgetIDs))
+	for i, id := range budgetIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	in := strings.Join(placeholders, ",")
+
+	var categories []struct {
+		BudgetID   int  `db:"budget_id"`
+		CategoryID int  `db:"category_id"`
+		IsExcluded bool `db:"is_excluded"`
+	}
+	query := fmt.Sprintf(`SELECT budget_id, category_id, is_excluded FROM budget_categories WHERE budget_id IN (%s) ORDER BY category_id`, in)
+	if err := db.Select(&categories, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get budget categories: %w", err)
+	}
+	for _, row := range categories {
+		filter := filters[row.BudgetID]
+		if row.IsExcluded {
+			filter.ExcludedCategories = append(filter.ExcludedCategories, row.CategoryID)
+		} else {
+			filter.IncludedCategories = append(filter.IncludedCategories, row.CategoryID)
+		}
+		filters[row.BudgetID] = filter
+	}
+
+	var accounts []struct {
+		BudgetID  int `db:"budget_id"`
+		AccountID int `db:"account_id"`
+	}
+	query = fmt.Sprintf(`SELECT budget_id, account_id FROM budget_accounts WHERE budget_id IN (%s) ORDER BY account_id`, in)
+	if err := db.Select(&accounts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get budget accounts: %w", err)
+	}
+	for _, row := range accounts {
+		filter := filters[row.BudgetID]
+		filter.Accounts = append(filter.Accounts, row.AccountID)
+		filters[row.BudgetID] = filter
+	}
+
+	var tags []struct {
+		BudgetID   int    `db:"budget_id"`
+		Tag        string `db:"tag"`
+		IsExcluded bool   `db:"is_excluded"`
+	}
+	query = fmt.Sprintf(`SELECT budget_id, tag, is_excluded FROM budget_tags WHERE budget_id IN (%s) ORDER BY tag`, in)
+	if err := db.Select(&tags, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get budget tags: %w", err)
+	}
+	for _, row := range tags {
+		filter := filters[row.BudgetID]
+		if row.IsExcluded {
+			filter.ExcludedTags = append(filter.ExcludedTags, row.Tag)
+		} else {
+			filter.IncludedTags = append(filter.IncludedTags, row.Tag)
+		}
+		filters[row.BudgetID] = filter
+	}
+
+	return filters, nil
+}
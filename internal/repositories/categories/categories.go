@@ -1,6 +1,11 @@
 package categories
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 	"ypeskov/budget-go/internal/models"
 
 	"github.com/jmoiron/sqlx"
@@ -10,6 +15,48 @@ type Repository interface {
 	GetUserCategories(userId int) ([]models.UserCategory, error)
 	CreateCategory(category models.UserCategory) (*models.UserCategory, error)
 	ValidateCategoryOwnership(categoryId int, userId int) (bool, error)
+	GetCategory(categoryId int, userId int) (*models.UserCategory, error)
+	GetChildCategories(categoryId int, userId int) ([]models.UserCategory, error)
+	// GetCategoryUsage counts transactions, templates and budgets referencing the category
+	GetCategoryUsage(categoryId int, userId int) (*CategoryUsage, error)
+	// GetCategoryTransactionPeriod returns the dates of the first and last transactions of the category
+	// and its subcategories
+	GetCategoryTransactionPeriod(categoryId int, userId int) (*TransactionPeriod, error)
+	UpdateCategory(category models.UserCategory) error
+	// DeleteCategory marks an unused category as deleted
+	DeleteCategory(categoryId int, userId int) error
+	// MergeCategory moves everything referencing the source category to the target category,
+	// puts subcategories of the source under the target and deletes the source
+	MergeCategory(sourceId int, targetId int, userId int) error
+	// GetDefaultCategories returns the default category set of the language, parents first
+	GetDefaultCategories(languageCode string) ([]models.DefaultCategory, error)
+	// ApplyCategoryTree creates the categories of the tree the user does not have yet. Names are matched
+	// case-insensitively under the same parent and type. With replace the categories outside the tree
+	// are deleted unless transactions, templates, budgets or envelopes use them.
+	ApplyCategoryTree(userId int, nodes []models.CategoryNode, replace bool) (*CategoryTreeResult, error)
+}
+
+type CategoryUsage struct {
+	Transactions int `db:"transactions"`
+	Templates    int `db:"templates"`
+	Budgets      int `db:"budgets"`
+}
+
+// TransactionPeriod is the range of transaction dates, both are nil when there are no transactions
+type TransactionPeriod struct {
+	First *time.Time `db:"first"`
+	Last  *time.Time `db:"last"`
+}
+
+// InUse reports whether anything still references the category
+func (u CategoryUsage) InUse() bool {
+	return u.Transactions > 0 || u.Templates > 0 || u.Budgets > 0
+}
+
+type CategoryTreeResult struct {
+	Created  int
+	Existing int
+	Deleted  int
 }
 
 type RepositoryInstance struct{}
@@ -32,7 +79,11 @@ SELECT
     c.user_id,
     c.is_deleted,
     c.created_at,
-    c.updated_at
+    c.updated_at,
+    c.color,
+    c.icon,
+    p.color AS parent_color,
+    p.icon AS parent_icon
 FROM user_categories c
 LEFT JOIN user_categories p ON p.id = c.parent_id AND p.user_id = c.user_id AND p.is_deleted = false
 WHERE c.user_id = $1 AND c.is_deleted = false
@@ -49,9 +100,9 @@ ORDER BY LOWER(c.name) ASC;
 
 func (r *RepositoryInstance) CreateCategory(category models.UserCategory) (*models.UserCategory, error) {
 	const createCategoryQuery = `
-		INSERT INTO user_categories (name, parent_id, is_income, user_id, is_deleted, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-		RETURNING id, name, parent_id, is_income, user_id, is_deleted, created_at, updated_at
+		INSERT INTO user_categories (name, parent_id, is_income, user_id, is_deleted, color, icon, created_at, updated_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING id, name, parent_id, is_income, user_id, is_deleted, color, icon, created_at, updated_at
 	`
 
 	var createdCategory models.UserCategory
@@ -62,6 +113,8 @@ func (r *RepositoryInstance) CreateCategory(category models.UserCategory) (*mode
 		category.IsIncome,
 		category.UserID,
 		category.IsDeleted,
+		category.Color,
+		category.Icon,
 	).Scan(
 		&createdCategory.ID,
 		&createdCategory.Name,
@@ -69,6 +122,8 @@ func (r *RepositoryInstance) CreateCategory(category models.UserCategory) (*mode
 		&createdCategory.IsIncome,
 		&createdCategory.UserID,
 		&createdCategory.IsDeleted,
+		&createdCategory.Color,
+		&createdCategory.Icon,
 		&createdCategory.CreatedAt,
 		&createdCategory.UpdatedAt,
 	)
@@ -77,13 +132,19 @@ func (r *RepositoryInstance) CreateCategory(category models.UserCategory) (*mode
 		return nil, err
 	}
 
-	// If it has a parent, get the parent name for the response
+	// If it has a parent, get the parent name and appearance for the response
 	if createdCategory.ParentID != nil {
-		const getParentNameQuery = `SELECT name FROM user_categories WHERE id = $1 AND user_id = $2`
-		var parentName string
-		err := db.Get(&parentName, getParentNameQuery, *createdCategory.ParentID, category.UserID)
+		const getParentQuery = `SELECT name, color, icon FROM user_categories WHERE id = $1 AND user_id = $2`
+		var parent struct {
+			Name  string  `db:"name"`
+			Color *string `db:"color"`
+			Icon  *string `db:"icon"`
+		}
+		err := db.Get(&parent, getParentQuery, *createdCategory.ParentID, category.UserID)
 		if err == nil {
-			createdCategory.ParentName = &parentName
+			createdCategory.ParentName = &parent.Name
+			createdCategory.ParentColor = parent.Color
+			createdCategory.ParentIcon = parent.Icon
 		}
 	}
 
@@ -103,3 +164,303 @@ WHERE id = $1 AND user_id = $2 AND is_deleted = false
 
 	return count > 0, nil
 }
+
+func (r *RepositoryInstance) GetCategory(categoryId int, userId int) (*models.UserCategory, error) {
+	const getCategoryQuery = `
+SELECT c.id, c.name, c.parent_id, p.name AS parent_name, c.is_income, c.user_id, c.is_deleted, c.created_at, c.updated_at,
+       c.color, c.icon, p.color AS parent_color, p.icon AS parent_icon
+FROM user_categories c
+LEFT JOIN user_categories p ON p.id = c.parent_id AND p.user_id = c.user_id AND p.is_deleted = false
+WHERE c.id = $1 AND c.user_id = $2 AND c.is_deleted = false
+`
+	var category models.UserCategory
+	err := db.Get(&category, getCategoryQuery, categoryId, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (r *RepositoryInstance) GetChildCategories(categoryId int, userId int) ([]models.UserCategory, error) {
+	const getChildrenQuery = `
+SELECT id, name, parent_id, is_income, user_id, is_deleted, created_at, updated_at, color, icon
+FROM user_categories
+WHERE parent_id = $1 AND user_id = $2 AND is_deleted = false
+ORDER BY LOWER(name) ASC
+`
+	var children []models.UserCategory
+	if err := db.Select(&children, getChildrenQuery, categoryId, userId); err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+func (r *RepositoryInstance) GetCategoryUsage(categoryId int, userId int) (*CategoryUsage, error) {
+	const getUsageQuery = `
+SELECT
+    (SELECT COUNT(*) FROM transactions WHERE category_id = $1 AND user_id = $2 AND is_deleted = false) AS transactions,
+    (SELECT COUNT(*) FROM transaction_templates WHERE category_id = $1 AND user_id = $2) AS templates,
+    (SELECT COUNT(DISTINCT bc.budget_id) FROM budget_categories bc JOIN budgets b ON b.id = bc.budget_id
+     WHERE bc.category_id = $1 AND b.user_id = $2 AND b.is_deleted = false) AS budgets
+`
+	var usage CategoryUsage
+	if err := db.Get(&usage, getUsageQuery, categoryId, userId); err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+func (r *RepositoryInstance) GetCategoryTransactionPeriod(categoryId int, userId int) (*TransactionPeriod, error) {
+	const getPeriodQuery = `
+SELECT MIN(date_time) AS first, MAX(date_time) AS last
+FROM transactions
+WHERE user_id = $2 AND is_deleted = false
+  AND category_id IN (SELECT id FROM user_categories WHERE (id = $1 OR parent_id = $1) AND user_id = $2)
+`
+	var period TransactionPeriod
+	if err := db.Get(&period, getPeriodQuery, categoryId, userId); err != nil {
+		return nil, err
+	}
+
+	return &period, nil
+}
+
+func (r *RepositoryInstance) UpdateCategory(category models.UserCategory) error {
+	const updateCategoryQuery = `
+UPDATE user_categories SET name = $1, parent_id = $2, color = $3, icon = $4, updated_at = NOW()
+WHERE id = $5 AND user_id = $6 AND is_deleted = false
+`
+	result, err := db.Exec(updateCategoryQuery, category.Name, category.ParentID, category.Color, category.Icon,
+		category.ID, category.UserID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+func (r *RepositoryInstance) DeleteCategory(categoryId int, userId int) error {
+	const deleteCategoryQuery = `
+UPDATE user_categories SET is_deleted = true, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND is_deleted = false
+`
+	result, err := db.Exec(deleteCategoryQuery, categoryId, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+func (r *RepositoryInstance) MergeCategory(sourceId int, targetId int, userId int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"transactions", `UPDATE transactions SET category_id = $2, updated_at = NOW() WHERE category_id = $1 AND user_id = $3`},
+		{"templates", `UPDATE transaction_templates SET category_id = $2, updated_at = NOW() WHERE category_id = $1 AND user_id = $3`},
+		// A budget already having a row for the target keeps it
+		{"budgets", `
+INSERT INTO budget_categories (budget_id, category_id, is_excluded)
+SELECT bc.budget_id, $2, bc.is_excluded
+FROM budget_categories bc JOIN budgets b ON b.id = bc.budget_id
+WHERE bc.category_id = $1 AND b.user_id = $3
+ON CONFLICT (budget_id, category_id) DO NOTHING`},
+		{"budgets", `DELETE FROM budget_categories WHERE category_id = $1`},
+		// Money assigned to both envelopes in the same month is added up
+		{"envelopes", `
+INSERT INTO envelope_assignments (user_id, category_id, month, amount)
+SELECT user_id, $2, month, amount FROM envelope_assignments WHERE category_id = $1 AND user_id = $3
+ON CONFLICT (user_id, category_id, month)
+DO UPDATE SET amount = envelope_assignments.amount + EXCLUDED.amount, updated_at = NOW()`},
+		{"envelopes", `DELETE FROM envelope_assignments WHERE category_id = $1 AND user_id = $3`},
+		{"subcategories", `UPDATE user_categories SET parent_id = $2, updated_at = NOW() WHERE parent_id = $1 AND user_id = $3 AND is_deleted = false`},
+		{"category", `UPDATE user_categories SET is_deleted = true, updated_at = NOW() WHERE id = $1 AND user_id = $3`},
+	}
+
+	for _, step := range steps {
+		if _, err = tx.Exec(step.query, sourceId, targetId, userId); err != nil {
+			return fmt.Errorf("failed to merge %s of category %d into %d: %w", step.name, sourceId, targetId, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *RepositoryInstance) GetDefaultCategories(languageCode string) ([]models.DefaultCategory, error) {
+	const getDefaultCategoriesQuery = `
+SELECT id, name, parent_id, is_income, language_code
+FROM default_categories
+WHERE language_code = $1 AND is_deleted = false
+ORDER BY parent_id NULLS FIRST, id
+`
+	var defaultCategories []models.DefaultCategory
+	if err := db.Select(&defaultCategories, getDefaultCategoriesQuery, languageCode); err != nil {
+		return nil, err
+	}
+
+	return defaultCategories, nil
+}
+
+func (r *RepositoryInstance) ApplyCategoryTree(userId int, nodes []models.CategoryNode, replace bool) (*CategoryTreeResult, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var existing []models.UserCategory
+	const getExistingQuery = `
+SELECT id, name, parent_id, is_income FROM user_categories WHERE user_id = $1 AND is_deleted = false
+`
+	if err = tx.Select(&existing, getExistingQuery, userId); err != nil {
+		return nil, err
+	}
+
+	// Top-level categories are keyed by type and name, subcategories by parent and name
+	topLevel := make(map[string]int)
+	subcategories := make(map[string]int)
+	for _, category := range existing {
+		if category.ParentID == nil {
+			topLevel[topLevelCategoryKey(*category.IsIncome, *category.Name)] = *category.ID
+		} else {
+			subcategories[subcategoryKey(*category.ParentID, *category.Name)] = *category.ID
+		}
+	}
+
+	const insertCategoryQuery = `
+INSERT INTO user_categories (name, parent_id, is_income, user_id, is_deleted, color, icon, created_at, updated_at)
+VALUES ($1, $2, $3, $4, false, $5, $6, NOW(), NOW())
+RETURNING id
+`
+	result := &CategoryTreeResult{}
+	inTree := make(map[int]bool)
+	apply := func(node models.CategoryNode, parentId *int, keys map[string]int, key string) (int, error) {
+		if id, ok := keys[key]; ok {
+			result.Existing++
+			inTree[id] = true
+			return id, nil
+		}
+
+		var id int
+		err := tx.Get(&id, insertCategoryQuery, node.Name, parentId, node.IsIncome, userId, node.Color, node.Icon)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create category %s: %w", node.Name, err)
+		}
+		keys[key] = id
+		inTree[id] = true
+		result.Created++
+		return id, nil
+	}
+
+	for _, node := range nodes {
+		parentId, err := apply(node, nil, topLevel, topLevelCategoryKey(node.IsIncome, node.Name))
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range node.Children {
+			if _, err = apply(child, &parentId, subcategories, subcategoryKey(parentId, child.Name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if replace {
+		if result.Deleted, err = deleteCategoriesOutsideTree(tx, userId, existing, inTree); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// deleteCategoriesOutsideTree deletes the unused categories that are not in the tree.
+// A parent stays as long as one of its subcategories stays.
+func deleteCategoriesOutsideTree(tx *sqlx.Tx, userId int, existing []models.UserCategory, inTree map[int]bool) (int, error) {
+	const getUsedCategoriesQuery = `
+SELECT category_id FROM transactions WHERE user_id = $1 AND is_deleted = false AND category_id IS NOT NULL
+UNION
+SELECT category_id FROM transaction_templates WHERE user_id = $1
+UNION
+SELECT bc.category_id FROM budget_categories bc JOIN budgets b ON b.id = bc.budget_id
+WHERE b.user_id = $1 AND b.is_deleted = false
+UNION
+SELECT category_id FROM envelope_assignments WHERE user_id = $1
+`
+	var usedIds []int
+	if err := tx.Select(&usedIds, getUsedCategoriesQuery, userId); err != nil {
+		return 0, err
+	}
+
+	kept := make(map[int]bool, len(usedIds))
+	for _, id := range usedIds {
+		kept[id] = true
+	}
+	for id := range inTree {
+		kept[id] = true
+	}
+	for _, category := range existing {
+		if category.ParentID != nil && kept[*category.ID] {
+			kept[*category.ParentID] = true
+		}
+	}
+
+	deleteIds := make([]int, 0)
+	for _, category := range existing {
+		if !kept[*category.ID] {
+			deleteIds = append(deleteIds, *category.ID)
+		}
+	}
+	if len(deleteIds) == 0 {
+		return 0, nil
+	}
+
+	const deleteCategoriesQuery = `
+UPDATE user_categories SET is_deleted = true, updated_at = NOW() WHERE user_id = $1 AND id = ANY($2)
+`
+	if _, err := tx.Exec(deleteCategoriesQuery, userId, deleteIds); err != nil {
+		return 0, err
+	}
+
+	return len(deleteIds), nil
+}
+
+func topLevelCategoryKey(isIncome bool, name string) string {
+	return fmt.Sprintf("%t:%s", isIncome, strings.ToLower(strings.TrimSpace(name)))
+}
+
+func subcategoryKey(parentId int, name string) string {
+	return fmt.Sprintf("%d:%s", parentId, strings.ToLower(strings.TrimSpace(name)))
+}
@@ -8,9 +8,15 @@ import (
 )
 
 type Repository interface {
-	GetCurrencies() ([]models.Currency, error)
+	GetCurrencies(userID int) ([]models.Currency, error)
 	GetCurrency(id int) (models.Currency, error)
 	GetCurrencyByCode(code string) (models.Currency, error)
+	GetUserCurrencyByCode(userID int, code string) (models.Currency, error)
+	GetCurrencyScales(userID int) (map[string]int32, error)
+	CreateCurrency(currency models.Currency) (models.Currency, error)
+	UpdateCurrency(currency models.Currency) error
+	DeleteCurrency(id int, userID int) error
+	IsCurrencyUsed(id int) (bool, error)
 }
 
 type RepositoryInstance struct{}
@@ -22,11 +28,14 @@ func NewCurrenciesRepository(dbInstance *sqlx.DB) Repository {
 	return &RepositoryInstance{}
 }
 
-func (r *RepositoryInstance) GetCurrencies() ([]models.Currency, error) {
-	const getCurrenciesQuery = `SELECT id, code, name FROM currencies WHERE is_deleted = false;`
+// GetCurrencies returns the common currencies and the private ones of the user
+func (r *RepositoryInstance) GetCurrencies(userID int) ([]models.Currency, error) {
+	const getCurrenciesQuery = `SELECT id, code, name, user_id, decimal_places FROM currencies
+WHERE is_deleted = false AND (user_id IS NULL OR user_id = $1)
+ORDER BY user_id NULLS FIRST, code;`
 
 	var currencies []models.Currency
-	err := db.Select(&currencies, getCurrenciesQuery)
+	err := db.Select(&currencies, getCurrenciesQuery, userID)
 	if err != nil {
 		logger.Error("Failed to get currencies", "error", err)
 		return nil, err
@@ -35,7 +44,8 @@ func (r *RepositoryInstance) GetCurrencies() ([]models.Currency, error) {
 }
 
 func (r *RepositoryInstance) GetCurrency(id int) (models.Currency, error) {
-	const getCurrencyQuery = `SELECT id, code, name, created_at, updated_at FROM currencies WHERE id = $1 AND is_deleted = false;`
+	const getCurrencyQuery = `SELECT id, code, name, user_id, decimal_places, created_at, updated_at
+FROM currencies WHERE id = $1 AND is_deleted = false;`
 
 	var currency models.Currency
 	err := db.Get(&currency, getCurrencyQuery, id)
@@ -46,9 +56,10 @@ func (r *RepositoryInstance) GetCurrency(id int) (models.Currency, error) {
 	return currency, nil
 }
 
+// GetCurrencyByCode returns a common currency, private currencies are looked up with GetUserCurrencyByCode
 func (r *RepositoryInstance) GetCurrencyByCode(code string) (models.Currency, error) {
-	const getCurrencyByCodeQuery = `SELECT id, code, name, created_at, updated_at
-FROM currencies WHERE code = $1 AND is_deleted = false;`
+	const getCurrencyByCodeQuery = `SELECT id, code, name, user_id, decimal_places, created_at, updated_at
+FROM currencies WHERE code = $1 AND user_id IS NULL AND is_deleted = false;`
 
 	var currency models.Currency
 	err := db.Get(&currency, getCurrencyByCodeQuery, code)
@@ -58,3 +69,100 @@ FROM currencies WHERE code = $1 AND is_deleted = false;`
 
 	return currency, nil
 }
+
+// GetUserCurrencyByCode returns the common or private currency of the user with the code
+func (r *RepositoryInstance) GetUserCurrencyByCode(userID int, code string) (models.Currency, error) {
+	const getUserCurrencyByCodeQuery = `SELECT id, code, name, user_id, decimal_places, created_at, updated_at
+FROM currencies WHERE code = $1 AND (user_id IS NULL OR user_id = $2) AND is_deleted = false
+ORDER BY user_id NULLS LAST
+LIMIT 1;`
+
+	var currency models.Currency
+	err := db.Get(&currency, getUserCurrencyByCodeQuery, code, userID)
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	return currency, nil
+}
+
+// GetCurrencyScales returns the decimal places of the currencies of the user that override the ISO ones
+func (r *RepositoryInstance) GetCurrencyScales(userID int) (map[string]int32, error) {
+	const getCurrencyScalesQuery = `SELECT code, decimal_places FROM currencies
+WHERE decimal_places IS NOT NULL AND is_deleted = false AND (user_id IS NULL OR user_id = $1);`
+
+	var rows []struct {
+		Code          string `db:"code"`
+		DecimalPlaces int32  `db:"decimal_places"`
+	}
+	if err := db.Select(&rows, getCurrencyScalesQuery, userID); err != nil {
+		logger.Error("Failed to get currency scales", "error", err)
+		return nil, err
+	}
+
+	scales := make(map[string]int32, len(rows))
+	for _, row := range rows {
+		scales[row.Code] = row.DecimalPlaces
+	}
+	return scales, nil
+}
+
+func (r *RepositoryInstance) CreateCurrency(currency models.Currency) (models.Currency, error) {
+	const createCurrencyQuery = `
+INSERT INTO currencies (code, name, user_id, decimal_places, is_deleted, created_at, updated_at)
+VALUES (:code, :name, :user_id, :decimal_places, false, :created_at, :updated_at)
+RETURNING id
+`
+
+	stmt, err := db.PrepareNamed(createCurrencyQuery)
+	if err != nil {
+		return models.Currency{}, err
+	}
+	defer stmt.Close()
+
+	if err = stmt.Get(&currency.ID, currency); err != nil {
+		logger.Error("Failed to create currency", "error", err)
+		return models.Currency{}, err
+	}
+
+	return currency, nil
+}
+
+// UpdateCurrency updates the name and decimal places of a private currency, the code stays
+// because rates and reports refer to it
+func (r *RepositoryInstance) UpdateCurrency(currency models.Currency) error {
+	const updateCurrencyQuery = `
+UPDATE currencies SET
+    name = :name,
+    decimal_places = :decimal_places,
+    updated_at = :updated_at
+WHERE id = :id AND user_id = :user_id AND is_deleted = false
+`
+
+	_, err := db.NamedExec(updateCurrencyQuery, currency)
+	return err
+}
+
+func (r *RepositoryInstance) DeleteCurrency(id int, userID int) error {
+	const deleteCurrencyQuery = `
+UPDATE currencies SET is_deleted = true, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND is_deleted = false
+`
+
+	_, err := db.Exec(deleteCurrencyQuery, id, userID)
+	return err
+}
+
+// IsCurrencyUsed reports whether accounts, budgets or goals that are not deleted or a user's base currency setting use the currency
+func (r *RepositoryInstance) IsCurrencyUsed(id int) (bool, error) {
+	const isCurrencyUsedQuery = `
+SELECT EXISTS (SELECT 1 FROM accounts WHERE currency_id = $1 AND is_deleted = false)
+    OR EXISTS (SELECT 1 FROM budgets WHERE currency_id = $1 AND is_deleted = false)
+    OR EXISTS (SELECT 1 FROM goals WHERE currency_id = $1 AND is_deleted = false)
+    OR EXISTS (SELECT 1 FROM users WHERE base_currency_id = $1)
+`
+
+	var used bool
+	err := db.Get(&used, isCurrencyUsedQuery, id)
+	return used, err
+}
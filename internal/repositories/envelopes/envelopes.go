@@ -0,0 +1,111 @@
+package envelopes
+
+import (
+	"fmt"
+	"time"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+type Repository interface {
+	// GetAssignmentsUntil returns all assignments of the user for months up to and including the given month
+	GetAssignmentsUntil(userID int, month time.Time) ([]models.EnvelopeAssignment, error)
+	SetAssignment(userID int, categoryID int, month time.Time, amount decimal.Decimal) error
+	MoveAssignment(userID int, fromCategoryID int, toCategoryID int, month time.Time, amount decimal.Decimal) error
+	// GetDailyActivity returns income and expense totals per day, category and currency before the given time
+	GetDailyActivity(userID int, before time.Time) ([]ActivityRow, error)
+}
+
+type RepositoryInstance struct{}
+
+// ActivityRow is the sum of non-transfer transactions of one day, category and account currency
+type ActivityRow struct {
+	CategoryID   *int            `db:"category_id"`
+	IsIncome     bool            `db:"is_income"`
+	Day          time.Time       `db:"day"`
+	Amount       decimal.Decimal `db:"amount"`
+	CurrencyCode string          `db:"currency_code"`
+}
+
+var db *sqlx.DB
+
+func NewEnvelopesRepository(dbInstance *sqlx.DB) Repository {
+	db = dbInstance
+	return &RepositoryInstance{}
+}
+
+func (r *RepositoryInstance) GetAssignmentsUntil(userID int, month time.Time) ([]models.EnvelopeAssignment, error) {
+	const q = `
+SELECT id, user_id, category_id, month, amount, created_at, updated_at
+FROM envelope_assignments
+WHERE user_id = $1 AND month <= $2
+ORDER BY month ASC
+`
+
+	var assignments []models.EnvelopeAssignment
+	if err := db.Select(&assignments, q, userID, month); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+const upsertAssignmentQuery = `
+INSERT INTO envelope_assignments (user_id, category_id, month, amount)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, category_id, month) DO UPDATE SET amount = %s, updated_at = NOW()
+`
+
+func (r *RepositoryInstance) SetAssignment(userID int, categoryID int, month time.Time, amount decimal.Decimal) error {
+	_, err := db.Exec(fmt.Sprintf(upsertAssignmentQuery, "EXCLUDED.amount"), userID, categoryID, month, amount)
+	return err
+}
+
+// MoveAssignment lowers the assignment of one envelope and raises the other one by the same amount
+func (r *RepositoryInstance) MoveAssignment(userID int, fromCategoryID int, toCategoryID int, month time.Time, amount decimal.Decimal) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	addQuery := fmt.Sprintf(upsertAssignmentQuery, "envelope_assignments.amount + EXCLUDED.amount")
+
+	if _, err = tx.Exec(addQuery, userID, fromCategoryID, month, amount.Neg()); err != nil {
+		return fmt.Errorf("failed to take money from envelope %d: %w", fromCategoryID, err)
+	}
+	if _, err = tx.Exec(addQuery, userID, toCategoryID, month, amount); err != nil {
+		return fmt.Errorf("failed to add money to envelope %d: %w", toCategoryID, err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *RepositoryInstance) GetDailyActivity(userID int, before time.Time) ([]ActivityRow, error) {
+	const q = `
+SELECT
+    t.category_id,
+    t.is_income,
+    DATE_TRUNC('day', t.date_time) AS day,
+    SUM(ABS(t.amount)) AS amount,
+    c.code AS currency_code
+FROM transactions t
+JOIN accounts a ON t.account_id = a.id
+JOIN currencies c ON a.currency_id = c.id
+WHERE a.user_id = $1
+  AND t.date_time < $2
+  AND t.is_deleted = false
+  AND t.is_transfer = false
+GROUP BY t.category_id, t.is_income, DATE_TRUNC('day', t.date_time), c.code
+ORDER BY day ASC
+`
+
+	var rows []ActivityRow
+	if err := db.Select(&rows, q, userID, before); err != nil {
+		return nil, fmt.Errorf("failed to get envelope activity: %w", err)
+	}
+
+	return rows, nil
+}
@@ -1,6 +1,7 @@
 package exchangeRates
 
 import (
+	"time"
 	"ypeskov/budget-go/internal/models"
 
 	"github.com/jmoiron/sqlx"
@@ -10,9 +11,12 @@ import (
 var db *sqlx.DB
 
 type Repository interface {
-	GetExchangeRates() ([]models.ExchangeRates, error)
-	SaveExchangeRates(rates *models.ExchangeRates) error
-	DeleteExchangeRatesByDate(date string) error
+	GetExchangeRatesForRange(startDate, endDate string) ([]models.ExchangeRates, error)
+	ReplaceExchangeRates(rates *models.ExchangeRates) error
+	GetRateDates(startDate, endDate string) ([]time.Time, error)
+	// SaveRateGap records that the rates asked for on requestedDate were the rates of an earlier actualDate
+	SaveRateGap(requestedDate, actualDate, serviceName string) error
+	GetRateGapDates(startDate, endDate string) ([]time.Time, error)
 }
 
 type RepositoryInstance struct{}
@@ -22,17 +26,25 @@ func NewExchangeRatesRepository(dbInstance *sqlx.DB) Repository {
 	return &RepositoryInstance{}
 }
 
-func (r *RepositoryInstance) GetExchangeRates() ([]models.ExchangeRates, error) {
-	logger.Debug("GetExchangeRates Repository")
+// GetExchangeRatesForRange returns the rates of the dates between startDate and endDate inclusive
+// and the latest rates before startDate, which apply to the first days of the range
+func (r *RepositoryInstance) GetExchangeRatesForRange(startDate, endDate string) ([]models.ExchangeRates, error) {
+	logger.Debug("GetExchangeRatesForRange Repository")
 	const query = `
 	SELECT id AS id, rates AS rates, actual_date AS actual_date, base_currency_code AS base_currency_code, 
 			service_name AS service_name, is_deleted AS is_deleted, created_at AS created_at, updated_at AS updated_at
 	FROM exchange_rates
-	WHERE is_deleted = false
+	WHERE is_deleted = false AND actual_date BETWEEN $1 AND $2
+	UNION ALL
+	(SELECT id, rates, actual_date, base_currency_code, service_name, is_deleted, created_at, updated_at
+	FROM exchange_rates
+	WHERE is_deleted = false AND actual_date < $1
+	ORDER BY actual_date DESC
+	LIMIT 1)
 	`
 
 	var exchangeRates []models.ExchangeRates
-	err := db.Select(&exchangeRates, query)
+	err := db.Select(&exchangeRates, query, startDate, endDate)
 	if err != nil {
 		logger.Error("Error getting exchange rates: ", err)
 		return nil, err
@@ -41,16 +53,37 @@ func (r *RepositoryInstance) GetExchangeRates() ([]models.ExchangeRates, error)
 	return exchangeRates, nil
 }
 
-func (r *RepositoryInstance) SaveExchangeRates(rates *models.ExchangeRates) error {
-	logger.Debug("SaveExchangeRates Repository")
-	const query = `
+// ReplaceExchangeRates stores the rates in place of the rates stored for their date, readers see
+// either the old or the new rates
+func (r *RepositoryInstance) ReplaceExchangeRates(rates *models.ExchangeRates) error {
+	logger.Debug("ReplaceExchangeRates Repository")
+	const deleteQuery = `DELETE FROM exchange_rates WHERE actual_date = $1`
+	const insertQuery = `
 		INSERT INTO exchange_rates (rates, actual_date, base_currency_code, service_name, is_deleted, created_at, updated_at)
 		VALUES (:rates, :actual_date, :base_currency_code, :service_name, :is_deleted, :created_at, :updated_at)
 	`
 
-	_, err := db.NamedExec(query, rates)
+	tx, err := db.Beginx()
 	if err != nil {
-		logger.Error("Error saving exchange rates: ", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	date := rates.ActualDate.Format(time.DateOnly)
+	result, err := tx.Exec(deleteQuery, date)
+	if err != nil {
+		logger.Error("Error deleting exchange rates", "date", date, "error", err)
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	logger.Debug("Deleted exchange rate records", "count", rowsAffected, "date", date)
+
+	if _, err = tx.NamedExec(insertQuery, rates); err != nil {
+		logger.Error("Error saving exchange rates", "date", date, "error", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return err
 	}
 
@@ -58,17 +91,56 @@ func (r *RepositoryInstance) SaveExchangeRates(rates *models.ExchangeRates) erro
 	return nil
 }
 
-func (r *RepositoryInstance) DeleteExchangeRatesByDate(date string) error {
-	logger.Debug("DeleteExchangeRatesByDate Repository")
-	const query = `DELETE FROM exchange_rates WHERE actual_date = $1`
+// GetRateDates returns the dates between startDate and endDate inclusive that have stored rates
+func (r *RepositoryInstance) GetRateDates(startDate, endDate string) ([]time.Time, error) {
+	logger.Debug("GetRateDates Repository")
+	const query = `
+	SELECT DISTINCT actual_date
+	FROM exchange_rates
+	WHERE is_deleted = false AND actual_date BETWEEN $1 AND $2
+	ORDER BY actual_date
+	`
 
-	result, err := db.Exec(query, date)
+	var dates []time.Time
+	err := db.Select(&dates, query, startDate, endDate)
 	if err != nil {
-		logger.Error("Error deleting exchange rates: ", err)
-		return err
+		logger.Error("Error getting exchange rate dates", "error", err)
+		return nil, err
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	logger.Debug("Deleted exchange rate records", "count", rowsAffected, "date", date)
+	return dates, nil
+}
+
+func (r *RepositoryInstance) SaveRateGap(requestedDate, actualDate, serviceName string) error {
+	logger.Debug("SaveRateGap Repository")
+	const query = `
+	INSERT INTO exchange_rate_gaps (requested_date, actual_date, service_name)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (requested_date) DO UPDATE SET actual_date = EXCLUDED.actual_date, service_name = EXCLUDED.service_name
+	`
+
+	if _, err := db.Exec(query, requestedDate, actualDate, serviceName); err != nil {
+		logger.Error("Error saving exchange rate gap", "date", requestedDate, "error", err)
+		return err
+	}
 	return nil
 }
+
+// GetRateGapDates returns the dates between startDate and endDate inclusive that the providers had no rates of their own for
+func (r *RepositoryInstance) GetRateGapDates(startDate, endDate string) ([]time.Time, error) {
+	logger.Debug("GetRateGapDates Repository")
+	const query = `
+	SELECT requested_date
+	FROM exchange_rate_gaps
+	WHERE requested_date BETWEEN $1 AND $2
+	ORDER BY requested_date
+	`
+
+	var dates []time.Time
+	if err := db.Select(&dates, query, startDate, endDate); err != nil {
+		logger.Error("Error getting exchange rate gaps", "error", err)
+		return nil, err
+	}
+
+	return dates, nil
+}
@@ -0,0 +1,309 @@
+package goals
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+type Repository interface {
+	CreateGoal(goal models.Goal) (*models.Goal, error)
+	UpdateGoal(goal models.Goal) error
+	GetGoalByID(goalID int, userID int) (*models.Goal, error)
+	GetUserGoals(userID int, includeArchived bool) ([]models.Goal, error)
+	// GetActiveGoals returns non-archived goals of all users (used by the daily recalculation)
+	GetActiveGoals() ([]models.Goal, error)
+	DeleteGoal(goalID int, userID int) error
+	UpdateGoalProgress(goalID int, currentAmount decimal.Decimal, requiredMonthly *decimal.Decimal, recalculatedAt time.Time) error
+	SetGoalAccounts(goalID int, accountIDs []int) error
+	GetGoalAccountIDs(goalID int) ([]int, error)
+	GetGoalAccountBalances(goalID int) ([]AccountBalance, error)
+	GetUserAccountIDs(userID int, accountIDs []int) ([]int, error)
+	AddContribution(goalID int, transactionID int) error
+	RemoveContribution(goalID int, transactionID int) error
+	GetGoalContributions(goalID int) ([]models.GoalContribution, error)
+	IsUserTransaction(userID int, transactionID int) (bool, error)
+}
+
+type RepositoryInstance struct{}
+
+// AccountBalance is the balance of a goal's linked account in the account currency
+type AccountBalance struct {
+	AccountID    int             `db:"account_id"`
+	Balance      decimal.Decimal `db:"balance"`
+	CurrencyCode string          `db:"currency_code"`
+}
+
+var db *sqlx.DB
+
+func NewGoalsRepository(dbInstance *sqlx.DB) Repository {
+	db = dbInstance
+	return &RepositoryInstance{}
+}
+
+func (r *RepositoryInstance) CreateGoal(goal models.Goal) (*models.Goal, error) {
+	const createGoalQuery = `
+INSERT INTO goals (user_id, name, target_amount, currency_id, target_date, progress_source, current_amount,
+                   comment, is_archived, is_deleted, created_at, updated_at)
+VALUES (:user_id, :name, :target_amount, :currency_id, :target_date, :progress_source, :current_amount,
+        :comment, :is_archived, :is_deleted, :created_at, :updated_at)
+RETURNING id
+`
+
+	stmt, err := db.PrepareNamed(createGoalQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var id int
+	if err = stmt.Get(&id, goal); err != nil {
+		return nil, err
+	}
+
+	goal.ID = &id
+	return &goal, nil
+}
+
+func (r *RepositoryInstance) UpdateGoal(goal models.Goal) error {
+	const updateGoalQuery = `
+UPDATE goals SET
+    name = :name,
+    target_amount = :target_amount,
+    currency_id = :currency_id,
+    target_date = :target_date,
+    progress_source = :progress_source,
+    comment = :comment,
+    is_archived = :is_archived,
+    updated_at = :updated_at
+WHERE id = :id AND user_id = :user_id
+`
+
+	_, err := db.NamedExec(updateGoalQuery, goal)
+	return err
+}
+
+func (r *RepositoryInstance) GetGoalByID(goalID int, userID int) (*models.Goal, error) {
+	const getGoalQuery = `
+SELECT id, user_id, name, target_amount, currency_id, target_date, progress_source, current_amount,
+       required_monthly_contribution, recalculated_at, comment, is_archived, is_deleted, created_at, updated_at
+FROM goals
+WHERE id = $1 AND user_id = $2 AND is_deleted = false
+`
+
+	var goal models.Goal
+	if err := db.Get(&goal, getGoalQuery, goalID, userID); err != nil {
+		return nil, err
+	}
+
+	return &goal, nil
+}
+
+func (r *RepositoryInstance) GetUserGoals(userID int, includeArchived bool) ([]models.Goal, error) {
+	query := `
+SELECT id, user_id, name, target_amount, currency_id, target_date, progress_source, current_amount,
+       required_monthly_contribution, recalculated_at, comment, is_archived, is_deleted, created_at, updated_at
+FROM goals
+WHERE user_id = $1 AND is_deleted = false
+`
+	if !includeArchived {
+		query += " AND is_archived = false"
+	}
+	query += " ORDER BY is_archived ASC, target_date ASC NULLS LAST, name ASC"
+
+	var goals []models.Goal
+	if err := db.Select(&goals, query, userID); err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+func (r *RepositoryInstance) GetActiveGoals() ([]models.Goal, error) {
+	const q = `
+SELECT id, user_id, name, target_amount, currency_id, target_date, progress_source, current_amount,
+       required_monthly_contribution, recalculated_at, comment, is_archived, is_deleted, created_at, updated_at
+FROM goals
+WHERE is_archived = false AND is_deleted = false
+`
+
+	var goals []models.Goal
+	if err := db.Select(&goals, q); err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+func (r *RepositoryInstance) DeleteGoal(goalID int, userID int) error {
+	const deleteGoalQuery = `
+UPDATE goals SET is_deleted = true, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+`
+
+	result, err := db.Exec(deleteGoalQuery, goalID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("goal not found")
+	}
+
+	return nil
+}
+
+func (r *RepositoryInstance) UpdateGoalProgress(goalID int, currentAmount decimal.Decimal, requiredMonthly *decimal.Decimal, recalculatedAt time.Time) error {
+	const q = `
+UPDATE goals SET current_amount = $1, required_monthly_contribution = $2, recalculated_at = $3
+WHERE id = $4
+`
+
+	_, err := db.Exec(q, currentAmount, requiredMonthly, recalculatedAt, goalID)
+	return err
+}
+
+// SetGoalAccounts replaces the list of accounts linked to the goal
+func (r *RepositoryInstance) SetGoalAccounts(goalID int, accountIDs []int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`DELETE FROM goal_accounts WHERE goal_id = $1`, goalID); err != nil {
+		return fmt.Errorf("failed to clear accounts of goal %d: %w", goalID, err)
+	}
+
+	for _, accountID := range accountIDs {
+		if _, err = tx.Exec(`INSERT INTO goal_accounts (goal_id, account_id) VALUES ($1, $2)`, goalID, accountID); err != nil {
+			return fmt.Errorf("failed to link account %d to goal %d: %w", accountID, goalID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *RepositoryInstance) GetGoalAccountIDs(goalID int) ([]int, error) {
+	var accountIDs []int
+	err := db.Select(&accountIDs, `SELECT account_id FROM goal_accounts WHERE goal_id = $1 ORDER BY account_id`, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	return accountIDs, nil
+}
+
+func (r *RepositoryInstance) GetGoalAccountBalances(goalID int) ([]AccountBalance, error) {
+	const q = `
+SELECT a.id AS account_id, a.balance AS balance, c.code AS currency_code
+FROM goal_accounts ga
+JOIN accounts a ON a.id = ga.account_id
+JOIN currencies c ON c.id = a.currency_id
+WHERE ga.goal_id = $1 AND a.is_deleted = false
+`
+
+	var balances []AccountBalance
+	if err := db.Select(&balances, q, goalID); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// GetUserAccountIDs filters the given account IDs down to non-deleted accounts owned by the user
+func (r *RepositoryInstance) GetUserAccountIDs(userID int, accountIDs []int) ([]int, error) {
+	if len(accountIDs) == 0 {
+		return []int{}, nil
+	}
+
+	// Create placeholders for the IN clause
+	placeholders := make([]string, len(accountIDs))
+	args := make([]interface{}, len(accountIDs)+1)
+	args[0] = userID
+
+	for i, id := range accountIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args[i+1] = id
+	}
+
+	query := fmt.Sprintf(`
+SELECT id FROM accounts
+WHERE user_id = $1 AND id IN (%s) AND is_deleted = false
+`, strings.Join(placeholders, ","))
+
+	var validIDs []int
+	if err := db.Select(&validIDs, query, args...); err != nil {
+		return nil, err
+	}
+
+	return validIDs, nil
+}
+
+func (r *RepositoryInstance) AddContribution(goalID int, transactionID int) error {
+	const q = `
+INSERT INTO goal_contributions (goal_id, transaction_id)
+VALUES ($1, $2)
+ON CONFLICT (goal_id, transaction_id) DO NOTHING
+`
+
+	_, err := db.Exec(q, goalID, transactionID)
+	return err
+}
+
+func (r *RepositoryInstance) RemoveContribution(goalID int, transactionID int) error {
+	result, err := db.Exec(`DELETE FROM goal_contributions WHERE goal_id = $1 AND transaction_id = $2`, goalID, transactionID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("contribution not found")
+	}
+
+	return nil
+}
+
+func (r *RepositoryInstance) GetGoalContributions(goalID int) ([]models.GoalContribution, error) {
+	const q = `
+SELECT gc.goal_id, gc.transaction_id, t.amount, c.code AS currency_code, t.date_time, COALESCE(t.label, '') AS label
+FROM goal_contributions gc
+JOIN transactions t ON t.id = gc.transaction_id
+JOIN accounts a ON a.id = t.account_id
+JOIN currencies c ON c.id = a.currency_id
+WHERE gc.goal_id = $1 AND t.is_deleted = false
+ORDER BY t.date_time ASC
+`
+
+	var contributions []models.GoalContribution
+	if err := db.Select(&contributions, q, goalID); err != nil {
+		return nil, err
+	}
+
+	return contributions, nil
+}
+
+func (r *RepositoryInstance) IsUserTransaction(userID int, transactionID int) (bool, error) {
+	var exists bool
+	err := db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM transactions WHERE id = $1 AND user_id = $2 AND is_deleted = false)`,
+		transactionID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,175 @@
+package insights
+
+import (
+	"fmt"
+	"time"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+type Repository interface {
+	// CreateInsights stores the insights and returns the new ones, insights with a known fingerprint are skipped
+	CreateInsights(insights []models.Insight) ([]models.Insight, error)
+	GetUserInsights(userID int, includeDismissed bool) ([]models.Insight, error)
+	DismissInsight(insightID int, userID int) error
+	// GetAnalysisUserIDs returns active users with expenses since the date (used by the nightly analysis)
+	GetAnalysisUserIDs(since time.Time) ([]int, error)
+	GetAnalysisTransactions(userID int, since time.Time) ([]AnalysisTransaction, error)
+}
+
+type RepositoryInstance struct{}
+
+// AnalysisTransaction is an expense of the user in the account currency
+type AnalysisTransaction struct {
+	ID           int             `db:"id"`
+	AccountID    int             `db:"account_id"`
+	CategoryID   *int            `db:"category_id"`
+	CategoryName *string         `db:"category_name"`
+	Label        string          `db:"label"`
+	CurrencyCode string          `db:"currency_code"`
+	Amount       decimal.Decimal `db:"amount"`
+	DateTime     time.Time       `db:"date_time"`
+}
+
+var db *sqlx.DB
+
+func NewInsightsRepository(dbInstance *sqlx.DB) Repository {
+	db = dbInstance
+	return &RepositoryInstance{}
+}
+
+func (r *RepositoryInstance) CreateInsights(insights []models.Insight) ([]models.Insight, error) {
+	const createInsightQuery = `
+INSERT INTO insights (user_id, type, severity, title, message, fingerprint, category_id, transaction_id,
+                      amount, details, detected_at, created_at, updated_at)
+VALUES (:user_id, :type, :severity, :title, :message, :fingerprint, :category_id, :transaction_id,
+        :amount, :details, :detected_at, :created_at, :updated_at)
+ON CONFLICT (user_id, fingerprint) DO NOTHING
+RETURNING id
+`
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareNamed(createInsightQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	created := make([]models.Insight, 0, len(insights))
+	for _, insight := range insights {
+		// Known fingerprints return no rows
+		var ids []int
+		if err = stmt.Select(&ids, insight); err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		insight.ID = &ids[0]
+		created = append(created, insight)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *RepositoryInstance) GetUserInsights(userID int, includeDismissed bool) ([]models.Insight, error) {
+	const getInsightsQuery = `
+SELECT id, user_id, type, severity, title, message, fingerprint, category_id, transaction_id,
+       amount, details, detected_at, is_dismissed, dismissed_at, created_at, updated_at
+FROM insights
+WHERE user_id = $1 AND ($2 OR is_dismissed = false)
+ORDER BY detected_at DESC, id DESC
+`
+
+	insights := []models.Insight{}
+	if err := db.Select(&insights, getInsightsQuery, userID, includeDismissed); err != nil {
+		return nil, err
+	}
+
+	return insights, nil
+}
+
+func (r *RepositoryInstance) DismissInsight(insightID int, userID int) error {
+	const dismissInsightQuery = `
+UPDATE insights SET
+    is_dismissed = true,
+    dismissed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+`
+
+	result, err := db.Exec(dismissInsightQuery, insightID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("insight not found")
+	}
+
+	return nil
+}
+
+func (r *RepositoryInstance) GetAnalysisUserIDs(since time.Time) ([]int, error) {
+	const getUserIDsQuery = `
+SELECT DISTINCT u.id
+FROM users u
+JOIN transactions t ON t.user_id = u.id
+WHERE t.date_time >= $1
+  AND t.is_income = false
+  AND t.is_transfer = false
+  AND t.is_deleted = false
+  AND u.is_active = true
+  AND u.is_deleted = false
+ORDER BY u.id
+`
+
+	var userIDs []int
+	if err := db.Select(&userIDs, getUserIDsQuery, since); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+func (r *RepositoryInstance) GetAnalysisTransactions(userID int, since time.Time) ([]AnalysisTransaction, error) {
+	const getTransactionsQuery = `
+SELECT t.id, t.account_id, t.category_id, uc.name AS category_name, COALESCE(t.label, '') AS label,
+       c.code AS currency_code, t.amount, t.date_time
+FROM transactions t
+JOIN accounts a ON t.account_id = a.id
+JOIN currencies c ON a.currency_id = c.id
+LEFT JOIN user_categories uc ON t.category_id = uc.id
+WHERE t.user_id = $1
+  AND t.date_time >= $2
+  AND t.is_income = false
+  AND t.is_transfer = false
+  AND t.is_deleted = false
+  AND a.is_deleted = false
+ORDER BY t.date_time, t.id
+`
+
+	transactions := []AnalysisTransaction{}
+	if err := db.Select(&transactions, getTransactionsQuery, userID, since); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
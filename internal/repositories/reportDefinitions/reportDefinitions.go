@@ -0,0 +1,127 @@
+package reportDefinitions
+
+import (
+	"fmt"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	CreateDefinition(definition models.ReportDefinition) (*models.ReportDefinition, error)
+	UpdateDefinition(definition models.ReportDefinition) error
+	GetDefinitionByID(definitionID int, userID int) (*models.ReportDefinition, error)
+	GetUserDefinitions(userID int) ([]models.ReportDefinition, error)
+	DeleteDefinition(definitionID int, userID int) error
+}
+
+type RepositoryInstance struct{}
+
+var db *sqlx.DB
+
+func NewReportDefinitionsRepository(dbInstance *sqlx.DB) Repository {
+	db = dbInstance
+	return &RepositoryInstance{}
+}
+
+func (r *RepositoryInstance) CreateDefinition(definition models.ReportDefinition) (*models.ReportDefinition, error) {
+	const createDefinitionQuery = `
+INSERT INTO report_definitions (user_id, name, definition, is_deleted, created_at, updated_at)
+VALUES (:user_id, :name, :definition, :is_deleted, :created_at, :updated_at)
+RETURNING id
+`
+
+	stmt, err := db.PrepareNamed(createDefinitionQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var id int
+	if err = stmt.Get(&id, definition); err != nil {
+		return nil, err
+	}
+
+	definition.ID = &id
+	return &definition, nil
+}
+
+func (r *RepositoryInstance) UpdateDefinition(definition models.ReportDefinition) error {
+	const updateDefinitionQuery = `
+UPDATE report_definitions SET
+    name = :name,
+    definition = :definition,
+    updated_at = :updated_at
+WHERE id = :id AND user_id = :user_id AND is_deleted = false
+`
+
+	result, err := db.NamedExec(updateDefinitionQuery, definition)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("report definition not found")
+	}
+
+	return nil
+}
+
+func (r *RepositoryInstance) GetDefinitionByID(definitionID int, userID int) (*models.ReportDefinition, error) {
+	const getDefinitionQuery = `
+SELECT id, user_id, name, definition, is_deleted, created_at, updated_at
+FROM report_definitions
+WHERE id = $1 AND user_id = $2 AND is_deleted = false
+`
+
+	var definition models.ReportDefinition
+	if err := db.Get(&definition, getDefinitionQuery, definitionID, userID); err != nil {
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+func (r *RepositoryInstance) GetUserDefinitions(userID int) ([]models.ReportDefinition, error) {
+	const getDefinitionsQuery = `
+SELECT id, user_id, name, definition, is_deleted, created_at, updated_at
+FROM report_definitions
+WHERE user_id = $1 AND is_deleted = false
+ORDER BY name ASC
+`
+
+	var definitions []models.ReportDefinition
+	if err := db.Select(&definitions, getDefinitionsQuery, userID); err != nil {
+		return nil, err
+	}
+
+	return definitions, nil
+}
+
+func (r *RepositoryInstance) DeleteDefinition(definitionID int, userID int) error {
+	const deleteDefinitionQuery = `
+UPDATE report_definitions SET is_deleted = true, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND is_deleted = false
+`
+
+	result, err := db.Exec(deleteDefinitionQuery, definitionID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("report definition not found")
+	}
+
+	return nil
+}
@@ -1,11 +1,11 @@
 package reports
 
 import (
-	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/shopspring/decimal"
@@ -53,6 +53,8 @@ func (r *ReportsRepository) GetCashFlowRawData(userID int, input dto.CashFlowRep
 		periodFormat = "TO_CHAR(t.date_time, 'YYYY-\"W\"IW')"
 	case "monthly":
 		periodFormat = "TO_CHAR(t.date_time, 'YYYY-MM')"
+	case "quarterly":
+		periodFormat = "TO_CHAR(t.date_time, 'YYYY-\"Q\"Q')"
 	case "yearly":
 		periodFormat = "TO_CHAR(t.date_time, 'YYYY')"
 	default:
@@ -102,7 +104,7 @@ func (r *ReportsRepository) GetCashFlowRawData(userID int, input dto.CashFlowRep
 	for rows.Next() {
 		var accountID int
 		var period string
-		var income, expenses sql.NullFloat64
+		var income, expenses decimal.NullDecimal
 		var currencyCode string
 
 		if err := rows.Scan(&accountID, &period, &income, &expenses, &currencyCode); err != nil {
@@ -116,12 +118,12 @@ func (r *ReportsRepository) GetCashFlowRawData(userID int, input dto.CashFlowRep
 
 		incomeVal := decimal.Zero
 		if income.Valid {
-			incomeVal = decimal.NewFromFloat(income.Float64)
+			incomeVal = income.Decimal
 		}
 
 		expensesVal := decimal.Zero
 		if expenses.Valid {
-			expensesVal = decimal.NewFromFloat(expenses.Float64)
+			expensesVal = expenses.Decimal
 		}
 
 		results = append(results, CashFlowRawData{
@@ -171,26 +173,11 @@ func (r *ReportsRepository) GetCashFlow(userID int, input dto.CashFlowReportInpu
 		netFlow[data.Period] = totalIncome[data.Period].Sub(totalExpenses[data.Period])
 	}
 
-	// Convert decimals to float64 for JSON marshaling
-	totalIncomeFloat := make(map[string]float64)
-	totalExpensesFloat := make(map[string]float64)
-	netFlowFloat := make(map[string]float64)
-
-	for period, income := range totalIncome {
-		totalIncomeFloat[period], _ = income.Round(2).Float64()
-	}
-	for period, expenses := range totalExpenses {
-		totalExpensesFloat[period], _ = expenses.Round(2).Float64()
-	}
-	for period, net := range netFlow {
-		netFlowFloat[period], _ = net.Round(2).Float64()
-	}
-
 	return &dto.CashFlowReportOutputDTO{
 		Currency:      baseCurrencyCode,
-		TotalIncome:   totalIncomeFloat,
-		TotalExpenses: totalExpensesFloat,
-		NetFlow:       netFlowFloat,
+		TotalIncome:   totalIncome,
+		TotalExpenses: totalExpenses,
+		NetFlow:       netFlow,
 	}, nil
 }
 
@@ -305,74 +292,94 @@ func (r *ReportsRepository) getBalanceReportWithFilter(userID int, input dto.Bal
 	return results, nil
 }
 
-func (r *ReportsRepository) GetExpensesByCategories(userID int, input dto.ExpensesReportInputDTO) ([]dto.ExpensesReportOutputItemDTO, error) {
-	// Get user's base currency
-	baseCurrencyCode, err := r.GetUserBaseCurrency(userID)
-	if err != nil {
-		return nil, err
-	}
+// reportCategoryRow is a category of the user with the names and appearance needed by category reports
+type reportCategoryRow struct {
+	ID          int     `db:"id"`
+	Name        string  `db:"name"`
+	ParentID    *int    `db:"parent_id"`
+	ParentName  *string `db:"parent_name"`
+	IsParent    bool    `db:"is_parent"`
+	Color       *string `db:"color"`
+	Icon        *string `db:"icon"`
+	ParentColor *string `db:"parent_color"`
+	ParentIcon  *string `db:"parent_icon"`
+}
 
-	// First, get all user categories like Python does
+// getReportCategories returns the expense or income categories of the user, optionally limited to the given ids
+func (r *ReportsRepository) getReportCategories(userID int, categoryIDs []int, isIncome bool) ([]reportCategoryRow, error) {
 	allCategoriesQuery := `
 		SELECT 
 			cat.id,
 			cat.name,
 			cat.parent_id,
 			parent_cat.name as parent_name,
-			CASE WHEN cat.parent_id IS NULL THEN true ELSE false END as is_parent
+			CASE WHEN cat.parent_id IS NULL THEN true ELSE false END as is_parent,
+			cat.color,
+			cat.icon,
+			parent_cat.color as parent_color,
+			parent_cat.icon as parent_icon
 		FROM user_categories cat
 		LEFT JOIN user_categories parent_cat ON cat.parent_id = parent_cat.id
 		WHERE cat.user_id = $1 
 		  AND cat.is_deleted = false 
-		  AND cat.is_income = false`
+		  AND cat.is_income = $2`
 
-	args := []interface{}{userID}
+	args := []interface{}{userID, isIncome}
 
-	if len(input.Categories) > 0 {
-		placeholders := make([]string, len(input.Categories))
-		for i := range input.Categories {
+	if len(categoryIDs) > 0 {
+		placeholders := make([]string, len(categoryIDs))
+		for i := range categoryIDs {
 			placeholders[i] = fmt.Sprintf("$%d", len(args)+1+i)
 		}
 		allCategoriesQuery += fmt.Sprintf(" AND cat.id IN (%s)", strings.Join(placeholders, ","))
-		for _, catID := range input.Categories {
+		for _, catID := range categoryIDs {
 			args = append(args, catID)
 		}
 	}
 
 	allCategoriesQuery += " ORDER BY cat.name"
 
-	// Execute query to get all categories
-	type CategoryRow struct {
-		ID         int     `db:"id"`
-		Name       string  `db:"name"`
-		ParentID   *int    `db:"parent_id"`
-		ParentName *string `db:"parent_name"`
-		IsParent   bool    `db:"is_parent"`
+	var allCategories []reportCategoryRow
+	if err := r.db.Select(&allCategories, allCategoriesQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to get user categories: %w", err)
+	}
+
+	return allCategories, nil
+}
+
+// reportCategoryName formats child categories like Python: "Parent >> Child"
+func reportCategoryName(cat reportCategoryRow) string {
+	if cat.ParentID != nil && cat.ParentName != nil {
+		return fmt.Sprintf("%s >> %s", *cat.ParentName, cat.Name)
 	}
+	return cat.Name
+}
 
-	var allCategories []CategoryRow
-	err = r.db.Select(&allCategories, allCategoriesQuery, args...)
+func (r *ReportsRepository) GetExpensesByCategories(userID int, input dto.ExpensesReportInputDTO) ([]dto.ExpensesReportOutputItemDTO, error) {
+	// Get user's base currency
+	baseCurrencyCode, err := r.GetUserBaseCurrency(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user categories: %w", err)
+		return nil, err
+	}
+
+	allCategories, err := r.getReportCategories(userID, input.Categories, false)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create results map initialized with zero expenses
 	results := make(map[int]dto.ExpensesReportOutputItemDTO)
 	for _, cat := range allCategories {
-		displayName := cat.Name
-		if cat.ParentID != nil {
-			// Format child categories like Python: "Parent >> Child"
-			displayName = fmt.Sprintf("%s >> %s", *cat.ParentName, cat.Name)
-		}
-
 		results[cat.ID] = dto.ExpensesReportOutputItemDTO{
 			ID:            cat.ID,
-			Name:          displayName,
+			Name:          reportCategoryName(cat),
 			ParentID:      cat.ParentID,
 			ParentName:    cat.ParentName,
-			TotalExpenses: 0.0,
+			TotalExpenses: decimal.Zero,
 			CurrencyCode:  nil,
 			IsParent:      cat.IsParent,
+			Color:         models.CategoryColor(cat.ID, cat.ParentID, cat.Color, cat.ParentColor),
+			Icon:          models.CategoryIcon(cat.Icon, cat.ParentIcon),
 		}
 	}
 
@@ -406,9 +413,9 @@ func (r *ReportsRepository) GetExpensesByCategories(userID int, input dto.Expens
 	}
 
 	type ExpenseRow struct {
-		CategoryID   *int    `db:"category_id"`
-		Amount       float64 `db:"amount"`
-		CurrencyCode string  `db:"currency_code"`
+		CategoryID   *int            `db:"category_id"`
+		Amount       decimal.Decimal `db:"amount"`
+		CurrencyCode string          `db:"currency_code"`
 	}
 
 	var expenses []ExpenseRow
@@ -418,13 +425,13 @@ func (r *ReportsRepository) GetExpensesByCategories(userID int, input dto.Expens
 	}
 
 	// Aggregate expenses by category (without currency conversion for now)
-	categoryExpenses := make(map[int]float64)
+	categoryExpenses := make(map[int]decimal.Decimal)
 	for _, expense := range expenses {
 		// Skip transactions without a category (NULL category_id)
 		if expense.CategoryID == nil {
 			continue
 		}
-		categoryExpenses[*expense.CategoryID] += expense.Amount
+		categoryExpenses[*expense.CategoryID] = categoryExpenses[*expense.CategoryID].Add(expense.Amount)
 	}
 
 	// Update results with actual expenses
@@ -440,7 +447,7 @@ func (r *ReportsRepository) GetExpensesByCategories(userID int, input dto.Expens
 	finalResults := make([]dto.ExpensesReportOutputItemDTO, 0)
 	for _, result := range results {
 		// Apply hide empty categories filter
-		if input.HideEmptyCategories && result.TotalExpenses == 0 {
+		if input.HideEmptyCategories && result.TotalExpenses.IsZero() {
 			continue
 		}
 		finalResults = append(finalResults, result)
@@ -449,17 +456,67 @@ func (r *ReportsRepository) GetExpensesByCategories(userID int, input dto.Expens
 	return finalResults, nil
 }
 
-// ExpenseRawRow represents a single expense transaction row for conversion/aggregation in services
+// ExpenseRawRow represents a single expense (or income) transaction row for conversion/aggregation in services
 type ExpenseRawRow struct {
-	CategoryID   *int      `db:"category_id"`
-	Amount       float64   `db:"amount"`
-	CurrencyCode string    `db:"currency_code"`
-	DateTime     time.Time `db:"date_time"`
+	CategoryID   *int            `db:"category_id"`
+	Amount       decimal.Decimal `db:"amount"`
+	CurrencyCode string          `db:"currency_code"`
+	DateTime     time.Time       `db:"date_time"`
 }
 
 // GetRawExpensesRows returns per-transaction expenses for the given period and optional category filter.
 // This is used by services to perform currency conversion like the FastAPI implementation.
 func (r *ReportsRepository) GetRawExpensesRows(userID int, input dto.ExpensesReportInputDTO) ([]ExpenseRawRow, error) {
+	return r.getRawCategoryRows(userID, input, false)
+}
+
+// GetRawIncomeRows returns per-transaction income for the given period and optional category filter
+func (r *ReportsRepository) GetRawIncomeRows(userID int, input dto.IncomeReportInputDTO) ([]ExpenseRawRow, error) {
+	return r.getRawCategoryRows(userID, input, true)
+}
+
+// TransactionRow is an income or expense transaction of the period in its account currency
+type TransactionRow struct {
+	ID           int             `db:"id"`
+	Label        string          `db:"label"`
+	CategoryName string          `db:"category_name"`
+	IsIncome     bool            `db:"is_income"`
+	Amount       decimal.Decimal `db:"amount"`
+	CurrencyCode string          `db:"currency_code"`
+	DateTime     time.Time       `db:"date_time"`
+}
+
+// GetTransactionRows returns income and expense transactions of the period, transfers are left out.
+// Both dates are inclusive.
+func (r *ReportsRepository) GetTransactionRows(userID int, startDate, endDate time.Time) ([]TransactionRow, error) {
+	query := `
+        SELECT
+            t.id,
+            COALESCE(t.label, '') as label,
+            COALESCE(cat.name, '') as category_name,
+            t.is_income,
+            ABS(t.amount) as amount,
+            c.code as currency_code,
+            t.date_time
+        FROM transactions t
+        JOIN accounts a ON t.account_id = a.id
+        JOIN currencies c ON a.currency_id = c.id
+        LEFT JOIN user_categories cat ON t.category_id = cat.id
+        WHERE a.user_id = $1
+          AND t.date_time >= $2
+          AND t.date_time < $3
+          AND t.is_deleted = false
+          AND t.is_transfer = false`
+
+	var rows []TransactionRow
+	if err := r.db.Select(&rows, query, userID, startDate, endDate.Add(24*time.Hour)); err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	return rows, nil
+}
+
+func (r *ReportsRepository) getRawCategoryRows(userID int, input dto.ExpensesReportInputDTO, isIncome bool) ([]ExpenseRawRow, error) {
 	query := `
         SELECT 
             t.category_id,
@@ -472,11 +529,11 @@ func (r *ReportsRepository) GetRawExpensesRows(userID int, input dto.ExpensesRep
         WHERE a.user_id = $1
           AND t.date_time >= $2
           AND t.date_time < $3
-          AND t.is_income = false
+          AND t.is_income = $4
           AND t.is_deleted = false
           AND t.is_transfer = false`
 
-	args := []interface{}{userID, input.StartDate.Time, input.EndDate.Time.Add(24 * time.Hour)}
+	args := []interface{}{userID, input.StartDate.Time, input.EndDate.Time.Add(24 * time.Hour), isIncome}
 
 	if len(input.Categories) > 0 {
 		placeholders := make([]string, len(input.Categories))
@@ -492,7 +549,7 @@ func (r *ReportsRepository) GetRawExpensesRows(userID int, input dto.ExpensesRep
 	var rows []ExpenseRawRow
 	err := r.db.Select(&rows, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get raw expenses: %w", err)
+		return nil, fmt.Errorf("failed to get raw category transactions: %w", err)
 	}
 
 	return rows, nil
@@ -506,17 +563,115 @@ func (r *ReportsRepository) GetExpensesDiagramData(userID int, input dto.Expense
 
 	// Convert expenses to diagram data format
 	var diagramData []dto.ExpensesDiagramDataDTO
-	colors := []string{"#FF6384", "#36A2EB", "#FFCE56", "#4BC0C0", "#9966FF", "#FF9F40", "#FF6384", "#C9CBCF"}
 
-	for i, expense := range expenses {
-		if expense.TotalExpenses > 0 {
+	for _, expense := range expenses {
+		if expense.TotalExpenses.IsPositive() {
 			diagramData = append(diagramData, dto.ExpensesDiagramDataDTO{
 				CategoryName: expense.Name,
 				Amount:       expense.TotalExpenses,
-				Color:        colors[i%len(colors)],
+				Color:        expense.Color,
+				Icon:         expense.Icon,
 			})
 		}
 	}
 
 	return diagramData, nil
 }
+
+// GetIncomeCategories returns the income categories of the report with zero totals,
+// the service fills the totals converted to the base currency
+func (r *ReportsRepository) GetIncomeCategories(userID int, input dto.IncomeReportInputDTO) ([]dto.IncomeReportOutputItemDTO, error) {
+	allCategories, err := r.getReportCategories(userID, input.Categories, true)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]dto.IncomeReportOutputItemDTO, 0, len(allCategories))
+	for _, cat := range allCategories {
+		results = append(results, dto.IncomeReportOutputItemDTO{
+			ID:         cat.ID,
+			Name:       reportCategoryName(cat),
+			ParentID:   cat.ParentID,
+			ParentName: cat.ParentName,
+			IsParent:   cat.IsParent,
+			Color:      models.CategoryColor(cat.ID, cat.ParentID, cat.Color, cat.ParentColor),
+			Icon:       models.CategoryIcon(cat.Icon, cat.ParentIcon),
+		})
+	}
+
+	return results, nil
+}
+
+// CustomReportRow is a transaction with every attribute custom reports can group by, the amount is in the account currency
+type CustomReportRow struct {
+	CategoryID      *int            `db:"category_id"`
+	CategoryName    *string         `db:"category_name"`
+	ParentID        *int            `db:"parent_id"`
+	ParentName      *string         `db:"parent_name"`
+	AccountID       int             `db:"account_id"`
+	AccountName     string          `db:"account_name"`
+	AccountTypeID   int             `db:"account_type_id"`
+	AccountTypeName string          `db:"account_type_name"`
+	CurrencyCode    string          `db:"currency_code"`
+	IsIncome        bool            `db:"is_income"`
+	Amount          decimal.Decimal `db:"amount"`
+	DateTime        time.Time       `db:"date_time"`
+}
+
+// GetCustomReportRows returns income and expense transactions matching the filter of a custom report,
+// the dates of the filter must be set
+func (r *ReportsRepository) GetCustomReportRows(userID int, filter dto.CustomReportFilterDTO) ([]CustomReportRow, error) {
+	query := `
+        SELECT
+            t.category_id,
+            cat.name as category_name,
+            cat.parent_id,
+            parent_cat.name as parent_name,
+            a.id as account_id,
+            a.name as account_name,
+            at.id as account_type_id,
+            at.type_name as account_type_name,
+            c.code as currency_code,
+            t.is_income,
+            ABS(t.amount) as amount,
+            t.date_time
+        FROM transactions t
+        JOIN accounts a ON t.account_id = a.id
+        JOIN account_types at ON a.account_type_id = at.id
+        JOIN currencies c ON a.currency_id = c.id
+        LEFT JOIN user_categories cat ON t.category_id = cat.id
+        LEFT JOIN user_categories parent_cat ON cat.parent_id = parent_cat.id
+        WHERE a.user_id = $1
+          AND t.date_time >= $2
+          AND t.date_time < $3
+          AND t.is_deleted = false
+          AND t.is_transfer = false`
+
+	args := []interface{}{userID, filter.StartDate.Time, filter.EndDate.Time.Add(24 * time.Hour)}
+
+	if len(filter.Accounts) > 0 {
+		args = append(args, filter.Accounts)
+		query += fmt.Sprintf(" AND a.id = ANY($%d)", len(args))
+	}
+	if len(filter.Categories) > 0 {
+		args = append(args, filter.Categories)
+		query += fmt.Sprintf(" AND (t.category_id = ANY($%d) OR cat.parent_id = ANY($%d))", len(args), len(args))
+	}
+	if len(filter.Currencies) > 0 {
+		args = append(args, filter.Currencies)
+		query += fmt.Sprintf(" AND c.code = ANY($%d)", len(args))
+	}
+	switch filter.Type {
+	case "income":
+		query += " AND t.is_income = true"
+	case "expense":
+		query += " AND t.is_income = false"
+	}
+
+	var rows []CustomReportRow
+	if err := r.db.Select(&rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get custom report transactions: %w", err)
+	}
+
+	return rows, nil
+}
@@ -106,6 +106,7 @@ SET account_id = :account_id,
     is_income = :is_income,
     is_transfer = :is_transfer,
     linked_transaction_id = :linked_transaction_id,
+    base_currency_amount = :base_currency_amount,
     updated_at = :updated_at
 WHERE id = :id
 `
@@ -28,6 +28,8 @@ type Repository interface {
 	DeleteTemplates(templateIds []int, userId int) error
 	CreateTransaction(transaction models.Transaction) (*models.Transaction, error)
 	GetExpenseTransactionsForBudget(userId int, categoryIds []int, startDate time.Time, endDate time.Time, transactionIds []int) ([]models.Transaction, error)
+	SetTransactionTags(transactionId int, tags []string) error
+	GetTransactionsTags(transactionIds []int) (map[int][]string, error)
 }
 
 type RepositoryInstance struct {
@@ -187,6 +189,7 @@ func (r *RepositoryInstance) UpdateTransaction(transaction models.Transaction) e
 		"is_income":             transaction.IsIncome,
 		"is_transfer":           transaction.IsTransfer,
 		"linked_transaction_id": transaction.LinkedTransactionID,
+		"base_currency_amount":  transaction.BaseCurrencyAmount,
 		"updated_at":            transaction.UpdatedAt,
 	}
 
@@ -285,6 +288,49 @@ func (r *RepositoryInstance) GetExpenseTransactionsForBudget(userId int, categor
 	return transactions, nil
 }
 
+// SetTransactionTags replaces all tags of the transaction
+func (r *RepositoryInstance) SetTransactionTags(transactionId int, tags []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return logAndReturnError(err, "Error starting transaction tags update: ")
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = $1`, transactionId); err != nil {
+		return logAndReturnError(err, "Error clearing transaction tags: ")
+	}
+
+	for _, tag := range tags {
+		if _, err = tx.Exec(`INSERT INTO transaction_tags (transaction_id, tag) VALUES ($1, $2)`, transactionId, tag); err != nil {
+			return logAndReturnError(err, "Error adding transaction tag: ")
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *RepositoryInstance) GetTransactionsTags(transactionIds []int) (map[int][]string, error) {
+	tags := make(map[int][]string, len(transactionIds))
+	if len(transactionIds) == 0 {
+		return tags, nil
+	}
+
+	var rows []struct {
+		TransactionID int    `db:"transaction_id"`
+		Tag           string `db:"tag"`
+	}
+	query := `SELECT transaction_id, tag FROM transaction_tags WHERE transaction_id = ANY($1) ORDER BY tag`
+	if err := r.db.Select(&rows, query, transactionIds); err != nil {
+		return nil, logAndReturnError(err, "Error getting transaction tags: ")
+	}
+
+	for _, row := range rows {
+		tags[row.TransactionID] = append(tags[row.TransactionID], row.Tag)
+	}
+
+	return tags, nil
+}
+
 func logAndReturnError(err error, message string) error {
 	logger.Error(message, err)
 	return err
@@ -8,6 +8,7 @@ import (
 type RepositoryInterface interface {
 	GetAllUsers() ([]*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(userID int) (*models.User, error)
 	CreateUser(user *models.User) (*models.User, error)
 	ActivateUser(userID int) error
 }
@@ -42,6 +43,16 @@ func (u *RepositoryInstance) GetUserByEmail(email string) (*models.User, error)
 	return &user, nil
 }
 
+func (u *RepositoryInstance) GetUserByID(userID int) (*models.User, error) {
+	var user models.User
+	err := u.db.Db.Get(&user, "SELECT * FROM users WHERE id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (u *RepositoryInstance) CreateUser(user *models.User) (*models.User, error) {
 	query := `
 		INSERT INTO users (email, first_name, last_name, password_hash, is_active, base_currency_id, is_deleted)
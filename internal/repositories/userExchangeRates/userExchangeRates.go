@@ -0,0 +1,82 @@
+package userExchangeRates
+
+import (
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var db *sqlx.DB
+
+type Repository interface {
+	GetUserExchangeRates(userID int) ([]models.UserExchangeRate, error)
+	SaveUserExchangeRate(rate models.UserExchangeRate) (models.UserExchangeRate, error)
+	DeleteUserExchangeRate(id int, userID int) (bool, error)
+}
+
+type RepositoryInstance struct{}
+
+func NewUserExchangeRatesRepository(dbInstance *sqlx.DB) Repository {
+	db = dbInstance
+	return &RepositoryInstance{}
+}
+
+// GetUserExchangeRates returns the rates entered by the user ordered by date
+func (r *RepositoryInstance) GetUserExchangeRates(userID int) ([]models.UserExchangeRate, error) {
+	const query = `
+SELECT id, user_id, rate_date, currency_from, currency_to, rate, created_at, updated_at
+FROM user_exchange_rates
+WHERE user_id = $1
+ORDER BY rate_date, currency_from, currency_to
+`
+
+	var rates []models.UserExchangeRate
+	if err := db.Select(&rates, query, userID); err != nil {
+		logger.Error("Error getting user exchange rates", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// SaveUserExchangeRate stores the rate of a pair for a day, replacing the rate the user entered before
+func (r *RepositoryInstance) SaveUserExchangeRate(rate models.UserExchangeRate) (models.UserExchangeRate, error) {
+	const query = `
+INSERT INTO user_exchange_rates (user_id, rate_date, currency_from, currency_to, rate, created_at, updated_at)
+VALUES (:user_id, :rate_date, :currency_from, :currency_to, :rate, :created_at, :updated_at)
+ON CONFLICT (user_id, rate_date, currency_from, currency_to)
+DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+RETURNING id, created_at
+`
+
+	stmt, err := db.PrepareNamed(query)
+	if err != nil {
+		return models.UserExchangeRate{}, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.QueryRowx(rate).Scan(&rate.ID, &rate.CreatedAt); err != nil {
+		logger.Error("Error saving user exchange rate", "userID", rate.UserID, "error", err)
+		return models.UserExchangeRate{}, err
+	}
+
+	return rate, nil
+}
+
+// DeleteUserExchangeRate removes a rate of the user and reports whether it existed
+func (r *RepositoryInstance) DeleteUserExchangeRate(id int, userID int) (bool, error) {
+	const query = `DELETE FROM user_exchange_rates WHERE id = $1 AND user_id = $2`
+
+	result, err := db.Exec(query, id, userID)
+	if err != nil {
+		logger.Error("Error deleting user exchange rate", "id", id, "error", err)
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
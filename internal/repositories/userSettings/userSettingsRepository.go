@@ -12,6 +12,8 @@ type Repository interface {
 	GetBaseCurrency(userId int) (models.Currency, error)
 	UpsertUserSettings(userID int, settingsData map[string]interface{}) (*models.UserSettings, error)
 	GetUserSettings(userID int) (*models.UserSettings, error)
+	// GetUserIDsBySetting returns active users whose setting key has the given value
+	GetUserIDsBySetting(key, value string) ([]int, error)
 }
 
 type RepositoryInstance struct{}
@@ -125,3 +127,21 @@ func (r *RepositoryInstance) GetUserSettings(userID int) (*models.UserSettings,
 
 	return &userSettings, nil
 }
+
+func (r *RepositoryInstance) GetUserIDsBySetting(key, value string) ([]int, error) {
+	const getUserIDsQuery = `
+		SELECT u.id
+		FROM users u
+		JOIN user_settings s ON s.user_id = u.id
+		WHERE s.settings->>$1 = $2
+		  AND u.is_active = true
+		  AND u.is_deleted = false
+		ORDER BY u.id;`
+
+	var userIDs []int
+	if err := db.Select(&userIDs, getUserIDsQuery, key, value); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
@@ -125,6 +125,9 @@ func CreateAccount(c echo.Context) error {
 
 	createdAccount, err := sm.AccountsService.CreateAccount(account)
 	if err != nil {
+		if errors.Is(err, appErrors.ErrCurrencyNotFound) {
+			return c.String(http.StatusBadRequest, "unknown currency")
+		}
 		logger.Error("Error creating account: ", err)
 		return c.String(http.StatusInternalServerError, "Internal server error")
 	}
@@ -163,6 +166,9 @@ func UpdateAccount(c echo.Context) error {
 			logger.Error("No account found with the provided ID", "accountID", account.ID)
 			return c.String(http.StatusNotFound, "not found")
 		}
+		if errors.Is(err, appErrors.ErrCurrencyNotFound) {
+			return c.String(http.StatusBadRequest, "unknown currency")
+		}
 		logger.Error("Error updating account: ", err)
 		return c.String(http.StatusInternalServerError, "Internal server error")
 	}
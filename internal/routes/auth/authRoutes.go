@@ -11,6 +11,7 @@ import (
 	appErrors "ypeskov/budget-go/internal/errors"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/middleware"
+	"ypeskov/budget-go/internal/models"
 	"ypeskov/budget-go/internal/services"
 	"ypeskov/budget-go/internal/utils"
 
@@ -117,7 +118,7 @@ func RegisterUser(c echo.Context) error {
 	}
 
 	// RegisterUser user using service layer (handles complete registration flow)
-	createdUser, err := sm.UserService.RegisterUser(u, sm.CurrenciesService, sm.ActivationTokenService)
+	createdUser, err := sm.UserService.RegisterUser(u, sm.CurrenciesService, sm.ActivationTokenService, sm.CategoriesService)
 	if err != nil {
 		if strings.Contains(err.Error(), "User already exists") {
 			logger.Error("User already exists with email", "email", u.Email)
@@ -221,6 +222,8 @@ func Profile(c echo.Context) error {
 		logger.Error("Error getting user settings", "error", err)
 		// Fallback to default language if we can't get settings
 		settings["language"] = "en"
+		settings[models.SettingEmailDigest] = models.DigestOff
+		settings[models.SettingInsightEmails] = models.InsightEmailsOff
 	} else {
 		// Extract language from settings, default to "en" if not found
 		if lang, ok := userSettings.Settings["language"].(string); ok {
@@ -228,6 +231,16 @@ func Profile(c echo.Context) error {
 		} else {
 			settings["language"] = "en"
 		}
+		if digest, ok := userSettings.Settings[models.SettingEmailDigest].(string); ok {
+			settings[models.SettingEmailDigest] = digest
+		} else {
+			settings[models.SettingEmailDigest] = models.DigestOff
+		}
+		if insightEmails, ok := userSettings.Settings[models.SettingInsightEmails].(string); ok {
+			settings[models.SettingInsightEmails] = insightEmails
+		} else {
+			settings[models.SettingInsightEmails] = models.InsightEmailsOff
+		}
 	}
 
 	logger.Debug("Profile request completed")
@@ -275,7 +288,7 @@ func OAuth(c echo.Context) error {
 		familyName = ""
 	}
 
-	user, err := sm.UserService.LoginOrRegisterOAuth(email, givenName, familyName)
+	user, err := sm.UserService.LoginOrRegisterOAuth(email, givenName, familyName, sm.CategoriesService)
 	if err != nil {
 		if _, ok := err.(*appErrors.UserNotActivatedError); ok {
 			logger.Error("Error while logging in user", "email", email, "error", err)
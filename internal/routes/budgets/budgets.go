@@ -26,6 +26,7 @@ func RegisterBudgetsRoutes(g *echo.Group, manager *services.Manager) {
 	g.PUT("/:id", UpdateBudget)
 	g.DELETE("/:id", DeleteBudget)
 	g.PUT("/:id/archive", ArchiveBudget)
+	g.GET("/:id/history", GetBudgetHistory)
 	g.GET("/daily-processing", DailyProcessing)
 }
 
@@ -116,6 +117,31 @@ func GetBudgets(c echo.Context) error {
 	return c.JSON(http.StatusOK, budgets)
 }
 
+func GetBudgetHistory(c echo.Context) error {
+	logger.Debug("GetBudgetHistory request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid budget ID format"}, http.StatusBadRequest)
+	}
+
+	history, err := sm.BudgetsService.GetBudgetHistory(id, user.ID)
+	if err != nil {
+		if err.Error() == "budget not found" {
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "budget", ID: id}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetBudgetHistory request completed")
+	return c.JSON(http.StatusOK, history)
+}
+
 func DeleteBudget(c echo.Context) error {
 	logger.Debug("DeleteBudget request started", "method", c.Request().Method, "url", c.Request().URL)
 
@@ -1,13 +1,17 @@
 package categories
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"ypeskov/budget-go/internal/logger"
 
 	"ypeskov/budget-go/internal/dto"
 	"ypeskov/budget-go/internal/models"
+	categoriesRepo "ypeskov/budget-go/internal/repositories/categories"
 	"ypeskov/budget-go/internal/services"
 )
 
@@ -21,6 +25,12 @@ func RegisterCategoriesRoutes(g *echo.Group, manager *services.Manager) {
 	g.GET("", GetCategories)
 	g.GET("/grouped", GetGroupedCategories)
 	g.POST("", CreateCategory)
+	g.PUT("/:id", UpdateCategory)
+	g.DELETE("/:id", DeleteCategory)
+	g.POST("/:id/merge", MergeCategory)
+	g.POST("/defaults", ApplyDefaultCategories)
+	g.GET("/export", ExportCategories)
+	g.POST("/import", ImportCategories)
 }
 
 func GetCategories(c echo.Context) error {
@@ -38,18 +48,7 @@ func GetCategories(c echo.Context) error {
 
 	var categories []dto.CategoryDTO
 	for i := range userCategories {
-		category := dto.CategoryDTO{
-			ID:         userCategories[i].ID,
-			Name:       userCategories[i].Name,
-			ParentID:   userCategories[i].ParentID,
-			ParentName: userCategories[i].ParentName,
-			IsIncome:   userCategories[i].IsIncome,
-			UserID:     userCategories[i].UserID,
-			IsDeleted:  userCategories[i].IsDeleted,
-			CreatedAt:  userCategories[i].CreatedAt,
-			UpdatedAt:  userCategories[i].UpdatedAt,
-		}
-		categories = append(categories, category)
+		categories = append(categories, toCategoryDTO(userCategories[i]))
 	}
 
 	logger.Debug("GetCategories request completed")
@@ -96,26 +95,240 @@ func CreateCategory(c echo.Context) error {
 		createDTO.Name,
 		createDTO.IsIncome,
 		createDTO.ParentID,
+		createDTO.Color,
+		createDTO.Icon,
 		user.ID,
 	)
 	if err != nil {
 		logger.Error("Failed to create category: ", err)
+		if strings.HasPrefix(err.Error(), "invalid") {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create category")
 	}
 
-	// Convert to DTO format for response
-	categoryDTO := dto.CategoryDTO{
-		ID:         createdCategory.ID,
-		Name:       createdCategory.Name,
-		ParentID:   createdCategory.ParentID,
-		ParentName: createdCategory.ParentName,
-		IsIncome:   createdCategory.IsIncome,
-		UserID:     createdCategory.UserID,
-		IsDeleted:  createdCategory.IsDeleted,
-		CreatedAt:  createdCategory.CreatedAt,
-		UpdatedAt:  createdCategory.UpdatedAt,
+	logger.Debug("CreateCategory request completed")
+	return c.JSON(http.StatusCreated, toCategoryDTO(*createdCategory))
+}
+
+func UpdateCategory(c echo.Context) error {
+	logger.Debug("UpdateCategory request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID format")
 	}
 
-	logger.Debug("CreateCategory request completed")
-	return c.JSON(http.StatusCreated, categoryDTO)
+	var updateDTO dto.UpdateCategoryDTO
+	if err := c.Bind(&updateDTO); err != nil {
+		logger.Error("Failed to bind update category DTO: ", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	updatedCategory, err := sm.CategoriesService.UpdateCategory(id, updateDTO.Name, updateDTO.ParentID,
+		updateDTO.Color, updateDTO.Icon, user.ID)
+	if err != nil {
+		logger.Error("Failed to update category: ", err)
+		return categoryError(err)
+	}
+
+	logger.Debug("UpdateCategory request completed")
+	return c.JSON(http.StatusOK, toCategoryDTO(*updatedCategory))
+}
+
+// DeleteCategory deletes the category, the optional replaceWith query parameter names the category
+// that takes over its transactions, templates, budgets and subcategories
+func DeleteCategory(c echo.Context) error {
+	logger.Debug("DeleteCategory request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID format")
+	}
+
+	var replacementID *int
+	if replaceWith := c.QueryParam("replaceWith"); replaceWith != "" {
+		replacement, err := strconv.Atoi(replaceWith)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid replacement category ID format")
+		}
+		replacementID = &replacement
+	}
+
+	if err = sm.CategoriesService.DeleteCategory(id, replacementID, user.ID); err != nil {
+		logger.Error("Failed to delete category: ", err)
+		return categoryError(err)
+	}
+
+	logger.Debug("DeleteCategory request completed")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Category deleted successfully",
+	})
+}
+
+func MergeCategory(c echo.Context) error {
+	logger.Debug("MergeCategory request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID format")
+	}
+
+	var mergeDTO dto.MergeCategoryDTO
+	if err := c.Bind(&mergeDTO); err != nil {
+		logger.Error("Failed to bind merge category DTO: ", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	targetCategory, err := sm.CategoriesService.MergeCategories(id, mergeDTO.TargetID, user.ID)
+	if err != nil {
+		logger.Error("Failed to merge categories: ", err)
+		return categoryError(err)
+	}
+
+	logger.Debug("MergeCategory request completed")
+	return c.JSON(http.StatusOK, toCategoryDTO(*targetCategory))
+}
+
+// ApplyDefaultCategories adds the missing default categories of the language query parameter
+// (the user language when omitted). With mode=reset categories outside the defaults are deleted
+// unless they are in use.
+func ApplyDefaultCategories(c echo.Context) error {
+	logger.Debug("ApplyDefaultCategories request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	reset, err := parseTreeMode(c.QueryParam("mode"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	result, err := sm.CategoriesService.ApplyDefaultCategories(user.ID, c.QueryParam("language"), reset)
+	if err != nil {
+		logger.Error("Failed to apply default categories: ", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to apply default categories")
+	}
+
+	logger.Debug("ApplyDefaultCategories request completed")
+	return c.JSON(http.StatusOK, toCategoryTreeResultDTO(result))
+}
+
+func ExportCategories(c echo.Context) error {
+	logger.Debug("ExportCategories request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	nodes, err := sm.CategoriesService.ExportCategories(user.ID)
+	if err != nil {
+		logger.Error("Failed to export categories: ", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export categories")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="categories.json"`)
+
+	logger.Debug("ExportCategories request completed")
+	return c.JSON(http.StatusOK, dto.CategoriesExportDTO{
+		Version:    dto.CategoriesExportVersion,
+		Categories: nodes,
+	})
+}
+
+// ImportCategories adds the categories of an exported tree the user does not have yet.
+// With mode=reset categories outside the tree are deleted unless they are in use.
+func ImportCategories(c echo.Context) error {
+	logger.Debug("ImportCategories request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	reset, err := parseTreeMode(c.QueryParam("mode"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var importDTO dto.CategoriesExportDTO
+	if err := c.Bind(&importDTO); err != nil {
+		logger.Error("Failed to bind import categories DTO: ", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if importDTO.Version > dto.CategoriesExportVersion {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported export version")
+	}
+
+	result, err := sm.CategoriesService.ImportCategories(user.ID, importDTO.Categories, reset)
+	if err != nil {
+		logger.Error("Failed to import categories: ", err)
+		return categoryError(err)
+	}
+
+	logger.Debug("ImportCategories request completed")
+	return c.JSON(http.StatusOK, toCategoryTreeResultDTO(result))
+}
+
+// parseTreeMode reports whether the mode query parameter asks to reset the categories, extend is the default
+func parseTreeMode(mode string) (bool, error) {
+	switch mode {
+	case "", "extend":
+		return false, nil
+	case "reset":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid mode: %s. Allowed values are extend and reset", mode)
+}
+
+func toCategoryTreeResultDTO(result *categoriesRepo.CategoryTreeResult) dto.CategoryTreeResultDTO {
+	return dto.CategoryTreeResultDTO{
+		Created:  result.Created,
+		Existing: result.Existing,
+		Deleted:  result.Deleted,
+	}
+}
+
+func categoryError(err error) error {
+	switch err.Error() {
+	case "category not found", "target category not found":
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+}
+
+func toCategoryDTO(category models.UserCategory) dto.CategoryDTO {
+	return dto.CategoryDTO{
+		ID:            category.ID,
+		Name:          category.Name,
+		ParentID:      category.ParentID,
+		ParentName:    category.ParentName,
+		IsIncome:      category.IsIncome,
+		UserID:        category.UserID,
+		IsDeleted:     category.IsDeleted,
+		CreatedAt:     category.CreatedAt,
+		UpdatedAt:     category.UpdatedAt,
+		Color:         category.EffectiveColor(),
+		Icon:          category.EffectiveIcon(),
+		InheritsColor: category.Color == nil,
+		InheritsIcon:  category.Icon == nil,
+	}
 }
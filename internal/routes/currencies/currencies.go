@@ -1,13 +1,22 @@
 package currencies
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
 
+	"ypeskov/budget-go/internal/dto"
+	appErrors "ypeskov/budget-go/internal/errors"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/routes/routeErrors"
 	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
 )
 
 var (
@@ -18,12 +27,26 @@ func RegisterCurrenciesRoutes(g *echo.Group, manager *services.Manager) {
 	sm = manager
 
 	g.GET("", GetCurrencies)
+	g.POST("", CreateCurrency)
+	g.PUT("/:id", UpdateCurrency)
+	g.DELETE("/:id", DeleteCurrency)
+	g.GET("/convert", ConvertCurrency)
+	g.GET("/rates", GetRates)
+	g.GET("/user-rates", GetUserRates)
+	g.POST("/user-rates", SaveUserRate)
+	g.DELETE("/user-rates/:id", DeleteUserRate)
 }
 
+// GetCurrencies lists the common currencies and the private currencies of the user
 func GetCurrencies(c echo.Context) error {
 	logger.Debug("GetCurrencies request started", "method", c.Request().Method, "url", c.Request().URL)
 
-	currencies, err := sm.CurrenciesService.GetCurrencies()
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	currencies, err := sm.CurrenciesService.GetCurrencies(user.ID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
@@ -36,3 +59,309 @@ func GetCurrencies(c echo.Context) error {
 	logger.Debug("GetCurrencies request completed")
 	return c.JSON(http.StatusOK, currenciesResponse)
 }
+
+// ConvertCurrency converts amount from one currency to another with the latest rates on or before
+// date, today by default
+func ConvertCurrency(c echo.Context) error {
+	logger.Debug("ConvertCurrency request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	from, err := currencyParam(c, user.ID, "from")
+	if err != nil {
+		return currencyError(c, err)
+	}
+	to, err := currencyParam(c, user.ID, "to")
+	if err != nil {
+		return currencyError(c, err)
+	}
+
+	amount, err := decimal.NewFromString(c.QueryParam("amount"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid amount"}, http.StatusBadRequest)
+	}
+
+	date := time.Now()
+	if value := c.QueryParam("date"); value != "" {
+		date, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid date format, expected YYYY-MM-DD"}, http.StatusBadRequest)
+		}
+	}
+
+	conversion, err := sm.ExchangeRatesService.ConvertAmount(user.ID, date, amount, from, to)
+	if err != nil {
+		return rateError(c, err)
+	}
+
+	logger.Debug("ConvertCurrency request completed")
+	return c.JSON(http.StatusOK, conversion)
+}
+
+// GetRates returns the daily rates of the comma separated symbols against base between from and to,
+// with the rates the user entered applied. The base defaults to the base currency of the user, the range to the last 30 days and the symbols
+// to all currencies with rates.
+func GetRates(c echo.Context) error {
+	logger.Debug("GetRates request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	var base string
+	if c.QueryParam("base") == "" {
+		currency, err := sm.CurrenciesService.GetCurrency(user.BaseCurrencyID)
+		if err != nil {
+			return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+		}
+		base = currency.Code
+	} else {
+		currency, err := currencyParam(c, user.ID, "base")
+		if err != nil {
+			return currencyError(c, err)
+		}
+		base = currency.Code
+	}
+
+	var symbols []string
+	for _, symbol := range strings.Split(c.QueryParam("symbols"), ",") {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" {
+			continue
+		}
+		if _, err := sm.CurrenciesService.GetUserCurrencyByCode(user.ID, symbol); err != nil {
+			return currencyError(c, err)
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -30)
+	for param, date := range map[string]*time.Time{"from": &startDate, "to": &endDate} {
+		value := c.QueryParam(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid " + param + " date format, expected YYYY-MM-DD"}, http.StatusBadRequest)
+		}
+		*date = parsed
+	}
+
+	history, err := sm.ExchangeRatesService.GetRateHistory(user.ID, base, symbols, startDate, endDate)
+	if err != nil {
+		return rateError(c, err)
+	}
+
+	logger.Debug("GetRates request completed", "points", len(history.Points))
+	return c.JSON(http.StatusOK, history)
+}
+
+// currencyParam returns the common or private currency of the user with the code in a required query parameter
+func currencyParam(c echo.Context, userID int, name string) (models.Currency, error) {
+	code := strings.ToUpper(strings.TrimSpace(c.QueryParam(name)))
+	if code == "" {
+		return models.Currency{}, &routeErrors.BadRequestError{Message: "Missing " + name + " currency"}
+	}
+
+	currency, err := sm.CurrenciesService.GetUserCurrencyByCode(userID, code)
+	if errors.Is(err, appErrors.ErrCurrencyNotFound) {
+		return models.Currency{}, &routeErrors.BadRequestError{Message: "Unknown currency: " + code}
+	}
+	return currency, err
+}
+
+func currencyError(c echo.Context, err error) error {
+	var badRequest *routeErrors.BadRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		return utils.LogAndReturnError(c, err, http.StatusBadRequest)
+	case errors.Is(err, appErrors.ErrCurrencyNotFound):
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	default:
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+}
+
+// rateError answers with 400 for invalid ranges and 404 when there are no rates for the currencies
+func rateError(c echo.Context, err error) error {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "no exchange rate"):
+		return utils.LogAndReturnError(c, err, http.StatusNotFound)
+	default:
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+}
+
+// CreateCurrency defines a private currency or asset of the user
+func CreateCurrency(c echo.Context) error {
+	logger.Debug("CreateCurrency request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	var input dto.CurrencyInputDTO
+	if err := c.Bind(&input); err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid input"}, http.StatusBadRequest)
+	}
+
+	currency, err := sm.CurrenciesService.CreateUserCurrency(user.ID, input)
+	if err != nil {
+		return privateCurrencyError(c, err, 0)
+	}
+
+	logger.Debug("CreateCurrency request completed")
+	return c.JSON(http.StatusCreated, &currency)
+}
+
+// UpdateCurrency changes the name and decimal places of a private currency
+func UpdateCurrency(c echo.Context) error {
+	logger.Debug("UpdateCurrency request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid currency ID format"}, http.StatusBadRequest)
+	}
+
+	var input dto.CurrencyInputDTO
+	if err := c.Bind(&input); err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid input"}, http.StatusBadRequest)
+	}
+
+	currency, err := sm.CurrenciesService.UpdateUserCurrency(id, user.ID, input)
+	if err != nil {
+		return privateCurrencyError(c, err, id)
+	}
+
+	logger.Debug("UpdateCurrency request completed")
+	return c.JSON(http.StatusOK, &currency)
+}
+
+// DeleteCurrency deletes a private currency that no account, budget or goal uses
+func DeleteCurrency(c echo.Context) error {
+	logger.Debug("DeleteCurrency request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid currency ID format"}, http.StatusBadRequest)
+	}
+
+	if err := sm.CurrenciesService.DeleteUserCurrency(id, user.ID); err != nil {
+		return privateCurrencyError(c, err, id)
+	}
+
+	logger.Debug("DeleteCurrency request completed")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Currency deleted successfully",
+	})
+}
+
+func privateCurrencyError(c echo.Context, err error, id int) error {
+	switch {
+	case errors.Is(err, appErrors.ErrCurrencyNotFound):
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "currency", ID: id}, http.StatusNotFound)
+	case errors.Is(err, appErrors.ErrCurrencyCodeTaken), errors.Is(err, appErrors.ErrCurrencyInUse):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	default:
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+}
+
+// GetUserRates lists the rates the user entered, they override the provider rates of their dates
+func GetUserRates(c echo.Context) error {
+	logger.Debug("GetUserRates request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	rates, err := sm.ExchangeRatesService.GetUserExchangeRates(user.ID)
+	if err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetUserRates request completed")
+	return c.JSON(http.StatusOK, rates)
+}
+
+// SaveUserRate stores the rate of a currency pair for a day, replacing the one entered for the day before
+func SaveUserRate(c echo.Context) error {
+	logger.Debug("SaveUserRate request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	var input dto.UserExchangeRateInputDTO
+	if err := c.Bind(&input); err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid input"}, http.StatusBadRequest)
+	}
+
+	for _, code := range []string{input.From, input.To} {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if _, err := sm.CurrenciesService.GetUserCurrencyByCode(user.ID, code); err != nil {
+			if errors.Is(err, appErrors.ErrCurrencyNotFound) {
+				return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Unknown currency: " + code}, http.StatusBadRequest)
+			}
+			return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+		}
+	}
+
+	rate, err := sm.ExchangeRatesService.SaveUserExchangeRate(user.ID, input)
+	if err != nil {
+		return rateError(c, err)
+	}
+
+	logger.Debug("SaveUserRate request completed")
+	return c.JSON(http.StatusOK, rate)
+}
+
+func DeleteUserRate(c echo.Context) error {
+	logger.Debug("DeleteUserRate request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid rate ID format"}, http.StatusBadRequest)
+	}
+
+	err = sm.ExchangeRatesService.DeleteUserExchangeRate(id, user.ID)
+	if errors.Is(err, appErrors.ErrUserExchangeRateNotFound) {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "exchange rate", ID: id}, http.StatusNotFound)
+	}
+	if err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("DeleteUserRate request completed")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Exchange rate deleted successfully",
+	})
+}
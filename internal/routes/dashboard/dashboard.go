@@ -0,0 +1,44 @@
+package dashboard
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"ypeskov/budget-go/internal/logger"
+
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/routes/routeErrors"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+)
+
+var (
+	sm *services.Manager
+)
+
+func RegisterDashboardRoutes(g *echo.Group, manager *services.Manager) {
+	sm = manager
+
+	g.GET("", GetDashboard)
+}
+
+// GetDashboard returns the key figures of the home screen. They are cached for a short time,
+// refresh=true recomputes them.
+func GetDashboard(c echo.Context) error {
+	logger.Debug("GetDashboard request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	refresh := c.QueryParam("refresh") == "true"
+
+	dashboard, err := sm.DashboardService.GetDashboard(user.ID, refresh)
+	if err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetDashboard request completed")
+	return c.JSON(http.StatusOK, dashboard)
+}
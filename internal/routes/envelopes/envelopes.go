@@ -0,0 +1,103 @@
+package envelopes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"ypeskov/budget-go/internal/logger"
+
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/routes/routeErrors"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+)
+
+var (
+	sm *services.Manager
+)
+
+func RegisterEnvelopesRoutes(g *echo.Group, manager *services.Manager) {
+	sm = manager
+
+	g.GET("", GetEnvelopes)
+	g.PUT("/:categoryId/assign", AssignToEnvelope)
+	g.POST("/move", MoveBetweenEnvelopes)
+}
+
+func GetEnvelopes(c echo.Context) error {
+	logger.Debug("GetEnvelopes request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	month, err := sm.EnvelopesService.GetMonth(user.ID, c.QueryParam("month"))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid month") {
+			return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetEnvelopes request completed")
+	return c.JSON(http.StatusOK, month)
+}
+
+func AssignToEnvelope(c echo.Context) error {
+	logger.Debug("AssignToEnvelope request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	categoryID, err := strconv.Atoi(c.Param("categoryId"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid category ID format"}, http.StatusBadRequest)
+	}
+
+	var assignDTO dto.EnvelopeAssignDTO
+	if err := c.Bind(&assignDTO); err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusBadRequest)
+	}
+
+	// Set the category ID from the URL parameter
+	assignDTO.CategoryID = categoryID
+
+	month, err := sm.EnvelopesService.AssignToEnvelope(user.ID, assignDTO)
+	if err != nil {
+		if err.Error() == "envelope not found" {
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "envelope", ID: categoryID}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	}
+
+	logger.Debug("AssignToEnvelope request completed")
+	return c.JSON(http.StatusOK, month)
+}
+
+func MoveBetweenEnvelopes(c echo.Context) error {
+	logger.Debug("MoveBetweenEnvelopes request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	var moveDTO dto.EnvelopeMoveDTO
+	if err := c.Bind(&moveDTO); err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusBadRequest)
+	}
+
+	month, err := sm.EnvelopesService.MoveBetweenEnvelopes(user.ID, moveDTO)
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	}
+
+	logger.Debug("MoveBetweenEnvelopes request completed")
+	return c.JSON(http.StatusOK, month)
+}
@@ -0,0 +1,223 @@
+package goals
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"ypeskov/budget-go/internal/logger"
+
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/routes/routeErrors"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+)
+
+var (
+	sm *services.Manager
+)
+
+func RegisterGoalsRoutes(g *echo.Group, manager *services.Manager) {
+	sm = manager
+
+	g.GET("", GetGoals)
+	g.POST("", CreateGoal)
+	g.GET("/:id", GetGoal)
+	g.PUT("/:id", UpdateGoal)
+	g.DELETE("/:id", DeleteGoal)
+	g.POST("/:id/contributions", AddContribution)
+	g.DELETE("/:id/contributions/:transactionId", RemoveContribution)
+}
+
+func GetGoals(c echo.Context) error {
+	logger.Debug("GetGoals request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	includeArchived := c.QueryParam("includeArchived") == "true"
+
+	goals, err := sm.GoalsService.GetUserGoals(user.ID, includeArchived)
+	if err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetGoals request completed")
+	return c.JSON(http.StatusOK, goals)
+}
+
+func CreateGoal(c echo.Context) error {
+	logger.Debug("CreateGoal request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	var goalDTO dto.CreateGoalDTO
+	if err := c.Bind(&goalDTO); err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusBadRequest)
+	}
+
+	goal, err := sm.GoalsService.CreateGoal(goalDTO, user.ID)
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	}
+
+	logger.Debug("CreateGoal request completed")
+	return c.JSON(http.StatusOK, goal)
+}
+
+func GetGoal(c echo.Context) error {
+	logger.Debug("GetGoal request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid goal ID format"}, http.StatusBadRequest)
+	}
+
+	goal, err := sm.GoalsService.GetGoal(id, user.ID)
+	if err != nil {
+		if err.Error() == "goal not found" {
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "goal", ID: id}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetGoal request completed")
+	return c.JSON(http.StatusOK, goal)
+}
+
+func UpdateGoal(c echo.Context) error {
+	logger.Debug("UpdateGoal request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid goal ID format"}, http.StatusBadRequest)
+	}
+
+	var goalDTO dto.UpdateGoalDTO
+	if err := c.Bind(&goalDTO); err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusBadRequest)
+	}
+
+	// Set the ID from the URL parameter
+	goalDTO.ID = id
+
+	goal, err := sm.GoalsService.UpdateGoal(goalDTO, user.ID)
+	if err != nil {
+		if err.Error() == "goal not found" {
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "goal", ID: id}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+	}
+
+	logger.Debug("UpdateGoal request completed")
+	return c.JSON(http.StatusOK, goal)
+}
+
+func DeleteGoal(c echo.Context) error {
+	logger.Debug("DeleteGoal request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid goal ID format"}, http.StatusBadRequest)
+	}
+
+	err = sm.GoalsService.DeleteGoal(id, user.ID)
+	if err != nil {
+		if err.Error() == "goal not found" {
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "goal", ID: id}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("DeleteGoal request completed")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Goal deleted successfully",
+	})
+}
+
+func AddContribution(c echo.Context) error {
+	logger.Debug("AddContribution request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid goal ID format"}, http.StatusBadRequest)
+	}
+
+	var contributionDTO dto.GoalContributionDTO
+	if err := c.Bind(&contributionDTO); err != nil {
+		return utils.LogAndReturnError(c, err, http.StatusBadRequest)
+	}
+
+	goal, err := sm.GoalsService.AddContribution(id, contributionDTO.TransactionID, user.ID)
+	if err != nil {
+		switch err.Error() {
+		case "goal not found":
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "goal", ID: id}, http.StatusNotFound)
+		case "transaction not found":
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "transaction", ID: contributionDTO.TransactionID}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("AddContribution request completed")
+	return c.JSON(http.StatusOK, goal)
+}
+
+func RemoveContribution(c echo.Context) error {
+	logger.Debug("RemoveContribution request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid goal ID format"}, http.StatusBadRequest)
+	}
+
+	transactionID, err := strconv.Atoi(c.Param("transactionId"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid transaction ID format"}, http.StatusBadRequest)
+	}
+
+	goal, err := sm.GoalsService.RemoveContribution(id, transactionID, user.ID)
+	if err != nil {
+		switch err.Error() {
+		case "goal not found":
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "goal", ID: id}, http.StatusNotFound)
+		case "contribution not found":
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "contribution", ID: transactionID}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("RemoveContribution request completed")
+	return c.JSON(http.StatusOK, goal)
+}
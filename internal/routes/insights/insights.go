@@ -0,0 +1,78 @@
+package insights
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"ypeskov/budget-go/internal/logger"
+
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/routes/routeErrors"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+)
+
+var (
+	sm *services.Manager
+)
+
+func RegisterInsightsRoutes(g *echo.Group, manager *services.Manager) {
+	sm = manager
+
+	g.GET("", GetInsights)
+	g.POST("/:id/dismiss", DismissInsight)
+}
+
+// GetInsights lists the insights of the spending analysis, newest first. Dismissed insights are
+// included with includeDismissed=true, severity=warning leaves out less important ones.
+func GetInsights(c echo.Context) error {
+	logger.Debug("GetInsights request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	includeDismissed := c.QueryParam("includeDismissed") == "true"
+	severity := strings.ToLower(c.QueryParam("severity"))
+
+	insights, err := sm.InsightsService.GetUserInsights(user.ID, includeDismissed, severity)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: err.Error()}, http.StatusBadRequest)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("GetInsights request completed")
+	return c.JSON(http.StatusOK, insights)
+}
+
+func DismissInsight(c echo.Context) error {
+	logger.Debug("DismissInsight request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	user, ok := c.Get("authenticated_user").(*models.User)
+	if !ok || user == nil {
+		return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "user", ID: 0}, http.StatusBadRequest)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return utils.LogAndReturnError(c, &routeErrors.BadRequestError{Message: "Invalid insight ID format"}, http.StatusBadRequest)
+	}
+
+	err = sm.InsightsService.DismissInsight(id, user.ID)
+	if err != nil {
+		if err.Error() == "insight not found" {
+			return utils.LogAndReturnError(c, &routeErrors.NotFoundError{Resource: "insight", ID: id}, http.StatusNotFound)
+		}
+		return utils.LogAndReturnError(c, err, http.StatusInternalServerError)
+	}
+
+	logger.Debug("DismissInsight request completed")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Insight dismissed successfully",
+	})
+}
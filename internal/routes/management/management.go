@@ -1,9 +1,12 @@
 package management
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"ypeskov/budget-go/internal/config"
+	"ypeskov/budget-go/internal/queue"
 	"ypeskov/budget-go/internal/services"
 
 	"github.com/labstack/echo/v4"
@@ -18,6 +21,9 @@ func RegisterManagementRoutes(g *echo.Group, cfg *config.Config, sm *services.Ma
 	g.GET("/update-exchange-rates", func(c echo.Context) error {
 		return triggerUpdateExchangeRates(c, sm)
 	})
+	g.GET("/backfill-exchange-rates", func(c echo.Context) error {
+		return triggerBackfillExchangeRates(c, cfg, sm)
+	})
 }
 
 func triggerBackup(c echo.Context, sm *services.Manager) error {
@@ -44,3 +50,56 @@ func triggerUpdateExchangeRates(c echo.Context, sm *services.Manager) error {
 	logger.Debug("triggerUpdateExchangeRates request completed")
 	return c.JSON(http.StatusAccepted, map[string]string{"message": "exchange rates update scheduled"})
 }
+
+// triggerBackfillExchangeRates queues fetching the rates of the days without stored rates between
+// the from and to query dates, by default the days the scheduler checks when it starts
+func triggerBackfillExchangeRates(c echo.Context, cfg *config.Config, sm *services.Manager) error {
+	logger.Debug("triggerBackfillExchangeRates request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	endDate := time.Now()
+	if to := c.QueryParam("to"); to != "" {
+		parsed, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid to date, expected YYYY-MM-DD"})
+		}
+		endDate = parsed
+	}
+	startDate := endDate.AddDate(0, 0, -cfg.ExchangeRatesCatchUpDays)
+	if from := c.QueryParam("from"); from != "" {
+		parsed, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid from date, expected YYYY-MM-DD"})
+		}
+		startDate = parsed
+	}
+
+	startDate, endDate, err := services.NormalizeBackfillRange(startDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	missing, err := sm.ExchangeRatesService.GetMissingRateDates(startDate, endDate)
+	if err != nil {
+		logger.Error("failed to get missing exchange rate dates", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get missing dates"})
+	}
+	if len(missing) == 0 {
+		return c.JSON(http.StatusOK, map[string]string{"message": "no missing exchange rates"})
+	}
+
+	err = sm.QueueService.EnqueueExchangeRatesBackfill(queue.ExchangeRatesBackfillPayload{
+		StartDate: startDate.Format(time.DateOnly),
+		EndDate:   endDate.Format(time.DateOnly),
+	})
+	if err != nil {
+		logger.Error("failed to enqueue exchange rates backfill task", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to enqueue task"})
+	}
+
+	logger.Debug("triggerBackfillExchangeRates request completed", "missing", len(missing))
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"message":   fmt.Sprintf("exchange rates backfill of %d missing days scheduled", len(missing)),
+		"startDate": startDate.Format(time.DateOnly),
+		"endDate":   endDate.Format(time.DateOnly),
+	})
+}
@@ -0,0 +1,236 @@
+package reports
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// customReportError maps validation errors to 400 and missing definitions to 404
+func customReportError(c echo.Context, userID int, message string, err error) error {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err.Error() == "report definition not found":
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	logger.Error(message, "userID", userID, "error", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": message})
+}
+
+// RunCustomReport groups transactions by one or two dimensions without saving the definition
+func RunCustomReport(c echo.Context) error {
+	logger.Debug("RunCustomReport request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var input dto.CustomReportInputDTO
+	if err := c.Bind(&input); err != nil {
+		logger.Error("Error binding custom report input", "userID", userID, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	result, err := sm.ReportsService.RunCustomReport(userID, input)
+	if err != nil {
+		return customReportError(c, userID, "Error generating report", err)
+	}
+
+	logger.Debug("RunCustomReport request completed")
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return customReportDocument("Custom report", "custom-report", input.Filter, result), nil
+	})
+}
+
+func GetReportDefinitions(c echo.Context) error {
+	logger.Debug("GetReportDefinitions request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	definitions, err := sm.ReportsService.GetReportDefinitions(userID)
+	if err != nil {
+		return customReportError(c, userID, "Error getting report definitions", err)
+	}
+
+	logger.Debug("GetReportDefinitions request completed")
+	return c.JSON(http.StatusOK, definitions)
+}
+
+func GetReportDefinition(c echo.Context) error {
+	logger.Debug("GetReportDefinition request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid report definition ID"})
+	}
+
+	definition, err := sm.ReportsService.GetReportDefinition(userID, id)
+	if err != nil {
+		return customReportError(c, userID, "Error getting report definition", err)
+	}
+
+	logger.Debug("GetReportDefinition request completed")
+	return c.JSON(http.StatusOK, definition)
+}
+
+func CreateReportDefinition(c echo.Context) error {
+	logger.Debug("CreateReportDefinition request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var input dto.SaveReportDefinitionDTO
+	if err := c.Bind(&input); err != nil {
+		logger.Error("Error binding report definition input", "userID", userID, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+
+	definition, err := sm.ReportsService.CreateReportDefinition(userID, input)
+	if err != nil {
+		return customReportError(c, userID, "Error creating report definition", err)
+	}
+
+	logger.Debug("CreateReportDefinition request completed")
+	return c.JSON(http.StatusCreated, definition)
+}
+
+func UpdateReportDefinition(c echo.Context) error {
+	logger.Debug("UpdateReportDefinition request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid report definition ID"})
+	}
+
+	var input dto.SaveReportDefinitionDTO
+	if err := c.Bind(&input); err != nil {
+		logger.Error("Error binding report definition input", "userID", userID, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+
+	definition, err := sm.ReportsService.UpdateReportDefinition(userID, id, input)
+	if err != nil {
+		return customReportError(c, userID, "Error updating report definition", err)
+	}
+
+	logger.Debug("UpdateReportDefinition request completed")
+	return c.JSON(http.StatusOK, definition)
+}
+
+func DeleteReportDefinition(c echo.Context) error {
+	logger.Debug("DeleteReportDefinition request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid report definition ID"})
+	}
+
+	if err := sm.ReportsService.DeleteReportDefinition(userID, id); err != nil {
+		return customReportError(c, userID, "Error deleting report definition", err)
+	}
+
+	logger.Debug("DeleteReportDefinition request completed")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RunReportDefinition runs a saved report, startDate and endDate query parameters override the saved period
+func RunReportDefinition(c echo.Context) error {
+	logger.Debug("RunReportDefinition request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid report definition ID"})
+	}
+
+	startDate, err := queryDate(c, "startDate")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	endDate, err := queryDate(c, "endDate")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	definition, err := sm.ReportsService.GetReportDefinition(userID, id)
+	if err != nil {
+		return customReportError(c, userID, "Error getting report definition", err)
+	}
+
+	result, err := sm.ReportsService.RunReportDefinition(userID, id, startDate, endDate)
+	if err != nil {
+		return customReportError(c, userID, "Error generating report", err)
+	}
+
+	filter := definition.Definition.Filter
+	if startDate != nil {
+		filter.StartDate = startDate
+	}
+	if endDate != nil {
+		filter.EndDate = endDate
+	}
+
+	logger.Debug("RunReportDefinition request completed")
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return customReportDocument(definition.Name, fmt.Sprintf("report-%d", id), filter, result), nil
+	})
+}
+
+// queryDate parses an optional YYYY-MM-DD query parameter
+func queryDate(c echo.Context, name string) (*utils.CustomDate, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s. Use the YYYY-MM-DD format", name, value)
+	}
+	return &utils.CustomDate{Time: date}, nil
+}
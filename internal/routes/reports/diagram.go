@@ -0,0 +1,196 @@
+package reports
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Diagram types of /diagram/:diagram_type
+const (
+	diagramPie        = "pie"
+	diagramBar        = "bar"
+	diagramStackedBar = "stacked-bar"
+	diagramLine       = "line"
+)
+
+// GetDiagram renders a chart of the period as a data URL. Pie and bar charts show expenses (or income with
+// report=income) by category, the stacked bar chart shows income against expenses per period of the cash flow
+// and the line chart shows the net worth history, or the balance history of one account with account=<id>.
+// The width, height, format (png or svg) and theme (light or dark) query parameters control the image.
+func GetDiagram(c echo.Context) error {
+	logger.Debug("GetDiagram request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+	diagramType := c.Param("diagram_type")
+	startDateStr := c.Param("start_date")
+	endDateStr := c.Param("end_date")
+
+	startDate, err := time.Parse("2006-01-02", startDateStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid start date format"})
+	}
+
+	endDate, err := time.Parse("2006-01-02", endDateStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid end date format"})
+	}
+
+	options, err := chartOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var image []byte
+	switch diagramType {
+	case diagramPie, diagramBar:
+		image, err = categoriesDiagram(c, userID, diagramType, startDate, endDate, options)
+	case diagramStackedBar:
+		image, err = cashFlowDiagram(c, userID, startDate, endDate, options)
+	case diagramLine:
+		image, err = balanceHistoryDiagram(c, userID, startDate, endDate, options)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported diagram type"})
+	}
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid"):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case err.Error() == "account not found":
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Account not found"})
+		}
+		logger.Error("Error generating diagram", "userID", userID, "diagramType", diagramType, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating diagram image"})
+	}
+
+	// Return the image in the expected format: { "image": "data:image/png;base64,..." }
+	logger.Debug("GetDiagram request completed")
+	return c.JSON(http.StatusOK, sm.ChartService.ChartImage(image, options.Format))
+}
+
+func chartOptions(c echo.Context) (services.ChartOptions, error) {
+	options := services.ChartOptions{
+		Format: strings.ToLower(c.QueryParam("format")),
+		Theme:  strings.ToLower(c.QueryParam("theme")),
+	}
+
+	var err error
+	if width := c.QueryParam("width"); width != "" {
+		if options.Width, err = strconv.Atoi(width); err != nil {
+			return options, fmt.Errorf("invalid width: %s", width)
+		}
+	}
+	if height := c.QueryParam("height"); height != "" {
+		if options.Height, err = strconv.Atoi(height); err != nil {
+			return options, fmt.Errorf("invalid height: %s", height)
+		}
+	}
+	if options.Format == "" {
+		options.Format = services.ChartFormatPNG
+	}
+
+	return options, nil
+}
+
+// categoriesDiagram draws expenses (default) or income by category, the report query parameter selects which
+func categoriesDiagram(c echo.Context, userID int, diagramType string, startDate, endDate time.Time, options services.ChartOptions) ([]byte, error) {
+	var (
+		data []dto.ExpensesDiagramDataDTO
+		err  error
+	)
+	switch report := c.QueryParam("report"); report {
+	case "", "expenses":
+		data, err = sm.ReportsService.GetExpensesDiagramData(userID, startDate, endDate)
+	case "income":
+		data, err = sm.ReportsService.GetIncomeDiagramData(userID, startDate, endDate)
+	default:
+		return nil, fmt.Errorf("invalid report: %s. Allowed values are expenses and income", report)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if diagramType == diagramBar {
+		return sm.ChartService.RenderBar(data, options)
+	}
+	return sm.ChartService.RenderPie(data, options)
+}
+
+// cashFlowDiagram stacks the expenses covered by income with the savings, or with the overspending
+// when expenses exceed income, so every bar is as high as the larger of the two
+func cashFlowDiagram(c echo.Context, userID int, startDate, endDate time.Time, options services.ChartOptions) ([]byte, error) {
+	period := c.QueryParam("period")
+	if period == "" {
+		period = "monthly"
+	}
+
+	cashFlow, err := sm.ReportsService.GetCashFlow(userID, dto.CashFlowReportInputDTO{
+		StartDate: &utils.CustomDate{Time: startDate},
+		EndDate:   &utils.CustomDate{Time: endDate},
+		Period:    period,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	periods := make([]string, 0, len(cashFlow.NetFlow))
+	for key := range cashFlow.NetFlow {
+		periods = append(periods, key)
+	}
+	sort.Strings(periods)
+
+	spent := services.ChartSeries{Name: "Spent", Color: "#4E79A7"}
+	saved := services.ChartSeries{Name: "Saved", Color: "#59A14F"}
+	overspent := services.ChartSeries{Name: "Overspent", Color: "#E15759"}
+	for _, key := range periods {
+		income, expenses := cashFlow.TotalIncome[key].InexactFloat64(), cashFlow.TotalExpenses[key].InexactFloat64()
+		spent.Values = append(spent.Values, min(income, expenses))
+		saved.Values = append(saved.Values, max(income-expenses, 0))
+		overspent.Values = append(overspent.Values, max(expenses-income, 0))
+	}
+
+	return sm.ChartService.RenderStackedBar(periods, []services.ChartSeries{spent, saved, overspent}, options)
+}
+
+func balanceHistoryDiagram(c echo.Context, userID int, startDate, endDate time.Time, options services.ChartOptions) ([]byte, error) {
+	period := c.QueryParam("period")
+	if period == "" {
+		period = "monthly"
+	}
+
+	var accountID *int
+	if account := c.QueryParam("account"); account != "" {
+		id, err := strconv.Atoi(account)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account: %s", account)
+		}
+		accountID = &id
+	}
+
+	history, err := sm.ReportsService.GetBalanceHistory(userID, startDate, endDate, period, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]time.Time, 0, len(history.Points))
+	series := services.ChartSeries{Name: fmt.Sprintf("%s, %s", history.Name, history.CurrencyCode)}
+	for _, point := range history.Points {
+		dates = append(dates, point.BalanceDate.Time)
+		series.Values = append(series.Values, point.Balance.InexactFloat64())
+	}
+
+	return sm.ChartService.RenderLine(dates, []services.ChartSeries{series}, options)
+}
@@ -0,0 +1,308 @@
+package reports
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/services"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+)
+
+const dateLayout = "2006-01-02"
+
+// exportFormat returns the lowercased format query parameter, empty or "json" means a JSON response
+func exportFormat(c echo.Context) (string, error) {
+	format := strings.ToLower(c.QueryParam("format"))
+	if format == "" || format == "json" || services.IsExportFormat(format) {
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported format: %s. Allowed values are json, csv, xlsx and pdf", format)
+}
+
+// respond writes the report as JSON or, for export formats, as a downloadable file built from the document.
+// Charts are only rendered into PDF, so the document builder is told whether to prepare them.
+func respond(c echo.Context, format string, result interface{}, document func(withCharts bool) (services.ReportDocument, error)) error {
+	if format == "" || format == "json" {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	doc, err := document(format == services.ExportFormatPDF)
+	if err != nil {
+		logger.Error("Error preparing report export", "format", format, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error exporting report"})
+	}
+
+	file, err := sm.ReportExportService.Export(doc, format)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error exporting report"})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, file.Name))
+	return c.Blob(http.StatusOK, file.ContentType, file.Content)
+}
+
+func formatPeriod(startDate, endDate *utils.CustomDate) string {
+	start, end := "…", "…"
+	if startDate != nil && !startDate.IsZero() {
+		start = startDate.Format(dateLayout)
+	}
+	if endDate != nil && !endDate.IsZero() {
+		end = endDate.Format(dateLayout)
+	}
+	return fmt.Sprintf("%s – %s", start, end)
+}
+
+func cashFlowDocument(input dto.CashFlowReportInputDTO, result *dto.CashFlowReportOutputDTO) services.ReportDocument {
+	periods := make([]string, 0, len(result.NetFlow))
+	for period := range result.NetFlow {
+		periods = append(periods, period)
+	}
+	sort.Strings(periods)
+
+	rows := make([][]interface{}, 0, len(periods))
+	for _, period := range periods {
+		rows = append(rows, []interface{}{
+			period, result.TotalIncome[period], result.TotalExpenses[period], result.NetFlow[period],
+		})
+	}
+
+	return services.ReportDocument{
+		Title:    "Cash flow",
+		Subtitle: fmt.Sprintf("%s, %s, %s", formatPeriod(input.StartDate, input.EndDate), strings.ToLower(input.Period), result.Currency),
+		FileName: "cashflow",
+		Columns:  []string{"Period", "Income", "Expenses", "Net flow"},
+		Rows:     rows,
+	}
+}
+
+func balanceDocument(input dto.BalanceReportInputDTO, result []dto.BalanceReportOutputDTO) services.ReportDocument {
+	baseCurrency := ""
+	total := decimal.Zero
+	rows := make([][]interface{}, 0, len(result)+1)
+	for _, balance := range result {
+		baseCurrency = balance.BaseCurrencyCode
+		total = total.Add(balance.BaseCurrencyBalance)
+		rows = append(rows, []interface{}{
+			balance.AccountName, balance.CurrencyCode, balance.Balance, balance.BaseCurrencyBalance,
+		})
+	}
+	rows = append(rows, []interface{}{"Total", baseCurrency, nil, total})
+
+	date := input.BalanceDate.Format(dateLayout)
+	return services.ReportDocument{
+		Title:    "Balance",
+		Subtitle: fmt.Sprintf("Balance on %s", date),
+		FileName: fmt.Sprintf("balance-%s", date),
+		Columns:  []string{"Account", "Currency", "Balance", fmt.Sprintf("Balance (%s)", baseCurrency)},
+		Rows:     rows,
+	}
+}
+
+// categoriesDocument lists category totals followed by the grand total
+func categoriesDocument(title, fileName string, startDate, endDate utils.CustomDate, currency string, names []string, amounts []decimal.Decimal) services.ReportDocument {
+	total := decimal.Zero
+	rows := make([][]interface{}, 0, len(names)+1)
+	for i, name := range names {
+		total = total.Add(amounts[i])
+		rows = append(rows, []interface{}{name, amounts[i]})
+	}
+	rows = append(rows, []interface{}{"Total", total})
+
+	return services.ReportDocument{
+		Title:    title,
+		Subtitle: fmt.Sprintf("%s, %s", formatPeriod(&startDate, &endDate), currency),
+		FileName: fmt.Sprintf("%s-%s", fileName, startDate.Format(dateLayout)),
+		Columns:  []string{"Category", "Amount"},
+		Rows:     rows,
+	}
+}
+
+func expensesDocument(userID int, input dto.ExpensesReportInputDTO, items []dto.ExpensesReportOutputItemDTO, withCharts bool) (services.ReportDocument, error) {
+	currency := ""
+	names := make([]string, 0, len(items))
+	amounts := make([]decimal.Decimal, 0, len(items))
+	for _, item := range items {
+		if item.CurrencyCode != nil {
+			currency = *item.CurrencyCode
+		}
+		names = append(names, item.Name)
+		amounts = append(amounts, item.TotalExpenses)
+	}
+
+	doc := categoriesDocument("Expenses by categories", "expenses", input.StartDate, input.EndDate, currency, names, amounts)
+
+	// The chart covers all categories of the period, so it is left out of filtered reports
+	if withCharts && len(input.Categories) == 0 {
+		data, err := sm.ReportsService.GetExpensesDiagramData(userID, input.StartDate.Time, input.EndDate.Time)
+		if err != nil {
+			return services.ReportDocument{}, err
+		}
+		doc.Charts = []services.ReportChart{{Title: "Expenses", Data: data}}
+	}
+
+	return doc, nil
+}
+
+func incomeDocument(userID int, input dto.IncomeReportInputDTO, items []dto.IncomeReportOutputItemDTO, withCharts bool) (services.ReportDocument, error) {
+	currency := ""
+	names := make([]string, 0, len(items))
+	amounts := make([]decimal.Decimal, 0, len(items))
+	for _, item := range items {
+		if item.CurrencyCode != nil {
+			currency = *item.CurrencyCode
+		}
+		names = append(names, item.Name)
+		amounts = append(amounts, item.TotalIncome)
+	}
+
+	doc := categoriesDocument("Income by categories", "income", input.StartDate, input.EndDate, currency, names, amounts)
+
+	if withCharts && len(input.Categories) == 0 {
+		data, err := sm.ReportsService.GetIncomeDiagramData(userID, input.StartDate.Time, input.EndDate.Time)
+		if err != nil {
+			return services.ReportDocument{}, err
+		}
+		doc.Charts = []services.ReportChart{{Title: "Income", Data: data}}
+	}
+
+	return doc, nil
+}
+
+func incomeVsExpensesDocument(input dto.IncomeVsExpensesInputDTO, result *dto.IncomeVsExpensesOutputDTO, withCharts bool) services.ReportDocument {
+	rows := make([][]interface{}, 0, len(result.Income)+len(result.Expenses)+3)
+	for _, item := range result.Income {
+		rows = append(rows, []interface{}{item.Label, "Income", item.Amount})
+	}
+	for _, item := range result.Expenses {
+		rows = append(rows, []interface{}{item.Label, "Expenses", item.Amount})
+	}
+	rows = append(rows,
+		[]interface{}{"Total", "Income", result.TotalIncome},
+		[]interface{}{"Total", "Expenses", result.TotalExpenses},
+		[]interface{}{"Net", "", result.Net},
+	)
+
+	doc := services.ReportDocument{
+		Title:    "Income vs. expenses",
+		Subtitle: fmt.Sprintf("%s, %s", formatPeriod(&input.StartDate, &input.EndDate), result.CurrencyCode),
+		FileName: fmt.Sprintf("income-vs-expenses-%s", input.StartDate.Format(dateLayout)),
+		Columns:  []string{"Category", "Type", "Amount"},
+		Rows:     rows,
+	}
+
+	if withCharts {
+		doc.Charts = []services.ReportChart{
+			{Title: "Income", Data: aggregatedChartData(result.Income)},
+			{Title: "Expenses", Data: aggregatedChartData(result.Expenses)},
+		}
+	}
+
+	return doc
+}
+
+func aggregatedChartData(items []dto.AggregatedDiagramItemDTO) []dto.ExpensesDiagramDataDTO {
+	items = combineSmallAggregated(items, 0.02)
+	data := make([]dto.ExpensesDiagramDataDTO, 0, len(items))
+	for _, item := range items {
+		data = append(data, dto.ExpensesDiagramDataDTO{
+			CategoryName: item.Label,
+			Amount:       item.Amount,
+			Color:        item.Color,
+			Icon:         item.Icon,
+		})
+	}
+	return data
+}
+
+// compareDocument has a column per period and the change of the last period
+func compareDocument(result *dto.CompareReportOutputDTO) services.ReportDocument {
+	columns := []string{"Category"}
+	for _, period := range result.Periods {
+		columns = append(columns, formatPeriod(&period.StartDate, &period.EndDate))
+	}
+	columns = append(columns, "Change", "Change, %")
+
+	row := func(name string, amounts []decimal.Decimal, changes []dto.CompareChangeDTO) []interface{} {
+		cells := []interface{}{name}
+		for _, amount := range amounts {
+			cells = append(cells, amount)
+		}
+		if len(changes) == 0 {
+			return append(cells, nil, nil)
+		}
+		last := changes[len(changes)-1]
+		if last.Percent == nil {
+			return append(cells, last.Absolute, "")
+		}
+		return append(cells, last.Absolute, *last.Percent)
+	}
+
+	rows := make([][]interface{}, 0, len(result.Categories)+1)
+	for _, category := range result.Categories {
+		rows = append(rows, row(category.Name, category.Amounts, category.Changes))
+	}
+	rows = append(rows, row("Total", result.Totals, result.TotalChanges))
+
+	title := "Expenses comparison"
+	if result.IsIncome {
+		title = "Income comparison"
+	}
+
+	return services.ReportDocument{
+		Title:    title,
+		Subtitle: fmt.Sprintf("%d periods compared to the %s period, %s", len(result.Periods), result.CompareTo, result.CurrencyCode),
+		FileName: "compare",
+		Columns:  columns,
+		Rows:     rows,
+	}
+}
+
+// customReportDocument has a row per group of the first dimension and a column per group of the second one.
+// Reports grouped by one dimension get a single value column.
+func customReportDocument(title, fileName string, filter dto.CustomReportFilterDTO, result *dto.CustomReportOutputDTO) services.ReportDocument {
+	columns := []string{result.GroupBy[0]}
+	for _, column := range result.Columns {
+		columns = append(columns, column.Label)
+	}
+	if len(result.Columns) > 0 {
+		columns = append(columns, "Total")
+	} else {
+		columns = append(columns, result.Measure)
+	}
+
+	rows := make([][]interface{}, 0, len(result.Rows)+1)
+	for i, group := range result.Rows {
+		cells := []interface{}{group.Label}
+		if len(result.Columns) > 0 {
+			for _, value := range result.Values[i] {
+				if value == nil {
+					cells = append(cells, nil)
+				} else {
+					cells = append(cells, *value)
+				}
+			}
+		}
+		rows = append(rows, append(cells, result.RowTotals[i]))
+	}
+
+	totals := []interface{}{"Total"}
+	for _, total := range result.ColumnTotals {
+		totals = append(totals, total)
+	}
+	rows = append(rows, append(totals, result.Total))
+
+	return services.ReportDocument{
+		Title:    title,
+		Subtitle: fmt.Sprintf("%s, %s of %s, %s", formatPeriod(filter.StartDate, filter.EndDate), result.Measure, strings.Join(result.GroupBy, " by "), result.CurrencyCode),
+		FileName: fileName,
+		Columns:  columns,
+		Rows:     rows,
+	}
+}
@@ -3,7 +3,7 @@ package reports
 import (
 	"net/http"
 	"sort"
-	"time"
+	"strings"
 
 	"ypeskov/budget-go/internal/config"
 	"ypeskov/budget-go/internal/dto"
@@ -11,6 +11,7 @@ import (
 	"ypeskov/budget-go/internal/services"
 
 	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
 	"ypeskov/budget-go/internal/logger"
 )
 
@@ -29,6 +30,16 @@ func RegisterReportsRoutes(g *echo.Group, cfgGlobal *config.Config, manager *ser
 	g.POST("/expenses-by-categories", GetExpensesByCategories)
 	g.GET("/diagram/:diagram_type/:start_date/:end_date", GetDiagram)
 	g.POST("/expenses-data", GetExpensesData)
+	g.POST("/income-by-categories", GetIncomeByCategories)
+	g.POST("/income-vs-expenses", GetIncomeVsExpenses)
+	g.POST("/compare", ComparePeriods)
+	g.POST("/custom", RunCustomReport)
+	g.GET("/definitions", GetReportDefinitions)
+	g.POST("/definitions", CreateReportDefinition)
+	g.GET("/definitions/:id", GetReportDefinition)
+	g.PUT("/definitions/:id", UpdateReportDefinition)
+	g.DELETE("/definitions/:id", DeleteReportDefinition)
+	g.GET("/definitions/:id/run", RunReportDefinition)
 }
 
 func getUserID(c echo.Context) (int, error) {
@@ -52,6 +63,11 @@ func GetCashFlow(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	result, err := sm.ReportsService.GetCashFlow(userID, input)
 	if err != nil {
 		logger.Error("Error generating cash flow report", "userID", userID, "error", err)
@@ -59,7 +75,9 @@ func GetCashFlow(c echo.Context) error {
 	}
 
 	logger.Debug("GetCashFlow request completed")
-	return c.JSON(http.StatusOK, result)
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return cashFlowDocument(input, result), nil
+	})
 }
 
 func GetBalanceReport(c echo.Context) error {
@@ -75,6 +93,11 @@ func GetBalanceReport(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	result, err := sm.ReportsService.GetBalanceReport(userID, input)
 	if err != nil {
 		logger.Error("Error generating balance report", "userID", userID, "error", err)
@@ -82,7 +105,9 @@ func GetBalanceReport(c echo.Context) error {
 	}
 
 	logger.Debug("GetBalanceReport request completed")
-	return c.JSON(http.StatusOK, result)
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return balanceDocument(input, result), nil
+	})
 }
 
 func GetNonHiddenBalance(c echo.Context) error {
@@ -98,6 +123,11 @@ func GetNonHiddenBalance(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	result, err := sm.ReportsService.GetNonHiddenBalanceReport(userID, input)
 	if err != nil {
 		logger.Error("Error generating non-hidden balance report", "userID", userID, "error", err)
@@ -105,7 +135,9 @@ func GetNonHiddenBalance(c echo.Context) error {
 	}
 
 	logger.Debug("GetNonHiddenBalance request completed")
-	return c.JSON(http.StatusOK, result)
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return balanceDocument(input, result), nil
+	})
 }
 
 func GetExpensesByCategories(c echo.Context) error {
@@ -122,6 +154,11 @@ func GetExpensesByCategories(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	result, err := sm.ReportsService.GetExpensesByCategories(userID, input)
 	if err != nil {
 		logger.Error("Error generating expenses by categories report", "userID", userID, "error", err)
@@ -129,82 +166,139 @@ func GetExpensesByCategories(c echo.Context) error {
 	}
 
 	logger.Debug("GetExpensesByCategories request completed")
-	return c.JSON(http.StatusOK, result)
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return expensesDocument(userID, input, result, withCharts)
+	})
 }
 
-func GetDiagram(c echo.Context) error {
-	logger.Debug("GetDiagram request started", "method", c.Request().Method, "url", c.Request().URL)
+func GetExpensesData(c echo.Context) error {
+	logger.Debug("GetExpensesData request started", "method", c.Request().Method, "url", c.Request().URL)
 
 	userID, err := getUserID(c)
 	if err != nil {
 		return err
 	}
-	diagramType := c.Param("diagram_type")
-	startDateStr := c.Param("start_date")
-	endDateStr := c.Param("end_date")
 
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	var input dto.ExpensesReportInputDTO
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+
+	// Build parent-level aggregated data, combine small categories, and output in Python-compatible format
+	items, err := sm.ReportsService.GetExpensesByCategories(userID, input)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid start date format"})
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error getting expenses data"})
 	}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+	// Aggregate to parent level
+	aggregated := aggregateForExpensesData(items)
+	// Combine small categories
+	aggregated = combineSmallAggregated(aggregated, 0.02)
+	// Sort desc
+	sort.Slice(aggregated, func(i, j int) bool { return aggregated[i].Amount.GreaterThan(aggregated[j].Amount) })
+
+	logger.Debug("GetExpensesData request completed")
+	return c.JSON(http.StatusOK, aggregated)
+}
+
+func GetIncomeByCategories(c echo.Context) error {
+	logger.Debug("GetIncomeByCategories request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid end date format"})
+		return err
 	}
 
-	// Currently only pie diagram is supported
-	if diagramType != "pie" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported diagram type"})
+	var input dto.IncomeReportInputDTO
+	if err := c.Bind(&input); err != nil {
+		logger.Error("Error binding income report input", "userID", userID, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
-	// Get expenses data for the given date range
-	data, err := sm.ReportsService.GetExpensesDiagramData(userID, startDate, endDate)
+	format, err := exportFormat(c)
 	if err != nil {
-		logger.Error("Error getting expenses data for diagram", "userID", userID, "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating diagram"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	// Generate pie chart image (as base64 data URL)
-	chartImage, err := sm.ChartService.GeneratePieChart(data, "")
+	result, err := sm.ReportsService.GetIncomeByCategories(userID, input)
 	if err != nil {
-		logger.Error("Error generating pie chart", "userID", userID, "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating diagram image"})
+		logger.Error("Error generating income by categories report", "userID", userID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating report"})
 	}
 
-	// Return the image in the expected format: { "image": "data:image/png;base64,..." }
-	logger.Debug("GetDiagram request completed")
-	return c.JSON(http.StatusOK, chartImage)
+	logger.Debug("GetIncomeByCategories request completed")
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return incomeDocument(userID, input, result, withCharts)
+	})
 }
 
-func GetExpensesData(c echo.Context) error {
-	logger.Debug("GetExpensesData request started", "method", c.Request().Method, "url", c.Request().URL)
+func GetIncomeVsExpenses(c echo.Context) error {
+	logger.Debug("GetIncomeVsExpenses request started", "method", c.Request().Method, "url", c.Request().URL)
 
 	userID, err := getUserID(c)
 	if err != nil {
 		return err
 	}
 
-	var input dto.ExpensesReportInputDTO
+	var input dto.IncomeVsExpensesInputDTO
 	if err := c.Bind(&input); err != nil {
+		logger.Error("Error binding income vs expenses input", "userID", userID, "error", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
-	// Build parent-level aggregated data, combine small categories, and output in Python-compatible format
-	items, err := sm.ReportsService.GetExpensesByCategories(userID, input)
+	format, err := exportFormat(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error getting expenses data"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if input.StartDate.IsZero() || input.EndDate.IsZero() || input.EndDate.Before(input.StartDate.Time) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid period"})
 	}
 
-	// Aggregate to parent level
-	aggregated := aggregateForExpensesData(items)
-	// Combine small categories
-	aggregated = combineSmallAggregated(aggregated, 0.02)
-	// Sort desc
-	sort.Slice(aggregated, func(i, j int) bool { return aggregated[i].Amount > aggregated[j].Amount })
+	result, err := sm.ReportsService.GetIncomeVsExpenses(userID, input)
+	if err != nil {
+		logger.Error("Error generating income vs expenses report", "userID", userID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating report"})
+	}
 
-	logger.Debug("GetExpensesData request completed")
-	return c.JSON(http.StatusOK, aggregated)
+	logger.Debug("GetIncomeVsExpenses request completed")
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return incomeVsExpensesDocument(input, result, withCharts), nil
+	})
+}
+
+// ComparePeriods compares category amounts between two or more periods
+func ComparePeriods(c echo.Context) error {
+	logger.Debug("ComparePeriods request started", "method", c.Request().Method, "url", c.Request().URL)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var input dto.CompareReportInputDTO
+	if err := c.Bind(&input); err != nil {
+		logger.Error("Error binding compare report input", "userID", userID, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	result, err := sm.ReportsService.ComparePeriods(userID, input)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") || strings.HasPrefix(err.Error(), "anchor day") {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		logger.Error("Error generating compare report", "userID", userID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating report"})
+	}
+
+	logger.Debug("ComparePeriods request completed")
+	return respond(c, format, result, func(withCharts bool) (services.ReportDocument, error) {
+		return compareDocument(result), nil
+	})
 }
 
 // aggregateForExpensesData groups items by parent category (or itself if top-level) like Python prepare_data
@@ -212,29 +306,30 @@ func aggregateForExpensesData(items []dto.ExpensesReportOutputItemDTO) []dto.Agg
 	// Map parentID -> total
 	totals := make(map[int]dto.AggregatedDiagramItemDTO)
 
-	// Build parent name lookup
-	parentNames := make(map[int]string)
+	// Build parent lookup
+	parents := make(map[int]dto.ExpensesReportOutputItemDTO)
 	for _, it := range items {
 		if it.ParentID == nil { // parent
-			parentNames[it.ID] = it.Name
+			parents[it.ID] = it
 		}
 	}
 
 	for _, it := range items {
 		var parentID int
-		var label string
-		if it.ParentID == nil { // parent
-			parentID = it.ID
-			label = it.Name
-		} else { // child -> roll into parent
+		parent := it
+		if it.ParentID != nil { // child -> roll into parent
 			parentID = *it.ParentID
-			label = parentNames[parentID]
+			parent = parents[parentID]
+		} else { // parent
+			parentID = it.ID
 		}
 
 		agg := totals[parentID]
 		agg.CategoryID = parentID
-		agg.Label = label
-		agg.Amount += it.TotalExpenses
+		agg.Label = parent.Name
+		agg.Color = parent.Color
+		agg.Icon = parent.Icon
+		agg.Amount = agg.Amount.Add(it.TotalExpenses)
 		totals[parentID] = agg
 	}
 
@@ -250,24 +345,25 @@ func combineSmallAggregated(items []dto.AggregatedDiagramItemDTO, threshold floa
 	if len(items) == 0 {
 		return items
 	}
-	var total float64
+	total := decimal.Zero
 	for _, it := range items {
-		total += it.Amount
+		total = total.Add(it.Amount)
 	}
-	if total <= 0 {
+	if !total.IsPositive() {
 		return items
 	}
+	limit := total.Mul(decimal.NewFromFloat(threshold))
 	large := make([]dto.AggregatedDiagramItemDTO, 0, len(items))
-	var other float64
+	other := decimal.Zero
 	for _, it := range items {
-		if it.Amount/total < threshold {
-			other += it.Amount
+		if it.Amount.LessThan(limit) {
+			other = other.Add(it.Amount)
 		} else {
 			large = append(large, it)
 		}
 	}
-	if other > 0 {
-		large = append(large, dto.AggregatedDiagramItemDTO{CategoryID: 0, Label: "Other", Amount: other})
+	if other.IsPositive() {
+		large = append(large, dto.AggregatedDiagramItemDTO{CategoryID: 0, Label: "Other", Amount: other, Color: models.CategoryOtherColor})
 	}
 	return large
 }
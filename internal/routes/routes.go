@@ -5,11 +5,16 @@ import (
 	"ypeskov/budget-go/internal/config"
 	"ypeskov/budget-go/internal/logger"
 	customMiddleware "ypeskov/budget-go/internal/middleware"
+	"ypeskov/budget-go/internal/models"
 	"ypeskov/budget-go/internal/routes/accounts"
 	"ypeskov/budget-go/internal/routes/auth"
 	"ypeskov/budget-go/internal/routes/budgets"
 	"ypeskov/budget-go/internal/routes/categories"
 	"ypeskov/budget-go/internal/routes/currencies"
+	"ypeskov/budget-go/internal/routes/dashboard"
+	"ypeskov/budget-go/internal/routes/envelopes"
+	"ypeskov/budget-go/internal/routes/goals"
+	"ypeskov/budget-go/internal/routes/insights"
 	"ypeskov/budget-go/internal/routes/management"
 	"ypeskov/budget-go/internal/routes/reports"
 	"ypeskov/budget-go/internal/routes/transactions"
@@ -22,6 +27,20 @@ import (
 
 func RegisterRoutes(cfg *config.Config, servicesManager *services.Manager) *echo.Echo {
 	e := echo.New()
+	e.JSONSerializer = moneySerializer{
+		scales: func(c echo.Context) map[string]int32 {
+			user, ok := c.Get("authenticated_user").(*models.User)
+			if !ok || user == nil {
+				return nil
+			}
+			scales, err := servicesManager.CurrenciesService.GetCurrencyScales(user.ID)
+			if err != nil {
+				logger.Error("Failed to get currency scales", "userID", user.ID, "error", err)
+				return nil
+			}
+			return scales
+		},
+	}
 	// e.Use(middleware.Logger())
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Use(middleware.Recover())
@@ -34,10 +53,13 @@ func RegisterRoutes(cfg *config.Config, servicesManager *services.Manager) *echo
 	})
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
+		ExposeHeaders: []string{customMiddleware.HeaderMoneyFormat},
 	}))
 
+	e.Use(customMiddleware.MoneyFormatMiddleware())
+
 	e.GET("/health", Health)
 
 	authRoutesGroup := e.Group("/auth")
@@ -65,6 +87,18 @@ func RegisterRoutes(cfg *config.Config, servicesManager *services.Manager) *echo
 	currenciesRoutesGroup := protectedRoutes.Group("/currencies")
 	currencies.RegisterCurrenciesRoutes(currenciesRoutesGroup, servicesManager)
 
+	envelopesRoutesGroup := protectedRoutes.Group("/envelopes")
+	envelopes.RegisterEnvelopesRoutes(envelopesRoutesGroup, servicesManager)
+
+	goalsRoutesGroup := protectedRoutes.Group("/goals")
+	goals.RegisterGoalsRoutes(goalsRoutesGroup, servicesManager)
+
+	dashboardRoutesGroup := protectedRoutes.Group("/dashboard")
+	dashboard.RegisterDashboardRoutes(dashboardRoutesGroup, servicesManager)
+
+	insightsRoutesGroup := protectedRoutes.Group("/insights")
+	insights.RegisterInsightsRoutes(insightsRoutesGroup, servicesManager)
+
 	transactionsRoutesGroup := protectedRoutes.Group("/transactions")
 	transactions.RegisterTransactionsRoutes(transactionsRoutesGroup, servicesManager)
 
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	customMiddleware "ypeskov/budget-go/internal/middleware"
+	"ypeskov/budget-go/internal/money"
+
+	"github.com/labstack/echo/v4"
+)
+
+// moneySerializer writes JSON responses with money in the format negotiated by the client
+type moneySerializer struct {
+	echo.DefaultJSONSerializer
+	// scales returns the decimal places of the private currencies of the user of the request,
+	// minor units of those currencies use them instead of the ISO ones
+	scales func(c echo.Context) map[string]int32
+}
+
+func (s moneySerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	format := customMiddleware.MoneyFormat(c)
+	if !format.IsPrecise() {
+		return s.DefaultJSONSerializer.Serialize(c, i, indent)
+	}
+
+	encoder := money.Encoder{Format: format}
+	if format == money.FormatMinor && s.scales != nil {
+		encoder.Scales = s.scales(c)
+	}
+	return s.DefaultJSONSerializer.Serialize(c, encoder.Value(i), indent)
+}
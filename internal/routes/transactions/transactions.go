@@ -148,6 +148,7 @@ func CreateTransaction(c echo.Context) error {
 		IsTransfer: transaction.IsTransfer,
 		Notes:      transaction.Notes,
 		DateTime:   transaction.DateTime,
+		Tags:       transaction.Tags,
 	}
 
 	_, err := sm.TransactionsService.CreateTransaction(transactionModel, transaction.TargetAccountID, transaction.TargetAmount)
@@ -77,6 +77,18 @@ func UpdateSettings(c echo.Context) error {
 	settingsData := map[string]interface{}{
 		"language": settingsDTO.Language,
 	}
+	if settingsDTO.EmailDigest != nil {
+		if !models.ValidateDigestFrequency(*settingsDTO.EmailDigest) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Email digest must be off, weekly or monthly")
+		}
+		settingsData[models.SettingEmailDigest] = *settingsDTO.EmailDigest
+	}
+	if settingsDTO.InsightEmails != nil {
+		if !models.ValidateInsightEmails(*settingsDTO.InsightEmails) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Insight emails must be off, info, warning or critical")
+		}
+		settingsData[models.SettingInsightEmails] = *settingsDTO.InsightEmails
+	}
 
 	userSettings, err := sm.UserSettingsService.UpdateUserSettings(user.ID, settingsData)
 	if err != nil {
@@ -79,6 +79,7 @@ func (a *AccountsServiceInstance) GetUserAccounts(
 		}
 
 		amount, err := sm.ExchangeRatesService.CalcAmountFromCurrency(
+			userId,
 			time.Now(),
 			account.Balance,
 			account.Currency.Code,
@@ -88,6 +89,7 @@ func (a *AccountsServiceInstance) GetUserAccounts(
 			return nil, err
 		}
 		userAccounts[i].BalanceInBaseCurrency = &amount
+		userAccounts[i].BaseCurrencyCode = &baseCurrency.Code
 	}
 
 	return userAccounts, nil
@@ -155,6 +157,11 @@ func (a *AccountsServiceInstance) CreateAccount(account models.Account) (dto.Acc
 		account.CreditLimit = &zero
 	}
 
+	// Private currencies of other users are reported like unknown ones
+	if _, err := sm.CurrenciesService.GetUserCurrency(account.CurrencyId, account.UserID); err != nil {
+		return dto.AccountDTO{}, err
+	}
+
 	newAccount, err := a.accountsRepo.CreateAccount(account)
 	if err != nil {
 		return dto.AccountDTO{}, err
@@ -184,6 +191,7 @@ func buildAccountDTO(account models.Account) (dto.AccountDTO, error) {
 	accountDto.Currency = accountCurrency
 
 	amount, err := sm.ExchangeRatesService.CalcAmountFromCurrency(
+		accountDto.UserID,
 		time.Now(),
 		accountDto.Balance,
 		accountCurrency.Code,
@@ -194,6 +202,7 @@ func buildAccountDTO(account models.Account) (dto.AccountDTO, error) {
 	}
 
 	accountDto.BalanceInBaseCurrency = &amount
+	accountDto.BaseCurrencyCode = &baseCurrency.Code
 
 	return accountDto, nil
 }
@@ -212,6 +221,10 @@ func (a *AccountsServiceInstance) UpdateAccount(account models.Account) (dto.Acc
 		account.CreditLimit = &zero
 	}
 
+	if _, err := sm.CurrenciesService.GetUserCurrency(account.CurrencyId, account.UserID); err != nil {
+		return dto.AccountDTO{}, err
+	}
+
 	updatedAccount, err := a.accountsRepo.UpdateAccount(account)
 	if err != nil {
 		if errors.Is(err, appErrors.ErrNoAccountFound) {
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+type TransactionEventKind string
+
+const (
+	TransactionCreated TransactionEventKind = "created"
+	TransactionUpdated TransactionEventKind = "updated"
+	TransactionDeleted TransactionEventKind = "deleted"
+)
+
+// BudgetDelta is the change of a budget collected amount, in the budget currency.
+// Budget holds the budget state before the change.
+type BudgetDelta struct {
+	Budget models.Budget
+	Amount decimal.Decimal
+}
+
+// TransactionEvent is emitted after a transaction is created, updated or deleted
+// and carries its effect on the collected amounts of budgets
+type TransactionEvent struct {
+	Kind          TransactionEventKind
+	UserID        int
+	TransactionID int
+	BudgetDeltas  []BudgetDelta
+}
+
+// NewTransactionEvent builds the event for a transaction write. before is the transaction as it was
+// (nil on create) and after is the transaction as it is now (nil on delete).
+// The old version is taken out of the budgets that covered it and the new version is added to the budgets
+// that cover it now, so changes of amount, category, date or account are handled the same way.
+func (s *BudgetsServiceInstance) NewTransactionEvent(kind TransactionEventKind, userID int, transactionID int,
+	before *models.Transaction, after *models.Transaction) (TransactionEvent, error) {
+	event := TransactionEvent{
+		Kind:          kind,
+		UserID:        userID,
+		TransactionID: transactionID,
+	}
+
+	deltas := make(map[int]*BudgetDelta)
+	order := make([]int, 0)
+
+	collect := func(transaction *models.Transaction, sign int64) error {
+		if transaction == nil || transaction.IsIncome || transaction.IsTransfer ||
+			transaction.CategoryID == nil || transaction.DateTime == nil {
+			return nil
+		}
+
+		budgets, err := s.budgetsRepository.GetActiveBudgetsByCategoryAndDate(userID, *transaction.CategoryID, *transaction.DateTime)
+		if err != nil {
+			return fmt.Errorf("failed to get budgets for category %d: %w", *transaction.CategoryID, err)
+		}
+
+		for _, budget := range budgets {
+			if !budgetFilterMatches(budget.Filter, *transaction) {
+				continue
+			}
+
+			amount, err := s.convertTransactionAmountToBudgetCurrency(*transaction, budget.CurrencyID)
+			if err != nil {
+				return fmt.Errorf("failed to convert transaction %d to budget %d currency: %w", transactionID, *budget.ID, err)
+			}
+
+			delta, ok := deltas[*budget.ID]
+			if !ok {
+				delta = &BudgetDelta{Budget: budget, Amount: decimal.Zero}
+				deltas[*budget.ID] = delta
+				order = append(order, *budget.ID)
+			}
+			delta.Amount = delta.Amount.Add(amount.Mul(decimal.NewFromInt(sign)))
+		}
+		return nil
+	}
+
+	if err := collect(before, -1); err != nil {
+		return event, err
+	}
+	if err := collect(after, 1); err != nil {
+		return event, err
+	}
+
+	for _, budgetID := range order {
+		if !deltas[budgetID].Amount.IsZero() {
+			event.BudgetDeltas = append(event.BudgetDeltas, *deltas[budgetID])
+		}
+	}
+
+	return event, nil
+}
+
+// ApplyTransactionEvent adds the deltas of the event to the collected amounts of the affected budgets
+// and checks whether any alert threshold was crossed
+func (s *BudgetsServiceInstance) ApplyTransactionEvent(event TransactionEvent) error {
+	var firstErr error
+
+	for _, delta := range event.BudgetDeltas {
+		if err := s.budgetsRepository.AddBudgetCollectedAmount(*delta.Budget.ID, delta.Amount); err != nil {
+			logger.Error("failed to apply transaction delta to budget", "budgetID", *delta.Budget.ID,
+				"transactionID", event.TransactionID, "event", event.Kind, "delta", delta.Amount.String(), "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if delta.Amount.IsPositive() {
+			s.checkBudgetAlerts(delta.Budget)
+		}
+	}
+
+	return firstErr
+}
+
+// ReconcileCollectedAmounts recomputes the collected amount of every running budget from its transactions
+// and repairs totals that drifted from the incrementally maintained value. Returns the number of repaired budgets.
+func (s *BudgetsServiceInstance) ReconcileCollectedAmounts() (int, error) {
+	logger.Debug("ReconcileCollectedAmounts Service")
+
+	budgets, err := s.budgetsRepository.GetBudgetsForReconciliation()
+	if err != nil {
+		logger.Error("Error getting budgets for reconciliation", "error", err)
+		return 0, err
+	}
+
+	repaired := 0
+	for _, budget := range budgets {
+		expected, err := s.calculateBudgetCollectedAmount(budget)
+		if err != nil { // handle error but continue processing
+			logger.Error("Error calculating budget collected amount", "budgetID", *budget.ID, "userID", budget.UserID, "error", err)
+			continue
+		}
+
+		if expected.Round(2).Equal(budget.CollectedAmount.Round(2)) {
+			continue
+		}
+
+		logger.Warn("Budget collected amount drifted", "budgetID", *budget.ID, "userID", budget.UserID,
+			"stored", budget.CollectedAmount.String(), "expected", expected.String())
+
+		if err = s.budgetsRepository.UpdateBudgetCollectedAmount(*budget.ID, expected); err != nil {
+			logger.Error("Error repairing budget collected amount", "budgetID", *budget.ID, "error", err)
+			continue
+		}
+		repaired++
+	}
+
+	return repaired, nil
+}
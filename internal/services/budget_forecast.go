@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// forecastCurveBuckets is the number of equal parts a period is split into when building a historical curve
+	forecastCurveBuckets = 20
+	// maxForecastHistoryPeriods limits how many past periods of a series shape the historical curve
+	maxForecastHistoryPeriods = 6
+)
+
+// minForecastExtrapolationShare is the expected share of spending below which the current pace is not
+// extrapolated (too early in the period), the rest of the period is assumed to go as planned instead
+var minForecastExtrapolationShare = decimal.NewFromFloat(0.1)
+
+// SpendingCurve is the cumulative share of period spending (0..1) at the end of each equal part of the period.
+// A nil curve means linear spending.
+type SpendingCurve []decimal.Decimal
+
+// ExpectedShare returns the share of the period spending expected once the given share of the period has elapsed
+func (c SpendingCurve) ExpectedShare(elapsed decimal.Decimal) decimal.Decimal {
+	if elapsed.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero
+	}
+	if elapsed.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return decimal.NewFromInt(1)
+	}
+	if len(c) == 0 {
+		return elapsed
+	}
+
+	// Linear interpolation between bucket boundaries, the curve implicitly starts at 0
+	position := elapsed.Mul(decimal.NewFromInt(int64(len(c))))
+	index := int(position.IntPart())
+	previous := decimal.Zero
+	if index > 0 {
+		previous = c[index-1]
+	}
+	return previous.Add(c[index].Sub(previous).Mul(position.Sub(decimal.NewFromInt(int64(index)))))
+}
+
+func normalizeForecastCurve(curve string) (string, error) {
+	if curve == "" {
+		return models.ForecastCurveLinear, nil
+	}
+	if !models.ValidateForecastCurve(curve) {
+		return "", fmt.Errorf("invalid forecast curve: %s. Valid curves are: linear, historical", curve)
+	}
+	return strings.ToUpper(curve), nil
+}
+
+// CalculateBudgetForecast computes the spending pace of a budget period at the given moment
+func CalculateBudgetForecast(budget models.Budget, curve SpendingCurve, now time.Time) dto.BudgetForecastDTO {
+	start, end := *budget.StartDate, *budget.EndDate
+	periodDuration := end.Sub(start)
+	elapsed := decimal.NewFromFloat(now.Sub(start).Seconds()).Div(decimal.NewFromFloat(periodDuration.Seconds()))
+	expectedShare := curve.ExpectedShare(elapsed)
+
+	forecast := dto.BudgetForecastDTO{
+		Curve:          strings.ToLower(models.ForecastCurveLinear),
+		ExpectedToDate: budget.TargetAmount.Mul(expectedShare).Round(2),
+		DaysElapsed:    int(now.Sub(start).Hours()/24) + 1,
+		DaysRemaining:  int(end.Sub(now).Hours()/24) + 1,
+	}
+	if len(curve) > 0 {
+		forecast.Curve = strings.ToLower(models.ForecastCurveHistorical)
+	}
+
+	// Project the end of period total from the current pace relative to the expected spending
+	projected := budget.CollectedAmount
+	if expectedShare.GreaterThanOrEqual(minForecastExtrapolationShare) {
+		projected = budget.CollectedAmount.Div(expectedShare)
+	} else {
+		projected = projected.Add(budget.TargetAmount.Mul(decimal.NewFromInt(1).Sub(expectedShare)))
+	}
+	forecast.ProjectedTotal = projected.Round(2)
+
+	left := budget.TargetAmount.Sub(budget.CollectedAmount)
+	if left.IsPositive() {
+		forecast.SafeDailyAllowance = left.Div(decimal.NewFromInt(int64(forecast.DaysRemaining))).Round(2)
+	} else {
+		forecast.SafeDailyAllowance = decimal.Zero
+	}
+
+	switch {
+	case budget.CollectedAmount.GreaterThan(budget.TargetAmount):
+		forecast.Status = models.BudgetStatusOver
+	case projected.GreaterThan(budget.TargetAmount):
+		forecast.Status = models.BudgetStatusAtRisk
+	default:
+		forecast.Status = models.BudgetStatusOnTrack
+	}
+
+	return forecast
+}
+
+// isBudgetPeriodActive reports whether the budget period is running at the given moment
+func isBudgetPeriodActive(budget models.Budget, now time.Time) bool {
+	return !budget.IsArchived && budget.StartDate != nil && budget.EndDate != nil &&
+		!now.Before(*budget.StartDate) && now.Before(*budget.EndDate)
+}
+
+// budgetForecast returns the forecast of an active budget, using the spend pattern of past periods
+// of its series when the budget asks for a historical curve
+func (s *BudgetsServiceInstance) budgetForecast(budget models.Budget, now time.Time) *dto.BudgetForecastDTO {
+	var curve SpendingCurve
+	if budget.ForecastCurve == models.ForecastCurveHistorical {
+		var err error
+		curve, err = s.historicalSpendingCurve(budget)
+		if err != nil {
+			// Fall back to the linear curve
+			logger.Error("Error building historical spending curve", "budgetID", *budget.ID, "error", err)
+			curve = nil
+		}
+	}
+
+	forecast := CalculateBudgetForecast(budget, curve, now)
+	return &forecast
+}
+
+// historicalSpendingCurve averages the cumulative spend shape of the latest past periods of the budget series.
+// Returns nil when there is no past spending to learn from.
+func (s *BudgetsServiceInstance) historicalSpendingCurve(budget models.Budget) (SpendingCurve, error) {
+	if budget.SeriesID == nil {
+		return nil, nil
+	}
+
+	series, err := s.budgetsRepository.GetBudgetSeries(*budget.SeriesID, budget.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget series %d: %w", *budget.SeriesID, err)
+	}
+
+	pastPeriods := make([]models.Budget, 0, len(series))
+	for _, period := range series {
+		if period.StartDate != nil && period.EndDate != nil && !period.EndDate.After(*budget.StartDate) {
+			pastPeriods = append(pastPeriods, period)
+		}
+	}
+	if len(pastPeriods) == 0 {
+		return nil, nil
+	}
+	if len(pastPeriods) > maxForecastHistoryPeriods {
+		pastPeriods = pastPeriods[len(pastPeriods)-maxForecastHistoryPeriods:]
+	}
+
+	categoryIDs, err := s.budgetsRepository.GetBudgetCategoryIDs(*budget.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(categoryIDs) == 0 {
+		return nil, nil
+	}
+
+	transactions, err := s.sm.TransactionsService.GetExpenseTransactionsForBudget(
+		budget.UserID, categoryIDs, *pastPeriods[0].StartDate, *budget.StartDate, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get past transactions of budget %d: %w", *budget.ID, err)
+	}
+
+	if err = s.loadTransactionTagsForFilter(budget.Filter, transactions); err != nil {
+		return nil, err
+	}
+
+	curve := make(SpendingCurve, forecastCurveBuckets)
+	for i := range curve {
+		curve[i] = decimal.Zero
+	}
+	usedPeriods := 0
+
+	for _, period := range pastPeriods {
+		start, end := *period.StartDate, *period.EndDate
+		buckets := make([]decimal.Decimal, forecastCurveBuckets)
+		total := decimal.Zero
+
+		for _, transaction := range transactions {
+			if transaction.DateTime == nil || transaction.DateTime.Before(start) || !transaction.DateTime.Before(end) ||
+				!budgetFilterMatches(budget.Filter, transaction) {
+				continue
+			}
+			// Only the shape matters, so amounts are compared in the user's base currency when available
+			amount := transaction.Amount
+			if transaction.BaseCurrencyAmount != nil {
+				amount = *transaction.BaseCurrencyAmount
+			}
+			amount = amount.Abs()
+
+			index := int(float64(forecastCurveBuckets) * transaction.DateTime.Sub(start).Seconds() / end.Sub(start).Seconds())
+			if index >= forecastCurveBuckets {
+				index = forecastCurveBuckets - 1
+			}
+			buckets[index] = buckets[index].Add(amount)
+			total = total.Add(amount)
+		}
+
+		if !total.IsPositive() {
+			continue
+		}
+
+		cumulative := decimal.Zero
+		for i, amount := range buckets {
+			cumulative = cumulative.Add(amount)
+			curve[i] = curve[i].Add(cumulative.Div(total))
+		}
+		usedPeriods++
+	}
+
+	if usedPeriods == 0 {
+		return nil, nil
+	}
+
+	for i := range curve {
+		curve[i] = curve[i].Div(decimal.NewFromInt(int64(usedPeriods)))
+	}
+	return curve, nil
+}
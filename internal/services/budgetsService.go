@@ -8,6 +8,7 @@ import (
 	"ypeskov/budget-go/internal/dto"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/queue"
 	budgetRepo "ypeskov/budget-go/internal/repositories/budgets"
 
 	"github.com/shopspring/decimal"
@@ -20,9 +21,18 @@ type BudgetsService interface {
 	DeleteBudget(budgetID int, userID int) error
 	ArchiveBudget(budgetID int, userID int) error
 	ProcessOutdatedBudgets() ([]int, error)
-	UpdateBudgetCollectedAmounts(userID int) error
-	// UpdateBudgetCollectedAmountsForCategories recalculates only budgets affected by given category/date pairs
-	UpdateBudgetCollectedAmountsForCategories(userID int, pairs []AffectedCategoryDate) error
+	// NewTransactionEvent computes the effect of a transaction write on budgets, converted to budget currencies
+	NewTransactionEvent(kind TransactionEventKind, userID int, transactionID int, before *models.Transaction, after *models.Transaction) (TransactionEvent, error)
+	// ApplyTransactionEvent applies budget deltas of a transaction event
+	ApplyTransactionEvent(event TransactionEvent) error
+	// ReconcileCollectedAmounts verifies incrementally maintained totals and repairs drift
+	ReconcileCollectedAmounts() (int, error)
+	// GetBudgetHistory returns target against actual amounts for past periods of the budget's series
+	GetBudgetHistory(budgetID int, userID int) (*dto.BudgetHistoryDTO, error)
+	// RecalculateCategoryBudgets recomputes collected amounts of the budgets of the user, archived ones included,
+	// that include or exclude any of the categories and whose period overlaps first to last, e.g. after
+	// transactions of those dates moved between the categories
+	RecalculateCategoryBudgets(userID int, categoryIDs []int, first time.Time, last time.Time) error
 }
 
 type BudgetsServiceInstance struct {
@@ -30,12 +40,6 @@ type BudgetsServiceInstance struct {
 	sm                *Manager
 }
 
-// AffectedCategoryDate describes a category and the date to match budgets' period window
-type AffectedCategoryDate struct {
-	CategoryID int
-	Date       time.Time
-}
-
 var (
 	budgetsInstance *BudgetsServiceInstance
 	budgetsOnce     sync.Once
@@ -61,14 +65,25 @@ func (s *BudgetsServiceInstance) CreateBudget(budgetDTO dto.CreateBudgetDTO, use
 		return nil, fmt.Errorf("invalid period: %s. Valid periods are: %v", budgetDTO.Period, models.GetValidPeriods())
 	}
 
-	// Validate and filter categories
-	validCategories, err := s.budgetsRepository.GetUserCategoriesForBudget(userID, budgetDTO.Categories)
+	filter, err := s.buildBudgetFilter(userID, budgetDTO.Categories, budgetDTO.ExcludedCategories,
+		budgetDTO.Accounts, budgetDTO.IncludedTags, budgetDTO.ExcludedTags)
 	if err != nil {
-		logger.Error("Error validating categories", "error", err)
 		return nil, err
 	}
 
-	categoriesStr := ConvertCategoryIDsToString(validCategories)
+	thresholdsStr, err := ConvertAlertThresholdsToString(budgetDTO.AlertThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := NewPeriodAlignment(budgetDTO.Period, budgetDTO.AnchorDay, budgetDTO.AnchorLastBusinessDay, budgetDTO.WeekStartDay); err != nil {
+		return nil, err
+	}
+
+	forecastCurve, err := normalizeForecastCurve(budgetDTO.ForecastCurve)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	startDate := budgetDTO.StartDate.ToTime()
@@ -77,21 +92,27 @@ func (s *BudgetsServiceInstance) CreateBudget(budgetDTO dto.CreateBudgetDTO, use
 	endDate = endDate.AddDate(0, 0, 1)
 
 	budget := models.Budget{
-		UserID:             userID,
-		Name:               budgetDTO.Name,
-		CurrencyID:         budgetDTO.CurrencyID,
-		TargetAmount:       budgetDTO.TargetAmount,
-		CollectedAmount:    decimal.Zero,
-		Period:             models.NormalizePeriod(budgetDTO.Period),
-		Repeat:             budgetDTO.Repeat,
-		StartDate:          startDate,
-		EndDate:            &endDate,
-		IncludedCategories: &categoriesStr,
-		Comment:            budgetDTO.Comment,
-		IsDeleted:          false,
-		IsArchived:         false,
-		CreatedAt:          &now,
-		UpdatedAt:          &now,
+		UserID:                userID,
+		Name:                  budgetDTO.Name,
+		CurrencyID:            budgetDTO.CurrencyID,
+		TargetAmount:          budgetDTO.TargetAmount,
+		CollectedAmount:       decimal.Zero,
+		Period:                models.NormalizePeriod(budgetDTO.Period),
+		Repeat:                budgetDTO.Repeat,
+		StartDate:             startDate,
+		EndDate:               &endDate,
+		AnchorDay:             budgetDTO.AnchorDay,
+		AnchorLastBusinessDay: budgetDTO.AnchorLastBusinessDay,
+		WeekStartDay:          budgetDTO.WeekStartDay,
+		ForecastCurve:         forecastCurve,
+		AlertThresholds:       &thresholdsStr,
+		AlertOverBudget:       budgetDTO.AlertOverBudget,
+		Comment:               budgetDTO.Comment,
+		IsDeleted:             false,
+		IsArchived:            false,
+		CreatedAt:             &now,
+		UpdatedAt:             &now,
+		Filter:                filter,
 	}
 
 	createdBudget, err := s.budgetsRepository.CreateBudget(budget)
@@ -100,6 +121,11 @@ func (s *BudgetsServiceInstance) CreateBudget(budgetDTO dto.CreateBudgetDTO, use
 		return nil, err
 	}
 
+	if err = s.budgetsRepository.SetBudgetFilter(*createdBudget.ID, filter); err != nil {
+		logger.Error("Error saving budget filter", "error", err)
+		return nil, err
+	}
+
 	// Fill budget with existing transactions
 	err = s.fillBudgetWithExistingTransactions(*createdBudget.ID, userID)
 	if err != nil {
@@ -125,14 +151,25 @@ func (s *BudgetsServiceInstance) UpdateBudget(budgetDTO dto.UpdateBudgetDTO, use
 		return nil, fmt.Errorf("budget not found")
 	}
 
-	// Validate and filter categories
-	validCategories, err := s.budgetsRepository.GetUserCategoriesForBudget(userID, budgetDTO.Categories)
+	filter, err := s.buildBudgetFilter(userID, budgetDTO.Categories, budgetDTO.ExcludedCategories,
+		budgetDTO.Accounts, budgetDTO.IncludedTags, budgetDTO.ExcludedTags)
 	if err != nil {
-		logger.Error("Error validating categories", "error", err)
 		return nil, err
 	}
 
-	categoriesStr := ConvertCategoryIDsToString(validCategories)
+	thresholdsStr, err := ConvertAlertThresholdsToString(budgetDTO.AlertThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := NewPeriodAlignment(budgetDTO.Period, budgetDTO.AnchorDay, budgetDTO.AnchorLastBusinessDay, budgetDTO.WeekStartDay); err != nil {
+		return nil, err
+	}
+
+	forecastCurve, err := normalizeForecastCurve(budgetDTO.ForecastCurve)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	startDate := budgetDTO.StartDate.ToTime()
@@ -141,22 +178,29 @@ func (s *BudgetsServiceInstance) UpdateBudget(budgetDTO dto.UpdateBudgetDTO, use
 	endDate = endDate.AddDate(0, 0, 1)
 
 	budget := models.Budget{
-		ID:                 existingBudget.ID,
-		UserID:             userID,
-		Name:               budgetDTO.Name,
-		CurrencyID:         budgetDTO.CurrencyID,
-		TargetAmount:       budgetDTO.TargetAmount,
-		CollectedAmount:    decimal.Zero, // Reset collected amount on update
-		Period:             models.NormalizePeriod(budgetDTO.Period),
-		Repeat:             budgetDTO.Repeat,
-		StartDate:          startDate,
-		EndDate:            &endDate,
-		IncludedCategories: &categoriesStr,
-		Comment:            budgetDTO.Comment,
-		IsDeleted:          existingBudget.IsDeleted,
-		IsArchived:         existingBudget.IsArchived,
-		CreatedAt:          existingBudget.CreatedAt,
-		UpdatedAt:          &now,
+		ID:                    existingBudget.ID,
+		SeriesID:              existingBudget.SeriesID,
+		UserID:                userID,
+		Name:                  budgetDTO.Name,
+		CurrencyID:            budgetDTO.CurrencyID,
+		TargetAmount:          budgetDTO.TargetAmount,
+		CollectedAmount:       decimal.Zero, // Reset collected amount on update
+		Period:                models.NormalizePeriod(budgetDTO.Period),
+		Repeat:                budgetDTO.Repeat,
+		StartDate:             startDate,
+		EndDate:               &endDate,
+		AnchorDay:             budgetDTO.AnchorDay,
+		AnchorLastBusinessDay: budgetDTO.AnchorLastBusinessDay,
+		WeekStartDay:          budgetDTO.WeekStartDay,
+		ForecastCurve:         forecastCurve,
+		AlertThresholds:       &thresholdsStr,
+		AlertOverBudget:       budgetDTO.AlertOverBudget,
+		Comment:               budgetDTO.Comment,
+		IsDeleted:             existingBudget.IsDeleted,
+		IsArchived:            existingBudget.IsArchived,
+		CreatedAt:             existingBudget.CreatedAt,
+		UpdatedAt:             &now,
+		Filter:                filter,
 	}
 
 	err = s.budgetsRepository.UpdateBudget(budget)
@@ -165,6 +209,11 @@ func (s *BudgetsServiceInstance) UpdateBudget(budgetDTO dto.UpdateBudgetDTO, use
 		return nil, err
 	}
 
+	if err = s.budgetsRepository.SetBudgetFilter(*budget.ID, filter); err != nil {
+		logger.Error("Error saving budget filter", "error", err)
+		return nil, err
+	}
+
 	// Fill budget with existing transactions
 	err = s.fillBudgetWithExistingTransactions(*budget.ID, userID)
 	if err != nil {
@@ -183,37 +232,142 @@ func (s *BudgetsServiceInstance) GetUserBudgets(userID int, include string) ([]d
 		return nil, err
 	}
 
+	now := time.Now()
 	budgetDTOs := make([]dto.BudgetResponseDTO, len(budgetsWithCurrency))
 	for i, budget := range budgetsWithCurrency {
 		// Subtract 1 day from end date for display (reverse of the add operation)
 		endDate := *budget.EndDate
 		endDate = endDate.AddDate(0, 0, -1)
 
-		categoriesStr := ""
-		if budget.IncludedCategories != nil {
-			categoriesStr = *budget.IncludedCategories
+		alertThresholds, err := ParseAlertThresholdsFromString(budget.AlertThresholds)
+		if err != nil {
+			logger.Error("Error parsing budget alert thresholds", "budgetID", *budget.ID, "error", err)
+			alertThresholds = []int{}
+		}
+
+		seriesID := *budget.ID
+		if budget.SeriesID != nil {
+			seriesID = *budget.SeriesID
 		}
 
 		budgetDTOs[i] = dto.BudgetResponseDTO{
-			ID:                 *budget.ID,
-			Name:               budget.Name,
-			CurrencyID:         budget.CurrencyID,
-			TargetAmount:       budget.TargetAmount,
-			CollectedAmount:    budget.CollectedAmount,
-			Period:             models.FormatPeriodForAPI(budget.Period),
-			Repeat:             budget.Repeat,
-			StartDate:          budget.StartDate,
-			EndDate:            &endDate,
-			IncludedCategories: categoriesStr,
-			Comment:            budget.Comment,
-			IsArchived:         budget.IsArchived,
-			Currency:           budget.Currency,
+			ID:                    *budget.ID,
+			SeriesID:              seriesID,
+			Name:                  budget.Name,
+			CurrencyID:            budget.CurrencyID,
+			TargetAmount:          budget.TargetAmount,
+			CollectedAmount:       budget.CollectedAmount,
+			Period:                models.FormatPeriodForAPI(budget.Period),
+			Repeat:                budget.Repeat,
+			StartDate:             budget.StartDate,
+			EndDate:               &endDate,
+			IncludedCategories:    ConvertCategoryIDsToString(budget.Filter.IncludedCategories),
+			ExcludedCategories:    budget.Filter.ExcludedCategories,
+			Accounts:              budget.Filter.Accounts,
+			IncludedTags:          budget.Filter.IncludedTags,
+			ExcludedTags:          budget.Filter.ExcludedTags,
+			AnchorDay:             budget.AnchorDay,
+			AnchorLastBusinessDay: budget.AnchorLastBusinessDay,
+			WeekStartDay:          budget.WeekStartDay,
+			ForecastCurve:         strings.ToLower(budget.ForecastCurve),
+			AlertThresholds:       alertThresholds,
+			AlertOverBudget:       budget.AlertOverBudget,
+			Comment:               budget.Comment,
+			IsArchived:            budget.IsArchived,
+			Currency:              budget.Currency,
+		}
+
+		if isBudgetPeriodActive(budget.Budget, now) {
+			budgetDTOs[i].Forecast = s.budgetForecast(budget.Budget, now)
 		}
 	}
 
 	return budgetDTOs, nil
 }
 
+func (s *BudgetsServiceInstance) GetBudgetHistory(budgetID int, userID int) (*dto.BudgetHistoryDTO, error) {
+	logger.Debug("GetBudgetHistory Service")
+
+	budget, err := s.budgetsRepository.GetBudgetByID(budgetID, userID)
+	if err != nil {
+		logger.Error("Error getting budget", "error", err)
+		return nil, fmt.Errorf("budget not found")
+	}
+
+	seriesID := *budget.ID
+	if budget.SeriesID != nil {
+		seriesID = *budget.SeriesID
+	}
+
+	seriesBudgets, err := s.budgetsRepository.GetBudgetSeries(seriesID, userID)
+	if err != nil {
+		logger.Error("Error getting budget series", "seriesID", seriesID, "error", err)
+		return nil, err
+	}
+
+	currency, err := s.sm.CurrenciesService.GetCurrency(budget.CurrencyID)
+	if err != nil {
+		logger.Error("Error getting budget currency", "currencyID", budget.CurrencyID, "error", err)
+		return nil, err
+	}
+
+	history := &dto.BudgetHistoryDTO{
+		SeriesID: seriesID,
+		Name:     budget.Name,
+		Period:   models.FormatPeriodForAPI(budget.Period),
+		Currency: currency,
+		Periods:  make([]dto.BudgetHistoryPeriodDTO, 0, len(seriesBudgets)),
+	}
+
+	now := time.Now()
+	totalTarget := decimal.Zero
+	totalActual := decimal.Zero
+	actualAmounts := make([]decimal.Decimal, 0, len(seriesBudgets))
+
+	for _, period := range seriesBudgets {
+		periodDTO := newBudgetHistoryPeriod(period)
+
+		// A period is in the past once it has been archived or its end date has passed
+		if !period.IsArchived && period.EndDate.After(now) {
+			history.CurrentPeriod = &periodDTO
+			continue
+		}
+
+		history.Periods = append(history.Periods, periodDTO)
+		totalTarget = totalTarget.Add(periodDTO.TargetAmount)
+		totalActual = totalActual.Add(periodDTO.ActualAmount)
+		actualAmounts = append(actualAmounts, periodDTO.ActualAmount)
+	}
+
+	if count := len(history.Periods); count > 0 {
+		history.AverageTarget = totalTarget.Div(decimal.NewFromInt(int64(count))).Round(2)
+		history.AverageActual = totalActual.Div(decimal.NewFromInt(int64(count))).Round(2)
+	}
+	history.Trend, history.TrendSlope = CalculateBudgetTrend(actualAmounts)
+
+	return history, nil
+}
+
+func newBudgetHistoryPeriod(budget models.Budget) dto.BudgetHistoryPeriodDTO {
+	// Subtract 1 day from end date for display (reverse of the add operation)
+	endDate := budget.EndDate.AddDate(0, 0, -1)
+
+	percentUsed := decimal.Zero
+	if budget.TargetAmount.IsPositive() {
+		percentUsed = budget.CollectedAmount.Mul(decimal.NewFromInt(100)).Div(budget.TargetAmount).Round(2)
+	}
+
+	return dto.BudgetHistoryPeriodDTO{
+		BudgetID:     *budget.ID,
+		StartDate:    budget.StartDate,
+		EndDate:      &endDate,
+		TargetAmount: budget.TargetAmount,
+		ActualAmount: budget.CollectedAmount,
+		Difference:   budget.TargetAmount.Sub(budget.CollectedAmount),
+		PercentUsed:  percentUsed,
+	}
+}
+
 func (s *BudgetsServiceInstance) DeleteBudget(budgetID int, userID int) error {
 	logger.Debug("DeleteBudget Service")
 
@@ -271,119 +425,162 @@ func (s *BudgetsServiceInstance) ProcessOutdatedBudgets() ([]int, error) {
 	return archivedBudgetIDs, nil
 }
 
-func (s *BudgetsServiceInstance) UpdateBudgetCollectedAmounts(userID int) error {
+func (s *BudgetsServiceInstance) RecalculateCategoryBudgets(userID int, categoryIDs []int, first time.Time, last time.Time) error {
+	logger.Debug("RecalculateCategoryBudgets Service")
+
+	// Archived periods are recomputed too, the budget history reports their totals
 	userBudgets, err := s.budgetsRepository.GetUserBudgets(userID, "all")
 	if err != nil {
-		return fmt.Errorf("failed to get budgets for user %d: %w", userID, err)
+		logger.Error("Error getting user budgets", "error", err)
+		return err
 	}
 
-	// Create a shared cache for transaction queries to avoid duplicate database calls
-	// Key: "categories:startDate:endDate", Value: transactions
-	transactionCache := make(map[string][]models.Transaction)
-	cacheMutex := sync.RWMutex{}
-
-	// Process budgets concurrently
-	var wg sync.WaitGroup
-	errorChan := make(chan error, len(userBudgets))
-
-	// Limit concurrent goroutines to avoid overwhelming the database
-	const maxConcurrency = 10
-	semaphore := make(chan struct{}, maxConcurrency)
+	affected := make(map[int]bool, len(categoryIDs))
+	for _, categoryID := range categoryIDs {
+		affected[categoryID] = true
+	}
 
+	var firstErr error
 	for _, budget := range userBudgets {
-		if budget.ID != nil {
-			wg.Add(1)
-			go func(budgetID int, budgetName string) {
-				defer wg.Done()
-
-				// Acquire semaphore
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
-
-				if err := s.fillBudgetWithExistingTransactionsOptimized(budgetID, userID, transactionCache, &cacheMutex); err != nil {
-					logger.Error("Failed to update budget", "budgetID", budgetID, "budgetName", budgetName, "userID", userID, "error", err)
-					errorChan <- fmt.Errorf("budget %d (%s): %w", budgetID, budgetName, err)
-				}
-			}(*budget.ID, budget.Name)
+		if !budgetUsesCategories(budget.Filter, affected) || !budgetOverlaps(budget, first, last) {
+			continue
+		}
+
+		total, err := s.calculateBudgetCollectedAmount(budget)
+		if err == nil {
+			err = s.budgetsRepository.UpdateBudgetCollectedAmount(*budget.ID, total)
+		}
+		if err != nil { // handle error but continue processing
+			logger.Error("Error recalculating budget collected amount", "budgetID", *budget.ID, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
 	}
 
-	// Wait for all goroutines to complete
-	wg.Wait()
-	close(errorChan)
+	return firstErr
+}
+
+// budgetOverlaps reports whether the period of the budget contains any moment between first and last,
+// the end date of a budget is its last day
+func budgetOverlaps(budget models.Budget, first time.Time, last time.Time) bool {
+	if budget.StartDate == nil || budget.EndDate == nil {
+		return false
+	}
+	return !budget.StartDate.After(last) && budget.EndDate.AddDate(0, 0, 1).After(first)
+}
 
-	// Collect any errors (non-blocking since channel is closed)
-	var errors []error
-	for err := range errorChan {
-		errors = append(errors, err)
+// budgetUsesCategories reports whether the budget includes or excludes any of the categories
+func budgetUsesCategories(filter models.BudgetFilter, categories map[int]bool) bool {
+	for _, categoryID := range filter.IncludedCategories {
+		if categories[categoryID] {
+			return true
+		}
+	}
+	for _, categoryID := range filter.ExcludedCategories {
+		if categories[categoryID] {
+			return true
+		}
 	}
+	return false
+}
 
-	// Return first error if any occurred
-	if len(errors) > 0 {
-		return errors[0]
+// checkBudgetAlerts compares the budget collected amount before and after recalculation and enqueues
+// a notification when an alert threshold was crossed. Every threshold is recorded as sent for the
+// budget period, so it fires only once even if the amount goes down and up again.
+// When several thresholds are crossed at once, only the highest one is notified.
+func (s *BudgetsServiceInstance) checkBudgetAlerts(previous models.Budget) {
+	if previous.IsArchived || previous.StartDate == nil || !previous.TargetAmount.IsPositive() {
+		return
 	}
 
-	return nil
-}
+	thresholds, err := ParseAlertThresholdsFromString(previous.AlertThresholds)
+	if err != nil {
+		logger.Error("failed to parse budget alert thresholds", "budgetID", *previous.ID, "error", err)
+		return
+	}
+	if len(thresholds) == 0 && !previous.AlertOverBudget {
+		return
+	}
 
-// UpdateBudgetCollectedAmountsForCategories finds budgets active at given dates that include the categories,
-// de-duplicates budgets, and recomputes their collected amounts (full scan of matching transactions).
-func (s *BudgetsServiceInstance) UpdateBudgetCollectedAmountsForCategories(userID int, pairs []AffectedCategoryDate) error {
-	if len(pairs) == 0 {
-		return nil
+	updated, err := s.budgetsRepository.GetBudgetByID(*previous.ID, previous.UserID)
+	if err != nil {
+		logger.Error("failed to get budget for alerts check", "budgetID", *previous.ID, "error", err)
+		return
+	}
+	if !updated.CollectedAmount.GreaterThan(previous.CollectedAmount) {
+		return
 	}
 
-	// Collect affected budget IDs
-	budgetIDSet := make(map[int]struct{})
-	for _, p := range pairs {
-		if p.CategoryID == 0 || p.Date.IsZero() {
-			continue
+	hundred := decimal.NewFromInt(100)
+	previousPercent := previous.CollectedAmount.Mul(hundred).Div(updated.TargetAmount)
+	currentPercent := updated.CollectedAmount.Mul(hundred).Div(updated.TargetAmount)
+
+	crossed := make([]int, 0)
+	for _, t := range thresholds {
+		threshold := decimal.NewFromInt(int64(t))
+		if previousPercent.LessThan(threshold) && currentPercent.GreaterThanOrEqual(threshold) {
+			crossed = append(crossed, t)
 		}
-		budgets, err := s.budgetsRepository.GetActiveBudgetsByCategoryAndDate(userID, p.CategoryID, p.Date)
+	}
+	if updated.AlertOverBudget &&
+		previous.CollectedAmount.LessThanOrEqual(updated.TargetAmount) &&
+		updated.CollectedAmount.GreaterThan(updated.TargetAmount) {
+		crossed = append(crossed, models.BudgetAlertOverBudget)
+	}
+
+	// Record every crossed threshold, but notify only about the most significant one
+	notifyThreshold := -1
+	for _, t := range crossed {
+		isNew, err := s.budgetsRepository.MarkBudgetAlertSent(*updated.ID, t, *updated.StartDate)
 		if err != nil {
-			logger.Error("failed to get active budgets", "userID", userID, "categoryID", p.CategoryID, "date", p.Date.Format(time.DateOnly), "error", err)
+			logger.Error("failed to mark budget alert as sent", "budgetID", *updated.ID, "threshold", t, "error", err)
 			continue
 		}
-		for _, b := range budgets {
-			if b.ID != nil {
-				budgetIDSet[*b.ID] = struct{}{}
-			}
+		if isNew && (t == models.BudgetAlertOverBudget || (notifyThreshold != models.BudgetAlertOverBudget && t > notifyThreshold)) {
+			notifyThreshold = t
 		}
 	}
-
-	if len(budgetIDSet) == 0 {
-		return nil
+	if notifyThreshold < 0 {
+		return
 	}
 
-	// Prepare shared cache for transaction queries
-	transactionCache := make(map[string][]models.Transaction)
-	cacheMutex := sync.RWMutex{}
+	if err := s.enqueueBudgetAlert(*updated, notifyThreshold); err != nil {
+		logger.Error("failed to enqueue budget alert", "budgetID", *updated.ID, "threshold", notifyThreshold, "error", err)
+	}
+}
 
-	// Process affected budgets concurrently but limited
-	const maxConcurrency = 10
-	semaphore := make(chan struct{}, maxConcurrency)
-	var wg sync.WaitGroup
-	var firstErr error
-	var firstErrOnce sync.Once
-
-	for budgetID := range budgetIDSet {
-		wg.Add(1)
-		id := budgetID
-		go func() {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-			if err := s.fillBudgetWithExistingTransactionsOptimized(id, userID, transactionCache, &cacheMutex); err != nil {
-				logger.Error("failed to update budget", "budgetID", id, "userID", userID, "error", err)
-				firstErrOnce.Do(func() { firstErr = err })
-			}
-		}()
+func (s *BudgetsServiceInstance) enqueueBudgetAlert(budget models.Budget, threshold int) error {
+	user, err := s.sm.UserService.GetUserByID(budget.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user %d: %w", budget.UserID, err)
 	}
-	wg.Wait()
 
-	return firstErr
+	currency, err := s.sm.CurrenciesService.GetCurrency(budget.CurrencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get budget currency %d: %w", budget.CurrencyID, err)
+	}
+
+	// End date is stored as the day after the last day of the period
+	periodEnd := budget.EndDate.AddDate(0, 0, -1)
+
+	return s.sm.QueueService.EnqueueBudgetAlert(queue.BudgetAlertPayload{
+		UserEmail:       user.Email,
+		UserName:        user.FirstName,
+		BudgetID:        *budget.ID,
+		BudgetName:      budget.Name,
+		Threshold:       threshold,
+		OverBudget:      threshold == models.BudgetAlertOverBudget,
+		TargetAmount:    budget.TargetAmount.StringFixed(2),
+		CollectedAmount: budget.CollectedAmount.StringFixed(2),
+		CurrencyCode:    currency.Code,
+		PeriodStart:     budget.StartDate.Format(time.DateOnly),
+		PeriodEnd:       periodEnd.Format(time.DateOnly),
+	})
 }
 
+// fillBudgetWithExistingTransactions sets the collected amount of a new or edited budget from its transactions.
+// Afterwards the amount is maintained incrementally by transaction events.
 func (s *BudgetsServiceInstance) fillBudgetWithExistingTransactions(budgetID int, userID int) error {
 	// Get budget details
 	budget, err := s.budgetsRepository.GetBudgetByID(budgetID, userID)
@@ -391,14 +588,32 @@ func (s *BudgetsServiceInstance) fillBudgetWithExistingTransactions(budgetID int
 		return fmt.Errorf("failed to get budget %d for user %d: %w", budgetID, userID, err)
 	}
 
-	// Parse included categories
-	categoryIDs, err := ParseCategoryIDsFromString(*budget.IncludedCategories)
+	totalAmount, err := s.calculateBudgetCollectedAmount(*budget)
+	if err != nil {
+		return err
+	}
+
+	// Update budget collected amount
+	err = s.budgetsRepository.UpdateBudgetCollectedAmount(budgetID, totalAmount)
+	if err != nil {
+		return fmt.Errorf("failed to update collected amount for budget %d to %s: %w", budgetID, totalAmount.String(), err)
+	}
+
+	return nil
+}
+
+// calculateBudgetCollectedAmount sums all expense transactions of the budget period in the budget currency
+func (s *BudgetsServiceInstance) calculateBudgetCollectedAmount(budget models.Budget) (decimal.Decimal, error) {
+	budgetID := *budget.ID
+
+	// Included categories together with their subcategories
+	categoryIDs, err := s.budgetsRepository.GetBudgetCategoryIDs(budgetID)
 	if err != nil {
-		return fmt.Errorf("failed to parse category IDs '%s' for budget %d: %w", *budget.IncludedCategories, budgetID, err)
+		return decimal.Zero, err
 	}
 
 	if len(categoryIDs) == 0 {
-		return s.budgetsRepository.UpdateBudgetCollectedAmount(budgetID, decimal.Zero)
+		return decimal.Zero, nil
 	}
 
 	// Get expense transactions for this budget
@@ -406,32 +621,144 @@ func (s *BudgetsServiceInstance) fillBudgetWithExistingTransactions(budgetID int
 	transactions, err := s.sm.TransactionsService.GetExpenseTransactionsForBudget(
 		budget.UserID, categoryIDs, *budget.StartDate, *budget.EndDate, transactionIds)
 	if err != nil {
-		return fmt.Errorf("failed to get expense transactions for budget %d (user=%d, categories=%v, start=%v, end=%v): %w",
+		return decimal.Zero, fmt.Errorf("failed to get expense transactions for budget %d (user=%d, categories=%v, start=%v, end=%v): %w",
 			budgetID, budget.UserID, categoryIDs, budget.StartDate, budget.EndDate, err)
 	}
 
+	if err = s.loadTransactionTagsForFilter(budget.Filter, transactions); err != nil {
+		return decimal.Zero, err
+	}
+
 	// Calculate total collected amount in budget's currency
 	totalAmount := decimal.Zero
 	for _, transaction := range transactions {
+		if !budgetFilterMatches(budget.Filter, transaction) {
+			continue
+		}
+
 		// Convert transaction amount to budget currency
 		convertedAmount, err := s.convertTransactionAmountToBudgetCurrency(transaction, budget.CurrencyID)
 		if err != nil {
-			return fmt.Errorf("failed to convert transaction %d (amount=%s) to budget %d currency: %w",
+			return decimal.Zero, fmt.Errorf("failed to convert transaction %d (amount=%s) to budget %d currency: %w",
 				*transaction.ID, transaction.Amount.String(), budgetID, err)
 		}
 
 		totalAmount = totalAmount.Add(convertedAmount)
 	}
 
-	// Update budget collected amount
-	err = s.budgetsRepository.UpdateBudgetCollectedAmount(budgetID, totalAmount)
+	return totalAmount, nil
+}
+
+// buildBudgetFilter keeps only categories and accounts of the user and normalizes the tags
+func (s *BudgetsServiceInstance) buildBudgetFilter(userID int, categories []int, excludedCategories []int,
+	accounts []int, includedTags []string, excludedTags []string) (models.BudgetFilter, error) {
+	validCategories, err := s.budgetsRepository.GetUserCategoriesForBudget(userID, categories)
 	if err != nil {
-		return fmt.Errorf("failed to update collected amount for budget %d to %s: %w", budgetID, totalAmount.String(), err)
+		logger.Error("Error validating categories", "error", err)
+		return models.BudgetFilter{}, err
+	}
+
+	validExcluded, err := s.budgetsRepository.GetUserCategoriesForBudget(userID, excludedCategories)
+	if err != nil {
+		logger.Error("Error validating excluded categories", "error", err)
+		return models.BudgetFilter{}, err
 	}
 
+	validAccounts, err := s.budgetsRepository.GetUserAccountsForBudget(userID, accounts)
+	if err != nil {
+		logger.Error("Error validating accounts", "error", err)
+		return models.BudgetFilter{}, err
+	}
+
+	filter := models.BudgetFilter{
+		IncludedCategories: validCategories,
+		ExcludedCategories: make([]int, 0, len(validExcluded)),
+		Accounts:           validAccounts,
+		IncludedTags:       NormalizeTags(includedTags),
+		ExcludedTags:       NormalizeTags(excludedTags),
+	}
+
+	included := make(map[int]struct{}, len(validCategories))
+	for _, id := range validCategories {
+		included[id] = struct{}{}
+	}
+	for _, id := range validExcluded {
+		if _, ok := included[id]; ok {
+			return models.BudgetFilter{}, fmt.Errorf("category %d cannot be both included and excluded", id)
+		}
+		filter.ExcludedCategories = append(filter.ExcludedCategories, id)
+	}
+
+	for _, tag := range filter.ExcludedTags {
+		for _, includedTag := range filter.IncludedTags {
+			if tag == includedTag {
+				return models.BudgetFilter{}, fmt.Errorf("tag %s cannot be both included and excluded", tag)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// loadTransactionTagsForFilter fills tags of the transactions when the filter depends on them
+func (s *BudgetsServiceInstance) loadTransactionTagsForFilter(filter models.BudgetFilter, transactions []models.Transaction) error {
+	if len(filter.IncludedTags) == 0 && len(filter.ExcludedTags) == 0 {
+		return nil
+	}
+
+	transactionIds := make([]int, 0, len(transactions))
+	for _, transaction := range transactions {
+		transactionIds = append(transactionIds, *transaction.ID)
+	}
+
+	tags, err := s.sm.TransactionsService.GetTransactionsTags(transactionIds)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction tags: %w", err)
+	}
+
+	for i := range transactions {
+		transactions[i].Tags = tags[*transactions[i].ID]
+	}
 	return nil
 }
 
+// budgetFilterMatches reports whether an expense transaction of a budget category passes the account and tag filters
+func budgetFilterMatches(filter models.BudgetFilter, transaction models.Transaction) bool {
+	if len(filter.Accounts) > 0 {
+		found := false
+		for _, accountID := range filter.Accounts {
+			if accountID == transaction.AccountID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	tags := make(map[string]struct{}, len(transaction.Tags))
+	for _, tag := range transaction.Tags {
+		tags[tag] = struct{}{}
+	}
+
+	for _, tag := range filter.ExcludedTags {
+		if _, ok := tags[tag]; ok {
+			return false
+		}
+	}
+
+	if len(filter.IncludedTags) == 0 {
+		return true
+	}
+	for _, tag := range filter.IncludedTags {
+		if _, ok := tags[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *BudgetsServiceInstance) convertTransactionAmountToBudgetCurrency(transaction models.Transaction, budgetCurrencyID int) (decimal.Decimal, error) {
 	// Get transaction account to know its currency
 	accountDTO, err := s.sm.AccountsService.GetAccountById(transaction.AccountID)
@@ -457,7 +784,7 @@ func (s *BudgetsServiceInstance) convertTransactionAmountToBudgetCurrency(transa
 	}
 
 	// Need currency conversion - use exchange rate service with currency codes
-	convertedAmount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(
+	convertedAmount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(transaction.UserID,
 		*transaction.DateTime, transaction.Amount, accountDTO.Currency.Code, budgetCurrency.Code)
 	if err != nil {
 		return decimal.Zero, fmt.Errorf("failed to convert %s %s to %s on %v: %w",
@@ -467,118 +794,64 @@ func (s *BudgetsServiceInstance) convertTransactionAmountToBudgetCurrency(transa
 	return convertedAmount, nil
 }
 
-// fillBudgetWithExistingTransactionsOptimized is a cache-aware version for concurrent processing
-func (s *BudgetsServiceInstance) fillBudgetWithExistingTransactionsOptimized(budgetID int, userID int, transactionCache map[string][]models.Transaction, cacheMutex *sync.RWMutex) error {
-	// Get budget details
-	budget, err := s.budgetsRepository.GetBudgetByID(budgetID, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get budget %d for user %d: %w", budgetID, userID, err)
-	}
+func (s *BudgetsServiceInstance) createCopyOfOutdatedBudget(budget models.Budget) error {
+	logger.Debug("createCopyOfOutdatedBudget Service")
 
-	// Parse included categories
-	categoryIDs, err := ParseCategoryIDsFromString(*budget.IncludedCategories)
+	// The next period starts right where the previous one ended and lasts until the next aligned boundary
+	newStartDate := *budget.EndDate
+	newEndDate, err := NextPeriodStart(budget.Period, newStartDate, BudgetPeriodAlignment(budget))
 	if err != nil {
-		return fmt.Errorf("failed to parse category IDs '%s' for budget %d: %w", *budget.IncludedCategories, budgetID, err)
-	}
-
-	if len(categoryIDs) == 0 {
-		return s.budgetsRepository.UpdateBudgetCollectedAmount(budgetID, decimal.Zero)
-	}
-
-	// Create cache key for this query
-	cacheKey := fmt.Sprintf("%v:%s:%s", categoryIDs, budget.StartDate.Format("2006-01-02"), budget.EndDate.Format("2006-01-02"))
-
-	// Try to get transactions from cache first
-	cacheMutex.RLock()
-	transactions, found := transactionCache[cacheKey]
-	cacheMutex.RUnlock()
-
-	if !found {
-		// Not in cache, fetch from database
-		var transactionIds []int
-		transactions, err = s.sm.TransactionsService.GetExpenseTransactionsForBudget(
-			budget.UserID, categoryIDs, *budget.StartDate, *budget.EndDate, transactionIds)
-		if err != nil {
-			return fmt.Errorf("failed to get expense transactions for budget %d (user=%d, categories=%v, start=%v, end=%v): %w",
-				budgetID, budget.UserID, categoryIDs, budget.StartDate, budget.EndDate, err)
-		}
-
-		// Store in cache for other budgets
-		cacheMutex.Lock()
-		transactionCache[cacheKey] = transactions
-		cacheMutex.Unlock()
+		return err
 	}
 
-	// Calculate total collected amount in budget's currency
-	totalAmount := decimal.Zero
-	for _, transaction := range transactions {
-		// Convert transaction amount to budget currency
-		convertedAmount, err := s.convertTransactionAmountToBudgetCurrency(transaction, budget.CurrencyID)
-		if err != nil {
-			return fmt.Errorf("failed to convert transaction %d (amount=%s) to budget %d currency: %w",
-				*transaction.ID, transaction.Amount.String(), budgetID, err)
-		}
+	now := time.Now()
 
-		totalAmount = totalAmount.Add(convertedAmount)
+	// The renewal is the next period of the same series
+	seriesID := budget.SeriesID
+	if seriesID == nil {
+		seriesID = budget.ID
 	}
 
-	// Update budget collected amount
-	err = s.budgetsRepository.UpdateBudgetCollectedAmount(budgetID, totalAmount)
+	newBudget := models.Budget{
+		SeriesID:              seriesID,
+		UserID:                budget.UserID,
+		Name:                  budget.Name,
+		CurrencyID:            budget.CurrencyID,
+		TargetAmount:          budget.TargetAmount,
+		CollectedAmount:       decimal.Zero,
+		Period:                budget.Period,
+		Repeat:                budget.Repeat,
+		StartDate:             &newStartDate,
+		EndDate:               &newEndDate,
+		AnchorDay:             budget.AnchorDay,
+		AnchorLastBusinessDay: budget.AnchorLastBusinessDay,
+		WeekStartDay:          budget.WeekStartDay,
+		ForecastCurve:         budget.ForecastCurve,
+		AlertThresholds:       budget.AlertThresholds,
+		AlertOverBudget:       budget.AlertOverBudget,
+		Comment:               budget.Comment,
+		IsDeleted:             false,
+		IsArchived:            false,
+		CreatedAt:             &now,
+		UpdatedAt:             &now,
+		Filter:                budget.Filter,
+	}
+
+	createdBudget, err := s.budgetsRepository.CreateBudget(newBudget)
 	if err != nil {
-		return fmt.Errorf("failed to update collected amount for budget %d to %s: %w", budgetID, totalAmount.String(), err)
+		logger.Error("Error creating copy of budget", "error", err)
+		return err
 	}
 
-	return nil
-}
-
-func (s *BudgetsServiceInstance) createCopyOfOutdatedBudget(budget models.Budget) error {
-	logger.Debug("createCopyOfOutdatedBudget Service")
-
-	endDate := *budget.EndDate
-	var newStartDate, newEndDate time.Time
-
-	switch strings.ToUpper(budget.Period) {
-	case string(models.PeriodDaily):
-		newStartDate = endDate
-		newEndDate = endDate.AddDate(0, 0, 1)
-	case string(models.PeriodWeekly):
-		newStartDate = endDate
-		newEndDate = endDate.AddDate(0, 0, 7)
-	case string(models.PeriodMonthly):
-		newStartDate = endDate
-		newEndDate = endDate.AddDate(0, 1, 0)
-	case string(models.PeriodYearly):
-		newStartDate = endDate
-		newEndDate = endDate.AddDate(1, 0, 0)
-	default:
-		return fmt.Errorf("invalid period for auto-renewal: %s", budget.Period)
+	if err = s.budgetsRepository.SetBudgetFilter(*createdBudget.ID, budget.Filter); err != nil {
+		logger.Error("Error copying budget filter", "error", err)
+		return err
 	}
 
-	now := time.Now()
-	copyName := fmt.Sprintf("%s (copy)", budget.Name)
-
-	newBudget := models.Budget{
-		UserID:             budget.UserID,
-		Name:               copyName,
-		CurrencyID:         budget.CurrencyID,
-		TargetAmount:       budget.TargetAmount,
-		CollectedAmount:    decimal.Zero,
-		Period:             budget.Period,
-		Repeat:             budget.Repeat,
-		StartDate:          &newStartDate,
-		EndDate:            &newEndDate,
-		IncludedCategories: budget.IncludedCategories,
-		Comment:            budget.Comment,
-		IsDeleted:          false,
-		IsArchived:         false,
-		CreatedAt:          &now,
-		UpdatedAt:          &now,
-	}
-
-	_, err := s.budgetsRepository.CreateBudget(newBudget)
+	// Expenses may already exist in the new period (e.g. made before the daily processing ran)
+	err = s.fillBudgetWithExistingTransactions(*createdBudget.ID, createdBudget.UserID)
 	if err != nil {
-		logger.Error("Error creating copy of budget", "error", err)
-		return err
+		logger.Error("Error filling renewed budget with existing transactions", "error", err)
 	}
 
 	return nil
@@ -2,8 +2,12 @@ package services
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/shopspring/decimal"
 )
 
 // ConvertCategoryIDsToString converts a slice of category IDs to a comma-separated string
@@ -39,3 +43,73 @@ func ParseCategoryIDsFromString(categoriesStr string) ([]int, error) {
 
 	return categoryIDs, nil
 }
+
+// ConvertAlertThresholdsToString validates alert thresholds (percentages) and converts them
+// to a sorted comma-separated string without duplicates
+func ConvertAlertThresholdsToString(thresholds []int) (string, error) {
+	unique := make(map[int]struct{}, len(thresholds))
+	for _, t := range thresholds {
+		if t <= 0 || t > models.MaxBudgetAlertThreshold {
+			return "", fmt.Errorf("invalid alert threshold: %d. Thresholds must be between 1 and %d",
+				t, models.MaxBudgetAlertThreshold)
+		}
+		unique[t] = struct{}{}
+	}
+
+	sorted := make([]int, 0, len(unique))
+	for t := range unique {
+		sorted = append(sorted, t)
+	}
+	sort.Ints(sorted)
+
+	return ConvertCategoryIDsToString(sorted), nil
+}
+
+// ParseAlertThresholdsFromString parses a comma-separated string of alert thresholds
+func ParseAlertThresholdsFromString(thresholdsStr *string) ([]int, error) {
+	if thresholdsStr == nil {
+		return []int{}, nil
+	}
+
+	thresholds, err := ParseCategoryIDsFromString(*thresholdsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert thresholds '%s': %w", *thresholdsStr, err)
+	}
+
+	return thresholds, nil
+}
+
+// budgetTrendStableRatio is the share of the average amount per period below which
+// the slope is considered too small to call it a trend
+var budgetTrendStableRatio = decimal.NewFromFloat(0.05)
+
+// CalculateBudgetTrend fits a least-squares line through the amounts (one per period, oldest first)
+// and returns the trend direction together with the slope (change of amount per period)
+func CalculateBudgetTrend(amounts []decimal.Decimal) (string, decimal.Decimal) {
+	n := len(amounts)
+	if n < 2 {
+		return models.BudgetTrendInsufficient, decimal.Zero
+	}
+
+	count := decimal.NewFromInt(int64(n))
+	sumX, sumY, sumXY, sumXX := decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero
+	for i, amount := range amounts {
+		x := decimal.NewFromInt(int64(i))
+		sumX = sumX.Add(x)
+		sumY = sumY.Add(amount)
+		sumXY = sumXY.Add(x.Mul(amount))
+		sumXX = sumXX.Add(x.Mul(x))
+	}
+
+	denominator := count.Mul(sumXX).Sub(sumX.Mul(sumX))
+	slope := count.Mul(sumXY).Sub(sumX.Mul(sumY)).Div(denominator).Round(2)
+
+	average := sumY.Div(count)
+	if slope.Abs().LessThanOrEqual(average.Abs().Mul(budgetTrendStableRatio)) {
+		return models.BudgetTrendStable, slope
+	}
+	if slope.IsPositive() {
+		return models.BudgetTrendIncreasing, slope
+	}
+	return models.BudgetTrendDecreasing, slope
+}
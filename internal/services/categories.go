@@ -39,12 +39,34 @@ func getBoolPointer(value bool) *bool {
 type CategoriesService interface {
 	GetUserCategories(userId int) ([]models.UserCategory, error)
 	GetUserCategoriesGrouped(userId int) (map[string][]models.GroupedCategory, error)
-	CreateCategory(name string, isIncome bool, parentID *int, userID int) (*models.UserCategory, error)
+	CreateCategory(name string, isIncome bool, parentID *int, color *string, icon *string, userID int) (*models.UserCategory, error)
 	ValidateCategoryOwnership(categoryId int, userId int) (bool, error)
+	// UpdateCategory renames the category, moves it under another parent (nil makes it top-level)
+	// and sets its color and icon (nil inherits them from the parent)
+	UpdateCategory(categoryID int, name string, parentID *int, color *string, icon *string, userID int) (*models.UserCategory, error)
+	// DeleteCategory deletes the category. A category in use or having subcategories can only be deleted
+	// with a replacement category that takes over everything referencing it.
+	DeleteCategory(categoryID int, replacementID *int, userID int) error
+	// MergeCategories moves transactions, templates, budgets and subcategories of the source into the target
+	// and deletes the source
+	MergeCategories(sourceID int, targetID int, userID int) (*models.UserCategory, error)
+	// ApplyDefaultCategories adds the default categories of the language the user does not have yet.
+	// An empty language means the language from the user settings. With reset the categories outside
+	// the default set are deleted unless something uses them.
+	ApplyDefaultCategories(userID int, languageCode string, reset bool) (*categories.CategoryTreeResult, error)
+	// ExportCategories returns the category tree of the user with the own colors and icons of the categories
+	ExportCategories(userID int) ([]models.CategoryNode, error)
+	// ImportCategories adds the categories of the tree the user does not have yet. With replace
+	// the categories outside the tree are deleted unless something uses them.
+	ImportCategories(userID int, nodes []models.CategoryNode, replace bool) (*categories.CategoryTreeResult, error)
 }
 
+// MaxImportedCategories limits the size of an imported category tree
+const MaxImportedCategories = 500
+
 type CategoryServiceInstance struct {
 	categoriesRepo categories.Repository
+	sm             *Manager
 }
 
 var (
@@ -52,11 +74,12 @@ var (
 	categoriesOnce     sync.Once
 )
 
-func NewCategoriesService(repository categories.Repository) CategoriesService {
+func NewCategoriesService(repository categories.Repository, sManager *Manager) CategoriesService {
 	categoriesOnce.Do(func() {
 		logger.Debug("Creating CategoriesService instance")
 		categoriesInstance = &CategoryServiceInstance{
 			categoriesRepo: repository,
+			sm:             sManager,
 		}
 	})
 
@@ -173,6 +196,8 @@ func (c *CategoryServiceInstance) GetUserCategoriesGrouped(userId int) (map[stri
 			Name:     getStringValue(category.Name),
 			ParentID: category.ParentID,
 			IsIncome: getBoolValue(category.IsIncome),
+			Color:    category.EffectiveColor(),
+			Icon:     category.EffectiveIcon(),
 			Children: make([]models.GroupedCategory, 0),
 		}
 
@@ -223,7 +248,7 @@ func (c *CategoryServiceInstance) GetUserCategoriesGrouped(userId int) (map[stri
 	}, nil
 }
 
-func (c *CategoryServiceInstance) CreateCategory(name string, isIncome bool, parentID *int, userID int) (*models.UserCategory, error) {
+func (c *CategoryServiceInstance) CreateCategory(name string, isIncome bool, parentID *int, color *string, icon *string, userID int) (*models.UserCategory, error) {
 	// Validate parent category if provided
 	if parentID != nil {
 		isValidParent, err := c.categoriesRepo.ValidateCategoryOwnership(*parentID, userID)
@@ -235,12 +260,19 @@ func (c *CategoryServiceInstance) CreateCategory(name string, isIncome bool, par
 		}
 	}
 
+	color, icon, err := normalizeCategoryAppearance(color, icon)
+	if err != nil {
+		return nil, err
+	}
+
 	category := models.UserCategory{
 		Name:      &name,
 		ParentID:  parentID,
 		IsIncome:  &isIncome,
 		UserID:    &userID,
 		IsDeleted: getBoolPointer(false),
+		Color:     color,
+		Icon:      icon,
 	}
 
 	return c.categoriesRepo.CreateCategory(category)
@@ -249,3 +281,404 @@ func (c *CategoryServiceInstance) CreateCategory(name string, isIncome bool, par
 func (c *CategoryServiceInstance) ValidateCategoryOwnership(categoryId int, userId int) (bool, error) {
 	return c.categoriesRepo.ValidateCategoryOwnership(categoryId, userId)
 }
+
+func (c *CategoryServiceInstance) UpdateCategory(categoryID int, name string, parentID *int, color *string, icon *string, userID int) (*models.UserCategory, error) {
+	logger.Debug("UpdateCategory Service")
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("category name is required")
+	}
+
+	color, icon, err := normalizeCategoryAppearance(color, icon)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := c.categoriesRepo.GetCategory(categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		if err = c.validateNewParent(*category, *parentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = c.validateUniqueName(*category, name, parentID, userID); err != nil {
+		return nil, err
+	}
+
+	oldParentID := getIntValue(category.ParentID)
+	moved := oldParentID != getIntValue(parentID)
+	var period *categories.TransactionPeriod
+	if moved {
+		if period, err = c.categoriesRepo.GetCategoryTransactionPeriod(categoryID, userID); err != nil {
+			logger.Error("Error getting category transaction period", "error", err)
+			return nil, err
+		}
+	}
+
+	category.Name = &name
+	category.ParentID = parentID
+	category.Color = color
+	category.Icon = icon
+	if err = c.categoriesRepo.UpdateCategory(*category); err != nil {
+		logger.Error("Error updating category", "error", err)
+		return nil, err
+	}
+
+	// Budgets including the old or the new parent count subcategories, so their totals change
+	if moved {
+		c.recalculateBudgets(userID, period, categoryID, oldParentID, getIntValue(parentID))
+	}
+
+	return c.categoriesRepo.GetCategory(categoryID, userID)
+}
+
+func (c *CategoryServiceInstance) DeleteCategory(categoryID int, replacementID *int, userID int) error {
+	logger.Debug("DeleteCategory Service")
+
+	if replacementID != nil {
+		_, err := c.MergeCategories(categoryID, *replacementID, userID)
+		return err
+	}
+
+	if _, err := c.categoriesRepo.GetCategory(categoryID, userID); err != nil {
+		return err
+	}
+
+	children, err := c.categoriesRepo.GetChildCategories(categoryID, userID)
+	if err != nil {
+		logger.Error("Error getting subcategories", "error", err)
+		return err
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("category has %d subcategories, move them or provide a replacement category", len(children))
+	}
+
+	usage, err := c.categoriesRepo.GetCategoryUsage(categoryID, userID)
+	if err != nil {
+		logger.Error("Error getting category usage", "error", err)
+		return err
+	}
+	if usage.InUse() {
+		return fmt.Errorf("category is used by %d transactions, %d templates and %d budgets, provide a replacement category",
+			usage.Transactions, usage.Templates, usage.Budgets)
+	}
+
+	if err = c.categoriesRepo.DeleteCategory(categoryID, userID); err != nil {
+		logger.Error("Error deleting category", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *CategoryServiceInstance) MergeCategories(sourceID int, targetID int, userID int) (*models.UserCategory, error) {
+	logger.Debug("MergeCategories Service")
+
+	if sourceID == targetID {
+		return nil, fmt.Errorf("cannot merge a category into itself")
+	}
+
+	source, err := c.categoriesRepo.GetCategory(sourceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := c.categoriesRepo.GetCategory(targetID, userID)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return nil, fmt.Errorf("target category not found")
+		}
+		return nil, err
+	}
+
+	if getBoolValue(source.IsIncome) != getBoolValue(target.IsIncome) {
+		return nil, fmt.Errorf("cannot merge income and expense categories")
+	}
+	if getIntValue(target.ParentID) == sourceID {
+		return nil, fmt.Errorf("cannot merge a category into its own subcategory")
+	}
+
+	children, err := c.categoriesRepo.GetChildCategories(sourceID, userID)
+	if err != nil {
+		logger.Error("Error getting subcategories", "error", err)
+		return nil, err
+	}
+	// Subcategories move under the target, and categories are only two levels deep
+	if len(children) > 0 && target.ParentID != nil {
+		return nil, fmt.Errorf("cannot merge a category with subcategories into a subcategory")
+	}
+
+	// The transactions of the source and its subcategories move, their dates bound the budgets to recompute
+	period, err := c.categoriesRepo.GetCategoryTransactionPeriod(sourceID, userID)
+	if err != nil {
+		logger.Error("Error getting category transaction period", "error", err)
+		return nil, err
+	}
+
+	if err = c.categoriesRepo.MergeCategory(sourceID, targetID, userID); err != nil {
+		logger.Error("Error merging categories", "error", err)
+		return nil, err
+	}
+
+	// Budgets of the source move to the target, and the parents of both gain or lose its transactions
+	c.recalculateBudgets(userID, period, sourceID, targetID, getIntValue(source.ParentID), getIntValue(target.ParentID))
+
+	return target, nil
+}
+
+// validateNewParent checks that the category can be placed under the parent without breaking
+// the two level hierarchy or mixing income and expense categories
+func (c *CategoryServiceInstance) validateNewParent(category models.UserCategory, parentID int, userID int) error {
+	if parentID == getIntValue(category.ID) {
+		return fmt.Errorf("category cannot be its own parent")
+	}
+
+	parent, err := c.categoriesRepo.GetCategory(parentID, userID)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return fmt.Errorf("parent category not found or does not belong to user")
+		}
+		return err
+	}
+	if parent.ParentID != nil {
+		return fmt.Errorf("parent category cannot be a subcategory")
+	}
+	if getBoolValue(parent.IsIncome) != getBoolValue(category.IsIncome) {
+		return fmt.Errorf("parent category must be of the same type (income or expense)")
+	}
+
+	children, err := c.categoriesRepo.GetChildCategories(getIntValue(category.ID), userID)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("category with subcategories cannot become a subcategory")
+	}
+
+	return nil
+}
+
+// validateUniqueName rejects a name already used by another category of the same type under the same parent
+func (c *CategoryServiceInstance) validateUniqueName(category models.UserCategory, name string, parentID *int, userID int) error {
+	userCategories, err := c.categoriesRepo.GetUserCategories(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range userCategories {
+		if getIntValue(other.ID) == getIntValue(category.ID) ||
+			getIntValue(other.ParentID) != getIntValue(parentID) ||
+			getBoolValue(other.IsIncome) != getBoolValue(category.IsIncome) {
+			continue
+		}
+		if strings.EqualFold(getStringValue(other.Name), name) {
+			return fmt.Errorf("category %s already exists", name)
+		}
+	}
+
+	return nil
+}
+
+// recalculateBudgets recomputes the totals of budgets using the categories after the transactions of the
+// period moved between them. Failures are only logged, the daily reconciliation repairs running budgets.
+func (c *CategoryServiceInstance) recalculateBudgets(userID int, period *categories.TransactionPeriod, categoryIDs ...int) {
+	if period.First == nil || period.Last == nil {
+		return
+	}
+	if err := c.sm.BudgetsService.RecalculateCategoryBudgets(userID, categoryIDs, *period.First, *period.Last); err != nil {
+		logger.Error("Error recalculating budgets after category change", "userID", userID, "error", err)
+	}
+}
+
+func (c *CategoryServiceInstance) ApplyDefaultCategories(userID int, languageCode string, reset bool) (*categories.CategoryTreeResult, error) {
+	logger.Debug("ApplyDefaultCategories Service")
+
+	if languageCode == "" {
+		languageCode = c.userLanguage(userID)
+	}
+
+	defaultCategories, err := c.categoriesRepo.GetDefaultCategories(languageCode)
+	if err != nil {
+		logger.Error("Error getting default categories", "language", languageCode, "error", err)
+		return nil, err
+	}
+	if len(defaultCategories) == 0 && languageCode != models.DefaultLanguage {
+		logger.Warn("No default categories for language, using the default language", "language", languageCode)
+		if defaultCategories, err = c.categoriesRepo.GetDefaultCategories(models.DefaultLanguage); err != nil {
+			logger.Error("Error getting default categories", "language", models.DefaultLanguage, "error", err)
+			return nil, err
+		}
+	}
+	if len(defaultCategories) == 0 {
+		return nil, fmt.Errorf("no default categories found")
+	}
+
+	// Parents come first, so every subcategory finds its parent node
+	nodes := make([]models.CategoryNode, 0)
+	nodeIndex := make(map[int]int)
+	for _, category := range defaultCategories {
+		node := models.CategoryNode{Name: category.Name, IsIncome: category.IsIncome}
+		if category.ParentID == nil {
+			nodeIndex[category.ID] = len(nodes)
+			nodes = append(nodes, node)
+			continue
+		}
+		if i, ok := nodeIndex[*category.ParentID]; ok {
+			nodes[i].Children = append(nodes[i].Children, node)
+		}
+	}
+
+	result, err := c.categoriesRepo.ApplyCategoryTree(userID, nodes, reset)
+	if err != nil {
+		logger.Error("Error applying default categories", "error", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *CategoryServiceInstance) ExportCategories(userID int) ([]models.CategoryNode, error) {
+	logger.Debug("ExportCategories Service")
+
+	userCategories, err := c.categoriesRepo.GetUserCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]models.CategoryNode, 0)
+	nodeIndex := make(map[int]int)
+	for _, category := range userCategories {
+		if category.ParentID == nil {
+			nodeIndex[getIntValue(category.ID)] = len(nodes)
+			nodes = append(nodes, toCategoryNode(category))
+		}
+	}
+	// Subcategories of a deleted parent are skipped, there is no place for them in the tree
+	for _, category := range userCategories {
+		if category.ParentID == nil {
+			continue
+		}
+		if i, ok := nodeIndex[*category.ParentID]; ok {
+			nodes[i].Children = append(nodes[i].Children, toCategoryNode(category))
+		}
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].IsIncome != nodes[j].IsIncome {
+			return !nodes[i].IsIncome
+		}
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+
+	return nodes, nil
+}
+
+func (c *CategoryServiceInstance) ImportCategories(userID int, nodes []models.CategoryNode, replace bool) (*categories.CategoryTreeResult, error) {
+	logger.Debug("ImportCategories Service")
+
+	nodes, err := validateCategoryTree(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.categoriesRepo.ApplyCategoryTree(userID, nodes, replace)
+	if err != nil {
+		logger.Error("Error importing categories", "error", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// userLanguage returns the language from the user settings or the default language
+func (c *CategoryServiceInstance) userLanguage(userID int) string {
+	settings, err := c.sm.UserSettingsService.GetUserSettings(userID)
+	if err != nil || settings == nil {
+		return models.DefaultLanguage
+	}
+	if language, ok := settings.Settings["language"].(string); ok && language != "" {
+		return language
+	}
+
+	return models.DefaultLanguage
+}
+
+// validateCategoryTree checks names, nesting and appearance of an imported tree and returns it normalized.
+// Subcategories always get the type of their parent.
+func validateCategoryTree(nodes []models.CategoryNode) ([]models.CategoryNode, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no categories to import")
+	}
+
+	count := 0
+	normalized := make([]models.CategoryNode, 0, len(nodes))
+	for _, node := range nodes {
+		parent, err := normalizeCategoryNode(node, node.IsIncome)
+		if err != nil {
+			return nil, err
+		}
+		count++
+
+		parent.Children = make([]models.CategoryNode, 0, len(node.Children))
+		for _, child := range node.Children {
+			if len(child.Children) > 0 {
+				return nil, fmt.Errorf("category %s is nested too deep, categories have only two levels", child.Name)
+			}
+			normalizedChild, err := normalizeCategoryNode(child, parent.IsIncome)
+			if err != nil {
+				return nil, err
+			}
+			parent.Children = append(parent.Children, normalizedChild)
+			count++
+		}
+		normalized = append(normalized, parent)
+	}
+
+	if count > MaxImportedCategories {
+		return nil, fmt.Errorf("too many categories: %d, at most %d can be imported", count, MaxImportedCategories)
+	}
+
+	return normalized, nil
+}
+
+func normalizeCategoryNode(node models.CategoryNode, isIncome bool) (models.CategoryNode, error) {
+	name := strings.TrimSpace(node.Name)
+	if name == "" {
+		return models.CategoryNode{}, fmt.Errorf("category name is required")
+	}
+
+	color, icon, err := normalizeCategoryAppearance(node.Color, node.Icon)
+	if err != nil {
+		return models.CategoryNode{}, err
+	}
+
+	return models.CategoryNode{Name: name, IsIncome: isIncome, Color: color, Icon: icon}, nil
+}
+
+func toCategoryNode(category models.UserCategory) models.CategoryNode {
+	return models.CategoryNode{
+		Name:     getStringValue(category.Name),
+		IsIncome: getBoolValue(category.IsIncome),
+		Color:    category.Color,
+		Icon:     category.Icon,
+	}
+}
+
+func normalizeCategoryAppearance(color *string, icon *string) (*string, *string, error) {
+	color, ok := models.NormalizeCategoryColor(color)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid color: %s. Expected format is #RRGGBB", *color)
+	}
+
+	icon, ok = models.NormalizeCategoryIcon(icon)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid icon: %s. Icon keys are up to %d lowercase letters, digits, - and _",
+			*icon, models.MaxCategoryIconLength)
+	}
+
+	return color, icon, nil
+}
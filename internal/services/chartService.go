@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"ypeskov/budget-go/internal/dto"
 	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
 
 	"github.com/wcharczuk/go-chart/v2"
 	"github.com/wcharczuk/go-chart/v2/drawing"
@@ -36,6 +39,109 @@ func (pr *pieCustomRenderer) Render(r chart.Renderer, cb chart.Box, chartDefault
 
 type ChartService interface {
 	GeneratePieChart(data []dto.ExpensesDiagramDataDTO, currency string) (*dto.ChartImageDTO, error)
+	// RenderPieChart renders the pie chart as PNG bytes
+	RenderPieChart(data []dto.ExpensesDiagramDataDTO, currency string) ([]byte, error)
+	// RenderPie renders the pie chart with the size, format and theme of the options
+	RenderPie(data []dto.ExpensesDiagramDataDTO, options ChartOptions) ([]byte, error)
+	// RenderBar renders a bar per category
+	RenderBar(data []dto.ExpensesDiagramDataDTO, options ChartOptions) ([]byte, error)
+	// RenderStackedBar renders a bar per label made of the values the series have at the label index
+	RenderStackedBar(labels []string, series []ChartSeries, options ChartOptions) ([]byte, error)
+	// RenderLine renders a line per series over the dates
+	RenderLine(dates []time.Time, series []ChartSeries, options ChartOptions) ([]byte, error)
+	// ChartImage wraps a rendered chart into a data URL
+	ChartImage(image []byte, format string) *dto.ChartImageDTO
+}
+
+// Chart output formats and themes
+const (
+	ChartFormatPNG  = "png"
+	ChartFormatSVG  = "svg"
+	ChartThemeLight = "light"
+	ChartThemeDark  = "dark"
+)
+
+const (
+	minChartSize = 200
+	maxChartSize = 2000
+)
+
+// ChartOptions sets the size, output format and theme of a chart. Zero values select
+// the default size of the chart type, PNG and the light theme.
+type ChartOptions struct {
+	Width  int
+	Height int
+	Format string
+	Theme  string
+}
+
+// ChartSeries is a named row of values drawn in one color
+type ChartSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+type chartTheme struct {
+	Background drawing.Color
+	Font       drawing.Color
+	Axis       drawing.Color
+}
+
+var chartThemes = map[string]chartTheme{
+	ChartThemeLight: {
+		Background: drawing.ColorWhite,
+		Font:       drawing.ColorFromHex("333333"),
+		Axis:       drawing.ColorFromHex("999999"),
+	},
+	ChartThemeDark: {
+		Background: drawing.ColorFromHex("1E1E1E"),
+		Font:       drawing.ColorFromHex("E0E0E0"),
+		Axis:       drawing.ColorFromHex("777777"),
+	},
+}
+
+// normalize validates the options and fills in the defaults of the chart type
+func (o ChartOptions) normalize(defaultWidth, defaultHeight int) (ChartOptions, error) {
+	if o.Width == 0 {
+		o.Width = defaultWidth
+	}
+	if o.Height == 0 {
+		o.Height = defaultHeight
+	}
+	if o.Width < minChartSize || o.Width > maxChartSize || o.Height < minChartSize || o.Height > maxChartSize {
+		return o, fmt.Errorf("invalid chart size: %dx%d. Width and height must be between %d and %d",
+			o.Width, o.Height, minChartSize, maxChartSize)
+	}
+
+	o.Format = strings.ToLower(o.Format)
+	if o.Format == "" {
+		o.Format = ChartFormatPNG
+	}
+	if o.Format != ChartFormatPNG && o.Format != ChartFormatSVG {
+		return o, fmt.Errorf("invalid chart format: %s. Allowed values are png and svg", o.Format)
+	}
+
+	o.Theme = strings.ToLower(o.Theme)
+	if o.Theme == "" {
+		o.Theme = ChartThemeLight
+	}
+	if _, ok := chartThemes[o.Theme]; !ok {
+		return o, fmt.Errorf("invalid chart theme: %s. Allowed values are light and dark", o.Theme)
+	}
+
+	return o, nil
+}
+
+func (o ChartOptions) renderer() chart.RendererProvider {
+	if o.Format == ChartFormatSVG {
+		return chart.SVG
+	}
+	return chart.PNG
+}
+
+func (o ChartOptions) theme() chartTheme {
+	return chartThemes[o.Theme]
 }
 
 type ChartServiceInstance struct{}
@@ -55,19 +161,53 @@ func NewChartService() ChartService {
 }
 
 func (s *ChartServiceInstance) GeneratePieChart(data []dto.ExpensesDiagramDataDTO, currency string) (*dto.ChartImageDTO, error) {
+	image, err := s.RenderPieChart(data, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ChartImage(image, ChartFormatPNG), nil
+}
+
+func (s *ChartServiceInstance) ChartImage(image []byte, format string) *dto.ChartImageDTO {
+	mimeType := "image/png"
+	if format == ChartFormatSVG {
+		mimeType = "image/svg+xml"
+	}
+
+	// Encode to base64
+	base64Image := base64.StdEncoding.EncodeToString(image)
+
+	return &dto.ChartImageDTO{
+		Image: fmt.Sprintf("data:%s;base64,%s", mimeType, base64Image),
+	}
+}
+
+func (s *ChartServiceInstance) RenderPieChart(data []dto.ExpensesDiagramDataDTO, currency string) ([]byte, error) {
+	return s.RenderPie(data, ChartOptions{})
+}
+
+func (s *ChartServiceInstance) RenderPie(data []dto.ExpensesDiagramDataDTO, options ChartOptions) ([]byte, error) {
+	// Use a square canvas to mimic matplotlib's axis('equal') circular pie
+	options, err := options.normalize(500, 500)
+	if err != nil {
+		return nil, err
+	}
+	theme := options.theme()
+
 	if len(data) == 0 {
 		return nil, fmt.Errorf("no data to generate chart")
 	}
 
 	// Sort data by amount in descending order (like Python)
 	sort.Slice(data, func(i, j int) bool {
-		return data[i].Amount > data[j].Amount
+		return data[i].Amount.GreaterThan(data[j].Amount)
 	})
 
 	// Calculate total for percentages
 	var total float64
 	for _, item := range data {
-		total += item.Amount
+		total += item.Amount.InexactFloat64()
 	}
 
 	if total == 0 {
@@ -76,27 +216,33 @@ func (s *ChartServiceInstance) GeneratePieChart(data []dto.ExpensesDiagramDataDT
 
 	// Prepare chart values
 	var values []chart.Value
-	for _, item := range data {
-		// Use consistent colors and suppress in-slice labels; rely on legend outside
-		col := drawing.ColorFromHex(item.Color)
+	for i, item := range data {
+		// Use the category colors and suppress in-slice labels; rely on legend outside
+		color := item.Color
+		if color == "" {
+			color = models.CategoryPalette[i%len(models.CategoryPalette)]
+		}
+		col := drawing.ColorFromHex(color)
 		values = append(values, chart.Value{
 			Label: item.CategoryName,
-			Value: item.Amount,
+			Value: item.Amount.InexactFloat64(),
 			Style: chart.Style{
 				FillColor:   col,
 				StrokeColor: col,
 				FontSize:    10,
+				FontColor:   theme.Font,
 			},
 		})
 	}
 
 	// Create pie chart
-	// Use a square canvas to mimic matplotlib's axis('equal') circular pie
 	pie := chart.PieChart{
-		Width:  500,
-		Height: 500,
+		Width:  options.Width,
+		Height: options.Height,
 		Values: values,
+		Canvas: chart.Style{FillColor: theme.Background},
 		Background: chart.Style{
+			FillColor: theme.Background,
 			Padding: chart.Box{
 				// Provide even padding so outside labels at ~1.1R fit similarly to matplotlib's labeldistance
 				Top:    10,
@@ -109,17 +255,12 @@ func (s *ChartServiceInstance) GeneratePieChart(data []dto.ExpensesDiagramDataDT
 
 	// Render chart to buffer
 	buffer := bytes.NewBuffer([]byte{})
-	err := pie.Render(chart.PNG, buffer)
+	err = pie.Render(options.renderer(), buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to render chart: %w", err)
 	}
 
-	// Encode to base64
-	base64Image := base64.StdEncoding.EncodeToString(buffer.Bytes())
-
-	return &dto.ChartImageDTO{
-		Image: fmt.Sprintf("data:image/png;base64,%s", base64Image),
-	}, nil
+	return buffer.Bytes(), nil
 }
 
 // pieLabelElement draws labels outside slices and percentage text near the slice, similar to matplotlib's
@@ -157,3 +298,228 @@ func drawPieLabels(r chart.Renderer, cb chart.Box, labels []pieSliceLabel, label
 		angle += sweep
 	}
 }
+
+func (s *ChartServiceInstance) RenderBar(data []dto.ExpensesDiagramDataDTO, options ChartOptions) ([]byte, error) {
+	options, err := options.normalize(800, 400)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]chart.Value, 0, len(data))
+	var top float64
+	for i, item := range data {
+		color := item.Color
+		if color == "" {
+			color = models.CategoryPalette[i%len(models.CategoryPalette)]
+		}
+		col := drawing.ColorFromHex(color)
+		bars = append(bars, chart.Value{
+			Label: item.CategoryName,
+			Value: item.Amount.InexactFloat64(),
+			Style: chart.Style{FillColor: col, StrokeColor: col},
+		})
+		top = math.Max(top, item.Amount.InexactFloat64())
+	}
+	if top <= 0 {
+		return nil, fmt.Errorf("no data to generate chart")
+	}
+
+	bar := barChart(bars, top, options)
+	buffer := bytes.NewBuffer([]byte{})
+	if err = bar.Render(options.renderer(), buffer); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// RenderStackedBar draws a bar per label made of the series values stacked in series order.
+// go-chart's StackedBarChart scales every bar to 100%, so the bars are drawn over a bar chart
+// of the totals, which provides the value axis and the labels.
+func (s *ChartServiceInstance) RenderStackedBar(labels []string, series []ChartSeries, options ChartOptions) ([]byte, error) {
+	options, err := options.normalize(800, 400)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]chart.Value, 0, len(labels))
+	var top float64
+	for i, label := range labels {
+		var total float64
+		for _, ser := range series {
+			if i < len(ser.Values) && ser.Values[i] > 0 {
+				total += ser.Values[i]
+			}
+		}
+		bars = append(bars, chart.Value{
+			Label: label,
+			Value: total,
+			Style: chart.Style{FillColor: drawing.ColorTransparent, StrokeColor: drawing.ColorTransparent},
+		})
+		top = math.Max(top, total)
+	}
+	if top <= 0 {
+		return nil, fmt.Errorf("no data to generate chart")
+	}
+
+	bar := barChart(bars, top, options)
+	bar.Background.Padding.Top = 40
+	bar.Elements = []chart.Renderable{stackedBarsRenderer(bar, series), seriesLegend(series, options.theme())}
+
+	buffer := bytes.NewBuffer([]byte{})
+	if err = bar.Render(options.renderer(), buffer); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (s *ChartServiceInstance) RenderLine(dates []time.Time, series []ChartSeries, options ChartOptions) ([]byte, error) {
+	options, err := options.normalize(800, 400)
+	if err != nil {
+		return nil, err
+	}
+	// A line needs at least two points
+	if len(dates) < 2 || len(series) == 0 {
+		return nil, fmt.Errorf("no data to generate chart")
+	}
+	theme := options.theme()
+
+	lines := make([]chart.Series, 0, len(series))
+	for i, ser := range series {
+		if len(ser.Values) != len(dates) {
+			return nil, fmt.Errorf("series %s has %d values for %d dates", ser.Name, len(ser.Values), len(dates))
+		}
+		col := drawing.ColorFromHex(seriesColor(ser, i))
+		lines = append(lines, chart.TimeSeries{
+			Name:    ser.Name,
+			XValues: dates,
+			YValues: ser.Values,
+			Style:   chart.Style{StrokeColor: col, StrokeWidth: 2, DotColor: col, DotWidth: 2},
+		})
+	}
+
+	graph := chart.Chart{
+		Width:      options.Width,
+		Height:     options.Height,
+		Series:     lines,
+		Background: chart.Style{FillColor: theme.Background, Padding: chart.Box{Top: 20, Left: 20, Right: 20, Bottom: 10}},
+		Canvas:     chart.Style{FillColor: theme.Background},
+		XAxis: chart.XAxis{
+			ValueFormatter: chart.TimeDateValueFormatter,
+			Style:          chart.Style{FontColor: theme.Font, StrokeColor: theme.Axis},
+		},
+		YAxis: chart.YAxis{Style: chart.Style{FontColor: theme.Font, StrokeColor: theme.Axis}},
+	}
+	if len(series) > 1 {
+		graph.Elements = []chart.Renderable{chart.Legend(&graph, chart.Style{FillColor: theme.Background, FontColor: theme.Font})}
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	if err = graph.Render(options.renderer(), buffer); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// barChart sets up a themed bar chart with the value axis starting at zero
+func barChart(bars []chart.Value, top float64, options ChartOptions) chart.BarChart {
+	theme := options.theme()
+	barWidth, barSpacing := barLayout(options.Width, len(bars))
+
+	return chart.BarChart{
+		Width:      options.Width,
+		Height:     options.Height,
+		BarWidth:   barWidth,
+		BarSpacing: barSpacing,
+		Bars:       bars,
+		// go-chart leaves almost no room for the labels under the bars, the bottom padding makes it
+		Background: chart.Style{FillColor: theme.Background, Padding: chart.Box{Top: 20, Left: 10, Right: 10, Bottom: 40}},
+		Canvas:     chart.Style{FillColor: theme.Background},
+		XAxis:      chart.Style{FontColor: theme.Font, StrokeColor: theme.Axis},
+		YAxis: chart.YAxis{
+			Style: chart.Style{FontColor: theme.Font, StrokeColor: theme.Axis},
+			Range: &chart.ContinuousRange{Min: 0, Max: top * 1.05},
+		},
+	}
+}
+
+// barLayout splits the chart width between the bars, keeping them between 10 and 60 pixels wide
+func barLayout(width, count int) (barWidth, barSpacing int) {
+	slot := (width - 100) / count
+	barWidth = slot * 2 / 3
+	if barWidth > 60 {
+		barWidth = 60
+	}
+	if barWidth < 10 {
+		barWidth = 10
+	}
+	barSpacing = slot - barWidth
+	if barSpacing < 2 {
+		barSpacing = 2
+	}
+	return barWidth, barSpacing
+}
+
+// barGeometry repeats how go-chart shrinks the bars when they don't fit into the canvas
+func barGeometry(bar chart.BarChart, canvasBox chart.Box) (width, spacing int) {
+	count := len(bar.Bars)
+	width, spacing = bar.BarWidth, bar.BarSpacing
+	if count*(width+spacing) > canvasBox.Width() {
+		spacing = int(math.Max(0, math.Ceil(float64(canvasBox.Width()-count*width)/float64(count))))
+	}
+	if count*(width+spacing) > canvasBox.Width() {
+		width = int(math.Max(0, math.Ceil(float64(canvasBox.Width()-count*spacing)/float64(count))))
+	}
+	return width, spacing
+}
+
+func stackedBarsRenderer(bar chart.BarChart, series []ChartSeries) chart.Renderable {
+	return func(r chart.Renderer, canvasBox chart.Box, defaults chart.Style) {
+		yRange := chart.ContinuousRange{Min: bar.YAxis.Range.GetMin(), Max: bar.YAxis.Range.GetMax(), Domain: canvasBox.Height()}
+		width, spacing := barGeometry(bar, canvasBox)
+
+		left := canvasBox.Left + spacing/2
+		for i := range bar.Bars {
+			var base float64
+			for j, ser := range series {
+				if i >= len(ser.Values) || ser.Values[i] <= 0 {
+					continue
+				}
+				col := drawing.ColorFromHex(seriesColor(ser, j))
+				chart.Draw.Box(r, chart.Box{
+					Top:    canvasBox.Bottom - yRange.Translate(base+ser.Values[i]),
+					Left:   left,
+					Right:  left + width,
+					Bottom: canvasBox.Bottom - yRange.Translate(base),
+				}, chart.Style{FillColor: col, StrokeColor: col, StrokeWidth: 1})
+				base += ser.Values[i]
+			}
+			left += width + spacing
+		}
+	}
+}
+
+func seriesColor(series ChartSeries, index int) string {
+	if series.Color != "" {
+		return series.Color
+	}
+	return models.CategoryPalette[index%len(models.CategoryPalette)]
+}
+
+// seriesLegend draws a row of color boxes with series names above the canvas
+func seriesLegend(series []ChartSeries, theme chartTheme) chart.Renderable {
+	return func(r chart.Renderer, cb chart.Box, defaults chart.Style) {
+		const boxSize = 10
+		textStyle := chart.Style{Font: defaults.Font, FontSize: 10, FontColor: theme.Font}
+
+		x, y := cb.Left, cb.Top-25
+		for i, ser := range series {
+			col := drawing.ColorFromHex(seriesColor(ser, i))
+			chart.Draw.Box(r, chart.Box{Left: x, Top: y, Right: x + boxSize, Bottom: y + boxSize}, chart.Style{FillColor: col, StrokeColor: col})
+			chart.Draw.Text(r, ser.Name, x+boxSize+4, y+boxSize, textStyle)
+			x += boxSize + 12 + chart.Draw.MeasureText(r, ser.Name, textStyle).Width()
+		}
+	}
+}
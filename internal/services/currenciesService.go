@@ -1,22 +1,45 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	appErrors "ypeskov/budget-go/internal/errors"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/models"
 	"ypeskov/budget-go/internal/repositories/currencies"
 )
 
 type CurrenciesService interface {
-	GetCurrencies() ([]models.Currency, error)
+	GetCurrencies(userID int) ([]models.Currency, error)
 	GetCurrency(id int) (models.Currency, error)
 	GetCurrencyByCode(code string) (models.Currency, error)
+	GetUserCurrency(id int, userID int) (models.Currency, error)
+	GetUserCurrencyByCode(userID int, code string) (models.Currency, error)
+	GetCurrencyScales(userID int) (map[string]int32, error)
+	CreateUserCurrency(userID int, input dto.CurrencyInputDTO) (models.Currency, error)
+	UpdateUserCurrency(id int, userID int, input dto.CurrencyInputDTO) (models.Currency, error)
+	DeleteUserCurrency(id int, userID int) error
 }
 
 type CurrenciesServiceInstance struct {
 	currenciesRepo currencies.Repository
 }
 
+// Codes of private currencies are upper-case letters and digits with dots, dashes or underscores,
+// longer than ISO codes for assets like "XAU_OZ" or "USDT-TRC20"
+var currencyCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]{1,19}$`)
+
+const (
+	maxCurrencyNameLength = 100
+	maxDecimalPlaces      = 18
+)
+
 var (
 	currenciesInstance *CurrenciesServiceInstance
 	currenciesOnce     sync.Once
@@ -33,8 +56,8 @@ func NewCurrenciesService(currenciesRepo currencies.Repository) CurrenciesServic
 	return currenciesInstance
 }
 
-func (c *CurrenciesServiceInstance) GetCurrencies() ([]models.Currency, error) {
-	return c.currenciesRepo.GetCurrencies()
+func (c *CurrenciesServiceInstance) GetCurrencies(userID int) ([]models.Currency, error) {
+	return c.currenciesRepo.GetCurrencies(userID)
 }
 
 func (c *CurrenciesServiceInstance) GetCurrency(id int) (models.Currency, error) {
@@ -54,3 +77,139 @@ func (c *CurrenciesServiceInstance) GetCurrencyByCode(code string) (models.Curre
 
 	return currency, nil
 }
+
+// GetUserCurrency returns a common currency or a private currency of the user
+func (c *CurrenciesServiceInstance) GetUserCurrency(id int, userID int) (models.Currency, error) {
+	currency, err := c.currenciesRepo.GetCurrency(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Currency{}, appErrors.ErrCurrencyNotFound
+	}
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	if currency.UserID != nil && *currency.UserID != userID {
+		return models.Currency{}, appErrors.ErrCurrencyNotFound
+	}
+	return currency, nil
+}
+
+// GetUserCurrencyByCode returns the private currency of the user with the code or the common one
+func (c *CurrenciesServiceInstance) GetUserCurrencyByCode(userID int, code string) (models.Currency, error) {
+	currency, err := c.currenciesRepo.GetUserCurrencyByCode(userID, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Currency{}, appErrors.ErrCurrencyNotFound
+	}
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	return currency, nil
+}
+
+// GetCurrencyScales returns the decimal places of the currencies of the user that differ from the ISO ones
+func (c *CurrenciesServiceInstance) GetCurrencyScales(userID int) (map[string]int32, error) {
+	return c.currenciesRepo.GetCurrencyScales(userID)
+}
+
+func (c *CurrenciesServiceInstance) CreateUserCurrency(userID int, input dto.CurrencyInputDTO) (models.Currency, error) {
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if !currencyCodePattern.MatchString(code) {
+		return models.Currency{}, fmt.Errorf("invalid currency code %q: use 2 to 20 letters, digits, dots, dashes or underscores", input.Code)
+	}
+	name, err := validateCurrencyInput(input)
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	// A private code must not hide a common currency or another private one of the user
+	_, err = c.currenciesRepo.GetUserCurrencyByCode(userID, code)
+	if err == nil {
+		return models.Currency{}, appErrors.ErrCurrencyCodeTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Currency{}, err
+	}
+
+	now := time.Now()
+	currency, err := c.currenciesRepo.CreateCurrency(models.Currency{
+		Code:          code,
+		Name:          name,
+		UserID:        &userID,
+		DecimalPlaces: input.DecimalPlaces,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	logger.Info("Private currency created", "userID", userID, "code", code)
+	return currency, nil
+}
+
+func (c *CurrenciesServiceInstance) UpdateUserCurrency(id int, userID int, input dto.CurrencyInputDTO) (models.Currency, error) {
+	currency, err := c.getPrivateCurrency(id, userID)
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	name, err := validateCurrencyInput(input)
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	currency.Name = name
+	currency.DecimalPlaces = input.DecimalPlaces
+	currency.UpdatedAt = time.Now()
+	if err := c.currenciesRepo.UpdateCurrency(currency); err != nil {
+		return models.Currency{}, err
+	}
+
+	return currency, nil
+}
+
+// DeleteUserCurrency deletes a private currency that no account, budget or goal uses
+func (c *CurrenciesServiceInstance) DeleteUserCurrency(id int, userID int) error {
+	if _, err := c.getPrivateCurrency(id, userID); err != nil {
+		return err
+	}
+
+	used, err := c.currenciesRepo.IsCurrencyUsed(id)
+	if err != nil {
+		return err
+	}
+	if used {
+		return appErrors.ErrCurrencyInUse
+	}
+
+	return c.currenciesRepo.DeleteCurrency(id, userID)
+}
+
+// getPrivateCurrency returns a private currency of the user, common currencies cannot be changed by users
+func (c *CurrenciesServiceInstance) getPrivateCurrency(id int, userID int) (models.Currency, error) {
+	currency, err := c.currenciesRepo.GetCurrency(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Currency{}, appErrors.ErrCurrencyNotFound
+	}
+	if err != nil {
+		return models.Currency{}, err
+	}
+
+	if currency.UserID == nil || *currency.UserID != userID {
+		return models.Currency{}, appErrors.ErrCurrencyNotFound
+	}
+	return currency, nil
+}
+
+// validateCurrencyInput checks the name and decimal places of a private currency and returns the trimmed name
+func validateCurrencyInput(input dto.CurrencyInputDTO) (string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxCurrencyNameLength {
+		return "", fmt.Errorf("invalid currency name: it is required and at most %d characters long", maxCurrencyNameLength)
+	}
+	if input.DecimalPlaces != nil && (*input.DecimalPlaces < 0 || *input.DecimalPlaces > maxDecimalPlaces) {
+		return "", fmt.Errorf("invalid decimal places %d: use 0 to %d", *input.DecimalPlaces, maxDecimalPlaces)
+	}
+	return name, nil
+}
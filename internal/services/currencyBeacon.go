@@ -5,54 +5,69 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sync"
+	"time"
 
 	"ypeskov/budget-go/internal/config"
 	"ypeskov/budget-go/internal/logger"
+
+	"github.com/shopspring/decimal"
 )
 
-type CurrencyBeaconService interface {
-	GetCurrencyRates(date string) (map[string]interface{}, error)
-}
+// CurrencyBeaconServiceName is stored as the service name of rates from CurrencyBeacon
+const CurrencyBeaconServiceName = "CurrencyBeacon"
 
-type CurrencyBeaconServiceInstance struct {
+type CurrencyBeaconProvider struct {
 	config *config.Config
 }
 
 type CurrencyBeaconResponse struct {
-	Date  string                 `json:"date"`
-	Base  string                 `json:"base"`
-	Rates map[string]interface{} `json:"rates"`
+	Date  string                     `json:"date"`
+	Base  string                     `json:"base"`
+	Rates map[string]decimal.Decimal `json:"rates"`
 }
 
 var (
-	currencyBeaconInstance *CurrencyBeaconServiceInstance
+	currencyBeaconInstance *CurrencyBeaconProvider
 	currencyBeaconOnce     sync.Once
 )
 
-func NewCurrencyBeaconService(cfg *config.Config) CurrencyBeaconService {
+func NewCurrencyBeaconProvider(cfg *config.Config) RateProvider {
 	currencyBeaconOnce.Do(func() {
-		logger.Debug("Creating CurrencyBeaconService instance")
-		currencyBeaconInstance = &CurrencyBeaconServiceInstance{config: cfg}
+		logger.Debug("Creating CurrencyBeaconProvider instance")
+		currencyBeaconInstance = &CurrencyBeaconProvider{config: cfg}
 	})
 
 	return currencyBeaconInstance
 }
 
-func (c *CurrencyBeaconServiceInstance) GetCurrencyRates(date string) (map[string]interface{}, error) {
+func (c *CurrencyBeaconProvider) Name() string {
+	return CurrencyBeaconServiceName
+}
+
+func (c *CurrencyBeaconProvider) GetRates(date time.Time) (*RateSnapshot, error) {
 	if c.config.CurrencyBeaconAPIKey == "" {
 		return nil, fmt.Errorf("CurrencyBeacon API key is not configured")
 	}
 
-	url := fmt.Sprintf("%s/%s/historical?api_key=%s&date=%s",
+	dateStr := date.Format(time.DateOnly)
+	endpoint := fmt.Sprintf("%s/%s/historical?%s",
 		c.config.CurrencyBeaconAPIURL,
 		c.config.CurrencyBeaconAPIVersion,
-		c.config.CurrencyBeaconAPIKey,
-		date)
+		url.Values{"date": {dateStr}}.Encode())
+
+	request, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The key goes into a header so it does not end up in proxy and access logs
+	request.Header.Set("Authorization", "Bearer "+c.config.CurrencyBeaconAPIKey)
+	request.Header.Set("Accept", "application/json")
 
-	logger.Info("Fetching exchange rates from CurrencyBeacon for date", "date", date)
+	logger.Info("Fetching exchange rates from CurrencyBeacon for date", "date", dateStr)
 
-	resp, err := http.Get(url)
+	resp, err := rateHTTPClient.Do(request)
 	if err != nil {
 		logger.Error("Failed to make request to CurrencyBeacon", "error", err)
 		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
@@ -60,7 +75,7 @@ func (c *CurrencyBeaconServiceInstance) GetCurrencyRates(date string) (map[strin
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		logger.Error("CurrencyBeacon API error", "status", resp.StatusCode, "body", string(body))
 		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
 	}
@@ -71,14 +86,25 @@ func (c *CurrencyBeaconServiceInstance) GetCurrencyRates(date string) (map[strin
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	result := map[string]interface{}{
-		"rates":              response.Rates,
-		"actual_date":        response.Date,
-		"base_currency_code": response.Base,
-		"service_name":       "CurrencyBeacon",
+	actualDate, err := time.Parse(time.DateOnly, response.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date in response: %q", response.Date)
+	}
+
+	rates := make(map[string]decimal.Decimal, len(response.Rates))
+	for code, rate := range response.Rates {
+		// Discontinued currencies come without a rate
+		if rate.IsPositive() {
+			rates[code] = rate
+		}
 	}
 
-	logger.Info("Successfully fetched exchange rates", "date", date, "base", response.Base, "rateCount", len(response.Rates))
+	logger.Info("Successfully fetched exchange rates", "date", dateStr, "base", response.Base, "rateCount", len(rates))
 
-	return result, nil
+	return &RateSnapshot{
+		Date:         actualDate,
+		BaseCurrency: response.Base,
+		Rates:        rates,
+		Provider:     CurrencyBeaconServiceName,
+	}, nil
 }
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// dashboardCacheTTL keeps the dashboard of a user for repeated home screen loads
+	dashboardCacheTTL = time.Minute
+	// dashboardRunwayMonths is the number of full months the average monthly expenses are taken from
+	dashboardRunwayMonths = 3
+	// dashboardUpcomingDays is how far ahead upcoming recurring charges are listed
+	dashboardUpcomingDays = 30
+)
+
+type DashboardService interface {
+	// GetDashboard returns the key figures of the user, refresh skips the cached ones
+	GetDashboard(userID int, refresh bool) (*dto.DashboardDTO, error)
+}
+
+type dashboardCacheEntry struct {
+	dashboard *dto.DashboardDTO
+	expiresAt time.Time
+}
+
+type DashboardServiceInstance struct {
+	sm *Manager
+
+	mu    sync.Mutex
+	cache map[int]dashboardCacheEntry
+}
+
+var (
+	dashboardInstance *DashboardServiceInstance
+	dashboardOnce     sync.Once
+)
+
+func NewDashboardService(sManager *Manager) DashboardService {
+	dashboardOnce.Do(func() {
+		logger.Debug("Creating DashboardService instance")
+		dashboardInstance = &DashboardServiceInstance{
+			sm:    sManager,
+			cache: make(map[int]dashboardCacheEntry),
+		}
+	})
+
+	return dashboardInstance
+}
+
+func (s *DashboardServiceInstance) GetDashboard(userID int, refresh bool) (*dto.DashboardDTO, error) {
+	logger.Debug("GetDashboard Service", "userID", userID, "refresh", refresh)
+
+	now := time.Now()
+	if !refresh {
+		if dashboard := s.cached(userID, now); dashboard != nil {
+			return dashboard, nil
+		}
+	}
+
+	baseCurrency, err := s.sm.UserSettingsService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &dto.DashboardDTO{
+		CurrencyCode: baseCurrency.Code,
+		GeneratedAt:  now,
+	}
+	today := truncateToDay(now)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+
+	// Every figure comes from its own queries, each goroutine fills its own part of the dashboard
+	var group errgroup.Group
+	group.Go(func() error {
+		return s.fillAccounts(userID, dashboard)
+	})
+	group.Go(func() error {
+		return s.fillMonthToDate(userID, monthStart, today, dashboard)
+	})
+	group.Go(func() error {
+		return s.fillAverageExpenses(userID, monthStart, dashboard)
+	})
+	group.Go(func() error {
+		return s.fillBudgets(userID, dashboard)
+	})
+	group.Go(func() error {
+		charges, err := s.sm.InsightsService.GetUpcomingCharges(userID, now, dashboardUpcomingDays)
+		if err != nil {
+			return err
+		}
+		dashboard.UpcomingCharges = charges
+		return nil
+	})
+	if err := group.Wait(); err != nil {
+		logger.Error("Error building dashboard", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	runway := &dashboard.Runway
+	if runway.AverageMonthlyExpenses.IsPositive() {
+		months := runway.LiquidAssets.Div(runway.AverageMonthlyExpenses).Round(1).InexactFloat64()
+		runway.Months = &months
+	}
+
+	s.store(userID, dashboard, now)
+	return dashboard, nil
+}
+
+func (s *DashboardServiceInstance) cached(userID int, now time.Time) *dto.DashboardDTO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[userID]
+	if !ok || now.After(entry.expiresAt) {
+		return nil
+	}
+	return entry.dashboard
+}
+
+// store caches the dashboard and drops expired entries so the cache only holds recently active users
+func (s *DashboardServiceInstance) store(userID int, dashboard *dto.DashboardDTO, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, entry := range s.cache {
+		if now.After(entry.expiresAt) {
+			delete(s.cache, id)
+		}
+	}
+	s.cache[userID] = dashboardCacheEntry{dashboard: dashboard, expiresAt: now.Add(dashboardCacheTTL)}
+}
+
+// fillAccounts computes net worth, liquid assets and credit utilization from the account balances.
+// Liquid assets are the positive balances of non-credit accounts, credit used is the debt on credit accounts.
+func (s *DashboardServiceInstance) fillAccounts(userID int, dashboard *dto.DashboardDTO) error {
+	accounts, err := s.sm.AccountsService.GetUserAccounts(userID, s.sm, true, false, false)
+	if err != nil {
+		return err
+	}
+
+	var assets, liabilities, liquid, creditUsed, creditLimit decimal.Decimal
+	for _, account := range accounts {
+		if account.BalanceInBaseCurrency == nil {
+			continue
+		}
+		balance := *account.BalanceInBaseCurrency
+		if balance.IsPositive() {
+			assets = assets.Add(balance)
+		} else {
+			liabilities = liabilities.Add(balance.Neg())
+		}
+
+		if !account.AccountType.IsCredit {
+			if balance.IsPositive() {
+				liquid = liquid.Add(balance)
+			}
+			continue
+		}
+		if balance.IsNegative() {
+			creditUsed = creditUsed.Add(balance.Neg())
+		}
+		if account.CreditLimit != nil && account.CreditLimit.IsPositive() {
+			limit, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(userID, time.Now(), *account.CreditLimit,
+				account.Currency.Code, dashboard.CurrencyCode)
+			if err != nil {
+				return err
+			}
+			creditLimit = creditLimit.Add(limit)
+		}
+	}
+
+	dashboard.NetWorth = dto.DashboardNetWorthDTO{
+		Total:       assets.Sub(liabilities).Round(2),
+		Assets:      assets.Round(2),
+		Liabilities: liabilities.Round(2),
+	}
+	dashboard.Runway.LiquidAssets = liquid.Round(2)
+	dashboard.CreditUtilization = dto.DashboardCreditDTO{
+		Used:  creditUsed.Round(2),
+		Limit: creditLimit.Round(2),
+	}
+	if creditLimit.IsPositive() {
+		percent := creditUsed.Div(creditLimit).Mul(decimal.NewFromInt(100)).Round(1).InexactFloat64()
+		dashboard.CreditUtilization.Percent = &percent
+	}
+
+	return nil
+}
+
+func (s *DashboardServiceInstance) fillMonthToDate(userID int, monthStart, today time.Time, dashboard *dto.DashboardDTO) error {
+	summary, err := s.sm.ReportsService.GetIncomeVsExpenses(userID, dto.IncomeVsExpensesInputDTO{
+		StartDate: utils.CustomDate{Time: monthStart},
+		EndDate:   utils.CustomDate{Time: today},
+	})
+	if err != nil {
+		return err
+	}
+
+	dashboard.MonthToDate = dto.DashboardMonthDTO{
+		StartDate: monthStart,
+		EndDate:   today,
+		Income:    summary.TotalIncome,
+		Expenses:  summary.TotalExpenses,
+		Net:       summary.Net,
+	}
+	if summary.TotalIncome.IsPositive() {
+		rate := summary.Net.Div(summary.TotalIncome).Mul(decimal.NewFromInt(100)).Round(1).InexactFloat64()
+		dashboard.MonthToDate.SavingsRate = &rate
+	}
+
+	return nil
+}
+
+// fillAverageExpenses averages the expenses of the last full months, months before the user
+// had any transactions do not count
+func (s *DashboardServiceInstance) fillAverageExpenses(userID int, monthStart time.Time, dashboard *dto.DashboardDTO) error {
+	startDate := utils.CustomDate{Time: monthStart.AddDate(0, -dashboardRunwayMonths, 0)}
+	endDate := utils.CustomDate{Time: monthStart.AddDate(0, 0, -1)}
+	cashFlow, err := s.sm.ReportsService.GetCashFlow(userID, dto.CashFlowReportInputDTO{
+		StartDate: &startDate,
+		EndDate:   &endDate,
+		Period:    "monthly",
+	})
+	if err != nil {
+		return err
+	}
+
+	total := decimal.Zero
+	for _, expenses := range cashFlow.TotalExpenses {
+		total = total.Add(expenses)
+	}
+
+	months := len(cashFlow.TotalExpenses)
+	dashboard.Runway.MonthsAveraged = months
+	if months > 0 {
+		dashboard.Runway.AverageMonthlyExpenses = total.Div(decimal.NewFromInt(int64(months))).Round(2)
+	}
+
+	return nil
+}
+
+// fillBudgets counts the active budgets by forecast status, budgets without a forecast only
+// tell whether they are over the target
+func (s *DashboardServiceInstance) fillBudgets(userID int, dashboard *dto.DashboardDTO) error {
+	budgets, err := s.sm.BudgetsService.GetUserBudgets(userID, "active")
+	if err != nil {
+		return err
+	}
+
+	summary := dto.DashboardBudgetsDTO{Total: len(budgets)}
+	for _, budget := range budgets {
+		status := models.BudgetStatusOnTrack
+		if budget.Forecast != nil {
+			status = budget.Forecast.Status
+		} else if budget.CollectedAmount.GreaterThan(budget.TargetAmount) {
+			status = models.BudgetStatusOver
+		}
+
+		switch status {
+		case models.BudgetStatusOver:
+			summary.Over++
+		case models.BudgetStatusAtRisk:
+			summary.AtRisk++
+		default:
+			summary.OnTrack++
+		}
+	}
+	dashboard.Budgets = summary
+
+	return nil
+}
+
+func roundTo(value float64, places int32) float64 {
+	return decimal.NewFromFloat(value).Round(places).InexactFloat64()
+}
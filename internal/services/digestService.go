@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/queue"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	digestTopCategories       = 5
+	digestLargestTransactions = 5
+)
+
+// Digest summarizes income, expenses and budgets of a user over a past period, amounts are in the base currency
+type Digest struct {
+	StartDate           time.Time
+	EndDate             time.Time
+	CurrencyCode        string
+	TotalIncome         decimal.Decimal
+	TotalExpenses       decimal.Decimal
+	Net                 decimal.Decimal
+	TopCategories       []dto.AggregatedDiagramItemDTO
+	Budgets             []dto.BudgetResponseDTO
+	LargestTransactions []dto.ReportTransactionDTO
+	// Chart is a PNG pie chart of the expenses by top-level category, nil without expenses
+	Chart []byte
+}
+
+// IsEmpty reports whether nothing was earned or spent in the period
+func (d *Digest) IsEmpty() bool {
+	return d.TotalIncome.IsZero() && d.TotalExpenses.IsZero()
+}
+
+type DigestService interface {
+	// DigestPeriod returns the last full week or month before the date, both dates are inclusive
+	DigestPeriod(frequency string, date time.Time) (time.Time, time.Time, error)
+	BuildDigest(userID int, startDate, endDate time.Time) (*Digest, error)
+	// SendDigest builds and emails the digest of the payload, digests of periods without transactions are skipped
+	SendDigest(payload queue.DigestPayload) error
+}
+
+type DigestServiceInstance struct {
+	sm *Manager
+}
+
+var (
+	digestInstance *DigestServiceInstance
+	digestOnce     sync.Once
+)
+
+func NewDigestService(sManager *Manager) DigestService {
+	digestOnce.Do(func() {
+		logger.Debug("Creating DigestService instance")
+		digestInstance = &DigestServiceInstance{
+			sm: sManager,
+		}
+	})
+
+	return digestInstance
+}
+
+func (s *DigestServiceInstance) DigestPeriod(frequency string, date time.Time) (time.Time, time.Time, error) {
+	day := truncateToDay(date)
+
+	switch frequency {
+	case models.DigestWeekly:
+		return day.AddDate(0, 0, -7), day.AddDate(0, 0, -1), nil
+	case models.DigestMonthly:
+		monthStart := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		return monthStart.AddDate(0, -1, 0), monthStart.AddDate(0, 0, -1), nil
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid digest frequency: %s", frequency)
+}
+
+func (s *DigestServiceInstance) BuildDigest(userID int, startDate, endDate time.Time) (*Digest, error) {
+	summary, err := s.sm.ReportsService.GetIncomeVsExpenses(userID, dto.IncomeVsExpensesInputDTO{
+		StartDate: utils.CustomDate{Time: startDate},
+		EndDate:   utils.CustomDate{Time: endDate},
+	})
+	if err != nil {
+		logger.Error("Error getting digest income and expenses", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	digest := &Digest{
+		StartDate:     startDate,
+		EndDate:       endDate,
+		CurrencyCode:  summary.CurrencyCode,
+		TotalIncome:   summary.TotalIncome,
+		TotalExpenses: summary.TotalExpenses,
+		Net:           summary.Net,
+		TopCategories: summary.Expenses,
+	}
+	if len(digest.TopCategories) > digestTopCategories {
+		digest.TopCategories = digest.TopCategories[:digestTopCategories]
+	}
+
+	digest.Budgets, err = s.sm.BudgetsService.GetUserBudgets(userID, "active")
+	if err != nil {
+		logger.Error("Error getting digest budgets", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	digest.LargestTransactions, err = s.sm.ReportsService.GetLargestTransactions(userID, startDate, endDate, digestLargestTransactions)
+	if err != nil {
+		logger.Error("Error getting digest transactions", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	if len(summary.Expenses) > 0 {
+		data := make([]dto.ExpensesDiagramDataDTO, 0, len(summary.Expenses))
+		for _, item := range summary.Expenses {
+			data = append(data, dto.ExpensesDiagramDataDTO{
+				CategoryName: item.Label,
+				Amount:       item.Amount,
+				Color:        item.Color,
+				Icon:         item.Icon,
+			})
+		}
+		// The digest is still worth sending without the chart
+		digest.Chart, err = s.sm.ChartService.RenderPie(data, ChartOptions{Width: 480, Height: 480})
+		if err != nil {
+			logger.Warn("Error rendering digest chart", "userID", userID, "error", err)
+			digest.Chart = nil
+		}
+	}
+
+	return digest, nil
+}
+
+func (s *DigestServiceInstance) SendDigest(payload queue.DigestPayload) error {
+	startDate, err := time.Parse(time.DateOnly, payload.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid digest start date: %w", err)
+	}
+	endDate, err := time.Parse(time.DateOnly, payload.EndDate)
+	if err != nil {
+		return fmt.Errorf("invalid digest end date: %w", err)
+	}
+
+	user, err := s.sm.UserService.GetUserByID(payload.UserID)
+	if err != nil {
+		logger.Error("Error getting digest user", "userID", payload.UserID, "error", err)
+		return err
+	}
+	if !user.IsActive || user.IsDeleted {
+		logger.Info("Skipping digest of inactive user", "userID", payload.UserID)
+		return nil
+	}
+
+	digest, err := s.BuildDigest(user.ID, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	if digest.IsEmpty() {
+		logger.Info("Skipping empty digest", "userID", user.ID, "frequency", payload.Frequency, "startDate", payload.StartDate)
+		return nil
+	}
+
+	return s.sm.EmailService.SendDigest(user, payload.Frequency, digest)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"ypeskov/budget-go/internal/config"
+	"ypeskov/budget-go/internal/logger"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// ECBServiceName is stored as the service name of rates from the European Central Bank
+	ECBServiceName  = "ECB"
+	ecbBaseCurrency = "EUR"
+
+	// The ECB publishes the reference rates of working days around 16:00 CET in files
+	// covering the last 90 days and the whole history since 1999
+	ecbRecentFile  = "eurofxref-hist-90d.xml"
+	ecbHistoryFile = "eurofxref-hist.xml"
+	ecbRecentDays  = 85
+	// ecbFileTTL keeps a downloaded file for the requests of a backfill
+	ecbFileTTL = time.Hour
+)
+
+type ECBRateProvider struct {
+	config *config.Config
+
+	mu    sync.Mutex
+	files map[string]ecbFile
+}
+
+type ecbFile struct {
+	snapshots []RateSnapshot
+	fetchedAt time.Time
+}
+
+// ecbEnvelope is the eurofxref XML document, a cube per day with a cube per currency
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+var (
+	ecbInstance *ECBRateProvider
+	ecbOnce     sync.Once
+)
+
+func NewECBRateProvider(cfg *config.Config) RateProvider {
+	ecbOnce.Do(func() {
+		logger.Debug("Creating ECBRateProvider instance")
+		ecbInstance = &ECBRateProvider{
+			config: cfg,
+			files:  make(map[string]ecbFile),
+		}
+	})
+
+	return ecbInstance
+}
+
+func (p *ECBRateProvider) Name() string {
+	return ECBServiceName
+}
+
+func (p *ECBRateProvider) GetRates(date time.Time) (*RateSnapshot, error) {
+	file := ecbHistoryFile
+	if time.Since(date) < ecbRecentDays*24*time.Hour {
+		file = ecbRecentFile
+	}
+
+	snapshots, err := p.load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return latestSnapshot(snapshots, date)
+}
+
+// load returns the days of the file, downloading it when it is not cached yet
+func (p *ECBRateProvider) load(file string) ([]RateSnapshot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.files[file]; ok && time.Since(cached.fetchedAt) < ecbFileTTL {
+		return cached.snapshots, nil
+	}
+
+	url := fmt.Sprintf("%s/%s", p.config.ECBRatesURL, file)
+	logger.Info("Fetching exchange rates from the ECB", "url", url)
+
+	resp, err := rateHTTPClient.Get(url)
+	if err != nil {
+		logger.Error("Failed to make request to the ECB", "error", err)
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		logger.Error("ECB rates error", "status", resp.StatusCode, "body", string(body))
+		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+
+	snapshots, err := parseECBRates(resp.Body)
+	if err != nil {
+		logger.Error("Failed to parse ECB rates", "error", err)
+		return nil, err
+	}
+
+	p.files[file] = ecbFile{snapshots: snapshots, fetchedAt: time.Now()}
+	return snapshots, nil
+}
+
+func parseECBRates(reader io.Reader) ([]RateSnapshot, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(reader).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode ECB rates: %w", err)
+	}
+
+	snapshots := make([]RateSnapshot, 0, len(envelope.Days))
+	for _, day := range envelope.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date in ECB rates: %q", day.Time)
+		}
+
+		rates := make(map[string]decimal.Decimal, len(day.Rates)+1)
+		for _, rate := range day.Rates {
+			value, err := decimal.NewFromString(rate.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate of %s on %s: %q", rate.Currency, day.Time, rate.Rate)
+			}
+			rates[rate.Currency] = value
+		}
+		rates[ecbBaseCurrency] = decimal.NewFromInt(1)
+
+		snapshots = append(snapshots, RateSnapshot{
+			Date:         date,
+			BaseCurrency: ecbBaseCurrency,
+			Rates:        rates,
+			Provider:     ECBServiceName,
+		})
+	}
+
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no rates in ECB file")
+	}
+	return snapshots, nil
+}
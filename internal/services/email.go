@@ -9,6 +9,7 @@ import (
 	"net/smtp"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,12 +17,19 @@ import (
 	"ypeskov/budget-go/internal/config"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/queue"
+
+	"github.com/shopspring/decimal"
 )
 
 type EmailService interface {
 	SendBackupNotification(backupResult *BackupResult) error
 	SendExchangeRatesUpdateNotification(exchangeRates *models.ExchangeRates) error
+	SendExchangeRatesBackfillNotification(result *ExchangeRatesBackfillResult) error
 	SendActivationEmail(toEmail, firstName, activationToken string) error
+	SendBudgetAlert(alert queue.BudgetAlertPayload) error
+	SendDigest(user *models.User, frequency string, digest *Digest) error
+	SendInsights(payload queue.InsightsEmailPayload) error
 }
 
 type EmailServiceInstance struct {
@@ -61,6 +69,15 @@ type EmailData struct {
 	Recipients     []string
 	Body           string
 	AttachmentPath string
+	InlineImages   []InlineImage
+}
+
+// InlineImage is an image shown in the HTML body with <img src="cid:ContentID">
+type InlineImage struct {
+	ContentID   string
+	FileName    string
+	ContentType string
+	Content     []byte
 }
 
 func (s *EmailServiceInstance) SendBackupNotification(backupResult *BackupResult) error {
@@ -134,6 +151,61 @@ func (s *EmailServiceInstance) SendExchangeRatesUpdateNotification(exchangeRates
 	return s.sendEmail(emailData)
 }
 
+func (s *EmailServiceInstance) SendExchangeRatesBackfillNotification(result *ExchangeRatesBackfillResult) error {
+	if s.cfg.AdminEmailsRaw == "" {
+		logger.Error("No admin emails configured for exchange rates backfill notification")
+		return fmt.Errorf("no admin emails configured for notifications")
+	}
+
+	recipients := s.parseAdminEmails()
+	if len(recipients) == 0 {
+		logger.Error("No valid admin emails found")
+		return fmt.Errorf("no valid admin emails found")
+	}
+
+	failed := make([]BackfillFailureRow, 0, len(result.Failed))
+	for date, reason := range result.Failed {
+		failed = append(failed, BackfillFailureRow{Date: date, Error: reason})
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Date < failed[j].Date })
+
+	unavailable := make([]string, 0, len(result.Unavailable))
+	for _, date := range result.Unavailable {
+		unavailable = append(unavailable, date.Format("2006-01-02"))
+	}
+
+	subject := "Missing exchange rates backfilled"
+	if len(failed) > 0 {
+		subject = fmt.Sprintf("Exchange rates of %d days could not be backfilled", len(failed))
+	}
+
+	body, err := s.templateRenderer.RenderExchangeRatesBackfill(&ExchangeRatesBackfillTemplateData{
+		Subject:          subject,
+		EnvName:          s.cfg.Environment,
+		FinishedAt:       time.Now().Format("2006-01-02 15:04:05 MST"),
+		StartDate:        result.StartDate.Format("2006-01-02"),
+		EndDate:          result.EndDate.Format("2006-01-02"),
+		MissingCount:     len(result.Missing),
+		FetchedCount:     len(result.Fetched),
+		UnavailableCount: len(result.Unavailable),
+		UnavailableDates: strings.Join(unavailable, ", "),
+		Failed:           failed,
+		AppName:          s.cfg.AppName,
+	})
+	if err != nil {
+		logger.Error("Failed to render exchange rates backfill email template", "error", err)
+		return fmt.Errorf("failed to render exchange rates backfill email template: %w", err)
+	}
+
+	emailData := &EmailData{
+		Subject:    subject,
+		Recipients: recipients,
+		Body:       body,
+	}
+
+	return s.sendEmail(emailData)
+}
+
 func (s *EmailServiceInstance) parseAdminEmails() []string {
 	if s.cfg.AdminEmailsRaw == "" {
 		return []string{}
@@ -210,14 +282,33 @@ func (s *EmailServiceInstance) buildBaseEmailContent(emailData *EmailData) (stri
 	msg.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n", boundary))
 	msg.WriteString("\r\n")
 
-	// HTML body part
+	// HTML body part, inline images go together with it into a multipart/related part
+	relatedBoundary := "related123456789"
 	msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	if len(emailData.InlineImages) > 0 {
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/related; boundary=%s\r\n", relatedBoundary))
+		msg.WriteString("\r\n")
+		msg.WriteString(fmt.Sprintf("--%s\r\n", relatedBoundary))
+	}
 	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
 	msg.WriteString("Content-Transfer-Encoding: 7bit\r\n")
 	msg.WriteString("\r\n")
 	msg.WriteString(emailData.Body)
 	msg.WriteString("\r\n")
 
+	if len(emailData.InlineImages) > 0 {
+		for _, image := range emailData.InlineImages {
+			msg.WriteString(fmt.Sprintf("--%s\r\n", relatedBoundary))
+			msg.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", image.ContentType, image.FileName))
+			msg.WriteString("Content-Transfer-Encoding: base64\r\n")
+			msg.WriteString(fmt.Sprintf("Content-ID: <%s>\r\n", image.ContentID))
+			msg.WriteString(fmt.Sprintf("Content-Disposition: inline; filename=\"%s\"\r\n", image.FileName))
+			msg.WriteString("\r\n")
+			writeBase64Lines(&msg, image.Content)
+		}
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", relatedBoundary))
+	}
+
 	// Attachment part
 	if emailData.AttachmentPath != "" {
 		filename := filepath.Base(emailData.AttachmentPath)
@@ -228,9 +319,6 @@ func (s *EmailServiceInstance) buildBaseEmailContent(emailData *EmailData) (stri
 			return "", fmt.Errorf("failed to read attachment file: %w", err)
 		}
 
-		// Encode file to base64
-		encodedFile := base64.StdEncoding.EncodeToString(fileData)
-
 		// Get MIME type
 		mimeType := mime.TypeByExtension(filepath.Ext(filename))
 		if mimeType == "" {
@@ -243,15 +331,7 @@ func (s *EmailServiceInstance) buildBaseEmailContent(emailData *EmailData) (stri
 		msg.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", filename))
 		msg.WriteString("\r\n")
 
-		// Write base64 content in chunks of 76 characters
-		for i := 0; i < len(encodedFile); i += 76 {
-			end := i + 76
-			if end > len(encodedFile) {
-				end = len(encodedFile)
-			}
-			msg.WriteString(encodedFile[i:end])
-			msg.WriteString("\r\n")
-		}
+		writeBase64Lines(&msg, fileData)
 	}
 
 	// End boundary
@@ -260,6 +340,19 @@ func (s *EmailServiceInstance) buildBaseEmailContent(emailData *EmailData) (stri
 	return msg.String(), nil
 }
 
+// writeBase64Lines writes the data base64 encoded in lines of 76 characters
+func writeBase64Lines(msg *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for i := 0; i < len(encoded); i += 76 {
+		end := i + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		msg.WriteString(encoded[i:end])
+		msg.WriteString("\r\n")
+	}
+}
+
 func (s *EmailServiceInstance) addRecipientHeader(baseContent, recipient string) string {
 	// Add To: header at the beginning
 	return fmt.Sprintf("To: %s\r\n%s", recipient, baseContent)
@@ -328,3 +421,175 @@ func (s *EmailServiceInstance) SendActivationEmail(toEmail, firstName, activatio
 
 	return s.sendEmail(emailData)
 }
+
+func (s *EmailServiceInstance) SendBudgetAlert(alert queue.BudgetAlertPayload) error {
+	logger.Debug("Sending budget alert email to", "email", alert.UserEmail, "budgetID", alert.BudgetID)
+
+	subject := fmt.Sprintf("Budget \"%s\" reached %d%%", alert.BudgetName, alert.Threshold)
+	if alert.OverBudget {
+		subject = fmt.Sprintf("Budget \"%s\" is over its target", alert.BudgetName)
+	}
+
+	if s.cfg.SendUserEmails == false {
+		logger.Info("BUDGET ALERT EMAIL", "email", alert.UserEmail, "subject", subject,
+			"collected", alert.CollectedAmount, "target", alert.TargetAmount, "currency", alert.CurrencyCode)
+		return nil
+	}
+
+	body, err := s.templateRenderer.RenderBudgetAlert(&BudgetAlertTemplateData{
+		Subject:         subject,
+		EnvName:         s.cfg.Environment,
+		FirstName:       alert.UserName,
+		BudgetName:      alert.BudgetName,
+		Threshold:       alert.Threshold,
+		OverBudget:      alert.OverBudget,
+		TargetAmount:    alert.TargetAmount,
+		CollectedAmount: alert.CollectedAmount,
+		CurrencyCode:    alert.CurrencyCode,
+		PeriodStart:     alert.PeriodStart,
+		PeriodEnd:       alert.PeriodEnd,
+		BudgetsLink:     fmt.Sprintf("%s/budgets", s.cfg.FrontendURL),
+		AppName:         s.cfg.AppName,
+	})
+	if err != nil {
+		logger.Error("Failed to render budget alert email template", "error", err)
+		return fmt.Errorf("failed to render budget alert email template: %w", err)
+	}
+
+	emailData := &EmailData{
+		Subject:    subject,
+		Recipients: []string{alert.UserEmail},
+		Body:       body,
+	}
+
+	return s.sendEmail(emailData)
+}
+
+func (s *EmailServiceInstance) SendDigest(user *models.User, frequency string, digest *Digest) error {
+	logger.Debug("Sending digest email to", "email", user.Email, "frequency", frequency)
+
+	periodStart := digest.StartDate.Format("2006-01-02")
+	periodEnd := digest.EndDate.Format("2006-01-02")
+	subject := fmt.Sprintf("Your %s %s digest for %s to %s", frequency, s.cfg.AppName, periodStart, periodEnd)
+
+	if s.cfg.SendUserEmails == false {
+		logger.Info("DIGEST EMAIL", "email", user.Email, "subject", subject,
+			"income", digest.TotalIncome, "expenses", digest.TotalExpenses, "currency", digest.CurrencyCode)
+		return nil
+	}
+
+	data := &DigestTemplateData{
+		Subject:       subject,
+		EnvName:       s.cfg.Environment,
+		FirstName:     user.FirstName,
+		PeriodName:    frequency,
+		PeriodStart:   periodStart,
+		PeriodEnd:     periodEnd,
+		CurrencyCode:  digest.CurrencyCode,
+		TotalIncome:   formatDigestAmount(digest.TotalIncome),
+		TotalExpenses: formatDigestAmount(digest.TotalExpenses),
+		NetFlow:       formatDigestAmount(digest.Net),
+		NetNegative:   digest.Net.IsNegative(),
+		ReportsLink:   fmt.Sprintf("%s/reports", s.cfg.FrontendURL),
+		SettingsLink:  fmt.Sprintf("%s/settings", s.cfg.FrontendURL),
+		AppName:       s.cfg.AppName,
+	}
+	for _, category := range digest.TopCategories {
+		share := 0
+		if digest.TotalExpenses.IsPositive() {
+			share = int(category.Amount.Div(digest.TotalExpenses).Mul(decimal.NewFromInt(100)).Round(0).IntPart())
+		}
+		data.Categories = append(data.Categories, DigestCategoryRow{
+			Name:   category.Label,
+			Amount: formatDigestAmount(category.Amount),
+			Share:  share,
+		})
+	}
+	for _, budget := range digest.Budgets {
+		percent := 0
+		if budget.TargetAmount.IsPositive() {
+			percent = int(budget.CollectedAmount.Div(budget.TargetAmount).Mul(decimal.NewFromInt(100)).Round(0).IntPart())
+		}
+		data.Budgets = append(data.Budgets, DigestBudgetRow{
+			Name:         budget.Name,
+			Collected:    budget.CollectedAmount.StringFixed(2),
+			Target:       budget.TargetAmount.StringFixed(2),
+			CurrencyCode: budget.Currency.Code,
+			Percent:      percent,
+			OverBudget:   budget.CollectedAmount.GreaterThan(budget.TargetAmount),
+		})
+	}
+	for _, transaction := range digest.LargestTransactions {
+		data.Transactions = append(data.Transactions, DigestTransactionRow{
+			Date:         transaction.DateTime.Format("2006-01-02"),
+			Label:        transaction.Label,
+			CategoryName: transaction.CategoryName,
+			Amount:       formatDigestAmount(transaction.Amount),
+			CurrencyCode: transaction.CurrencyCode,
+			IsIncome:     transaction.IsIncome,
+		})
+	}
+
+	emailData := &EmailData{
+		Subject:    subject,
+		Recipients: []string{user.Email},
+	}
+	if len(digest.Chart) > 0 {
+		data.ChartCID = "digest-chart"
+		emailData.InlineImages = []InlineImage{{
+			ContentID:   data.ChartCID,
+			FileName:    "expenses.png",
+			ContentType: "image/png",
+			Content:     digest.Chart,
+		}}
+	}
+
+	body, err := s.templateRenderer.RenderDigest(data)
+	if err != nil {
+		logger.Error("Failed to render digest email template", "error", err)
+		return fmt.Errorf("failed to render digest email template: %w", err)
+	}
+	emailData.Body = body
+
+	return s.sendEmail(emailData)
+}
+
+func (s *EmailServiceInstance) SendInsights(payload queue.InsightsEmailPayload) error {
+	logger.Debug("Sending insights email to", "email", payload.UserEmail, "insights", len(payload.Insights))
+
+	subject := fmt.Sprintf("%d new insights about your spending", len(payload.Insights))
+	if len(payload.Insights) == 1 {
+		subject = payload.Insights[0].Title
+	}
+
+	if s.cfg.SendUserEmails == false {
+		logger.Info("INSIGHTS EMAIL", "email", payload.UserEmail, "subject", subject, "insights", len(payload.Insights))
+		return nil
+	}
+
+	body, err := s.templateRenderer.RenderInsights(&InsightsTemplateData{
+		Subject:      subject,
+		EnvName:      s.cfg.Environment,
+		FirstName:    payload.UserName,
+		Insights:     payload.Insights,
+		InsightsLink: fmt.Sprintf("%s/insights", s.cfg.FrontendURL),
+		SettingsLink: fmt.Sprintf("%s/settings", s.cfg.FrontendURL),
+		AppName:      s.cfg.AppName,
+	})
+	if err != nil {
+		logger.Error("Failed to render insights email template", "error", err)
+		return fmt.Errorf("failed to render insights email template: %w", err)
+	}
+
+	emailData := &EmailData{
+		Subject:    subject,
+		Recipients: []string{payload.UserEmail},
+		Body:       body,
+	}
+
+	return s.sendEmail(emailData)
+}
+
+func formatDigestAmount(amount decimal.Decimal) string {
+	return amount.StringFixed(2)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"ypeskov/budget-go/internal/config"
 	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/queue"
 )
 
 //go:embed templates/email/*.html
@@ -16,7 +17,11 @@ var emailTemplates embed.FS
 type EmailTemplateRenderer interface {
 	RenderBackupNotification(data *BackupTemplateData) (string, error)
 	RenderExchangeRatesUpdate(data *ExchangeRatesTemplateData) (string, error)
+	RenderExchangeRatesBackfill(data *ExchangeRatesBackfillTemplateData) (string, error)
 	RenderActivationEmail(data *ActivationEmailTemplateData) (string, error)
+	RenderBudgetAlert(data *BudgetAlertTemplateData) (string, error)
+	RenderDigest(data *DigestTemplateData) (string, error)
+	RenderInsights(data *InsightsTemplateData) (string, error)
 }
 
 type EmailTemplateRendererInstance struct {
@@ -71,6 +76,25 @@ type ExchangeRatesTemplateData struct {
 	AppName      string
 }
 
+type ExchangeRatesBackfillTemplateData struct {
+	Subject          string
+	EnvName          string
+	FinishedAt       string
+	StartDate        string
+	EndDate          string
+	MissingCount     int
+	FetchedCount     int
+	UnavailableCount int
+	UnavailableDates string
+	Failed           []BackfillFailureRow
+	AppName          string
+}
+
+type BackfillFailureRow struct {
+	Date  string
+	Error string
+}
+
 type ActivationEmailTemplateData struct {
 	Subject        string
 	EnvName        string
@@ -79,6 +103,77 @@ type ActivationEmailTemplateData struct {
 	AppName        string
 }
 
+type BudgetAlertTemplateData struct {
+	Subject         string
+	EnvName         string
+	FirstName       string
+	BudgetName      string
+	Threshold       int
+	OverBudget      bool
+	TargetAmount    string
+	CollectedAmount string
+	CurrencyCode    string
+	PeriodStart     string
+	PeriodEnd       string
+	BudgetsLink     string
+	AppName         string
+}
+
+type DigestTemplateData struct {
+	Subject       string
+	EnvName       string
+	FirstName     string
+	PeriodName    string
+	PeriodStart   string
+	PeriodEnd     string
+	CurrencyCode  string
+	TotalIncome   string
+	TotalExpenses string
+	NetFlow       string
+	NetNegative   bool
+	Categories    []DigestCategoryRow
+	Budgets       []DigestBudgetRow
+	Transactions  []DigestTransactionRow
+	ChartCID      string
+	ReportsLink   string
+	SettingsLink  string
+	AppName       string
+}
+
+type DigestCategoryRow struct {
+	Name   string
+	Amount string
+	Share  int
+}
+
+type DigestBudgetRow struct {
+	Name         string
+	Collected    string
+	Target       string
+	CurrencyCode string
+	Percent      int
+	OverBudget   bool
+}
+
+type DigestTransactionRow struct {
+	Date         string
+	Label        string
+	CategoryName string
+	Amount       string
+	CurrencyCode string
+	IsIncome     bool
+}
+
+type InsightsTemplateData struct {
+	Subject      string
+	EnvName      string
+	FirstName    string
+	Insights     []queue.InsightEmailItem
+	InsightsLink string
+	SettingsLink string
+	AppName      string
+}
+
 func (r *EmailTemplateRendererInstance) RenderBackupNotification(data *BackupTemplateData) (string, error) {
 	return r.renderTemplate("backup_notification.html", data)
 }
@@ -87,10 +182,26 @@ func (r *EmailTemplateRendererInstance) RenderExchangeRatesUpdate(data *Exchange
 	return r.renderTemplate("exchange_rates_notification.html", data)
 }
 
+func (r *EmailTemplateRendererInstance) RenderExchangeRatesBackfill(data *ExchangeRatesBackfillTemplateData) (string, error) {
+	return r.renderTemplate("exchange_rates_backfill.html", data)
+}
+
 func (r *EmailTemplateRendererInstance) RenderActivationEmail(data *ActivationEmailTemplateData) (string, error) {
 	return r.renderTemplate("user_activation.html", data)
 }
 
+func (r *EmailTemplateRendererInstance) RenderBudgetAlert(data *BudgetAlertTemplateData) (string, error) {
+	return r.renderTemplate("budget_alert.html", data)
+}
+
+func (r *EmailTemplateRendererInstance) RenderDigest(data *DigestTemplateData) (string, error) {
+	return r.renderTemplate("digest.html", data)
+}
+
+func (r *EmailTemplateRendererInstance) RenderInsights(data *InsightsTemplateData) (string, error) {
+	return r.renderTemplate("insights.html", data)
+}
+
 func (r *EmailTemplateRendererInstance) renderTemplate(templateName string, data interface{}) (string, error) {
 	// Parse base template and the specific template
 	tmpl, err := template.New("email").ParseFS(emailTemplates, "templates/email/base.html", "templates/email/"+templateName)
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/repositories/envelopes"
+
+	"github.com/shopspring/decimal"
+)
+
+const envelopeMonthLayout = "2006-01"
+
+// EnvelopesService implements zero-based (envelope) budgeting on top of expense categories.
+// Income is assigned to envelopes month by month until nothing is left to be budgeted,
+// spending draws from the envelope of the transaction category and leftovers carry to the next month.
+// All amounts are in the user's base currency.
+type EnvelopesService interface {
+	GetMonth(userID int, month string) (*dto.EnvelopeMonthDTO, error)
+	AssignToEnvelope(userID int, assignDTO dto.EnvelopeAssignDTO) (*dto.EnvelopeMonthDTO, error)
+	MoveBetweenEnvelopes(userID int, moveDTO dto.EnvelopeMoveDTO) (*dto.EnvelopeMonthDTO, error)
+}
+
+type EnvelopesServiceInstance struct {
+	envelopesRepository envelopes.Repository
+	sm                  *Manager
+}
+
+var (
+	envelopesInstance *EnvelopesServiceInstance
+	envelopesOnce     sync.Once
+)
+
+func NewEnvelopesService(envelopesRepository envelopes.Repository, sManager *Manager) EnvelopesService {
+	envelopesOnce.Do(func() {
+		logger.Debug("Creating EnvelopesService instance")
+		envelopesInstance = &EnvelopesServiceInstance{
+			envelopesRepository: envelopesRepository,
+			sm:                  sManager,
+		}
+	})
+
+	return envelopesInstance
+}
+
+// envelopeTotals accumulates amounts of a single envelope
+type envelopeTotals struct {
+	carriedOver decimal.Decimal
+	assigned    decimal.Decimal
+	spent       decimal.Decimal
+}
+
+func (s *EnvelopesServiceInstance) GetMonth(userID int, month string) (*dto.EnvelopeMonthDTO, error) {
+	logger.Debug("GetMonth Service")
+
+	monthStart, err := parseEnvelopeMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	baseCurrency, err := s.sm.UserSettingsService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base currency: %w", err)
+	}
+
+	categories, err := s.expenseCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[int]*envelopeTotals, len(categories))
+	for _, category := range categories {
+		totals[getIntValue(category.ID)] = &envelopeTotals{}
+	}
+
+	assignments, err := s.envelopesRepository.GetAssignmentsUntil(userID, monthStart)
+	if err != nil {
+		logger.Error("Error getting envelope assignments", "error", err)
+		return nil, err
+	}
+
+	// To be budgeted is all income received so far minus everything assigned so far,
+	// so unassigned income of previous months is still available
+	assignedSoFar := decimal.Zero
+	for _, assignment := range assignments {
+		assignedSoFar = assignedSoFar.Add(assignment.Amount)
+
+		envelope, ok := totals[assignment.CategoryID]
+		if !ok {
+			continue
+		}
+		if assignment.Month.Before(monthStart) {
+			envelope.carriedOver = envelope.carriedOver.Add(assignment.Amount)
+		} else {
+			envelope.assigned = envelope.assigned.Add(assignment.Amount)
+		}
+	}
+
+	activity, err := s.envelopesRepository.GetDailyActivity(userID, monthEnd)
+	if err != nil {
+		logger.Error("Error getting envelope activity", "error", err)
+		return nil, err
+	}
+
+	incomeSoFar := decimal.Zero
+	monthIncome := decimal.Zero
+	for _, row := range activity {
+		amount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(userID, row.Day, row.Amount, row.CurrencyCode, baseCurrency.Code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s to %s: %w", row.CurrencyCode, baseCurrency.Code, err)
+		}
+		inMonth := !row.Day.Before(monthStart)
+
+		if row.IsIncome {
+			incomeSoFar = incomeSoFar.Add(amount)
+			if inMonth {
+				monthIncome = monthIncome.Add(amount)
+			}
+			continue
+		}
+
+		if row.CategoryID == nil {
+			continue
+		}
+		envelope, ok := totals[*row.CategoryID]
+		if !ok {
+			continue
+		}
+		if inMonth {
+			envelope.spent = envelope.spent.Add(amount)
+		} else {
+			// Spending of previous months reduces the carried over leftover
+			envelope.carriedOver = envelope.carriedOver.Sub(amount)
+		}
+	}
+
+	result := &dto.EnvelopeMonthDTO{
+		Month:         monthStart.Format(envelopeMonthLayout),
+		Currency:      baseCurrency.Code,
+		Income:        monthIncome.Round(2),
+		TotalAssigned: decimal.Zero,
+		TotalSpent:    decimal.Zero,
+		ToBeBudgeted:  incomeSoFar.Sub(assignedSoFar).Round(2),
+	}
+
+	envelopeDTOs := make(map[int]*dto.EnvelopeDTO, len(categories))
+	for _, category := range categories {
+		id := getIntValue(category.ID)
+		envelope := totals[id]
+		result.TotalAssigned = result.TotalAssigned.Add(envelope.assigned)
+		result.TotalSpent = result.TotalSpent.Add(envelope.spent)
+
+		envelopeDTOs[id] = &dto.EnvelopeDTO{
+			CategoryID:  id,
+			Name:        getStringValue(category.Name),
+			ParentID:    category.ParentID,
+			CarriedOver: envelope.carriedOver.Round(2),
+			Assigned:    envelope.assigned.Round(2),
+			Spent:       envelope.spent.Round(2),
+			Available:   envelope.carriedOver.Add(envelope.assigned).Sub(envelope.spent).Round(2),
+			Children:    []dto.EnvelopeDTO{},
+		}
+	}
+	result.TotalAssigned = result.TotalAssigned.Round(2)
+	result.TotalSpent = result.TotalSpent.Round(2)
+
+	// Categories are ordered with parents first, so children can be attached to already known parents
+	result.Envelopes = make([]dto.EnvelopeDTO, 0)
+	for _, category := range categories {
+		if category.ParentID == nil {
+			continue
+		}
+		if parent, ok := envelopeDTOs[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, *envelopeDTOs[getIntValue(category.ID)])
+		}
+	}
+	for _, category := range categories {
+		if category.ParentID != nil {
+			if _, ok := envelopeDTOs[*category.ParentID]; ok {
+				continue
+			}
+		}
+		result.Envelopes = append(result.Envelopes, *envelopeDTOs[getIntValue(category.ID)])
+	}
+
+	return result, nil
+}
+
+func (s *EnvelopesServiceInstance) AssignToEnvelope(userID int, assignDTO dto.EnvelopeAssignDTO) (*dto.EnvelopeMonthDTO, error) {
+	logger.Debug("AssignToEnvelope Service")
+
+	monthStart, err := parseEnvelopeMonth(assignDTO.Month)
+	if err != nil {
+		return nil, err
+	}
+	if assignDTO.Amount.IsNegative() {
+		return nil, fmt.Errorf("assigned amount cannot be negative")
+	}
+	if err = s.validateEnvelope(userID, assignDTO.CategoryID); err != nil {
+		return nil, err
+	}
+
+	if err = s.envelopesRepository.SetAssignment(userID, assignDTO.CategoryID, monthStart, assignDTO.Amount.Round(2)); err != nil {
+		logger.Error("Error assigning money to envelope", "error", err)
+		return nil, err
+	}
+
+	return s.GetMonth(userID, assignDTO.Month)
+}
+
+func (s *EnvelopesServiceInstance) MoveBetweenEnvelopes(userID int, moveDTO dto.EnvelopeMoveDTO) (*dto.EnvelopeMonthDTO, error) {
+	logger.Debug("MoveBetweenEnvelopes Service")
+
+	monthStart, err := parseEnvelopeMonth(moveDTO.Month)
+	if err != nil {
+		return nil, err
+	}
+	if !moveDTO.Amount.IsPositive() {
+		return nil, fmt.Errorf("amount to move must be positive")
+	}
+	if moveDTO.FromCategoryID == moveDTO.ToCategoryID {
+		return nil, fmt.Errorf("cannot move money to the same envelope")
+	}
+	if err = s.validateEnvelope(userID, moveDTO.FromCategoryID); err != nil {
+		return nil, err
+	}
+	if err = s.validateEnvelope(userID, moveDTO.ToCategoryID); err != nil {
+		return nil, err
+	}
+
+	month, err := s.GetMonth(userID, moveDTO.Month)
+	if err != nil {
+		return nil, err
+	}
+	available, _ := findEnvelopeAvailable(month.Envelopes, moveDTO.FromCategoryID)
+	if moveDTO.Amount.GreaterThan(available) {
+		return nil, fmt.Errorf("not enough money in envelope: available %s", available.String())
+	}
+
+	err = s.envelopesRepository.MoveAssignment(userID, moveDTO.FromCategoryID, moveDTO.ToCategoryID, monthStart, moveDTO.Amount.Round(2))
+	if err != nil {
+		logger.Error("Error moving money between envelopes", "error", err)
+		return nil, err
+	}
+
+	return s.GetMonth(userID, moveDTO.Month)
+}
+
+// expenseCategories returns the user's expense categories, parents first and children right after them
+func (s *EnvelopesServiceInstance) expenseCategories(userID int) ([]models.UserCategory, error) {
+	userCategories, err := s.sm.CategoriesService.GetUserCategories(userID)
+	if err != nil {
+		logger.Error("Error getting user categories", "error", err)
+		return nil, err
+	}
+
+	categories := make([]models.UserCategory, 0, len(userCategories))
+	for _, category := range userCategories {
+		if !getBoolValue(category.IsIncome) {
+			categories = append(categories, category)
+		}
+	}
+
+	return categories, nil
+}
+
+// validateEnvelope checks that the category belongs to the user and is an expense category
+func (s *EnvelopesServiceInstance) validateEnvelope(userID int, categoryID int) error {
+	categories, err := s.expenseCategories(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		if getIntValue(category.ID) == categoryID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("envelope not found")
+}
+
+func findEnvelopeAvailable(envelopeDTOs []dto.EnvelopeDTO, categoryID int) (decimal.Decimal, bool) {
+	for _, envelope := range envelopeDTOs {
+		if envelope.CategoryID == categoryID {
+			return envelope.Available, true
+		}
+		if available, ok := findEnvelopeAvailable(envelope.Children, categoryID); ok {
+			return available, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// parseEnvelopeMonth parses YYYY-MM into the first day of the month, empty string means the current month
+func parseEnvelopeMonth(month string) (time.Time, error) {
+	if month == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	monthStart, err := time.Parse(envelopeMonthLayout, month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month: %s. Expected format is YYYY-MM", month)
+	}
+
+	return monthStart, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+)
+
+const (
+	// MaxBackfillDays limits the range of one backfill, a year of daily requests
+	MaxBackfillDays = 366
+	// backfillAttempts is how many times a date is requested before it is reported as failed
+	backfillAttempts = 3
+)
+
+// ExchangeRatesBackfillResult reports what a backfill did with each date that had no stored rates
+type ExchangeRatesBackfillResult struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Missing   []time.Time
+	Fetched   []time.Time
+	// Unavailable dates have no rates of their own, the providers returned rates of an earlier
+	// date as they do for weekends and holidays
+	Unavailable []time.Time
+	// Failed maps the dates that could not be fetched to the last error
+	Failed map[string]string
+}
+
+// NormalizeBackfillRange validates a backfill range and returns it as UTC calendar days,
+// an end date in the future is moved to today
+func NormalizeBackfillRange(startDate, endDate time.Time) (time.Time, time.Time, error) {
+	startDate = calendarDay(startDate)
+	endDate = calendarDay(endDate)
+	today := calendarDay(time.Now())
+	if endDate.After(today) {
+		endDate = today
+	}
+
+	if startDate.After(endDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start date %s is after end date %s",
+			startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+	}
+	if days := int(endDate.Sub(startDate).Hours()/24) + 1; days > MaxBackfillDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("backfill range of %d days exceeds the limit of %d days", days, MaxBackfillDays)
+	}
+
+	return startDate, endDate, nil
+}
+
+// GetMissingRateDates returns the days between startDate and endDate inclusive without stored rates.
+// Past days the providers answered with earlier rates, like weekends and holidays, are not missing.
+func (s *ExchangeRatesServiceInstance) GetMissingRateDates(startDate, endDate time.Time) ([]time.Time, error) {
+	startDate, endDate, err := NormalizeBackfillRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := startDate.Format(time.DateOnly), endDate.Format(time.DateOnly)
+	storedDates, err := s.exchangeRatesRepository.GetRateDates(start, end)
+	if err != nil {
+		logger.Error("Failed to get stored exchange rate dates", "error", err)
+		return nil, err
+	}
+	gapDates, err := s.exchangeRatesRepository.GetRateGapDates(start, end)
+	if err != nil {
+		logger.Error("Failed to get exchange rate gaps", "error", err)
+		return nil, err
+	}
+
+	stored := make(map[string]bool, len(storedDates)+len(gapDates))
+	for _, date := range append(storedDates, gapDates...) {
+		stored[date.Format(time.DateOnly)] = true
+	}
+
+	var missing []time.Time
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		if !stored[date.Format(time.DateOnly)] {
+			missing = append(missing, date)
+		}
+	}
+
+	return missing, nil
+}
+
+// BackfillExchangeRates fetches the rates of every day in the range that has none stored. Requests
+// are spaced by the configured delay and retried with a growing pause. An error is returned when
+// some dates failed, so the task is retried, a retry only requests the dates that are still missing.
+func (s *ExchangeRatesServiceInstance) BackfillExchangeRates(startDate, endDate time.Time) (*ExchangeRatesBackfillResult, error) {
+	startDate, endDate, err := NormalizeBackfillRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	missing, err := s.GetMissingRateDates(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExchangeRatesBackfillResult{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Missing:   missing,
+		Failed:    make(map[string]string),
+	}
+
+	logger.Info("Backfilling exchange rates", "startDate", startDate.Format(time.DateOnly),
+		"endDate", endDate.Format(time.DateOnly), "missing", len(missing))
+
+	var errs []error
+	for i, date := range missing {
+		dateStr := date.Format(time.DateOnly)
+		if i > 0 {
+			time.Sleep(s.config.ExchangeRatesBackfillDelay)
+		}
+
+		rates, err := s.updateExchangeRatesWithRetry(date)
+		if err != nil {
+			result.Failed[dateStr] = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", dateStr, err))
+			continue
+		}
+
+		if rates.ActualDate.Format(time.DateOnly) == dateStr {
+			result.Fetched = append(result.Fetched, date)
+		} else {
+			result.Unavailable = append(result.Unavailable, date)
+		}
+	}
+
+	logger.Info("Exchange rates backfill finished", "missing", len(result.Missing), "fetched", len(result.Fetched),
+		"unavailable", len(result.Unavailable), "failed", len(result.Failed))
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("failed to backfill exchange rates of %d dates: %w", len(errs), errors.Join(errs...))
+	}
+	return result, nil
+}
+
+func (s *ExchangeRatesServiceInstance) updateExchangeRatesWithRetry(date time.Time) (*models.ExchangeRates, error) {
+	pause := s.config.ExchangeRatesBackfillDelay
+	if pause <= 0 {
+		pause = time.Second
+	}
+
+	var err error
+	for attempt := 1; attempt <= backfillAttempts; attempt++ {
+		var rates *models.ExchangeRates
+		rates, err = s.UpdateExchangeRates(date)
+		if err == nil {
+			return rates, nil
+		}
+
+		logger.Warn("Exchange rates backfill request failed", "date", date.Format(time.DateOnly),
+			"attempt", attempt, "error", err)
+		if attempt < backfillAttempts {
+			time.Sleep(pause)
+			pause *= 2
+		}
+	}
+
+	return nil, err
+}
+
+// calendarDay returns the date at midnight UTC, the way rate dates are stored
+func calendarDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
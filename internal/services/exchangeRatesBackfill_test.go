@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ypeskov/budget-go/internal/config"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeRateProvider publishes rates on weekdays and answers weekends with the rates of the Friday before
+type fakeRateProvider struct{}
+
+func (p fakeRateProvider) Name() string { return "fake" }
+
+func (p fakeRateProvider) GetRates(date time.Time) (*RateSnapshot, error) {
+	for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		date = date.AddDate(0, 0, -1)
+	}
+	return &RateSnapshot{
+		Date:         date,
+		BaseCurrency: "USD",
+		Rates:        map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.92"), "UAH": decimal.RequireFromString("41.5")},
+		Provider:     "fake",
+	}, nil
+}
+
+func newBackfillTestService(repository *fakeExchangeRatesRepository) *ExchangeRatesServiceInstance {
+	return &ExchangeRatesServiceInstance{
+		exchangeRatesRepository:     repository,
+		userExchangeRatesRepository: &fakeUserExchangeRatesRepository{},
+		rates:                       newRateStore(repository),
+		userRates:                   make(map[int]*userRateIndex),
+		rateProvider:                fakeRateProvider{},
+		config:                      &config.Config{},
+	}
+}
+
+func formatDates(dates []time.Time) string {
+	formatted := make([]string, 0, len(dates))
+	for _, date := range dates {
+		formatted = append(formatted, date.Format(time.DateOnly))
+	}
+	return fmt.Sprint(formatted)
+}
+
+func TestGetMissingRateDates(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored []string
+		gaps   []string
+		want   []string
+	}{
+		{
+			name:   "three weekdays without rates",
+			stored: []string{"2024-03-01", "2024-03-04", "2024-03-08"},
+			gaps:   []string{"2024-03-02", "2024-03-03"},
+			want:   []string{"2024-03-05", "2024-03-06", "2024-03-07"},
+		},
+		{
+			name:   "weekend answered with the rates of Friday",
+			stored: []string{"2024-03-01", "2024-03-04", "2024-03-05", "2024-03-06", "2024-03-07", "2024-03-08"},
+			gaps:   []string{"2024-03-02", "2024-03-03"},
+			want:   []string{},
+		},
+		{
+			name:   "weekend never requested",
+			stored: []string{"2024-03-01", "2024-03-04", "2024-03-05", "2024-03-06", "2024-03-07", "2024-03-08"},
+			want:   []string{"2024-03-02", "2024-03-03"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeExchangeRatesRepository{}
+			for _, day := range tt.stored {
+				repository.rates = append(repository.rates, testExchangeRates(testDate(t, day), "0.92"))
+			}
+			for _, day := range tt.gaps {
+				if err := repository.SaveRateGap(day, "2024-03-01", "fake"); err != nil {
+					t.Fatalf("SaveRateGap: %v", err)
+				}
+			}
+
+			missing, err := newBackfillTestService(repository).GetMissingRateDates(testDate(t, "2024-03-01"), testDate(t, "2024-03-08"))
+			if err != nil {
+				t.Fatalf("GetMissingRateDates: %v", err)
+			}
+			if got, want := formatDates(missing), fmt.Sprint(tt.want); got != want {
+				t.Errorf("GetMissingRateDates() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestBackfillExchangeRatesRecordsGaps(t *testing.T) {
+	repository := &fakeExchangeRatesRepository{}
+	repository.rates = append(repository.rates, testExchangeRates(testDate(t, "2024-03-01"), "0.92"))
+	s := newBackfillTestService(repository)
+
+	result, err := s.BackfillExchangeRates(testDate(t, "2024-03-01"), testDate(t, "2024-03-05"))
+	if err != nil {
+		t.Fatalf("BackfillExchangeRates: %v", err)
+	}
+	if got, want := formatDates(result.Fetched), "[2024-03-04 2024-03-05]"; got != want {
+		t.Errorf("Fetched = %s, want %s", got, want)
+	}
+	if got, want := formatDates(result.Unavailable), "[2024-03-02 2024-03-03]"; got != want {
+		t.Errorf("Unavailable = %s, want %s", got, want)
+	}
+
+	// The next backfill does not request the weekend again
+	missing, err := s.GetMissingRateDates(testDate(t, "2024-03-01"), testDate(t, "2024-03-05"))
+	if err != nil {
+		t.Fatalf("GetMissingRateDates: %v", err)
+	}
+	if len(missing) > 0 {
+		t.Errorf("GetMissingRateDates() = %s after the backfill, want none", formatDates(missing))
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxRateHistoryDays limits the range of a rate history, five years of daily points
+const MaxRateHistoryDays = 5*365 + 1
+
+// ConvertAmount converts the amount with the rates of the user on the date, the result is rounded
+// to the decimal places of the target currency
+func (s *ExchangeRatesServiceInstance) ConvertAmount(
+	userID int,
+	date time.Time,
+	amount decimal.Decimal,
+	currencyFrom models.Currency,
+	currencyTo models.Currency,
+) (*dto.CurrencyConversionDTO, error) {
+	rate, rateDate, err := s.rateBetween(userID, date, currencyFrom.Code, currencyTo.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CurrencyConversionDTO{
+		From:     currencyFrom.Code,
+		To:       currencyTo.Code,
+		Amount:   amount,
+		Result:   amount.Mul(rate).Round(currencyTo.Scale()),
+		Rate:     rate,
+		Date:     date.Format(time.DateOnly),
+		RateDate: rateDate,
+	}, nil
+}
+
+// GetRateHistory returns the rates of the symbols against the base currency for every day between
+// startDate and endDate, all currencies with rates when no symbols are given. Rates the user entered
+// apply like in conversions. Days before the first stored provider rates have no point.
+func (s *ExchangeRatesServiceInstance) GetRateHistory(
+	userID int,
+	baseCurrency string,
+	symbols []string,
+	startDate time.Time,
+	endDate time.Time,
+) (*dto.RateHistoryDTO, error) {
+	startDate = calendarDay(startDate)
+	endDate = calendarDay(endDate)
+	if today := calendarDay(time.Now()); endDate.After(today) {
+		endDate = today
+	}
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("invalid date range: %s is after %s", startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+	}
+	if days := int(endDate.Sub(startDate).Hours()/24) + 1; days > MaxRateHistoryDays {
+		return nil, fmt.Errorf("invalid date range: %d days exceed the limit of %d days", days, MaxRateHistoryDays)
+	}
+
+	days, err := s.rates.daysBetween(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	userRates, err := s.getUserRates(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &dto.RateHistoryDTO{
+		Base:      baseCurrency,
+		StartDate: startDate.Format(time.DateOnly),
+		EndDate:   endDate.Format(time.DateOnly),
+		Points:    []dto.RateHistoryPointDTO{},
+	}
+
+	// The rates used for a day are the last ones not after it
+	next := 0
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		day := date.Format(time.DateOnly)
+		for next < len(days) && days[next].date <= day {
+			next++
+		}
+		if next == 0 {
+			continue
+		}
+
+		actualDate := days[next-1].date
+		ratesOnDate := days[next-1].rates
+		ratesBase := days[next-1].baseCurrency
+
+		codes := symbols
+		if len(codes) == 0 {
+			codes = make([]string, 0, len(ratesOnDate)+len(userRates.currencies)+1)
+			for code := range ratesOnDate {
+				codes = append(codes, code)
+			}
+			codes = append(codes, ratesBase)
+			for _, code := range userRates.currencies {
+				if _, ok := ratesOnDate[code]; !ok && code != ratesBase {
+					codes = append(codes, code)
+				}
+			}
+		}
+
+		rates := make(map[string]decimal.Decimal, len(codes))
+		if len(userRates.rates) > 0 {
+			// The rates of users with rates of their own go through the conversions, currencies
+			// without a rate on the day are skipped quietly
+			for _, code := range codes {
+				if rate, _, err := s.rateBetween(userID, date, baseCurrency, code); err == nil {
+					rates[code] = rate
+				}
+			}
+		} else {
+			// Cross rates go through the base currency of the stored rates like crossRate, currencies
+			// the day lacks come from earlier rates like in providerRate, others are skipped quietly
+			rateOf := func(code string) (decimal.Decimal, bool) {
+				if code == ratesBase {
+					return decimal.NewFromInt(1), true
+				}
+				if rate, ok := ratesOnDate[code]; ok {
+					return rate, true
+				}
+				earlier, err := s.rates.earlierDays(date, days[next-1])
+				if err != nil {
+					return decimal.Decimal{}, false
+				}
+				rate, _, ok := againstBase(days[next-1], earlier, code)
+				return rate, ok
+			}
+
+			if baseRate, ok := rateOf(baseCurrency); ok {
+				for _, code := range codes {
+					if rate, ok := rateOf(code); ok {
+						rates[code] = rate.Div(baseRate)
+					}
+				}
+			}
+		}
+
+		history.Points = append(history.Points, dto.RateHistoryPointDTO{
+			Date:       day,
+			ActualDate: actualDate,
+			Rates:      rates,
+		})
+	}
+
+	return history, nil
+}
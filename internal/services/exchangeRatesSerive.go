@@ -8,179 +8,118 @@ import (
 
 	"github.com/shopspring/decimal"
 	"ypeskov/budget-go/internal/config"
+	"ypeskov/budget-go/internal/dto"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/models"
 	"ypeskov/budget-go/internal/repositories/exchangeRates"
+	"ypeskov/budget-go/internal/repositories/userExchangeRates"
 )
 
 type ExchangeRatesService interface {
-	GetExchangeRates() (map[string]map[string]decimal.Decimal, error)
 	GetExchangeRateByDate(date time.Time) (map[string]decimal.Decimal, error)
-	GetRateBetweenCurrencies(date time.Time, currencyFrom string, currencyTo string) (decimal.Decimal, error)
-	CalcAmountFromCurrency(date time.Time, amount decimal.Decimal, currencyFrom string, currencyTo string) (decimal.Decimal, error)
+	GetRateBetweenCurrencies(userID int, date time.Time, currencyFrom string, currencyTo string) (decimal.Decimal, error)
+	CalcAmountFromCurrency(userID int, date time.Time, amount decimal.Decimal, currencyFrom string, currencyTo string) (decimal.Decimal, error)
 	UpdateExchangeRates(date time.Time) (*models.ExchangeRates, error)
+	GetMissingRateDates(startDate, endDate time.Time) ([]time.Time, error)
+	BackfillExchangeRates(startDate, endDate time.Time) (*ExchangeRatesBackfillResult, error)
+	ConvertAmount(userID int, date time.Time, amount decimal.Decimal, currencyFrom models.Currency, currencyTo models.Currency) (*dto.CurrencyConversionDTO, error)
+	GetRateHistory(userID int, baseCurrency string, symbols []string, startDate, endDate time.Time) (*dto.RateHistoryDTO, error)
+	GetUserExchangeRates(userID int) ([]dto.UserExchangeRateDTO, error)
+	SaveUserExchangeRate(userID int, input dto.UserExchangeRateInputDTO) (dto.UserExchangeRateDTO, error)
+	DeleteUserExchangeRate(id int, userID int) error
 }
 
 type ExchangeRatesServiceInstance struct {
-	exchangeRatesRepository exchangeRates.Repository
-	cache                   *ExchangeRatesHistoryCache
-	currencyBeaconService   CurrencyBeaconService
-	config                  *config.Config
-}
-
-const cacheExpiration = time.Hour * 24
-
-type ExchangeRatesHistoryCache struct {
-	data           map[string]map[string]decimal.Decimal
-	baseCurrencies map[string]string // maps date -> base currency code
-	lastUpdate     time.Time
+	exchangeRatesRepository     exchangeRates.Repository
+	userExchangeRatesRepository userExchangeRates.Repository
+	rates                       *rateStore
+	userRates                   map[int]*userRateIndex
+	rateProvider                RateProvider
+	config                      *config.Config
 }
 
 var (
 	exchangeRatesInstance *ExchangeRatesServiceInstance
 	exchangeRatesOnce     sync.Once
-	exchangeRatesMu       sync.RWMutex
+	userExchangeRatesMu   sync.RWMutex
 )
 
-func NewExchangeRatesService(exchangeRatesRepository exchangeRates.Repository, cfg *config.Config) ExchangeRatesService {
+func NewExchangeRatesService(
+	exchangeRatesRepository exchangeRates.Repository,
+	userExchangeRatesRepository userExchangeRates.Repository,
+	cfg *config.Config,
+) ExchangeRatesService {
 	exchangeRatesOnce.Do(func() {
 		logger.Debug("Creating ExchangeRatesService instance")
 		exchangeRatesInstance = &ExchangeRatesServiceInstance{
-			exchangeRatesRepository: exchangeRatesRepository,
-			cache: &ExchangeRatesHistoryCache{
-				data:           make(map[string]map[string]decimal.Decimal),
-				baseCurrencies: make(map[string]string),
-			},
-			currencyBeaconService: NewCurrencyBeaconService(cfg),
-			config:                cfg,
+			exchangeRatesRepository:     exchangeRatesRepository,
+			userExchangeRatesRepository: userExchangeRatesRepository,
+			rates:                       newRateStore(exchangeRatesRepository),
+			userRates:                   make(map[int]*userRateIndex),
+			rateProvider:                NewRateProvider(cfg),
+			config:                      cfg,
 		}
 	})
 
 	return exchangeRatesInstance
 }
 
-func (s *ExchangeRatesServiceInstance) fetchExchangeRates() ([]models.ExchangeRates, error) {
-	exchangeRates, err := s.exchangeRatesRepository.GetExchangeRates()
+func (s *ExchangeRatesServiceInstance) GetExchangeRateByDate(date time.Time) (map[string]decimal.Decimal, error) {
+	day, err := s.rates.dayOn(date)
 	if err != nil {
-		logger.Error("Error getting exchange rates", "error", err)
 		return nil, err
 	}
-
-	return exchangeRates, nil
-}
-
-func (s *ExchangeRatesServiceInstance) GetExchangeRates() (map[string]map[string]decimal.Decimal, error) {
-	if s.isCacheExpired() {
-		logger.Debug("Cache expired, fetching exchange rates")
-		exchangeRates, err := s.fetchExchangeRates()
-		if err != nil {
-			return nil, err
-		}
-
-		s.fillCache(exchangeRates)
-	}
-
-	return s.cache.data, nil
-}
-
-func (s *ExchangeRatesServiceInstance) fillCache(exchangeRates []models.ExchangeRates) {
-	exchangeRatesMu.Lock()
-	defer exchangeRatesMu.Unlock()
-
-	for _, exchangeRate := range exchangeRates {
-		convertedRates := make(map[string]decimal.Decimal)
-
-		for key, value := range exchangeRate.Rates {
-			switch v := value.(type) {
-			case float64:
-				convertedRates[key] = decimal.NewFromFloat(v)
-			case string:
-				decimalValue, err := decimal.NewFromString(v)
-				if err != nil {
-					logger.Warn("Invalid decimal string for key", "key", key, "value", value)
-					continue
-				}
-				convertedRates[key] = decimalValue
-			default:
-				logger.Warn("Unsupported type for key", "key", key, "type", fmt.Sprintf("%T", value))
-			}
-		}
-
-		dateKey := exchangeRate.ActualDate.Format("2006-01-02")
-		s.cache.data[dateKey] = convertedRates
-		s.cache.baseCurrencies[dateKey] = exchangeRate.BaseCurrencyCode
+	if day != nil {
+		return day.rates, nil
 	}
 
-	s.cache.lastUpdate = time.Now()
-}
-
-func (s *ExchangeRatesServiceInstance) isCacheExpired() bool {
-	lastUpdateDate := s.cache.lastUpdate.Truncate(24 * time.Hour)
-	currentDate := time.Now().Truncate(24 * time.Hour)
-
-	return lastUpdateDate.Add(cacheExpiration).Before(currentDate)
-}
-
-func (s *ExchangeRatesServiceInstance) GetExchangeRateByDate(date time.Time) (map[string]decimal.Decimal, error) {
-	exchangeRatesMu.RLock()
-	defer exchangeRatesMu.RUnlock()
-
-	// get all date keys from cache that are before or equal to the date
-	dateKeys := make([]string, 0, len(s.cache.data))
-	for key := range s.cache.data {
-		keyDate, err := time.Parse(time.DateOnly, key)
-		if err != nil {
-			logger.Warn("Invalid date format in cache key", "key", key)
-			continue
-		}
-		if !keyDate.After(date) {
-			dateKeys = append(dateKeys, key)
-		}
-	}
-
-	// sort the date keys in descending order
-	sort.Sort(sort.Reverse(sort.StringSlice(dateKeys)))
-
-	// get the first cache value that is before or equal to the date
-	for _, key := range dateKeys {
-		keyDate, err := time.Parse(time.DateOnly, key)
-		if err != nil {
-			logger.Warn("Invalid date format in cache key", "key", key)
-			continue
-		}
-
-		if !keyDate.After(date) {
-			return s.cache.data[key], nil
-		}
-	}
-
-	err := fmt.Errorf("no exchange rates found for any prior date starting from: %s", date.Format(time.DateOnly))
+	err = fmt.Errorf("no exchange rates found for any prior date starting from: %s", date.Format(time.DateOnly))
 	logger.Error("Error occurred", "error", err)
 	return nil, err
 }
 
+// GetRateBetweenCurrencies returns the rate between two currencies on the date for the user. Rates the
+// user entered for the date come first, then the provider rates, then the latest rates the user entered
+// before the date, which is how private currencies are converted.
 func (s *ExchangeRatesServiceInstance) GetRateBetweenCurrencies(
+	userID int,
 	date time.Time,
 	currencyFrom string,
 	currencyTo string,
 ) (decimal.Decimal, error) {
-	// If both currencies are the same, return 1
-	if currencyFrom == currencyTo {
-		return decimal.NewFromInt(1), nil
-	}
+	rate, _, err := s.rateBetween(userID, date, currencyFrom, currencyTo)
+	return rate, err
+}
 
-	ratesOnDate, err := s.GetExchangeRateByDate(date)
+// providerRate returns the rate between two currencies from the latest provider rates on or before
+// the date and the date of those rates. Currencies missing from those rates come from the rates of
+// up to maxRateAge before the date.
+func (s *ExchangeRatesServiceInstance) providerRate(
+	date time.Time,
+	currencyFrom string,
+	currencyTo string,
+) (decimal.Decimal, string, error) {
+	day, err := s.rates.dayOn(date)
 	if err != nil {
-		return decimal.Decimal{}, err
+		return decimal.Decimal{}, "", err
+	}
+	if day == nil {
+		return decimal.Decimal{}, "", fmt.Errorf("no exchange rates found for date: %s", date.Format("2006-01-02"))
 	}
 
-	// Get the base currency for this date
-	dateKey := s.getDateKeyForRates(date)
-	if dateKey == "" {
-		return decimal.Decimal{}, fmt.Errorf("no exchange rates found for date: %s", date.Format("2006-01-02"))
+	// Rates far older than the date mean daily updates were missed, the backfill fetches them
+	if dayDate, err := time.Parse(time.DateOnly, day.date); err == nil && date.Sub(dayDate) > maxRateAge {
+		logger.Warn("Using stale exchange rates", "date", date.Format(time.DateOnly), "ratesDate", day.date)
 	}
 
-	baseCurrency := s.cache.baseCurrencies[dateKey]
+	return s.rates.rateWithEarlier(date, day, currencyFrom, currencyTo)
+}
+
+// crossRate returns the rate between two currencies from the rates of a date against its base currency
+func crossRate(ratesOnDate map[string]decimal.Decimal, baseCurrency, currencyFrom, currencyTo string) (decimal.Decimal, error) {
+	if currencyFrom == currencyTo {
+		return decimal.NewFromInt(1), nil
+	}
 
 	// Handle conversions based on the base currency
 	if currencyFrom == baseCurrency {
@@ -218,49 +157,14 @@ func (s *ExchangeRatesServiceInstance) GetRateBetweenCurrencies(
 	}
 }
 
-// getDateKeyForRates finds the appropriate date key for the given date
-func (s *ExchangeRatesServiceInstance) getDateKeyForRates(date time.Time) string {
-	exchangeRatesMu.RLock()
-	defer exchangeRatesMu.RUnlock()
-
-	// Get all date keys from cache that are before or equal to the date
-	dateKeys := make([]string, 0, len(s.cache.data))
-	for key := range s.cache.data {
-		keyDate, err := time.Parse(time.DateOnly, key)
-		if err != nil {
-			logger.Warn("Invalid date format in cache key", "key", key)
-			continue
-		}
-		if !keyDate.After(date) {
-			dateKeys = append(dateKeys, key)
-		}
-	}
-
-	if len(dateKeys) == 0 {
-		return ""
-	}
-
-	// Sort the date keys in descending order and return the first (most recent)
-	sort.Sort(sort.Reverse(sort.StringSlice(dateKeys)))
-	return dateKeys[0]
-}
-
 func (s *ExchangeRatesServiceInstance) CalcAmountFromCurrency(
+	userID int,
 	date time.Time,
 	amount decimal.Decimal,
 	currencyFrom string,
 	currencyTo string,
 ) (decimal.Decimal, error) {
-	// check if cache is empty or expired
-	if len(s.cache.data) == 0 || s.isCacheExpired() {
-		logger.Debug("Cache is empty or expired, fetching exchange rates")
-		_, err := s.GetExchangeRates()
-		if err != nil {
-			return decimal.Decimal{}, err
-		}
-	}
-
-	rate, err := s.GetRateBetweenCurrencies(date, currencyFrom, currencyTo)
+	rate, err := s.GetRateBetweenCurrencies(userID, date, currencyFrom, currencyTo)
 	if err != nil {
 		return decimal.Decimal{}, err
 	}
@@ -271,53 +175,83 @@ func (s *ExchangeRatesServiceInstance) CalcAmountFromCurrency(
 func (s *ExchangeRatesServiceInstance) UpdateExchangeRates(date time.Time) (*models.ExchangeRates, error) {
 	logger.Info("Updating exchange rates for date", "date", date.Format("2006-01-02"))
 
-	// Get exchange rates from external API
-	dateStr := date.Format("2006-01-02")
-	ratesData, err := s.currencyBeaconService.GetCurrencyRates(dateStr)
+	// Get exchange rates from the first provider that has them
+	snapshot, err := s.rateProvider.GetRates(date)
 	if err != nil {
-		logger.Error("Failed to fetch exchange rates from CurrencyBeacon", "error", err)
+		logger.Error("Failed to fetch exchange rates", "providers", s.rateProvider.Name(), "error", err)
 		return nil, err
 	}
 
-	// Delete existing rates for this date
-	err = s.exchangeRatesRepository.DeleteExchangeRatesByDate(dateStr)
-	if err != nil {
-		logger.Error("Failed to delete existing exchange rates", "date", dateStr, "error", err)
-		return nil, err
-	}
+	// A fallback provider may publish fewer currencies than the stored rates have, only the rates it
+	// returned are stored under its name. Conversions take the missing ones from earlier rates for a while.
+	s.warnMissingCurrencies(snapshot)
 
-	// Parse the actual_date from API response
-	actualDate, err := time.Parse("2006-01-02", ratesData["actual_date"].(string))
-	if err != nil {
-		logger.Error("Failed to parse actual_date from API response", "error", err)
-		return nil, err
+	// Rates are stored as strings to keep every digit the provider returned
+	rates := make(models.JSONB, len(snapshot.Rates))
+	for code, rate := range snapshot.Rates {
+		rates[code] = rate.String()
 	}
 
-	// Create new exchange rates model
 	excRates := &models.ExchangeRates{
-		Rates:            models.JSONB(ratesData["rates"].(map[string]interface{})),
-		ActualDate:       actualDate,
-		BaseCurrencyCode: ratesData["base_currency_code"].(string),
-		ServiceName:      ratesData["service_name"].(string),
+		Rates:            rates,
+		ActualDate:       snapshot.Date,
+		BaseCurrencyCode: snapshot.BaseCurrency,
+		ServiceName:      snapshot.Provider,
 		IsDeleted:        false,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
 
-	// Save to database
-	err = s.exchangeRatesRepository.SaveExchangeRates(excRates)
+	// Replace the stored rates of the date the rates are of, providers fall back to earlier dates
+	actualDateStr := snapshot.Date.Format(time.DateOnly)
+	err = s.exchangeRatesRepository.ReplaceExchangeRates(excRates)
 	if err != nil {
 		logger.Error("Failed to save exchange rates", "error", err)
 		return nil, err
 	}
 
-	// Clear cache to force refresh
-	exchangeRatesMu.Lock()
-	s.cache.data = make(map[string]map[string]decimal.Decimal)
-	s.cache.baseCurrencies = make(map[string]string)
-	s.cache.lastUpdate = time.Time{}
-	exchangeRatesMu.Unlock()
+	// Only the rates of the date change, conversions of other dates keep their cached rates
+	s.rates.replaceDay(newRateDay(*excRates))
+
+	// A past date answered with earlier rates has no publication, backfills skip it. Today may still
+	// get its rates later in the day.
+	requestedDate := calendarDay(date)
+	if snapshot.Date.Before(requestedDate) && requestedDate.Before(calendarDay(time.Now())) {
+		err = s.exchangeRatesRepository.SaveRateGap(requestedDate.Format(time.DateOnly), actualDateStr, snapshot.Provider)
+		if err != nil { // a backfill requests the date again
+			logger.Warn("Failed to record exchange rate gap", "date", requestedDate.Format(time.DateOnly), "error", err)
+		}
+	}
 
-	logger.Info("Exchange rates updated successfully", "date", date.Format("2006-01-02"))
+	logger.Info("Exchange rates updated successfully", "date", date.Format("2006-01-02"),
+		"actualDate", actualDateStr, "provider", snapshot.Provider)
 	return excRates, nil
 }
+
+// warnMissingCurrencies logs the currencies of the latest stored rates before the snapshot that the snapshot lacks
+func (s *ExchangeRatesServiceInstance) warnMissingCurrencies(snapshot *RateSnapshot) {
+	previous, err := s.rates.dayOn(snapshot.Date.AddDate(0, 0, -1))
+	if err != nil || previous == nil {
+		return
+	}
+
+	isMissing := func(code string) bool {
+		_, ok := snapshot.Rates[code]
+		return !ok && code != snapshot.BaseCurrency
+	}
+	var missing []string
+	if isMissing(previous.baseCurrency) {
+		missing = append(missing, previous.baseCurrency)
+	}
+	for code := range previous.rates {
+		if isMissing(code) {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		logger.Warn("Exchange rates lack currencies of the previous rates, earlier rates are used for them until they are too old",
+			"date", snapshot.Date.Format(time.DateOnly), "provider", snapshot.Provider, "previousDate", previous.date,
+			"currencies", missing)
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/repositories/exchangeRates"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// rateMonthExpiration is how long the stored rates of a past month are kept, rates saved
+	// by other instances, e.g. a backfill in the worker, are seen after it
+	rateMonthExpiration = 24 * time.Hour
+	// recentRateMonthExpiration is how long the rates of the current month are kept, the daily
+	// update of the worker is seen after it
+	recentRateMonthExpiration = time.Hour
+)
+
+// rateDay holds the provider rates of one date against its base currency
+type rateDay struct {
+	date         string
+	baseCurrency string
+	rates        map[string]decimal.Decimal
+
+	// crossRates memoizes the rates between pairs of currencies, a report converts the same
+	// pairs for every row
+	crossRates sync.Map
+}
+
+// rateStore keeps the stored provider rates ordered by date. Rates are loaded from the database
+// a month at a time when a date of the month is first asked for, along with the latest rates
+// before the month that apply to its first days.
+type rateStore struct {
+	repository exchangeRates.Repository
+
+	mu     sync.RWMutex
+	days   []*rateDay
+	months map[string]time.Time // maps "2006-01" -> load time
+
+	// loadMu lets one request load a month while the others wait for it
+	loadMu sync.Mutex
+}
+
+func newRateStore(repository exchangeRates.Repository) *rateStore {
+	return &rateStore{
+		repository: repository,
+		months:     make(map[string]time.Time),
+	}
+}
+
+func newRateDay(exchangeRate models.ExchangeRates) *rateDay {
+	rates := make(map[string]decimal.Decimal, len(exchangeRate.Rates))
+	for key, value := range exchangeRate.Rates {
+		switch v := value.(type) {
+		case float64:
+			rates[key] = decimal.NewFromFloat(v)
+		case string:
+			decimalValue, err := decimal.NewFromString(v)
+			if err != nil {
+				logger.Warn("Invalid decimal string for key", "key", key, "value", value)
+				continue
+			}
+			rates[key] = decimalValue
+		default:
+			logger.Warn("Unsupported type for key", "key", key, "type", fmt.Sprintf("%T", value))
+		}
+	}
+
+	return &rateDay{
+		date:         exchangeRate.ActualDate.Format(time.DateOnly),
+		baseCurrency: exchangeRate.BaseCurrencyCode,
+		rates:        rates,
+	}
+}
+
+// rate returns the rate between two currencies on the day
+func (d *rateDay) rate(currencyFrom, currencyTo string) (decimal.Decimal, error) {
+	pair := [2]string{currencyFrom, currencyTo}
+	if rate, ok := d.crossRates.Load(pair); ok {
+		return rate.(decimal.Decimal), nil
+	}
+
+	rate, err := crossRate(d.rates, d.baseCurrency, currencyFrom, currencyTo)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	d.crossRates.Store(pair, rate)
+	return rate, nil
+}
+
+// rateWithEarlier returns the rate between two currencies on the day of the date. A currency the day lacks,
+// e.g. because a fallback provider does not publish it, is taken from the latest earlier day that has it
+// and is at most maxRateAge older than the date. The date returned is the oldest date of the rates used.
+func (s *rateStore) rateWithEarlier(date time.Time, day *rateDay, currencyFrom, currencyTo string) (decimal.Decimal, string, error) {
+	rate, err := day.rate(currencyFrom, currencyTo)
+	if err == nil {
+		return rate, day.date, nil
+	}
+
+	earlier, loadErr := s.earlierDays(date, day)
+	if loadErr != nil {
+		return decimal.Decimal{}, "", loadErr
+	}
+	rateFrom, dateFrom, ok := againstBase(day, earlier, currencyFrom)
+	if !ok {
+		return decimal.Decimal{}, "", err
+	}
+	rateTo, dateTo, ok := againstBase(day, earlier, currencyTo)
+	if !ok {
+		return decimal.Decimal{}, "", err
+	}
+
+	rateDate := day.date
+	for _, used := range []string{dateFrom, dateTo} {
+		if used < rateDate {
+			rateDate = used
+		}
+	}
+	return rateTo.Div(rateFrom), rateDate, nil
+}
+
+// earlierDays returns the days before the day that are at most maxRateAge older than the date, latest first
+func (s *rateStore) earlierDays(date time.Time, day *rateDay) ([]*rateDay, error) {
+	oldest := date.Add(-maxRateAge)
+	if err := s.load(oldest, date); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from := oldest.Format(time.DateOnly)
+	var earlier []*rateDay
+	for i := sort.Search(len(s.days), func(i int) bool { return s.days[i].date >= day.date }) - 1; i >= 0 && s.days[i].date >= from; i-- {
+		earlier = append(earlier, s.days[i])
+	}
+	return earlier, nil
+}
+
+// againstBase returns how much of the currency one unit of the base currency of the day buys, from the day
+// or else from the first of the earlier days that has both currencies, and the date of the rates used
+func againstBase(day *rateDay, earlier []*rateDay, currency string) (decimal.Decimal, string, bool) {
+	if currency == day.baseCurrency {
+		return decimal.NewFromInt(1), day.date, true
+	}
+	if rate, ok := day.rates[currency]; ok {
+		return rate, day.date, true
+	}
+
+	has := func(d *rateDay, code string) bool {
+		_, ok := d.rates[code]
+		return ok || d.baseCurrency == code
+	}
+	for _, previous := range earlier {
+		if !has(previous, currency) || !has(previous, day.baseCurrency) {
+			continue
+		}
+		if rate, err := crossRate(previous.rates, previous.baseCurrency, day.baseCurrency, currency); err == nil {
+			return rate, previous.date, true
+		}
+	}
+	return decimal.Decimal{}, "", false
+}
+
+// dayOn returns the latest rates on or before the date, nil when there are none
+func (s *rateStore) dayOn(date time.Time) (*rateDay, error) {
+	if err := s.load(date, date); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day := date.Format(time.DateOnly)
+	i := sort.Search(len(s.days), func(i int) bool { return s.days[i].date > day })
+	if i == 0 {
+		return nil, nil
+	}
+	return s.days[i-1], nil
+}
+
+// daysBetween returns the rates of the dates between startDate and endDate inclusive ordered by date,
+// preceded by the latest rates before startDate when there are any
+func (s *rateStore) daysBetween(startDate, endDate time.Time) ([]*rateDay, error) {
+	if err := s.load(startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := startDate.Format(time.DateOnly)
+	end := endDate.Format(time.DateOnly)
+	from := sort.Search(len(s.days), func(i int) bool { return s.days[i].date >= start })
+	to := sort.Search(len(s.days), func(i int) bool { return s.days[i].date > end })
+	if from > 0 {
+		from--
+	}
+	return append([]*rateDay(nil), s.days[from:to]...), nil
+}
+
+// replaceDay puts rates just stored for their date in place of the old ones, so they are used
+// without reloading the month. The cross rates memoized for the date go with the old rates.
+func (s *rateStore) replaceDay(day *rateDay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.merge(day.date, day.date, []*rateDay{day})
+}
+
+// load loads the months between the dates that are not loaded yet or have expired
+func (s *rateStore) load(startDate, endDate time.Time) error {
+	if len(s.unloadedMonths(startDate, endDate)) == 0 {
+		return nil
+	}
+
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	// Another request may have loaded some of the months while this one waited
+	months := s.unloadedMonths(startDate, endDate)
+	for len(months) > 0 {
+		// Consecutive months are loaded with one query
+		n := 1
+		for n < len(months) && months[n].Equal(months[n-1].AddDate(0, 1, 0)) {
+			n++
+		}
+		if err := s.loadMonths(months[0], months[n-1]); err != nil {
+			return err
+		}
+		months = months[n:]
+	}
+	return nil
+}
+
+func (s *rateStore) unloadedMonths(startDate, endDate time.Time) []time.Time {
+	var months []time.Time
+	end := calendarDay(endDate)
+	for month := monthOf(startDate); !month.After(end); month = month.AddDate(0, 1, 0) {
+		if !s.isLoaded(month) {
+			months = append(months, month)
+		}
+	}
+	return months
+}
+
+// loadMonths loads the rates from the first day of the first month to the last day of the last month
+func (s *rateStore) loadMonths(first, last time.Time) error {
+	start := first.Format(time.DateOnly)
+	end := last.AddDate(0, 1, -1).Format(time.DateOnly)
+	stored, err := s.repository.GetExchangeRatesForRange(start, end)
+	if err != nil {
+		logger.Error("Error getting exchange rates", "startDate", start, "endDate", end, "error", err)
+		return err
+	}
+
+	days := make([]*rateDay, 0, len(stored))
+	for _, exchangeRate := range stored {
+		days = append(days, newRateDay(exchangeRate))
+	}
+
+	loadedAt := time.Now()
+	s.mu.Lock()
+	s.merge(start, end, days)
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		s.months[month.Format("2006-01")] = loadedAt
+	}
+	s.mu.Unlock()
+
+	logger.Debug("Exchange rates loaded", "startDate", start, "endDate", end, "days", len(days))
+	return nil
+}
+
+func (s *rateStore) isLoaded(month time.Time) bool {
+	s.mu.RLock()
+	loadedAt, ok := s.months[month.Format("2006-01")]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	expiration := rateMonthExpiration
+	if month.AddDate(0, 1, 0).After(calendarDay(time.Now())) {
+		expiration = recentRateMonthExpiration
+	}
+	return time.Since(loadedAt) < expiration
+}
+
+// merge replaces the days between start and end with the given days, the given days outside of
+// the range replace the days of their dates. The caller holds the write lock.
+func (s *rateStore) merge(start, end string, days []*rateDay) {
+	byDate := make(map[string]*rateDay, len(s.days)+len(days))
+	for _, day := range s.days {
+		if day.date < start || day.date > end {
+			byDate[day.date] = day
+		}
+	}
+	for _, day := range days {
+		byDate[day.date] = day
+	}
+
+	merged := make([]*rateDay, 0, len(byDate))
+	for _, day := range byDate {
+		merged = append(merged, day)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].date < merged[j].date })
+	s.days = merged
+}
+
+// monthOf returns the first day of the month of the date, the way months of rates are loaded
+func monthOf(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
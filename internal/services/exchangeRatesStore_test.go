@@ -0,0 +1,405 @@
+package services
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init("error")
+	os.Exit(m.Run())
+}
+
+// fakeExchangeRatesRepository keeps the rates in memory ordered by date and answers like the SQL queries
+type fakeExchangeRatesRepository struct {
+	rates []models.ExchangeRates
+	gaps  map[string]string // requested date -> actual date
+}
+
+func (r *fakeExchangeRatesRepository) GetExchangeRatesForRange(startDate, endDate string) ([]models.ExchangeRates, error) {
+	var result []models.ExchangeRates
+	var before *models.ExchangeRates
+	for i, rate := range r.rates {
+		date := rate.ActualDate.Format(time.DateOnly)
+		switch {
+		case date < startDate:
+			before = &r.rates[i]
+		case date <= endDate:
+			result = append(result, rate)
+		}
+	}
+	if before != nil {
+		result = append(result, *before)
+	}
+	return result, nil
+}
+
+func (r *fakeExchangeRatesRepository) ReplaceExchangeRates(rates *models.ExchangeRates) error {
+	date := rates.ActualDate.Format(time.DateOnly)
+	kept := r.rates[:0]
+	for _, rate := range r.rates {
+		if rate.ActualDate.Format(time.DateOnly) != date {
+			kept = append(kept, rate)
+		}
+	}
+	r.rates = append(kept, *rates)
+	sort.Slice(r.rates, func(i, j int) bool { return r.rates[i].ActualDate.Before(r.rates[j].ActualDate) })
+	return nil
+}
+
+func (r *fakeExchangeRatesRepository) GetRateDates(startDate, endDate string) ([]time.Time, error) {
+	var dates []time.Time
+	for _, rate := range r.rates {
+		if date := rate.ActualDate.Format(time.DateOnly); date >= startDate && date <= endDate {
+			dates = append(dates, rate.ActualDate)
+		}
+	}
+	return dates, nil
+}
+
+func (r *fakeExchangeRatesRepository) SaveRateGap(requestedDate, actualDate, serviceName string) error {
+	if r.gaps == nil {
+		r.gaps = make(map[string]string)
+	}
+	r.gaps[requestedDate] = actualDate
+	return nil
+}
+
+func (r *fakeExchangeRatesRepository) GetRateGapDates(startDate, endDate string) ([]time.Time, error) {
+	var dates []time.Time
+	for requested := range r.gaps {
+		if requested >= startDate && requested <= endDate {
+			date, err := time.Parse(time.DateOnly, requested)
+			if err != nil {
+				return nil, err
+			}
+			dates = append(dates, date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates, nil
+}
+
+type fakeUserExchangeRatesRepository struct {
+	rates []models.UserExchangeRate
+}
+
+func (r *fakeUserExchangeRatesRepository) GetUserExchangeRates(userID int) ([]models.UserExchangeRate, error) {
+	return r.rates, nil
+}
+
+func (r *fakeUserExchangeRatesRepository) SaveUserExchangeRate(rate models.UserExchangeRate) (models.UserExchangeRate, error) {
+	r.rates = append(r.rates, rate)
+	return rate, nil
+}
+
+func (r *fakeUserExchangeRatesRepository) DeleteUserExchangeRate(id int, userID int) (bool, error) {
+	return false, nil
+}
+
+func testDate(t testing.TB, value string) time.Time {
+	t.Helper()
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		t.Fatalf("invalid date %q: %v", value, err)
+	}
+	return date
+}
+
+func testExchangeRates(date time.Time, eur string) models.ExchangeRates {
+	return models.ExchangeRates{
+		Rates:            models.JSONB{"EUR": eur, "UAH": "41.5", "GBP": "0.79"},
+		ActualDate:       date,
+		BaseCurrencyCode: "USD",
+		ServiceName:      "test",
+	}
+}
+
+// testRateRepository has rates of the days around the end of January 2024, the 1st and the
+// weekend of February have none
+func testRateRepository(t testing.TB) *fakeExchangeRatesRepository {
+	repository := &fakeExchangeRatesRepository{}
+	for i, day := range []string{"2024-01-30", "2024-01-31", "2024-02-02", "2024-02-05"} {
+		repository.rates = append(repository.rates, testExchangeRates(testDate(t, day), decimal.NewFromInt(90+int64(i)).Shift(-2).String()))
+	}
+	return repository
+}
+
+// longRateRepository has the rates of the given number of days from the first one
+func longRateRepository(first time.Time, days int) *fakeExchangeRatesRepository {
+	repository := &fakeExchangeRatesRepository{}
+	for i := 0; i < days; i++ {
+		eur := decimal.NewFromInt(int64(9000 + i%500)).Shift(-4).String()
+		repository.rates = append(repository.rates, testExchangeRates(first.AddDate(0, 0, i), eur))
+	}
+	return repository
+}
+
+func TestRateStoreDayOn(t *testing.T) {
+	tests := []struct {
+		name     string
+		replaced []string // days stored again with EUR at 0.99 before the lookup
+		date     string
+		wantDate string // empty when there are no rates
+		wantEUR  string
+	}{
+		{name: "before the first day", date: "2024-01-29"},
+		{name: "exactly on a day", date: "2024-01-31", wantDate: "2024-01-31", wantEUR: "0.91"},
+		{name: "day without rates uses the previous one", date: "2024-02-04", wantDate: "2024-02-02", wantEUR: "0.92"},
+		{name: "across a month boundary", date: "2024-02-01", wantDate: "2024-01-31", wantEUR: "0.91"},
+		{name: "after replaceDay of the day", replaced: []string{"2024-02-02"}, date: "2024-02-02", wantDate: "2024-02-02", wantEUR: "0.99"},
+		{name: "after replaceDay of a new day", replaced: []string{"2024-02-03"}, date: "2024-02-04", wantDate: "2024-02-03", wantEUR: "0.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newRateStore(testRateRepository(t))
+			for _, day := range tt.replaced {
+				// The month is loaded first like it is when rates are updated on a running instance
+				if _, err := store.dayOn(testDate(t, day)); err != nil {
+					t.Fatalf("dayOn: %v", err)
+				}
+				store.replaceDay(newRateDay(testExchangeRates(testDate(t, day), "0.99")))
+			}
+
+			day, err := store.dayOn(testDate(t, tt.date))
+			if err != nil {
+				t.Fatalf("dayOn: %v", err)
+			}
+			if tt.wantDate == "" {
+				if day != nil {
+					t.Fatalf("dayOn(%s) = rates of %s, want none", tt.date, day.date)
+				}
+				return
+			}
+			if day == nil {
+				t.Fatalf("dayOn(%s) = none, want rates of %s", tt.date, tt.wantDate)
+			}
+			if day.date != tt.wantDate {
+				t.Errorf("dayOn(%s) = rates of %s, want %s", tt.date, day.date, tt.wantDate)
+			}
+			if eur := day.rates["EUR"].String(); eur != tt.wantEUR {
+				t.Errorf("dayOn(%s) EUR = %s, want %s", tt.date, eur, tt.wantEUR)
+			}
+		})
+	}
+}
+
+func TestRateStoreDaysBetween(t *testing.T) {
+	tests := []struct {
+		name      string
+		replaced  []string
+		startDate string
+		endDate   string
+		want      []string
+	}{
+		{name: "before the first day", startDate: "2024-01-01", endDate: "2024-01-29", want: nil},
+		{name: "starting exactly on a day", startDate: "2024-01-31", endDate: "2024-02-02",
+			want: []string{"2024-01-30", "2024-01-31", "2024-02-02"}},
+		{name: "across a month boundary", startDate: "2024-02-01", endDate: "2024-02-05",
+			want: []string{"2024-01-31", "2024-02-02", "2024-02-05"}},
+		{name: "within a month without rates at its start", startDate: "2024-02-03", endDate: "2024-02-04",
+			want: []string{"2024-02-02"}},
+		{name: "after replaceDay", replaced: []string{"2024-02-02", "2024-02-03"}, startDate: "2024-02-01", endDate: "2024-02-04",
+			want: []string{"2024-01-31", "2024-02-02", "2024-02-03"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newRateStore(testRateRepository(t))
+			for _, day := range tt.replaced {
+				if _, err := store.dayOn(testDate(t, day)); err != nil {
+					t.Fatalf("dayOn: %v", err)
+				}
+				store.replaceDay(newRateDay(testExchangeRates(testDate(t, day), "0.99")))
+			}
+
+			days, err := store.daysBetween(testDate(t, tt.startDate), testDate(t, tt.endDate))
+			if err != nil {
+				t.Fatalf("daysBetween: %v", err)
+			}
+			var got []string
+			for _, day := range days {
+				got = append(got, day.date)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("daysBetween(%s, %s) = %v, want %v", tt.startDate, tt.endDate, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("daysBetween(%s, %s) = %v, want %v", tt.startDate, tt.endDate, got, tt.want)
+				}
+			}
+			for _, day := range days {
+				for _, replaced := range tt.replaced {
+					if day.date == replaced && day.rates["EUR"].String() != "0.99" {
+						t.Errorf("daysBetween rates of %s were not replaced", day.date)
+					}
+				}
+			}
+		})
+	}
+}
+
+const benchmarkRateDays = 3000
+
+// dateKeyRates is the cache the rate store replaced, a map of the dates of the rates that every
+// lookup parses and sorts under a global lock. It is kept to compare the rate store against.
+type dateKeyRates struct {
+	mu   sync.RWMutex
+	data map[string]map[string]decimal.Decimal
+}
+
+func (c *dateKeyRates) dateKey(date time.Time) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	dateKeys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keyDate, err := time.Parse(time.DateOnly, key)
+		if err != nil {
+			continue
+		}
+		if !keyDate.After(date) {
+			dateKeys = append(dateKeys, key)
+		}
+	}
+	if len(dateKeys) == 0 {
+		return ""
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(dateKeys)))
+	return dateKeys[0]
+}
+
+func BenchmarkDayOn(b *testing.B) {
+	first := testDate(b, "2016-01-01")
+	repository := longRateRepository(first, benchmarkRateDays)
+
+	b.Run("rate store", func(b *testing.B) {
+		store := newRateStore(repository)
+		if _, err := store.daysBetween(first, first.AddDate(0, 0, benchmarkRateDays)); err != nil {
+			b.Fatalf("daysBetween: %v", err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.dayOn(first.AddDate(0, 0, i%benchmarkRateDays)); err != nil {
+				b.Fatalf("dayOn: %v", err)
+			}
+		}
+	})
+
+	b.Run("map of date keys", func(b *testing.B) {
+		cache := &dateKeyRates{data: make(map[string]map[string]decimal.Decimal, len(repository.rates))}
+		for _, rates := range repository.rates {
+			cache.data[rates.ActualDate.Format(time.DateOnly)] = newRateDay(rates).rates
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if cache.dateKey(first.AddDate(0, 0, i%benchmarkRateDays)) == "" {
+				b.Fatal("dateKey found no rates")
+			}
+		}
+	})
+}
+
+func BenchmarkGetRateBetweenCurrencies(b *testing.B) {
+	first := testDate(b, "2016-01-01")
+	repository := longRateRepository(first, benchmarkRateDays)
+
+	// The user prices a private currency in EUR every month, converting it to UAH needs a path
+	// through the provider rates
+	userRepository := &fakeUserExchangeRatesRepository{}
+	for month := first; month.Before(first.AddDate(0, 0, benchmarkRateDays)); month = month.AddDate(0, 1, 0) {
+		userRepository.rates = append(userRepository.rates, models.UserExchangeRate{
+			UserID:       1,
+			RateDate:     month,
+			CurrencyFrom: "GLD",
+			CurrencyTo:   "EUR",
+			Rate:         decimal.NewFromInt(int64(1800 + month.Month())),
+		})
+	}
+
+	for _, bench := range []struct {
+		name string
+		from string
+		to   string
+	}{
+		{name: "provider rates", from: "EUR", to: "UAH"},
+		{name: "private currency", from: "GLD", to: "UAH"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			s := &ExchangeRatesServiceInstance{
+				exchangeRatesRepository:     repository,
+				userExchangeRatesRepository: userRepository,
+				rates:                       newRateStore(repository),
+				userRates:                   make(map[int]*userRateIndex),
+			}
+			if _, err := s.rates.daysBetween(first, first.AddDate(0, 0, benchmarkRateDays)); err != nil {
+				b.Fatalf("daysBetween: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// A report converts the rows of a few months, the same days come up again and again
+				date := first.AddDate(0, 0, benchmarkRateDays-1-i%90)
+				if _, err := s.GetRateBetweenCurrencies(1, date, bench.from, bench.to); err != nil {
+					b.Fatalf("GetRateBetweenCurrencies: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderRateTakesMissingCurrenciesFromEarlierDays(t *testing.T) {
+	repository := &fakeExchangeRatesRepository{}
+	repository.rates = append(repository.rates, testExchangeRates(testDate(t, "2024-03-01"), "0.92"))
+	// A fallback provider without GBP answered the following days
+	for _, day := range []string{"2024-03-04", "2024-03-08"} {
+		rates := testExchangeRates(testDate(t, day), "0.9")
+		delete(rates.Rates, "GBP")
+		repository.rates = append(repository.rates, rates)
+	}
+
+	tests := []struct {
+		name     string
+		date     string
+		from     string
+		to       string
+		wantRate string // empty when there is no rate
+		wantDate string
+	}{
+		{name: "currencies of the day", date: "2024-03-05", from: "USD", to: "EUR", wantRate: "0.9", wantDate: "2024-03-04"},
+		{name: "missing currency from an earlier day", date: "2024-03-05", from: "GBP", to: "EUR", wantRate: "1.1392405063291139", wantDate: "2024-03-01"},
+		{name: "missing currency older than maxRateAge", date: "2024-03-09", from: "GBP", to: "EUR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newBackfillTestService(repository)
+			rate, rateDate, err := s.providerRate(testDate(t, tt.date), tt.from, tt.to)
+			if tt.wantRate == "" {
+				if err == nil {
+					t.Fatalf("providerRate(%s) = %s of %s, want an error", tt.date, rate, rateDate)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("providerRate: %v", err)
+			}
+			if rate.String() != tt.wantRate || rateDate != tt.wantDate {
+				t.Errorf("providerRate(%s) = %s of %s, want %s of %s", tt.date, rate, rateDate, tt.wantRate, tt.wantDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ypeskov/budget-go/internal/config"
+	"ypeskov/budget-go/internal/logger"
+
+	"github.com/shopspring/decimal"
+)
+
+// FileServiceName is stored as the service name of rates read from the local file
+const FileServiceName = "File"
+
+// FileRateProvider reads rates from a local file for offline and self-hosted setups.
+// The file is read on every request, so it can be updated while the app is running.
+//
+// JSON files hold a list of days:
+//
+//	[{"date": "2025-03-03", "base": "USD", "rates": {"EUR": 0.9612, "UAH": "41.52"}}]
+//
+// CSV files have a header and a row per rate:
+//
+//	date,base,currency,rate
+//	2025-03-03,USD,EUR,0.9612
+type FileRateProvider struct {
+	config *config.Config
+}
+
+type fileRatesDay struct {
+	Date  string                     `json:"date"`
+	Base  string                     `json:"base"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+var (
+	fileRatesInstance *FileRateProvider
+	fileRatesOnce     sync.Once
+)
+
+func NewFileRateProvider(cfg *config.Config) RateProvider {
+	fileRatesOnce.Do(func() {
+		logger.Debug("Creating FileRateProvider instance")
+		fileRatesInstance = &FileRateProvider{config: cfg}
+	})
+
+	return fileRatesInstance
+}
+
+func (p *FileRateProvider) Name() string {
+	return FileServiceName
+}
+
+func (p *FileRateProvider) GetRates(date time.Time) (*RateSnapshot, error) {
+	path := p.config.ExchangeRatesFile
+	if path == "" {
+		return nil, fmt.Errorf("exchange rates file is not configured")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exchange rates file: %w", err)
+	}
+	defer file.Close()
+
+	var snapshots []RateSnapshot
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		snapshots, err = parseJSONRates(file)
+	case ".csv":
+		snapshots, err = parseCSVRates(file)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rates file %s, use a .json or .csv file", path)
+	}
+	if err != nil {
+		logger.Error("Failed to parse exchange rates file", "path", path, "error", err)
+		return nil, err
+	}
+
+	return latestSnapshot(snapshots, date)
+}
+
+func parseJSONRates(reader io.Reader) ([]RateSnapshot, error) {
+	var days []fileRatesDay
+	if err := json.NewDecoder(reader).Decode(&days); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange rates file: %w", err)
+	}
+
+	snapshots := make([]RateSnapshot, 0, len(days))
+	for _, day := range days {
+		date, err := time.Parse(time.DateOnly, day.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date in exchange rates file: %q", day.Date)
+		}
+		snapshots = append(snapshots, RateSnapshot{
+			Date:         date,
+			BaseCurrency: strings.ToUpper(day.Base),
+			Rates:        day.Rates,
+			Provider:     FileServiceName,
+		})
+	}
+	return snapshots, nil
+}
+
+func parseCSVRates(reader io.Reader) ([]RateSnapshot, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("exchange rates file is empty")
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, column := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range []string{"date", "base", "currency", "rate"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("exchange rates file has no %s column", column)
+		}
+	}
+
+	days := make(map[string]*RateSnapshot)
+	var snapshots []RateSnapshot
+	for line, record := range records[1:] {
+		field := func(column string) string {
+			return strings.TrimSpace(record[columns[column]])
+		}
+
+		base := strings.ToUpper(field("base"))
+		key := field("date") + "/" + base
+		day, ok := days[key]
+		if !ok {
+			date, err := time.Parse(time.DateOnly, field("date"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid date on line %d of exchange rates file: %q", line+2, field("date"))
+			}
+			day = &RateSnapshot{Date: date, BaseCurrency: base, Rates: make(map[string]decimal.Decimal), Provider: FileServiceName}
+			days[key] = day
+		}
+
+		rate, err := decimal.NewFromString(field("rate"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate on line %d of exchange rates file: %q", line+2, field("rate"))
+		}
+		day.Rates[strings.ToUpper(field("currency"))] = rate
+	}
+
+	for _, day := range days {
+		snapshots = append(snapshots, *day)
+	}
+	return snapshots, nil
+}
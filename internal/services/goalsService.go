@@ -0,0 +1,420 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	goalsRepo "ypeskov/budget-go/internal/repositories/goals"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+type GoalsService interface {
+	CreateGoal(goalDTO dto.CreateGoalDTO, userID int) (*dto.GoalResponseDTO, error)
+	UpdateGoal(goalDTO dto.UpdateGoalDTO, userID int) (*dto.GoalResponseDTO, error)
+	GetUserGoals(userID int, includeArchived bool) ([]dto.GoalResponseDTO, error)
+	GetGoal(goalID int, userID int) (*dto.GoalResponseDTO, error)
+	DeleteGoal(goalID int, userID int) error
+	AddContribution(goalID int, transactionID int, userID int) (*dto.GoalResponseDTO, error)
+	RemoveContribution(goalID int, transactionID int, userID int) (*dto.GoalResponseDTO, error)
+	// RecalculateAllGoals refreshes progress and required monthly contribution of all active goals
+	RecalculateAllGoals() (int, error)
+}
+
+type GoalsServiceInstance struct {
+	goalsRepository goalsRepo.Repository
+	sm              *Manager
+}
+
+var (
+	goalsInstance *GoalsServiceInstance
+	goalsOnce     sync.Once
+)
+
+func NewGoalsService(goalsRepository goalsRepo.Repository, sManager *Manager) GoalsService {
+	goalsOnce.Do(func() {
+		logger.Debug("Creating GoalsService instance")
+		goalsInstance = &GoalsServiceInstance{
+			goalsRepository: goalsRepository,
+			sm:              sManager,
+		}
+	})
+
+	return goalsInstance
+}
+
+func (s *GoalsServiceInstance) CreateGoal(goalDTO dto.CreateGoalDTO, userID int) (*dto.GoalResponseDTO, error) {
+	logger.Debug("CreateGoal Service")
+
+	progressSource, err := normalizeGoalProgressSource(goalDTO.ProgressSource)
+	if err != nil {
+		return nil, err
+	}
+	if !goalDTO.TargetAmount.IsPositive() {
+		return nil, fmt.Errorf("target amount must be positive")
+	}
+
+	validAccounts, err := s.goalsRepository.GetUserAccountIDs(userID, goalDTO.AccountIDs)
+	if err != nil {
+		logger.Error("Error validating goal accounts", "error", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	goal := models.Goal{
+		UserID:         userID,
+		Name:           goalDTO.Name,
+		TargetAmount:   goalDTO.TargetAmount,
+		CurrencyID:     goalDTO.CurrencyID,
+		TargetDate:     customDateToTime(goalDTO.TargetDate),
+		ProgressSource: progressSource,
+		CurrentAmount:  decimal.Zero,
+		Comment:        goalDTO.Comment,
+		CreatedAt:      &now,
+		UpdatedAt:      &now,
+	}
+
+	createdGoal, err := s.goalsRepository.CreateGoal(goal)
+	if err != nil {
+		logger.Error("Error creating goal", "error", err)
+		return nil, err
+	}
+
+	if err = s.goalsRepository.SetGoalAccounts(*createdGoal.ID, validAccounts); err != nil {
+		logger.Error("Error linking goal accounts", "error", err)
+		return nil, err
+	}
+
+	if err = s.recalculateGoal(createdGoal); err != nil {
+		logger.Error("Error calculating goal progress", "goalID", *createdGoal.ID, "error", err)
+	}
+
+	return s.GetGoal(*createdGoal.ID, userID)
+}
+
+func (s *GoalsServiceInstance) UpdateGoal(goalDTO dto.UpdateGoalDTO, userID int) (*dto.GoalResponseDTO, error) {
+	logger.Debug("UpdateGoal Service")
+
+	existingGoal, err := s.goalsRepository.GetGoalByID(goalDTO.ID, userID)
+	if err != nil {
+		logger.Error("Error getting existing goal", "error", err)
+		return nil, fmt.Errorf("goal not found")
+	}
+
+	progressSource, err := normalizeGoalProgressSource(goalDTO.ProgressSource)
+	if err != nil {
+		return nil, err
+	}
+	if !goalDTO.TargetAmount.IsPositive() {
+		return nil, fmt.Errorf("target amount must be positive")
+	}
+
+	validAccounts, err := s.goalsRepository.GetUserAccountIDs(userID, goalDTO.AccountIDs)
+	if err != nil {
+		logger.Error("Error validating goal accounts", "error", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	goal := *existingGoal
+	goal.Name = goalDTO.Name
+	goal.TargetAmount = goalDTO.TargetAmount
+	goal.CurrencyID = goalDTO.CurrencyID
+	goal.TargetDate = customDateToTime(goalDTO.TargetDate)
+	goal.ProgressSource = progressSource
+	goal.Comment = goalDTO.Comment
+	goal.IsArchived = goalDTO.IsArchived
+	goal.UpdatedAt = &now
+
+	if err = s.goalsRepository.UpdateGoal(goal); err != nil {
+		logger.Error("Error updating goal", "error", err)
+		return nil, err
+	}
+
+	if err = s.goalsRepository.SetGoalAccounts(*goal.ID, validAccounts); err != nil {
+		logger.Error("Error linking goal accounts", "error", err)
+		return nil, err
+	}
+
+	if err = s.recalculateGoal(&goal); err != nil {
+		logger.Error("Error calculating goal progress", "goalID", *goal.ID, "error", err)
+	}
+
+	return s.GetGoal(*goal.ID, userID)
+}
+
+func (s *GoalsServiceInstance) GetUserGoals(userID int, includeArchived bool) ([]dto.GoalResponseDTO, error) {
+	logger.Debug("GetUserGoals Service")
+
+	goals, err := s.goalsRepository.GetUserGoals(userID, includeArchived)
+	if err != nil {
+		logger.Error("Error getting user goals", "error", err)
+		return nil, err
+	}
+
+	goalDTOs := make([]dto.GoalResponseDTO, 0, len(goals))
+	for _, goal := range goals {
+		goalDTO, err := s.toGoalResponse(goal)
+		if err != nil {
+			return nil, err
+		}
+		goalDTOs = append(goalDTOs, *goalDTO)
+	}
+
+	return goalDTOs, nil
+}
+
+func (s *GoalsServiceInstance) GetGoal(goalID int, userID int) (*dto.GoalResponseDTO, error) {
+	logger.Debug("GetGoal Service")
+
+	goal, err := s.goalsRepository.GetGoalByID(goalID, userID)
+	if err != nil {
+		logger.Error("Error getting goal", "error", err)
+		return nil, fmt.Errorf("goal not found")
+	}
+
+	return s.toGoalResponse(*goal)
+}
+
+func (s *GoalsServiceInstance) DeleteGoal(goalID int, userID int) error {
+	logger.Debug("DeleteGoal Service")
+
+	if err := s.goalsRepository.DeleteGoal(goalID, userID); err != nil {
+		logger.Error("Error deleting goal", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *GoalsServiceInstance) AddContribution(goalID int, transactionID int, userID int) (*dto.GoalResponseDTO, error) {
+	logger.Debug("AddContribution Service")
+
+	goal, err := s.goalsRepository.GetGoalByID(goalID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("goal not found")
+	}
+
+	ownsTransaction, err := s.goalsRepository.IsUserTransaction(userID, transactionID)
+	if err != nil {
+		logger.Error("Error validating transaction", "error", err)
+		return nil, err
+	}
+	if !ownsTransaction {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	if err = s.goalsRepository.AddContribution(goalID, transactionID); err != nil {
+		logger.Error("Error adding goal contribution", "error", err)
+		return nil, err
+	}
+
+	if err = s.recalculateGoal(goal); err != nil {
+		logger.Error("Error calculating goal progress", "goalID", goalID, "error", err)
+	}
+
+	return s.GetGoal(goalID, userID)
+}
+
+func (s *GoalsServiceInstance) RemoveContribution(goalID int, transactionID int, userID int) (*dto.GoalResponseDTO, error) {
+	logger.Debug("RemoveContribution Service")
+
+	goal, err := s.goalsRepository.GetGoalByID(goalID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("goal not found")
+	}
+
+	if err = s.goalsRepository.RemoveContribution(goalID, transactionID); err != nil {
+		logger.Error("Error removing goal contribution", "error", err)
+		return nil, err
+	}
+
+	if err = s.recalculateGoal(goal); err != nil {
+		logger.Error("Error calculating goal progress", "goalID", goalID, "error", err)
+	}
+
+	return s.GetGoal(goalID, userID)
+}
+
+func (s *GoalsServiceInstance) RecalculateAllGoals() (int, error) {
+	logger.Debug("RecalculateAllGoals Service")
+
+	goals, err := s.goalsRepository.GetActiveGoals()
+	if err != nil {
+		logger.Error("Error getting active goals", "error", err)
+		return 0, err
+	}
+
+	recalculated := 0
+	for i := range goals {
+		if err := s.recalculateGoal(&goals[i]); err != nil { // handle error but continue processing
+			logger.Error("Error recalculating goal", "goalID", *goals[i].ID, "userID", goals[i].UserID, "error", err)
+			continue
+		}
+		recalculated++
+	}
+
+	return recalculated, nil
+}
+
+// recalculateGoal computes the current progress of the goal in its currency and the monthly
+// contribution still required to reach the target by the target date
+func (s *GoalsServiceInstance) recalculateGoal(goal *models.Goal) error {
+	currency, err := s.sm.CurrenciesService.GetCurrency(goal.CurrencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get goal currency %d: %w", goal.CurrencyID, err)
+	}
+
+	now := time.Now()
+	currentAmount := decimal.Zero
+
+	switch models.GoalProgressSource(goal.ProgressSource) {
+	case models.GoalProgressFromContributions:
+		contributions, err := s.goalsRepository.GetGoalContributions(*goal.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get contributions of goal %d: %w", *goal.ID, err)
+		}
+		for _, contribution := range contributions {
+			// Contributions are converted at the rate of the transaction date
+			amount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(goal.UserID,
+				contribution.DateTime, contribution.Amount, contribution.CurrencyCode, currency.Code)
+			if err != nil {
+				return fmt.Errorf("failed to convert contribution %d to %s: %w", contribution.TransactionID, currency.Code, err)
+			}
+			currentAmount = currentAmount.Add(amount)
+		}
+	default:
+		balances, err := s.goalsRepository.GetGoalAccountBalances(*goal.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get account balances of goal %d: %w", *goal.ID, err)
+		}
+		for _, balance := range balances {
+			// Balances are converted at today's rate
+			amount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(goal.UserID,
+				now, balance.Balance, balance.CurrencyCode, currency.Code)
+			if err != nil {
+				return fmt.Errorf("failed to convert balance of account %d to %s: %w", balance.AccountID, currency.Code, err)
+			}
+			currentAmount = currentAmount.Add(amount)
+		}
+	}
+
+	currentAmount = currentAmount.Round(2)
+	requiredMonthly := CalculateRequiredMonthlyContribution(goal.TargetAmount.Sub(currentAmount), goal.TargetDate, now)
+
+	if err = s.goalsRepository.UpdateGoalProgress(*goal.ID, currentAmount, requiredMonthly, now); err != nil {
+		return fmt.Errorf("failed to update progress of goal %d: %w", *goal.ID, err)
+	}
+
+	goal.CurrentAmount = currentAmount
+	goal.RequiredMonthlyContribution = requiredMonthly
+	goal.RecalculatedAt = &now
+	return nil
+}
+
+// CalculateRequiredMonthlyContribution spreads the remaining amount over the months left until the target date.
+// Returns nil when the goal has no target date. A target date in the past or within the current month
+// requires the whole remaining amount now.
+func CalculateRequiredMonthlyContribution(remaining decimal.Decimal, targetDate *time.Time, now time.Time) *decimal.Decimal {
+	if targetDate == nil {
+		return nil
+	}
+
+	if !remaining.IsPositive() {
+		zero := decimal.Zero
+		return &zero
+	}
+
+	months := monthsUntil(now, *targetDate)
+	required := remaining.Div(decimal.NewFromInt(int64(months))).Round(2)
+	return &required
+}
+
+// monthsUntil returns the number of months between from and to (a partial month counts as whole), at least 1
+func monthsUntil(from time.Time, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() > from.Day() {
+		months++
+	}
+	if months < 1 {
+		return 1
+	}
+	return months
+}
+
+func (s *GoalsServiceInstance) toGoalResponse(goal models.Goal) (*dto.GoalResponseDTO, error) {
+	currency, err := s.sm.CurrenciesService.GetCurrency(goal.CurrencyID)
+	if err != nil {
+		logger.Error("Error getting goal currency", "currencyID", goal.CurrencyID, "error", err)
+		return nil, err
+	}
+
+	accountIDs, err := s.goalsRepository.GetGoalAccountIDs(*goal.ID)
+	if err != nil {
+		logger.Error("Error getting goal accounts", "goalID", *goal.ID, "error", err)
+		return nil, err
+	}
+
+	contributions, err := s.goalsRepository.GetGoalContributions(*goal.ID)
+	if err != nil {
+		logger.Error("Error getting goal contributions", "goalID", *goal.ID, "error", err)
+		return nil, err
+	}
+
+	remaining := goal.TargetAmount.Sub(goal.CurrentAmount)
+	if remaining.IsNegative() {
+		remaining = decimal.Zero
+	}
+
+	progressPercent := decimal.Zero
+	if goal.TargetAmount.IsPositive() {
+		progressPercent = goal.CurrentAmount.Mul(decimal.NewFromInt(100)).Div(goal.TargetAmount).Round(2)
+	}
+
+	if accountIDs == nil {
+		accountIDs = []int{}
+	}
+	if contributions == nil {
+		contributions = []models.GoalContribution{}
+	}
+
+	return &dto.GoalResponseDTO{
+		ID:                          *goal.ID,
+		Name:                        goal.Name,
+		TargetAmount:                goal.TargetAmount,
+		CurrentAmount:               goal.CurrentAmount,
+		RemainingAmount:             remaining,
+		ProgressPercent:             progressPercent,
+		TargetDate:                  goal.TargetDate,
+		ProgressSource:              strings.ToLower(goal.ProgressSource),
+		RequiredMonthlyContribution: goal.RequiredMonthlyContribution,
+		RecalculatedAt:              goal.RecalculatedAt,
+		AccountIDs:                  accountIDs,
+		Contributions:               contributions,
+		Comment:                     goal.Comment,
+		IsArchived:                  goal.IsArchived,
+		Currency:                    currency,
+	}, nil
+}
+
+func normalizeGoalProgressSource(source string) (string, error) {
+	if source == "" {
+		return string(models.GoalProgressFromAccounts), nil
+	}
+	if !models.ValidateGoalProgressSource(source) {
+		return "", fmt.Errorf("invalid progress source: %s. Valid sources are: accounts, contributions", source)
+	}
+	return strings.ToUpper(source), nil
+}
+
+func customDateToTime(date *utils.CustomDate) *time.Time {
+	if date == nil {
+		return nil
+	}
+	return date.ToTime()
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/queue"
+	insightsRepo "ypeskov/budget-go/internal/repositories/insights"
+)
+
+type InsightsService interface {
+	// GetUserInsights returns the insights of the user with at least the given severity, empty means any
+	GetUserInsights(userID int, includeDismissed bool, minSeverity string) ([]dto.InsightResponseDTO, error)
+	DismissInsight(insightID int, userID int) error
+	// AnalyzeUser looks for anomalies in the expenses of the user up to the date and returns the new insights
+	AnalyzeUser(userID int, date time.Time) ([]models.Insight, error)
+	// AnalyzeAllUsers runs the analysis for every active user and emails new insights to users who opted in
+	AnalyzeAllUsers() (int, error)
+	// GetUpcomingCharges predicts the recurring charges expected within the given days from the date
+	GetUpcomingCharges(userID int, date time.Time, days int) ([]dto.UpcomingChargeDTO, error)
+}
+
+type InsightsServiceInstance struct {
+	insightsRepository insightsRepo.Repository
+	sm                 *Manager
+}
+
+var (
+	insightsInstance *InsightsServiceInstance
+	insightsOnce     sync.Once
+)
+
+func NewInsightsService(insightsRepository insightsRepo.Repository, sManager *Manager) InsightsService {
+	insightsOnce.Do(func() {
+		logger.Debug("Creating InsightsService instance")
+		insightsInstance = &InsightsServiceInstance{
+			insightsRepository: insightsRepository,
+			sm:                 sManager,
+		}
+	})
+
+	return insightsInstance
+}
+
+func (s *InsightsServiceInstance) GetUserInsights(userID int, includeDismissed bool, minSeverity string) ([]dto.InsightResponseDTO, error) {
+	logger.Debug("GetUserInsights Service")
+
+	if minSeverity != "" && models.InsightSeverityRank(minSeverity) == 0 {
+		return nil, fmt.Errorf("invalid severity: %s. Allowed values are info, warning and critical", minSeverity)
+	}
+
+	baseCurrency, err := s.sm.UserSettingsService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	insights, err := s.insightsRepository.GetUserInsights(userID, includeDismissed)
+	if err != nil {
+		logger.Error("Error getting user insights", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	result := make([]dto.InsightResponseDTO, 0, len(insights))
+	for _, insight := range insights {
+		if models.InsightSeverityRank(insight.Severity) < models.InsightSeverityRank(minSeverity) {
+			continue
+		}
+		result = append(result, dto.InsightResponseDTO{
+			ID:            *insight.ID,
+			Type:          insight.Type,
+			Severity:      insight.Severity,
+			Title:         insight.Title,
+			Message:       insight.Message,
+			CategoryID:    insight.CategoryID,
+			TransactionID: insight.TransactionID,
+			Amount:        insight.Amount,
+			CurrencyCode:  baseCurrency.Code,
+			Details:       insight.Details,
+			DetectedAt:    insight.DetectedAt,
+			IsDismissed:   insight.IsDismissed,
+			DismissedAt:   insight.DismissedAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *InsightsServiceInstance) DismissInsight(insightID int, userID int) error {
+	logger.Debug("DismissInsight Service")
+
+	return s.insightsRepository.DismissInsight(insightID, userID)
+}
+
+func (s *InsightsServiceInstance) AnalyzeUser(userID int, date time.Time) ([]models.Insight, error) {
+	logger.Debug("AnalyzeUser Service", "userID", userID)
+
+	analysis, err := s.loadAnalysis(userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.Insight
+	found = append(found, detectCategorySpikes(analysis)...)
+	found = append(found, detectNewPayees(analysis)...)
+	found = append(found, detectPriceIncreases(analysis)...)
+	found = append(found, detectDuplicateCharges(analysis)...)
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	for i := range found {
+		found[i].UserID = userID
+		found[i].DetectedAt = now
+		found[i].CreatedAt = now
+		found[i].UpdatedAt = now
+	}
+
+	created, err := s.insightsRepository.CreateInsights(found)
+	if err != nil {
+		logger.Error("Error saving insights", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *InsightsServiceInstance) GetUpcomingCharges(userID int, date time.Time, days int) ([]dto.UpcomingChargeDTO, error) {
+	logger.Debug("GetUpcomingCharges Service", "userID", userID)
+
+	analysis, err := s.loadAnalysis(userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	charges := upcomingCharges(analysis, days)
+	if charges == nil {
+		charges = []dto.UpcomingChargeDTO{}
+	}
+	return charges, nil
+}
+
+// loadAnalysis reads the expenses of the analyzed history up to the date and converts them to the base currency
+func (s *InsightsServiceInstance) loadAnalysis(userID int, date time.Time) (*analysisContext, error) {
+	baseCurrency, err := s.sm.UserSettingsService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	end := truncateToDay(date).AddDate(0, 0, 1)
+	transactions, err := s.insightsRepository.GetAnalysisTransactions(userID, end.AddDate(0, 0, -insightsLookbackDays))
+	if err != nil {
+		logger.Error("Error getting analysis transactions", "userID", userID, "error", err)
+		return nil, err
+	}
+
+	analysis := &analysisContext{
+		expenses:     make([]analysisExpense, 0, len(transactions)),
+		currencyCode: baseCurrency.Code,
+		end:          end,
+		recentStart:  end.AddDate(0, 0, -insightsRecentDays),
+	}
+	for _, transaction := range transactions {
+		// Planned transactions in the future are not spending yet
+		if !transaction.DateTime.Before(end) {
+			continue
+		}
+		baseAmount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(userID,
+			transaction.DateTime, transaction.Amount, transaction.CurrencyCode, baseCurrency.Code)
+		if err != nil {
+			// If conversion fails, use original amount like the reports
+			baseAmount = transaction.Amount
+		}
+		analysis.expenses = append(analysis.expenses, analysisExpense{
+			AnalysisTransaction: transaction,
+			BaseAmount:          baseAmount,
+			Payee:               normalizePayee(transaction.Label),
+		})
+	}
+
+	return analysis, nil
+}
+
+func (s *InsightsServiceInstance) AnalyzeAllUsers() (int, error) {
+	logger.Debug("AnalyzeAllUsers Service")
+
+	now := time.Now()
+	userIDs, err := s.insightsRepository.GetAnalysisUserIDs(truncateToDay(now).AddDate(0, 0, -insightsRecentDays))
+	if err != nil {
+		logger.Error("Error getting analysis users", "error", err)
+		return 0, err
+	}
+
+	created := 0
+	for _, userID := range userIDs {
+		insights, err := s.AnalyzeUser(userID, now)
+		if err != nil { // handle error but continue processing
+			logger.Error("Error analyzing user transactions", "userID", userID, "error", err)
+			continue
+		}
+		created += len(insights)
+
+		if err := s.notify(userID, insights); err != nil {
+			// The insights are saved and shown in the app, the email is a courtesy
+			logger.Error("Error queueing insights email", "userID", userID, "error", err)
+		}
+	}
+
+	return created, nil
+}
+
+// notify emails the new insights that reach the severity the user chose in the settings
+func (s *InsightsServiceInstance) notify(userID int, insights []models.Insight) error {
+	if len(insights) == 0 {
+		return nil
+	}
+
+	settings, err := s.sm.UserSettingsService.GetUserSettings(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	minSeverity, _ := settings.Settings[models.SettingInsightEmails].(string)
+	if models.InsightSeverityRank(minSeverity) == 0 {
+		return nil
+	}
+
+	var items []queue.InsightEmailItem
+	for _, insight := range insights {
+		if models.InsightSeverityRank(insight.Severity) >= models.InsightSeverityRank(minSeverity) {
+			items = append(items, queue.InsightEmailItem{
+				Severity: insight.Severity,
+				Title:    insight.Title,
+				Message:  insight.Message,
+			})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	user, err := s.sm.UserService.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.sm.QueueService.EnqueueInsightsEmail(queue.InsightsEmailPayload{
+		UserEmail: user.Email,
+		UserName:  user.FirstName,
+		Insights:  items,
+	})
+}
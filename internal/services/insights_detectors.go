@@ -0,0 +1,467 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/repositories/insights"
+
+	"github.com/shopspring/decimal"
+)
+
+// Thresholds of the spending analysis
+const (
+	// insightsLookbackDays is the history the analysis looks at
+	insightsLookbackDays = 365
+	// insightsRecentDays is how far back a transaction may date to raise an insight, older findings were
+	// already reported by earlier runs
+	insightsRecentDays = 7
+
+	spikeWindowDays      = 30
+	spikeBaselineWindows = 3
+	spikeRatio           = 3
+	spikeCriticalRatio   = 5
+	// spikeMinExcessShare ignores spikes smaller than this share of the average monthly spending
+	spikeMinExcessShare = 0.05
+
+	// newPayeeMinHistory is the number of expenses needed to know what a large amount is
+	newPayeeMinHistory     = 20
+	newPayeeRatio          = 3
+	newPayeeWarningRatio   = 10
+	priceIncreaseMinShare  = 0.01
+	priceIncreaseWarnShare = 0.2
+	// recurringMinCharges is the number of earlier charges with the same price that make a subscription
+	recurringMinCharges    = 2
+	recurringMaxCharges    = 3
+	recurringMinInterval   = 6
+	recurringMaxInterval   = 400
+	recurringIntervalSlack = 0.2
+
+	duplicateWindow = 24 * time.Hour
+)
+
+// analysisExpense is an expense with its amount in the base currency and the normalized payee
+type analysisExpense struct {
+	insights.AnalysisTransaction
+	BaseAmount decimal.Decimal
+	Payee      string
+}
+
+// analysisContext holds the expenses of a user sorted by date and the bounds of the analysis
+type analysisContext struct {
+	expenses     []analysisExpense
+	currencyCode string
+	// end is the start of the day after the analysis date, recentStart starts the recent days
+	end         time.Time
+	recentStart time.Time
+}
+
+func (a *analysisContext) isRecent(expense analysisExpense) bool {
+	return !expense.DateTime.Before(a.recentStart) && expense.DateTime.Before(a.end)
+}
+
+// normalizePayee makes labels differing only in case and spacing the same payee
+func normalizePayee(label string) string {
+	return strings.Join(strings.Fields(strings.ToLower(label)), " ")
+}
+
+func formatInsightAmount(amount decimal.Decimal, currencyCode string) string {
+	return fmt.Sprintf("%s %s", amount.StringFixed(2), currencyCode)
+}
+
+func insightDetails(details map[string]interface{}) json.RawMessage {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return data
+}
+
+// detectCategorySpikes flags categories whose spending in the last 30 days is several times
+// the average of the three 30-day windows before
+func detectCategorySpikes(a *analysisContext) []models.Insight {
+	currentStart := a.end.AddDate(0, 0, -spikeWindowDays)
+	baselineStart := currentStart.AddDate(0, 0, -spikeWindowDays*spikeBaselineWindows)
+
+	// A short history would make every category look like a spike
+	if len(a.expenses) == 0 || a.expenses[0].DateTime.After(baselineStart.AddDate(0, 0, insightsRecentDays)) {
+		return nil
+	}
+
+	type categoryTotals struct {
+		name     string
+		current  decimal.Decimal
+		baseline decimal.Decimal
+	}
+	categories := map[int]*categoryTotals{}
+	var categoryIDs []int
+	baselineTotal := decimal.Zero
+
+	for _, expense := range a.expenses {
+		if expense.DateTime.Before(baselineStart) || !expense.DateTime.Before(a.end) {
+			continue
+		}
+		isCurrent := !expense.DateTime.Before(currentStart)
+		if !isCurrent {
+			baselineTotal = baselineTotal.Add(expense.BaseAmount)
+		}
+		if expense.CategoryID == nil {
+			continue
+		}
+
+		totals, ok := categories[*expense.CategoryID]
+		if !ok {
+			totals = &categoryTotals{}
+			if expense.CategoryName != nil {
+				totals.name = *expense.CategoryName
+			}
+			categories[*expense.CategoryID] = totals
+			categoryIDs = append(categoryIDs, *expense.CategoryID)
+		}
+		if isCurrent {
+			totals.current = totals.current.Add(expense.BaseAmount)
+		} else {
+			totals.baseline = totals.baseline.Add(expense.BaseAmount)
+		}
+	}
+
+	windows := decimal.NewFromInt(spikeBaselineWindows)
+	minExcess := baselineTotal.Div(windows).Mul(decimal.NewFromFloat(spikeMinExcessShare))
+	month := a.end.AddDate(0, 0, -1).Format("2006-01")
+
+	var result []models.Insight
+	for _, categoryID := range categoryIDs {
+		totals := categories[categoryID]
+		average := totals.baseline.Div(windows)
+		// Categories without earlier spending have no average to compare with
+		if !average.IsPositive() || totals.current.LessThan(average.Mul(decimal.NewFromInt(spikeRatio))) {
+			continue
+		}
+		if totals.current.Sub(average).LessThan(minExcess) {
+			continue
+		}
+
+		ratio := totals.current.Div(average)
+		severity := models.InsightSeverityWarning
+		if ratio.GreaterThanOrEqual(decimal.NewFromInt(spikeCriticalRatio)) {
+			severity = models.InsightSeverityCritical
+		}
+
+		id := categoryID
+		amount := totals.current.Round(2)
+		result = append(result, models.Insight{
+			Type:     models.InsightCategorySpike,
+			Severity: severity,
+			Title:    fmt.Sprintf("Unusual spending in %s", totals.name),
+			Message: fmt.Sprintf("You spent %s on %s in the last %d days, %s times the average of %s.",
+				formatInsightAmount(totals.current, a.currencyCode), totals.name, spikeWindowDays,
+				ratio.StringFixed(1), formatInsightAmount(average, a.currencyCode)),
+			// One insight per category and month, a spike lasts for days of nightly runs
+			Fingerprint: fmt.Sprintf("%s:%d:%s", models.InsightCategorySpike, categoryID, month),
+			CategoryID:  &id,
+			Amount:      &amount,
+			Details: insightDetails(map[string]interface{}{
+				"currentAmount": totals.current.Round(2),
+				"averageAmount": average.Round(2),
+				"ratio":         ratio.Round(2),
+				"windowDays":    spikeWindowDays,
+			}),
+		})
+	}
+
+	return result
+}
+
+// detectNewPayees flags large first payments to payees not seen in the analyzed history
+func detectNewPayees(a *analysisContext) []models.Insight {
+	var history []decimal.Decimal
+	for _, expense := range a.expenses {
+		if expense.DateTime.Before(a.recentStart) {
+			history = append(history, expense.BaseAmount)
+		}
+	}
+	if len(history) < newPayeeMinHistory {
+		return nil
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].LessThan(history[j]) })
+	median := history[len(history)/2]
+	if !median.IsPositive() {
+		return nil
+	}
+	threshold := median.Mul(decimal.NewFromInt(newPayeeRatio))
+
+	seen := map[string]bool{}
+	var result []models.Insight
+	for _, expense := range a.expenses {
+		if expense.Payee == "" || seen[expense.Payee] {
+			continue
+		}
+		seen[expense.Payee] = true
+
+		if !a.isRecent(expense) || expense.BaseAmount.LessThan(threshold) {
+			continue
+		}
+
+		severity := models.InsightSeverityInfo
+		if expense.BaseAmount.GreaterThanOrEqual(median.Mul(decimal.NewFromInt(newPayeeWarningRatio))) {
+			severity = models.InsightSeverityWarning
+		}
+
+		label := strings.TrimSpace(expense.Label)
+		transactionID := expense.ID
+		amount := expense.BaseAmount.Round(2)
+		result = append(result, models.Insight{
+			Type:     models.InsightNewPayee,
+			Severity: severity,
+			Title:    fmt.Sprintf("Large payment to a new payee: %s", label),
+			Message: fmt.Sprintf("Your first payment to %s on %s was %s, your typical expense is %s.",
+				label, expense.DateTime.Format(time.DateOnly), formatInsightAmount(expense.Amount, expense.CurrencyCode),
+				formatInsightAmount(median, a.currencyCode)),
+			Fingerprint:   fmt.Sprintf("%s:%d", models.InsightNewPayee, expense.ID),
+			CategoryID:    expense.CategoryID,
+			TransactionID: &transactionID,
+			Amount:        &amount,
+			Details: insightDetails(map[string]interface{}{
+				"payee":         label,
+				"medianExpense": median.Round(2),
+			}),
+		})
+	}
+
+	return result
+}
+
+// detectPriceIncreases flags recurring charges whose latest amount is higher than the steady amount
+// of the earlier charges. Amounts are compared in the account currency, so exchange rates do not
+// look like price changes.
+func detectPriceIncreases(a *analysisContext) []models.Insight {
+	var result []models.Insight
+	for _, series := range chargesByPayee(a) {
+		if len(series) < recurringMinCharges+1 {
+			continue
+		}
+		latest := series[len(series)-1]
+		if !a.isRecent(latest) {
+			continue
+		}
+
+		earlier := series[:len(series)-1]
+		if len(earlier) > recurringMaxCharges {
+			earlier = earlier[len(earlier)-recurringMaxCharges:]
+		}
+		if _, ok := recurringInterval(append(append([]analysisExpense(nil), earlier...), latest)); !ok {
+			continue
+		}
+
+		previous := earlier[len(earlier)-1].Amount
+		if !previous.IsPositive() {
+			continue
+		}
+		steady := true
+		for _, charge := range earlier {
+			if charge.Amount.Sub(previous).Abs().Div(previous).GreaterThan(decimal.NewFromFloat(priceIncreaseMinShare)) {
+				steady = false
+				break
+			}
+		}
+		increase := latest.Amount.Sub(previous).Div(previous)
+		if !steady || increase.LessThan(decimal.NewFromFloat(priceIncreaseMinShare)) {
+			continue
+		}
+
+		severity := models.InsightSeverityInfo
+		if increase.GreaterThanOrEqual(decimal.NewFromFloat(priceIncreaseWarnShare)) {
+			severity = models.InsightSeverityWarning
+		}
+
+		label := strings.TrimSpace(latest.Label)
+		percent := increase.Mul(decimal.NewFromInt(100)).Round(1)
+		transactionID := latest.ID
+		amount := latest.BaseAmount.Round(2)
+		result = append(result, models.Insight{
+			Type:     models.InsightPriceIncrease,
+			Severity: severity,
+			Title:    fmt.Sprintf("Price increase: %s", label),
+			Message: fmt.Sprintf("%s charged %s on %s, up %s%% from %s.",
+				label, formatInsightAmount(latest.Amount, latest.CurrencyCode), latest.DateTime.Format(time.DateOnly),
+				percent.String(), formatInsightAmount(previous, latest.CurrencyCode)),
+			Fingerprint:   fmt.Sprintf("%s:%d", models.InsightPriceIncrease, latest.ID),
+			CategoryID:    latest.CategoryID,
+			TransactionID: &transactionID,
+			Amount:        &amount,
+			Details: insightDetails(map[string]interface{}{
+				"payee":           label,
+				"previousAmount":  previous,
+				"newAmount":       latest.Amount,
+				"currencyCode":    latest.CurrencyCode,
+				"increasePercent": percent,
+			}),
+		})
+	}
+
+	return result
+}
+
+// recurringInterval returns the median number of days between the charges when they come at a regular
+// interval, like weekly, monthly or yearly, and false otherwise
+func recurringInterval(charges []analysisExpense) (float64, bool) {
+	if len(charges) < 2 {
+		return 0, false
+	}
+	intervals := make([]float64, 0, len(charges)-1)
+	for i := 1; i < len(charges); i++ {
+		intervals = append(intervals, charges[i].DateTime.Sub(charges[i-1].DateTime).Hours()/24)
+	}
+
+	sorted := append([]float64(nil), intervals...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if median < recurringMinInterval || median > recurringMaxInterval {
+		return 0, false
+	}
+
+	for _, interval := range intervals {
+		if interval < median*(1-recurringIntervalSlack) || interval > median*(1+recurringIntervalSlack) {
+			return 0, false
+		}
+	}
+	return median, true
+}
+
+// nextChargeDate adds the interval to the date of the last charge, monthly and yearly charges
+// keep their day of the month
+func nextChargeDate(last time.Time, interval float64) time.Time {
+	day := truncateToDay(last)
+	switch {
+	case interval >= 27 && interval <= 33:
+		return day.AddDate(0, 1, 0)
+	case interval >= 355 && interval <= 375:
+		return day.AddDate(1, 0, 0)
+	}
+	return day.AddDate(0, 0, int(math.Round(interval)))
+}
+
+// chargesByPayee groups the expenses by payee and currency in the order payees first appear,
+// expenses without a label are left out
+func chargesByPayee(a *analysisContext) [][]analysisExpense {
+	type chargesKey struct{ payee, currencyCode string }
+	charges := map[chargesKey][]analysisExpense{}
+	var keys []chargesKey
+	for _, expense := range a.expenses {
+		if expense.Payee == "" || !expense.DateTime.Before(a.end) {
+			continue
+		}
+		key := chargesKey{payee: expense.Payee, currencyCode: expense.CurrencyCode}
+		if _, ok := charges[key]; !ok {
+			keys = append(keys, key)
+		}
+		charges[key] = append(charges[key], expense)
+	}
+
+	result := make([][]analysisExpense, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, charges[key])
+	}
+	return result
+}
+
+// upcomingCharges predicts the next charge of each recurring payee from its interval and returns
+// the charges expected from the analysis date on within the given number of days
+func upcomingCharges(a *analysisContext, days int) []dto.UpcomingChargeDTO {
+	today := a.end.AddDate(0, 0, -1)
+	until := a.end.AddDate(0, 0, days)
+
+	var result []dto.UpcomingChargeDTO
+	for _, series := range chargesByPayee(a) {
+		if len(series) < recurringMinCharges+1 {
+			continue
+		}
+		if len(series) > recurringMaxCharges+1 {
+			series = series[len(series)-recurringMaxCharges-1:]
+		}
+		interval, ok := recurringInterval(series)
+		if !ok {
+			continue
+		}
+
+		latest := series[len(series)-1]
+		expected := nextChargeDate(latest.DateTime, interval)
+		// A charge that is already overdue was probably cancelled
+		if expected.Before(today) || !expected.Before(until) {
+			continue
+		}
+
+		result = append(result, dto.UpcomingChargeDTO{
+			Payee:        strings.TrimSpace(latest.Label),
+			CategoryID:   latest.CategoryID,
+			CategoryName: latest.CategoryName,
+			AccountID:    latest.AccountID,
+			Amount:       latest.Amount,
+			CurrencyCode: latest.CurrencyCode,
+			BaseAmount:   latest.BaseAmount.Round(2),
+			ExpectedDate: expected,
+			IntervalDays: int(math.Round(interval)),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ExpectedDate.Before(result[j].ExpectedDate) })
+	return result
+}
+
+// detectDuplicateCharges flags recent expenses repeating an expense of the same amount and payee on the
+// same account within a day. Expenses without a label must share the category instead.
+func detectDuplicateCharges(a *analysisContext) []models.Insight {
+	var result []models.Insight
+	for i, expense := range a.expenses {
+		if !a.isRecent(expense) {
+			continue
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			original := a.expenses[j]
+			if expense.DateTime.Sub(original.DateTime) > duplicateWindow {
+				break
+			}
+			if original.AccountID != expense.AccountID || !original.Amount.Equal(expense.Amount) || original.Payee != expense.Payee {
+				continue
+			}
+			if expense.Payee == "" && (expense.CategoryID == nil || original.CategoryID == nil || *expense.CategoryID != *original.CategoryID) {
+				continue
+			}
+
+			name := strings.TrimSpace(original.Label)
+			if name == "" && original.CategoryName != nil {
+				name = *original.CategoryName
+			}
+			dates := original.DateTime.Format(time.DateOnly)
+			if day := expense.DateTime.Format(time.DateOnly); day != dates {
+				dates = fmt.Sprintf("%s and %s", dates, day)
+			}
+			transactionID := expense.ID
+			amount := expense.BaseAmount.Round(2)
+			result = append(result, models.Insight{
+				Type:     models.InsightDuplicateCharge,
+				Severity: models.InsightSeverityWarning,
+				Title:    fmt.Sprintf("Possible duplicate charge: %s", name),
+				Message: fmt.Sprintf("Two charges of %s for %s were made from the same account on %s.",
+					formatInsightAmount(expense.Amount, expense.CurrencyCode), name, dates),
+				Fingerprint:   fmt.Sprintf("%s:%d:%d", models.InsightDuplicateCharge, original.ID, expense.ID),
+				CategoryID:    expense.CategoryID,
+				TransactionID: &transactionID,
+				Amount:        &amount,
+				Details: insightDetails(map[string]interface{}{
+					"transactionIds": []int{original.ID, expense.ID},
+				}),
+			})
+			break
+		}
+	}
+
+	return result
+}
@@ -9,11 +9,16 @@ import (
 	"ypeskov/budget-go/internal/repositories/budgets"
 	"ypeskov/budget-go/internal/repositories/categories"
 	"ypeskov/budget-go/internal/repositories/currencies"
+	"ypeskov/budget-go/internal/repositories/envelopes"
 	"ypeskov/budget-go/internal/repositories/exchangeRates"
+	"ypeskov/budget-go/internal/repositories/goals"
+	"ypeskov/budget-go/internal/repositories/insights"
 	"ypeskov/budget-go/internal/repositories/languages"
+	"ypeskov/budget-go/internal/repositories/reportDefinitions"
 	"ypeskov/budget-go/internal/repositories/reports"
 	"ypeskov/budget-go/internal/repositories/transactions"
 	"ypeskov/budget-go/internal/repositories/user"
+	"ypeskov/budget-go/internal/repositories/userExchangeRates"
 	"ypeskov/budget-go/internal/repositories/userSettings"
 
 	"github.com/hibiken/asynq"
@@ -31,9 +36,15 @@ type Manager struct {
 	ExchangeRatesService   ExchangeRatesService
 	ReportsService         ReportsService
 	ChartService           ChartService
+	ReportExportService    ReportExportService
 	BackupService          BackupService
 	EmailService           EmailService
 	ActivationTokenService ActivationTokenService
+	GoalsService           GoalsService
+	EnvelopesService       EnvelopesService
+	DigestService          DigestService
+	InsightsService        InsightsService
+	DashboardService       DashboardService
 	QueueService           queue.QueueService
 }
 
@@ -44,6 +55,7 @@ func NewServicesManager(db *database.Database, cfg *config.Config) (*Manager, er
 
 	userRepo := user.New(db)
 	exchangeRatesRepo := exchangeRates.NewExchangeRatesRepository(db.Db)
+	userExchangeRatesRepo := userExchangeRates.NewUserExchangeRatesRepository(db.Db)
 	accountsRepo := accounts.NewAccountsService(db.Db)
 	budgetsRepo := budgets.NewBudgetsRepository(db.Db)
 	categoriesRepo := categories.NewCategoriesRepository(db.Db)
@@ -52,7 +64,11 @@ func NewServicesManager(db *database.Database, cfg *config.Config) (*Manager, er
 	languagesRepo := languages.NewLanguagesRepository(db.Db)
 	transactionsRepo := transactions.NewTransactionsRepository(db.Db)
 	reportsRepo := reports.NewReportsRepository(db.Db)
+	reportDefinitionsRepo := reportDefinitions.NewReportDefinitionsRepository(db.Db)
 	activationTokensRepo := activationTokens.New(db)
+	goalsRepo := goals.NewGoalsRepository(db.Db)
+	insightsRepo := insights.NewInsightsRepository(db.Db)
+	envelopesRepo := envelopes.NewEnvelopesRepository(db.Db)
 
 	sm = &Manager{}
 
@@ -61,15 +77,18 @@ func NewServicesManager(db *database.Database, cfg *config.Config) (*Manager, er
 	sm.UserService = NewUserService(userRepo, sm.QueueService)
 	sm.AccountsService = NewAccountsService(accountsRepo, sm)
 	sm.BudgetsService = NewBudgetsService(budgetsRepo, sm)
-	sm.CategoriesService = NewCategoriesService(categoriesRepo)
+	sm.CategoriesService = NewCategoriesService(categoriesRepo, sm)
 	sm.UserSettingsService = NewUserSettingsService(userSettingsRepo)
 	sm.CurrenciesService = NewCurrenciesService(currenciesRepo)
 	sm.LanguagesService = NewLanguagesService(languagesRepo)
-	sm.ExchangeRatesService = NewExchangeRatesService(exchangeRatesRepo, cfg)
+	sm.ExchangeRatesService = NewExchangeRatesService(exchangeRatesRepo, userExchangeRatesRepo, cfg)
 	sm.TransactionsService = NewTransactionsService(transactionsRepo, sm)
-	sm.ReportsService = NewReportsService(reportsRepo, sm.ExchangeRatesService)
+	sm.ReportsService = NewReportsService(reportsRepo, reportDefinitionsRepo, sm.ExchangeRatesService)
 	sm.ChartService = NewChartService()
+	sm.ReportExportService = NewReportExportService(sm.ChartService)
 	sm.BackupService = NewBackupService(cfg)
+	sm.GoalsService = NewGoalsService(goalsRepo, sm)
+	sm.EnvelopesService = NewEnvelopesService(envelopesRepo, sm)
 
 	sm.EmailService, err = NewEmailService(cfg)
 	if err != nil {
@@ -77,6 +96,9 @@ func NewServicesManager(db *database.Database, cfg *config.Config) (*Manager, er
 	}
 
 	sm.ActivationTokenService = NewActivationTokenService(activationTokensRepo, sm.EmailService)
+	sm.DigestService = NewDigestService(sm)
+	sm.InsightsService = NewInsightsService(insightsRepo, sm)
+	sm.DashboardService = NewDashboardService(sm)
 
 	return sm, nil
 }
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/models"
+)
+
+// PeriodAlignment describes where period boundaries fall for repeating budgets and report buckets.
+// Zero value means plain calendar periods (weeks start on Monday, months on the 1st).
+type PeriodAlignment struct {
+	// AnchorDay is the day of month (1-31) on which monthly, quarterly and yearly periods start.
+	// Months shorter than AnchorDay start on their last day.
+	AnchorDay int
+	// AnchorLastBusinessDay makes monthly, quarterly and yearly periods start on the last business day of a month
+	AnchorLastBusinessDay bool
+	// WeekStartDay is the weekday on which weekly periods start
+	WeekStartDay *time.Weekday
+}
+
+// NewPeriodAlignment validates raw alignment settings (as stored in budgets or sent by clients) for the period.
+// Month anchors only apply to monthly, quarterly and yearly periods and the week start only to weekly ones.
+func NewPeriodAlignment(period string, anchorDay *int, anchorLastBusinessDay bool, weekStartDay *int) (PeriodAlignment, error) {
+	alignment := PeriodAlignment{AnchorLastBusinessDay: anchorLastBusinessDay}
+
+	switch strings.ToUpper(period) {
+	case string(models.PeriodMonthly), string(models.PeriodQuarterly), string(models.PeriodYearly):
+		if weekStartDay != nil {
+			return PeriodAlignment{}, fmt.Errorf("invalid week start day: it only applies to weekly periods, not %s", strings.ToLower(period))
+		}
+	case string(models.PeriodWeekly):
+		if anchorDay != nil || anchorLastBusinessDay {
+			return PeriodAlignment{}, fmt.Errorf("invalid anchor: it only applies to monthly, quarterly and yearly periods, not weekly")
+		}
+	default:
+		if anchorDay != nil || anchorLastBusinessDay || weekStartDay != nil {
+			return PeriodAlignment{}, fmt.Errorf("invalid alignment: %s periods cannot be aligned", strings.ToLower(period))
+		}
+	}
+
+	if anchorDay != nil {
+		if *anchorDay < 1 || *anchorDay > 31 {
+			return PeriodAlignment{}, fmt.Errorf("invalid anchor day: %d. Must be between 1 and 31", *anchorDay)
+		}
+		if anchorLastBusinessDay {
+			return PeriodAlignment{}, fmt.Errorf("anchor day and last business day anchor cannot be used together")
+		}
+		alignment.AnchorDay = *anchorDay
+	}
+
+	if weekStartDay != nil {
+		if *weekStartDay < 0 || *weekStartDay > 6 {
+			return PeriodAlignment{}, fmt.Errorf("invalid week start day: %d. Must be between 0 (Sunday) and 6 (Saturday)", *weekStartDay)
+		}
+		weekday := time.Weekday(*weekStartDay)
+		alignment.WeekStartDay = &weekday
+	}
+
+	return alignment, nil
+}
+
+// BudgetPeriodAlignment returns the alignment stored in the budget. Invalid values are ignored.
+func BudgetPeriodAlignment(budget models.Budget) PeriodAlignment {
+	alignment, err := NewPeriodAlignment(budget.Period, budget.AnchorDay, budget.AnchorLastBusinessDay, budget.WeekStartDay)
+	if err != nil {
+		return PeriodAlignment{}
+	}
+	return alignment
+}
+
+// IsSet reports whether any non-calendar alignment is configured
+func (a PeriodAlignment) IsSet() bool {
+	return a.hasMonthAnchor() || a.WeekStartDay != nil
+}
+
+func (a PeriodAlignment) hasMonthAnchor() bool {
+	return a.AnchorDay > 0 || a.AnchorLastBusinessDay
+}
+
+func (a PeriodAlignment) weekStart() time.Weekday {
+	if a.WeekStartDay != nil {
+		return *a.WeekStartDay
+	}
+	return time.Monday
+}
+
+// anchorInMonth returns the period boundary that falls into the given month
+func (a PeriodAlignment) anchorInMonth(year int, month time.Month, loc *time.Location) time.Time {
+	// Normalize month overflow (e.g. month 13 is January of the next year)
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	if a.AnchorLastBusinessDay {
+		return LastBusinessDayOfMonth(first.Year(), first.Month(), loc)
+	}
+
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := a.AnchorDay
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, loc)
+}
+
+// LastBusinessDayOfMonth returns the last Monday-Friday day of the month
+func LastBusinessDayOfMonth(year int, month time.Month, loc *time.Location) time.Time {
+	day := time.Date(year, month+1, 0, 0, 0, 0, 0, loc)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+func periodMonths(period string) int {
+	switch strings.ToUpper(period) {
+	case string(models.PeriodQuarterly):
+		return 3
+	case string(models.PeriodYearly):
+		return 12
+	default:
+		return 1
+	}
+}
+
+func truncateToDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+// PeriodStart returns the start of the aligned period (daily, weekly, monthly, quarterly or yearly)
+// that contains the given date
+func PeriodStart(period string, date time.Time, alignment PeriodAlignment) time.Time {
+	day := truncateToDay(date)
+
+	switch strings.ToUpper(period) {
+	case string(models.PeriodDaily):
+		return day
+	case string(models.PeriodWeekly):
+		offset := (int(day.Weekday()) - int(alignment.weekStart()) + 7) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+
+	months := periodMonths(period)
+	if !alignment.hasMonthAnchor() {
+		// Calendar periods: months start on the 1st, quarters in January, April, July and October
+		month := int(day.Month()) - (int(day.Month())-1)%months
+		return time.Date(day.Year(), time.Month(month), 1, 0, 0, 0, 0, day.Location())
+	}
+
+	// Find the latest monthly boundary not after the date, then step back to the first month of its quarter/year
+	boundary := alignment.anchorInMonth(day.Year(), day.Month(), day.Location())
+	if boundary.After(day) {
+		boundary = alignment.anchorInMonth(day.Year(), day.Month()-1, day.Location())
+	}
+	month := int(boundary.Month()) - (int(boundary.Month())-1)%months
+	return alignment.anchorInMonth(boundary.Year(), time.Month(month), day.Location())
+}
+
+// NextPeriodStart returns the boundary that ends the period beginning at start.
+// Without alignment it keeps plain AddDate behavior; with alignment the period ends at the next
+// anchor, so a period that does not start on an anchor is shortened to get back in line.
+func NextPeriodStart(period string, start time.Time, alignment PeriodAlignment) (time.Time, error) {
+	switch strings.ToUpper(period) {
+	case string(models.PeriodDaily):
+		return start.AddDate(0, 0, 1), nil
+	case string(models.PeriodWeekly):
+		if alignment.WeekStartDay == nil {
+			return start.AddDate(0, 0, 7), nil
+		}
+		days := (int(*alignment.WeekStartDay) - int(start.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return start.AddDate(0, 0, days), nil
+	case string(models.PeriodMonthly), string(models.PeriodQuarterly), string(models.PeriodYearly):
+	default:
+		return time.Time{}, fmt.Errorf("invalid period for auto-renewal: %s", period)
+	}
+
+	months := periodMonths(period)
+	if !alignment.hasMonthAnchor() {
+		return start.AddDate(0, months, 0), nil
+	}
+
+	day := truncateToDay(start)
+	boundary := alignment.anchorInMonth(day.Year(), day.Month(), start.Location())
+	if !boundary.After(day) {
+		boundary = alignment.anchorInMonth(day.Year(), day.Month()+1, start.Location())
+	}
+	for i := 1; i < months; i++ {
+		boundary = alignment.anchorInMonth(boundary.Year(), boundary.Month()+1, start.Location())
+	}
+	return boundary, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ypeskov/budget-go/internal/config"
+	"ypeskov/budget-go/internal/logger"
+
+	"github.com/shopspring/decimal"
+)
+
+// Exchange rate provider names as configured in EXCHANGE_RATE_PROVIDERS
+const (
+	RateProviderCurrencyBeacon = "currencybeacon"
+	RateProviderECB            = "ecb"
+	RateProviderFile           = "file"
+)
+
+const (
+	// maxRateAge is how much older than the requested date rates may be, it covers
+	// weekends and holidays without publications but not a feed that stopped updating
+	maxRateAge = 7 * 24 * time.Hour
+	// rateRequestTimeout limits requests to the rate APIs
+	rateRequestTimeout = 30 * time.Second
+)
+
+// RateSnapshot is the rates of one day, a rate is the amount of the currency one unit of the base currency buys
+type RateSnapshot struct {
+	Date         time.Time
+	BaseCurrency string
+	Rates        map[string]decimal.Decimal
+	// Provider is stored as exchange_rates.service_name
+	Provider string
+}
+
+// RateProvider fetches exchange rates of a date, providers without rates for the date itself
+// return the latest earlier ones with their actual date
+type RateProvider interface {
+	Name() string
+	GetRates(date time.Time) (*RateSnapshot, error)
+}
+
+var rateHTTPClient = &http.Client{Timeout: rateRequestTimeout}
+
+// NewRateProvider returns the configured providers chained in priority order
+func NewRateProvider(cfg *config.Config) RateProvider {
+	chain := &rateProviderChain{}
+	for _, name := range strings.Split(cfg.ExchangeRateProvidersRaw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case RateProviderCurrencyBeacon:
+			chain.providers = append(chain.providers, NewCurrencyBeaconProvider(cfg))
+		case RateProviderECB:
+			chain.providers = append(chain.providers, NewECBRateProvider(cfg))
+		case RateProviderFile:
+			chain.providers = append(chain.providers, NewFileRateProvider(cfg))
+		default:
+			logger.Error("Unknown exchange rate provider, skipping it", "provider", name)
+		}
+	}
+
+	return chain
+}
+
+// rateProviderChain asks the providers in order and returns the rates of the first one that has them
+type rateProviderChain struct {
+	providers []RateProvider
+}
+
+func (c *rateProviderChain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *rateProviderChain) GetRates(date time.Time) (*RateSnapshot, error) {
+	if len(c.providers) == 0 {
+		return nil, fmt.Errorf("no exchange rate providers are configured")
+	}
+
+	// Providers work with calendar days, rates are stored with UTC dates
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	var errs []error
+	for _, provider := range c.providers {
+		snapshot, err := provider.GetRates(date)
+		if err == nil {
+			err = validateRateSnapshot(snapshot, date)
+		}
+		if err == nil {
+			return snapshot, nil
+		}
+
+		logger.Warn("Exchange rate provider failed, trying the next one", "provider", provider.Name(),
+			"date", date.Format(time.DateOnly), "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	return nil, fmt.Errorf("failed to fetch exchange rates for %s: %w", date.Format(time.DateOnly), errors.Join(errs...))
+}
+
+func validateRateSnapshot(snapshot *RateSnapshot, date time.Time) error {
+	if snapshot.BaseCurrency == "" || len(snapshot.Rates) == 0 {
+		return fmt.Errorf("no rates returned")
+	}
+	if snapshot.Date.After(date) {
+		return fmt.Errorf("rates of %s returned", snapshot.Date.Format(time.DateOnly))
+	}
+	if date.Sub(snapshot.Date) > maxRateAge {
+		return fmt.Errorf("latest rates are of %s", snapshot.Date.Format(time.DateOnly))
+	}
+	for code, rate := range snapshot.Rates {
+		if !rate.IsPositive() {
+			return fmt.Errorf("invalid rate of %s: %s", code, rate)
+		}
+	}
+	return nil
+}
+
+// latestSnapshot returns the snapshot with the latest date on or before the date
+func latestSnapshot(snapshots []RateSnapshot, date time.Time) (*RateSnapshot, error) {
+	var latest *RateSnapshot
+	for i := range snapshots {
+		if snapshots[i].Date.After(date) {
+			continue
+		}
+		if latest == nil || snapshots[i].Date.After(latest.Date) {
+			latest = &snapshots[i]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no rates on or before %s", date.Format(time.DateOnly))
+	}
+	return latest, nil
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/logger"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/shopspring/decimal"
+	"github.com/wcharczuk/go-chart/v2/roboto"
+	"github.com/xuri/excelize/v2"
+)
+
+// Report export formats, JSON stays the default response of the report endpoints
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatPDF  = "pdf"
+)
+
+// IsExportFormat reports whether the format is one of the file formats reports can be exported to
+func IsExportFormat(format string) bool {
+	switch format {
+	case ExportFormatCSV, ExportFormatXLSX, ExportFormatPDF:
+		return true
+	}
+	return false
+}
+
+// ReportDocument is a report flattened to a table for file export.
+// Cells are strings or float64 and decimal.Decimal amounts.
+type ReportDocument struct {
+	Title    string
+	Subtitle string
+	FileName string // without extension
+	Columns  []string
+	Rows     [][]interface{}
+	// Charts are only rendered into PDF
+	Charts []ReportChart
+}
+
+type ReportChart struct {
+	Title string
+	Data  []dto.ExpensesDiagramDataDTO
+}
+
+type ExportedFile struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+type ReportExportService interface {
+	Export(document ReportDocument, format string) (*ExportedFile, error)
+}
+
+type ReportExportServiceInstance struct {
+	chartService ChartService
+}
+
+var (
+	reportExportInstance *ReportExportServiceInstance
+	reportExportOnce     sync.Once
+)
+
+func NewReportExportService(chartService ChartService) ReportExportService {
+	reportExportOnce.Do(func() {
+		logger.Debug("Creating ReportExportService instance")
+		reportExportInstance = &ReportExportServiceInstance{
+			chartService: chartService,
+		}
+	})
+
+	return reportExportInstance
+}
+
+func (s *ReportExportServiceInstance) Export(document ReportDocument, format string) (*ExportedFile, error) {
+	var (
+		content     []byte
+		contentType string
+		err         error
+	)
+
+	switch format {
+	case ExportFormatCSV:
+		content, err = exportCSV(document)
+		contentType = "text/csv; charset=utf-8"
+	case ExportFormatXLSX:
+		content, err = exportXLSX(document)
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatPDF:
+		content, err = s.exportPDF(document)
+		contentType = "application/pdf"
+	default:
+		return nil, fmt.Errorf("invalid format: %s. Allowed values are json, csv, xlsx and pdf", format)
+	}
+	if err != nil {
+		logger.Error("Error exporting report", "format", format, "report", document.FileName, "error", err)
+		return nil, err
+	}
+
+	return &ExportedFile{
+		Name:        fmt.Sprintf("%s.%s", document.FileName, format),
+		ContentType: contentType,
+		Content:     content,
+	}, nil
+}
+
+// formatCell renders amounts with two decimals and everything else as is
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case decimal.Decimal:
+		return v.StringFixed(2)
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isAmount(value interface{}) bool {
+	switch value.(type) {
+	case float64, decimal.Decimal:
+		return true
+	}
+	return false
+}
+
+func exportCSV(document ReportDocument) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(document.Columns); err != nil {
+		return nil, err
+	}
+	for _, row := range document.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatCell(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func exportXLSX(document ReportDocument) ([]byte, error) {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	const sheet = "Report"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	titleStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return nil, err
+	}
+	headerStyle, err := file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E7E6E6"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	amountStyle, err := file.NewStyle(&excelize.Style{NumFmt: 4}) // #,##0.00
+	if err != nil {
+		return nil, err
+	}
+
+	if err = file.SetCellValue(sheet, "A1", document.Title); err != nil {
+		return nil, err
+	}
+	if err = file.SetCellStyle(sheet, "A1", "A1", titleStyle); err != nil {
+		return nil, err
+	}
+	if err = file.SetCellValue(sheet, "A2", document.Subtitle); err != nil {
+		return nil, err
+	}
+
+	// The table starts after the title, the subtitle and an empty row
+	const headerRow = 4
+	for i, column := range document.Columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, headerRow)
+		if err = file.SetCellValue(sheet, cell, column); err != nil {
+			return nil, err
+		}
+		if err = file.SetCellStyle(sheet, cell, cell, headerStyle); err != nil {
+			return nil, err
+		}
+		colName, _ := excelize.ColumnNumberToName(i + 1)
+		width := 16.0
+		if i == 0 {
+			width = 36
+		}
+		if err = file.SetColWidth(sheet, colName, colName, width); err != nil {
+			return nil, err
+		}
+	}
+
+	for r, row := range document.Rows {
+		for i, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(i+1, headerRow+1+r)
+			// Spreadsheet numbers are floating point, the cell format shows the cents
+			if amount, ok := value.(decimal.Decimal); ok {
+				value = amount.InexactFloat64()
+			}
+			if err = file.SetCellValue(sheet, cell, value); err != nil {
+				return nil, err
+			}
+			if isAmount(value) {
+				if err = file.SetCellStyle(sheet, cell, cell, amountStyle); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	buffer, err := file.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// exportPDF renders the table followed by the charts on A4 pages. Roboto is embedded
+// because the core PDF fonts cannot show non-Latin category names.
+func (s *ReportExportServiceInstance) exportPDF(document ReportDocument) ([]byte, error) {
+	const (
+		font       = "Roboto"
+		margin     = 15.0
+		rowHeight  = 7.0
+		chartWidth = 110.0
+	)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(font, "", roboto.Roboto)
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AliasNbPages("")
+
+	generated := time.Now().Format("2006-01-02 15:04")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont(font, "", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s, generated %s", document.Title, generated), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	usableWidth := pageWidth - 2*margin
+
+	pdf.SetFont(font, "", 16)
+	pdf.CellFormat(0, 10, document.Title, "", 1, "L", false, 0, "")
+	if document.Subtitle != "" {
+		pdf.SetFont(font, "", 10)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(0, 6, document.Subtitle, "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(4)
+
+	// The first column holds names and gets twice the width of the amount columns
+	widths := make([]float64, len(document.Columns))
+	if len(widths) > 0 {
+		unit := usableWidth / float64(len(widths)+1)
+		for i := range widths {
+			widths[i] = unit
+		}
+		widths[0] = 2 * unit
+	}
+
+	header := func() {
+		pdf.SetFont(font, "", 9)
+		pdf.SetFillColor(231, 230, 230)
+		for i, column := range document.Columns {
+			pdf.CellFormat(widths[i], rowHeight, fitText(pdf, column, widths[i]), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	header()
+
+	for _, row := range document.Rows {
+		if pdf.GetY()+rowHeight > pageHeight-margin {
+			pdf.AddPage()
+			header()
+		}
+		for i, value := range row {
+			if i >= len(widths) {
+				break
+			}
+			align := "L"
+			if isAmount(value) {
+				align = "R"
+			}
+			pdf.CellFormat(widths[i], rowHeight, fitText(pdf, formatCell(value), widths[i]), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	for i, reportChart := range document.Charts {
+		image, err := s.chartService.RenderPieChart(reportChart.Data, "")
+		if err != nil {
+			// A period without data has no chart, the table already says it all
+			logger.Debug("Skipping report chart", "chart", reportChart.Title, "error", err)
+			continue
+		}
+
+		if pdf.GetY()+chartWidth+15 > pageHeight-margin {
+			pdf.AddPage()
+		}
+		pdf.Ln(6)
+		pdf.SetFont(font, "", 12)
+		pdf.CellFormat(0, 8, reportChart.Title, "", 1, "L", false, 0, "")
+
+		name := fmt.Sprintf("chart%d", i)
+		options := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(image))
+		pdf.ImageOptions(name, margin+(usableWidth-chartWidth)/2, pdf.GetY(), chartWidth, 0, true, options, 0, "")
+	}
+
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// fitText shortens the text with an ellipsis until it fits into the cell width
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	const padding = 2.0
+	if pdf.GetStringWidth(text) <= width-padding {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"…") > width-padding {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
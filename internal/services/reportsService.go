@@ -1,12 +1,15 @@
 package services
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 	"ypeskov/budget-go/internal/dto"
 	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/repositories/reportDefinitions"
 	"ypeskov/budget-go/internal/repositories/reports"
 	"ypeskov/budget-go/internal/utils"
 
@@ -19,11 +22,29 @@ type ReportsService interface {
 	GetNonHiddenBalanceReport(userID int, input dto.BalanceReportInputDTO) ([]dto.BalanceReportOutputDTO, error)
 	GetExpensesByCategories(userID int, input dto.ExpensesReportInputDTO) ([]dto.ExpensesReportOutputItemDTO, error)
 	GetExpensesDiagramData(userID int, startDate, endDate time.Time) ([]dto.ExpensesDiagramDataDTO, error)
+	GetIncomeByCategories(userID int, input dto.IncomeReportInputDTO) ([]dto.IncomeReportOutputItemDTO, error)
+	GetIncomeDiagramData(userID int, startDate, endDate time.Time) ([]dto.ExpensesDiagramDataDTO, error)
+	// GetIncomeVsExpenses returns income and expenses of the period aggregated by top-level category
+	GetIncomeVsExpenses(userID int, input dto.IncomeVsExpensesInputDTO) (*dto.IncomeVsExpensesOutputDTO, error)
+	ComparePeriods(userID int, input dto.CompareReportInputDTO) (*dto.CompareReportOutputDTO, error)
+	// GetLargestTransactions returns the income and expense transactions of the period with the largest
+	// amounts in the base currency, largest first
+	GetLargestTransactions(userID int, startDate, endDate time.Time, limit int) ([]dto.ReportTransactionDTO, error)
+	// GetBalanceHistory returns the balance at the end of each period, of one account or of all accounts (net worth)
+	GetBalanceHistory(userID int, startDate, endDate time.Time, period string, accountID *int) (*dto.BalanceHistoryDTO, error)
+	RunCustomReport(userID int, input dto.CustomReportInputDTO) (*dto.CustomReportOutputDTO, error)
+	GetReportDefinitions(userID int) ([]dto.ReportDefinitionDTO, error)
+	GetReportDefinition(userID int, definitionID int) (*dto.ReportDefinitionDTO, error)
+	CreateReportDefinition(userID int, input dto.SaveReportDefinitionDTO) (*dto.ReportDefinitionDTO, error)
+	UpdateReportDefinition(userID int, definitionID int, input dto.SaveReportDefinitionDTO) (*dto.ReportDefinitionDTO, error)
+	DeleteReportDefinition(userID int, definitionID int) error
+	RunReportDefinition(userID int, definitionID int, startDate, endDate *utils.CustomDate) (*dto.CustomReportOutputDTO, error)
 }
 
 type ReportsServiceInstance struct {
-	reportsRepo          *reports.ReportsRepository
-	exchangeRatesService ExchangeRatesService
+	reportsRepo           *reports.ReportsRepository
+	reportDefinitionsRepo reportDefinitions.Repository
+	exchangeRatesService  ExchangeRatesService
 }
 
 var (
@@ -31,12 +52,14 @@ var (
 	reportsOnce     sync.Once
 )
 
-func NewReportsService(reportsRepo *reports.ReportsRepository, exchangeRatesService ExchangeRatesService) ReportsService {
+func NewReportsService(reportsRepo *reports.ReportsRepository, reportDefinitionsRepo reportDefinitions.Repository,
+	exchangeRatesService ExchangeRatesService) ReportsService {
 	reportsOnce.Do(func() {
 		logger.Debug("Creating ReportsService instance")
 		reportsInstance = &ReportsServiceInstance{
-			reportsRepo:          reportsRepo,
-			exchangeRatesService: exchangeRatesService,
+			reportsRepo:           reportsRepo,
+			reportDefinitionsRepo: reportDefinitionsRepo,
+			exchangeRatesService:  exchangeRatesService,
 		}
 	})
 
@@ -50,8 +73,20 @@ func (s *ReportsServiceInstance) GetCashFlow(userID int, input dto.CashFlowRepor
 		return nil, err
 	}
 
+	alignment, err := NewPeriodAlignment(input.Period, input.AnchorDay, input.AnchorLastBusinessDay, input.WeekStartDay)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aligned periods (payday cycles, custom week start) can't be expressed with TO_CHAR,
+	// so daily data is fetched and bucketed into aligned periods here
+	rawInput := input
+	if alignment.IsSet() {
+		rawInput.Period = "daily"
+	}
+
 	// Get raw data per account and currency
-	rawData, err := s.reportsRepo.GetCashFlowRawData(userID, input)
+	rawData, err := s.reportsRepo.GetCashFlowRawData(userID, rawInput)
 	if err != nil {
 		return nil, err
 	}
@@ -64,12 +99,14 @@ func (s *ReportsServiceInstance) GetCashFlow(userID int, input dto.CashFlowRepor
 	for _, data := range rawData {
 		// Parse period date for exchange rate lookup (use mid-period date)
 		var periodDate time.Time
-		switch input.Period {
+		switch rawInput.Period {
 		case "monthly":
 			periodDate, _ = time.Parse("2006-01", data.Period)
 			periodDate = periodDate.AddDate(0, 0, 15) // Mid-month
 		case "daily":
 			periodDate, _ = time.Parse("2006-01-02", data.Period)
+		case "quarterly":
+			periodDate = parseQuarterKey(data.Period).AddDate(0, 1, 15) // Mid-quarter
 		default:
 			periodDate, _ = time.Parse("2006-01", data.Period)
 			periodDate = periodDate.AddDate(0, 0, 15) // Mid-month
@@ -77,53 +114,60 @@ func (s *ReportsServiceInstance) GetCashFlow(userID int, input dto.CashFlowRepor
 
 		// Convert income to base currency
 		convertedIncome, err := s.exchangeRatesService.CalcAmountFromCurrency(
-			periodDate, data.TotalIncome, data.CurrencyCode, baseCurrencyCode)
+			userID, periodDate, data.TotalIncome, data.CurrencyCode, baseCurrencyCode)
 		if err != nil {
 			// If conversion fails, use original amount (this matches FastAPI behavior)
 			convertedIncome = data.TotalIncome
 		}
 		// Convert expenses to base currency
 		convertedExpenses, err := s.exchangeRatesService.CalcAmountFromCurrency(
-			periodDate, data.TotalExpenses, data.CurrencyCode, baseCurrencyCode)
+			userID, periodDate, data.TotalExpenses, data.CurrencyCode, baseCurrencyCode)
 		if err != nil {
 			// If conversion fails, use original amount
 			convertedExpenses = data.TotalExpenses
 		}
 
+		// Aligned periods are keyed by their start date
+		period := data.Period
+		if alignment.IsSet() {
+			period = PeriodStart(input.Period, periodDate, alignment).Format(time.DateOnly)
+		}
+
 		// Accumulate by period
-		if _, exists := totalIncome[data.Period]; !exists {
-			totalIncome[data.Period] = decimal.Zero
-			totalExpenses[data.Period] = decimal.Zero
+		if _, exists := totalIncome[period]; !exists {
+			totalIncome[period] = decimal.Zero
+			totalExpenses[period] = decimal.Zero
 		}
 
-		totalIncome[data.Period] = totalIncome[data.Period].Add(convertedIncome)
-		totalExpenses[data.Period] = totalExpenses[data.Period].Add(convertedExpenses)
-		netFlow[data.Period] = totalIncome[data.Period].Sub(totalExpenses[data.Period])
+		totalIncome[period] = totalIncome[period].Add(convertedIncome)
+		totalExpenses[period] = totalExpenses[period].Add(convertedExpenses)
+		netFlow[period] = totalIncome[period].Sub(totalExpenses[period])
 	}
 
-	// Convert decimals to float64 for JSON marshaling (round to 2 decimal places for financial precision)
-	totalIncomeFloat := make(map[string]float64)
-	totalExpensesFloat := make(map[string]float64)
-	netFlowFloat := make(map[string]float64)
-
-	for period, income := range totalIncome {
-		totalIncomeFloat[period], _ = income.Round(2).Float64()
-	}
-	for period, expenses := range totalExpenses {
-		totalExpensesFloat[period], _ = expenses.Round(2).Float64()
-	}
-	for period, net := range netFlow {
-		netFlowFloat[period], _ = net.Round(2).Float64()
+	// Round the converted totals to cents, net flow is computed from the rounded totals so it always adds up
+	for period := range totalIncome {
+		totalIncome[period] = totalIncome[period].Round(2)
+		totalExpenses[period] = totalExpenses[period].Round(2)
+		netFlow[period] = totalIncome[period].Sub(totalExpenses[period])
 	}
 
 	return &dto.CashFlowReportOutputDTO{
 		Currency:      baseCurrencyCode,
-		TotalIncome:   totalIncomeFloat,
-		TotalExpenses: totalExpensesFloat,
-		NetFlow:       netFlowFloat,
+		TotalIncome:   totalIncome,
+		TotalExpenses: totalExpenses,
+		NetFlow:       netFlow,
 	}, nil
 }
 
+// parseQuarterKey parses cash flow quarter keys like "2024-Q2" into the first day of the quarter
+func parseQuarterKey(key string) time.Time {
+	var year, quarter int
+	if _, err := fmt.Sscanf(key, "%d-Q%d", &year, &quarter); err != nil || quarter < 1 || quarter > 4 {
+		return time.Time{}
+	}
+	return time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
 func (s *ReportsServiceInstance) GetBalanceReport(userID int, input dto.BalanceReportInputDTO) ([]dto.BalanceReportOutputDTO, error) {
 	results, err := s.reportsRepo.GetBalanceReport(userID, input)
 	if err != nil {
@@ -133,8 +177,9 @@ func (s *ReportsServiceInstance) GetBalanceReport(userID int, input dto.BalanceR
 	// Convert each account balance into user's base currency using the balance date
 	// Repository already filled BaseCurrencyCode; use that as conversion target
 	for i := range results {
-		amountDec := decimal.NewFromFloat(results[i].Balance)
+		amountDec := results[i].Balance
 		converted, convErr := s.exchangeRatesService.CalcAmountFromCurrency(
+			userID,
 			input.BalanceDate.Time,
 			amountDec,
 			results[i].CurrencyCode,
@@ -144,7 +189,7 @@ func (s *ReportsServiceInstance) GetBalanceReport(userID int, input dto.BalanceR
 			// Fallback to original balance if conversion fails
 			converted = amountDec
 		}
-		results[i].BaseCurrencyBalance, _ = converted.Round(2).Float64()
+		results[i].BaseCurrencyBalance = converted.Round(2)
 	}
 
 	return results, nil
@@ -157,8 +202,9 @@ func (s *ReportsServiceInstance) GetNonHiddenBalanceReport(userID int, input dto
 	}
 
 	for i := range results {
-		amountDec := decimal.NewFromFloat(results[i].Balance)
+		amountDec := results[i].Balance
 		converted, convErr := s.exchangeRatesService.CalcAmountFromCurrency(
+			userID,
 			input.BalanceDate.Time,
 			amountDec,
 			results[i].CurrencyCode,
@@ -167,7 +213,7 @@ func (s *ReportsServiceInstance) GetNonHiddenBalanceReport(userID int, input dto
 		if convErr != nil {
 			converted = amountDec
 		}
-		results[i].BaseCurrencyBalance, _ = converted.Round(2).Float64()
+		results[i].BaseCurrencyBalance = converted.Round(2)
 	}
 
 	return results, nil
@@ -194,7 +240,7 @@ func (s *ReportsServiceInstance) GetExpensesByCategories(userID int, input dto.E
 	// Build index for quick lookup
 	byID := make(map[int]*dto.ExpensesReportOutputItemDTO)
 	for i := range categories {
-		categories[i].TotalExpenses = 0
+		categories[i].TotalExpenses = decimal.Zero
 	}
 	for i := range categories {
 		byID[categories[i].ID] = &categories[i]
@@ -207,21 +253,9 @@ func (s *ReportsServiceInstance) GetExpensesByCategories(userID int, input dto.E
 	}
 
 	// Sum converted amounts into categories
-	for _, row := range rawRows {
-		// Skip transactions without a category (NULL category_id)
-		if row.CategoryID == nil {
-			continue
-		}
-
-		// Convert each transaction amount to base currency using its date
-		converted, convErr := s.exchangeRatesService.CalcAmountFromCurrency(row.DateTime, decimal.NewFromFloat(row.Amount), row.CurrencyCode, baseCurrency)
-		if convErr != nil {
-			// Fallback to original amount if conversion fails (parity with FastAPI)
-			converted = decimal.NewFromFloat(row.Amount)
-		}
-		if cat, ok := byID[*row.CategoryID]; ok {
-			val, _ := converted.Float64()
-			cat.TotalExpenses += val
+	for categoryID, total := range s.sumConvertedByCategory(userID, rawRows, baseCurrency) {
+		if cat, ok := byID[categoryID]; ok {
+			cat.TotalExpenses = cat.TotalExpenses.Add(total)
 			cat.CurrencyCode = &baseCurrency
 		}
 	}
@@ -229,7 +263,7 @@ func (s *ReportsServiceInstance) GetExpensesByCategories(userID int, input dto.E
 	// Apply hideEmptyCategories filter
 	result := make([]dto.ExpensesReportOutputItemDTO, 0, len(categories))
 	for _, c := range categories {
-		if input.HideEmptyCategories && c.TotalExpenses == 0 {
+		if input.HideEmptyCategories && c.TotalExpenses.IsZero() {
 			continue
 		}
 		result = append(result, c)
@@ -262,16 +296,9 @@ func (s *ReportsServiceInstance) GetExpensesDiagramData(userID int, startDate, e
 	combined := combineSmallCategories(data, 0.02)
 
 	// Sort by amount descending
-	sort.Slice(combined, func(i, j int) bool { return combined[i].Amount > combined[j].Amount })
-
-	// Assign colors deterministically after combining and map proper field names
-	colors := []string{"#FF6384", "#36A2EB", "#FFCE56", "#4BC0C0", "#9966FF", "#FF9F40", "#FF6384", "#C9CBCF"}
-	for i := range combined {
-		// Ensure field naming parity: use Label instead of categoryName in downstream usage
-		// Here we keep CategoryName for internal chart service; the /expenses-data endpoint will output AggregatedDiagramItemDTO
-		combined[i].Color = colors[i%len(colors)]
-	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].Amount.GreaterThan(combined[j].Amount) })
 
+	// Colors come from the categories, so a category keeps its color across charts
 	return combined, nil
 }
 
@@ -293,13 +320,15 @@ func prepareDiagramData(items []dto.ExpensesReportOutputItemDTO) []dto.ExpensesD
 		total := parent.TotalExpenses
 		for _, it := range items {
 			if it.ParentID != nil && parent.ID == *it.ParentID {
-				total += it.TotalExpenses
+				total = total.Add(it.TotalExpenses)
 			}
 		}
 
 		result = append(result, dto.ExpensesDiagramDataDTO{
 			CategoryName: parent.Name, // label is parent name in Python builder
 			Amount:       total,
+			Color:        parent.Color,
+			Icon:         parent.Icon,
 		})
 	}
 
@@ -314,29 +343,267 @@ func combineSmallCategories(data []dto.ExpensesDiagramDataDTO, threshold float64
 	}
 
 	// Compute total amount
-	var total float64
+	total := decimal.Zero
 	for _, d := range data {
-		total += d.Amount
+		total = total.Add(d.Amount)
 	}
-	if total <= 0 {
+	if !total.IsPositive() {
 		return data
 	}
 
 	// Separate large and small categories
 	large := make([]dto.ExpensesDiagramDataDTO, 0, len(data))
-	var otherAmount float64
+	otherAmount := decimal.Zero
 	for _, d := range data {
-		size := d.Amount / total
-		if size < threshold {
-			otherAmount += d.Amount
+		size := d.Amount.Div(total)
+		if size.LessThan(decimal.NewFromFloat(threshold)) {
+			otherAmount = otherAmount.Add(d.Amount)
 		} else {
-			large = append(large, dto.ExpensesDiagramDataDTO{CategoryName: d.CategoryName, Amount: d.Amount})
+			large = append(large, d)
 		}
 	}
 
-	if otherAmount > 0 {
-		large = append(large, dto.ExpensesDiagramDataDTO{CategoryName: "Other", Amount: otherAmount})
+	if otherAmount.IsPositive() {
+		large = append(large, dto.ExpensesDiagramDataDTO{CategoryName: "Other", Amount: otherAmount, Color: models.CategoryOtherColor})
 	}
 
 	return large
 }
+
+func (s *ReportsServiceInstance) GetIncomeByCategories(userID int, input dto.IncomeReportInputDTO) ([]dto.IncomeReportOutputItemDTO, error) {
+	baseCurrency, err := s.reportsRepo.GetUserBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.reportsRepo.GetIncomeCategories(userID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	rawRows, err := s.reportsRepo.GetRawIncomeRows(userID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := s.sumConvertedByCategory(userID, rawRows, baseCurrency)
+
+	result := make([]dto.IncomeReportOutputItemDTO, 0, len(categories))
+	for _, c := range categories {
+		if total, ok := totals[c.ID]; ok {
+			c.TotalIncome = total
+			c.CurrencyCode = &baseCurrency
+		}
+		if input.HideEmptyCategories && c.TotalIncome.IsZero() {
+			continue
+		}
+		result = append(result, c)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+
+	return result, nil
+}
+
+func (s *ReportsServiceInstance) GetIncomeDiagramData(userID int, startDate, endDate time.Time) ([]dto.ExpensesDiagramDataDTO, error) {
+	items, err := s.GetIncomeByCategories(userID, dto.IncomeReportInputDTO{
+		StartDate: utils.CustomDate{Time: startDate},
+		EndDate:   utils.CustomDate{Time: endDate},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregated := aggregateByParent(incomeCategoryAmounts(items))
+	data := make([]dto.ExpensesDiagramDataDTO, 0, len(aggregated))
+	for _, item := range aggregated {
+		data = append(data, dto.ExpensesDiagramDataDTO{
+			CategoryName: item.Label,
+			Amount:       item.Amount,
+			Color:        item.Color,
+			Icon:         item.Icon,
+		})
+	}
+
+	combined := combineSmallCategories(data, 0.02)
+	sort.Slice(combined, func(i, j int) bool { return combined[i].Amount.GreaterThan(combined[j].Amount) })
+
+	return combined, nil
+}
+
+func (s *ReportsServiceInstance) GetIncomeVsExpenses(userID int, input dto.IncomeVsExpensesInputDTO) (*dto.IncomeVsExpensesOutputDTO, error) {
+	baseCurrency, err := s.reportsRepo.GetUserBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reportInput := dto.ExpensesReportInputDTO{StartDate: input.StartDate, EndDate: input.EndDate}
+
+	expenseItems, err := s.GetExpensesByCategories(userID, reportInput)
+	if err != nil {
+		return nil, err
+	}
+	incomeItems, err := s.GetIncomeByCategories(userID, reportInput)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dto.IncomeVsExpensesOutputDTO{
+		CurrencyCode: baseCurrency,
+		Income:       aggregateByParent(incomeCategoryAmounts(incomeItems)),
+		Expenses:     aggregateByParent(expenseCategoryAmounts(expenseItems)),
+	}
+	for _, it := range output.Income {
+		output.TotalIncome = output.TotalIncome.Add(it.Amount)
+	}
+	for _, it := range output.Expenses {
+		output.TotalExpenses = output.TotalExpenses.Add(it.Amount)
+	}
+
+	output.TotalIncome = output.TotalIncome.Round(2)
+	output.TotalExpenses = output.TotalExpenses.Round(2)
+	output.Net = output.TotalIncome.Sub(output.TotalExpenses)
+
+	return output, nil
+}
+
+func (s *ReportsServiceInstance) GetLargestTransactions(userID int, startDate, endDate time.Time, limit int) ([]dto.ReportTransactionDTO, error) {
+	baseCurrency, err := s.reportsRepo.GetUserBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.reportsRepo.GetTransactionRows(userID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]dto.ReportTransactionDTO, 0, len(rows))
+	for _, row := range rows {
+		converted, err := s.exchangeRatesService.CalcAmountFromCurrency(userID, row.DateTime, row.Amount, row.CurrencyCode, baseCurrency)
+		if err != nil {
+			// If conversion fails, use original amount like the other reports
+			converted = row.Amount
+		}
+		baseAmount := converted.Round(2)
+
+		transactions = append(transactions, dto.ReportTransactionDTO{
+			ID:               row.ID,
+			DateTime:         row.DateTime,
+			Label:            row.Label,
+			CategoryName:     row.CategoryName,
+			IsIncome:         row.IsIncome,
+			Amount:           row.Amount,
+			CurrencyCode:     row.CurrencyCode,
+			BaseAmount:       baseAmount,
+			BaseCurrencyCode: baseCurrency,
+		})
+	}
+
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].BaseAmount.GreaterThan(transactions[j].BaseAmount)
+	})
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+
+	return transactions, nil
+}
+
+// sumConvertedByCategory converts every transaction to the base currency using its date and sums them by category
+func (s *ReportsServiceInstance) sumConvertedByCategory(userID int, rows []reports.ExpenseRawRow, baseCurrency string) map[int]decimal.Decimal {
+	totals := make(map[int]decimal.Decimal)
+	for _, row := range rows {
+		// Skip transactions without a category (NULL category_id)
+		if row.CategoryID == nil {
+			continue
+		}
+
+		converted, convErr := s.exchangeRatesService.CalcAmountFromCurrency(userID, row.DateTime, row.Amount, row.CurrencyCode, baseCurrency)
+		if convErr != nil {
+			// Fallback to original amount if conversion fails (parity with FastAPI)
+			converted = row.Amount
+		}
+		totals[*row.CategoryID] = totals[*row.CategoryID].Add(converted)
+	}
+
+	return totals
+}
+
+// categoryAmount is a category total of a report, the common ground of expense and income items
+type categoryAmount struct {
+	ID       int
+	ParentID *int
+	Name     string
+	Color    string
+	Icon     string
+	Amount   decimal.Decimal
+}
+
+func expenseCategoryAmounts(items []dto.ExpensesReportOutputItemDTO) []categoryAmount {
+	amounts := make([]categoryAmount, 0, len(items))
+	for _, it := range items {
+		amounts = append(amounts, categoryAmount{
+			ID: it.ID, ParentID: it.ParentID, Name: it.Name, Color: it.Color, Icon: it.Icon, Amount: it.TotalExpenses,
+		})
+	}
+	return amounts
+}
+
+func incomeCategoryAmounts(items []dto.IncomeReportOutputItemDTO) []categoryAmount {
+	amounts := make([]categoryAmount, 0, len(items))
+	for _, it := range items {
+		amounts = append(amounts, categoryAmount{
+			ID: it.ID, ParentID: it.ParentID, Name: it.Name, Color: it.Color, Icon: it.Icon, Amount: it.TotalIncome,
+		})
+	}
+	return amounts
+}
+
+// aggregateByParent rolls subcategories into their top-level category and returns the non-empty
+// totals sorted by amount. A subcategory whose parent is not in the report stands on its own.
+func aggregateByParent(items []categoryAmount) []dto.AggregatedDiagramItemDTO {
+	parents := make(map[int]categoryAmount)
+	for _, it := range items {
+		if it.ParentID == nil {
+			parents[it.ID] = it
+		}
+	}
+
+	totals := make(map[int]dto.AggregatedDiagramItemDTO)
+	for _, it := range items {
+		bucket := it
+		if it.ParentID != nil {
+			if parent, ok := parents[*it.ParentID]; ok {
+				bucket = parent
+			}
+		}
+
+		agg := totals[bucket.ID]
+		agg.CategoryID = bucket.ID
+		agg.Label = bucket.Name
+		agg.Color = bucket.Color
+		agg.Icon = bucket.Icon
+		agg.Amount = agg.Amount.Add(it.Amount)
+		totals[bucket.ID] = agg
+	}
+
+	result := make([]dto.AggregatedDiagramItemDTO, 0, len(totals))
+	for _, agg := range totals {
+		if !agg.Amount.IsPositive() {
+			continue
+		}
+		agg.Amount = agg.Amount.Round(2)
+		result = append(result, agg)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Amount.Equal(result[j].Amount) {
+			return result[i].Label < result[j].Label
+		}
+		return result[i].Amount.GreaterThan(result[j].Amount)
+	})
+
+	return result
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxComparePeriods limits the number of periods of a comparison report
+const MaxComparePeriods = 24
+
+const (
+	CompareToPrevious = "previous"
+	CompareToFirst    = "first"
+)
+
+// ComparePeriods returns the expense (or income) amounts of every category in each of the periods
+// together with the changes between the periods. The amounts come from the category reports,
+// so they are converted to the base currency by transaction date like there.
+func (s *ReportsServiceInstance) ComparePeriods(userID int, input dto.CompareReportInputDTO) (*dto.CompareReportOutputDTO, error) {
+	compareTo := input.CompareTo
+	if compareTo == "" {
+		compareTo = CompareToPrevious
+	}
+	if compareTo != CompareToPrevious && compareTo != CompareToFirst {
+		return nil, fmt.Errorf("invalid compareTo: %s. Allowed values are %s and %s", compareTo, CompareToPrevious, CompareToFirst)
+	}
+
+	periods, err := comparePeriodRanges(input, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	baseCurrency, err := s.reportsRepo.GetUserBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[int]*dto.CompareCategoryItemDTO)
+	totals := make([]decimal.Decimal, len(periods))
+	for i, period := range periods {
+		items, err := s.periodCategoryAmounts(userID, input, period)
+		if err != nil {
+			return nil, err
+		}
+
+		present := make(map[int]bool, len(items))
+		for _, it := range items {
+			present[it.ID] = true
+		}
+
+		for _, it := range items {
+			row, ok := rows[it.ID]
+			if !ok {
+				row = &dto.CompareCategoryItemDTO{
+					ID:       it.ID,
+					Name:     it.Name,
+					ParentID: it.ParentID,
+					IsParent: it.ParentID == nil,
+					Color:    it.Color,
+					Icon:     it.Icon,
+					Amounts:  make([]decimal.Decimal, len(periods)),
+				}
+				rows[it.ID] = row
+			}
+			row.Amounts[i] = row.Amounts[i].Add(it.Amount)
+			totals[i] = totals[i].Add(it.Amount)
+		}
+
+		// Top-level categories also carry the amounts of their subcategories
+		for _, it := range items {
+			if it.ParentID != nil && present[*it.ParentID] {
+				parent := rows[*it.ParentID]
+				parent.Amounts[i] = parent.Amounts[i].Add(it.Amount)
+			}
+		}
+	}
+
+	categories := make([]dto.CompareCategoryItemDTO, 0, len(rows))
+	for _, row := range rows {
+		empty := true
+		for j := range row.Amounts {
+			row.Amounts[j] = row.Amounts[j].Round(2)
+			if !row.Amounts[j].IsZero() {
+				empty = false
+			}
+		}
+		if input.HideEmptyCategories && empty {
+			continue
+		}
+		row.Changes = compareChanges(row.Amounts, compareTo)
+		categories = append(categories, *row)
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		return strings.ToLower(categories[i].Name) < strings.ToLower(categories[j].Name)
+	})
+
+	for i := range totals {
+		totals[i] = totals[i].Round(2)
+	}
+
+	return &dto.CompareReportOutputDTO{
+		CurrencyCode: baseCurrency,
+		IsIncome:     input.IsIncome,
+		CompareTo:    compareTo,
+		Periods:      periods,
+		Totals:       totals,
+		TotalChanges: compareChanges(totals, compareTo),
+		Categories:   categories,
+	}, nil
+}
+
+// periodCategoryAmounts returns the own amount of every category in the period
+func (s *ReportsServiceInstance) periodCategoryAmounts(userID int, input dto.CompareReportInputDTO, period dto.ComparePeriodDTO) ([]categoryAmount, error) {
+	reportInput := dto.ExpensesReportInputDTO{
+		StartDate:  period.StartDate,
+		EndDate:    period.EndDate,
+		Categories: input.Categories,
+	}
+
+	if input.IsIncome {
+		items, err := s.GetIncomeByCategories(userID, reportInput)
+		if err != nil {
+			return nil, err
+		}
+		return incomeCategoryAmounts(items), nil
+	}
+
+	items, err := s.GetExpensesByCategories(userID, reportInput)
+	if err != nil {
+		return nil, err
+	}
+	return expenseCategoryAmounts(items), nil
+}
+
+// comparePeriodRanges returns the explicit periods of the input or generates Count consecutive periods
+// of the Period type, oldest first, ending with the period that contains EndDate (now when not set)
+func comparePeriodRanges(input dto.CompareReportInputDTO, now time.Time) ([]dto.ComparePeriodDTO, error) {
+	if len(input.Periods) > 0 {
+		if len(input.Periods) < 2 || len(input.Periods) > MaxComparePeriods {
+			return nil, fmt.Errorf("invalid number of periods: %d. Must be between 2 and %d", len(input.Periods), MaxComparePeriods)
+		}
+		for _, period := range input.Periods {
+			if period.StartDate.IsZero() || period.EndDate.IsZero() || period.EndDate.Before(period.StartDate.Time) {
+				return nil, fmt.Errorf("invalid period: start and end dates are required and the end cannot be before the start")
+			}
+		}
+		return input.Periods, nil
+	}
+
+	if !models.ValidatePeriod(input.Period) || strings.EqualFold(input.Period, string(models.PeriodCustom)) {
+		return nil, fmt.Errorf("invalid period: %s. Provide periods or one of daily, weekly, monthly, quarterly, yearly", input.Period)
+	}
+	if input.Count < 2 || input.Count > MaxComparePeriods {
+		return nil, fmt.Errorf("invalid count: %d. Must be between 2 and %d", input.Count, MaxComparePeriods)
+	}
+
+	alignment, err := NewPeriodAlignment(input.Period, input.AnchorDay, input.AnchorLastBusinessDay, input.WeekStartDay)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := now
+	if input.EndDate != nil && !input.EndDate.IsZero() {
+		reference = input.EndDate.Time
+	}
+
+	periods := make([]dto.ComparePeriodDTO, input.Count)
+	start := PeriodStart(input.Period, reference, alignment)
+	for i := input.Count - 1; i >= 0; i-- {
+		next, err := NextPeriodStart(input.Period, start, alignment)
+		if err != nil {
+			return nil, err
+		}
+		periods[i] = dto.ComparePeriodDTO{
+			StartDate: utils.CustomDate{Time: start},
+			EndDate:   utils.CustomDate{Time: next.AddDate(0, 0, -1)},
+		}
+		start = PeriodStart(input.Period, start.AddDate(0, 0, -1), alignment)
+	}
+
+	return periods, nil
+}
+
+// compareChanges compares every amount after the first with the previous or the first amount
+func compareChanges(amounts []decimal.Decimal, compareTo string) []dto.CompareChangeDTO {
+	changes := make([]dto.CompareChangeDTO, 0, len(amounts))
+	for i := 1; i < len(amounts); i++ {
+		base := amounts[i-1]
+		if compareTo == CompareToFirst {
+			base = amounts[0]
+		}
+
+		difference := amounts[i].Sub(base)
+		change := dto.CompareChangeDTO{Absolute: difference}
+		if !base.IsZero() {
+			percent := difference.Div(base).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+			change.Percent = &percent
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
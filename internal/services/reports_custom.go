@@ -0,0 +1,451 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/repositories/reports"
+	"ypeskov/budget-go/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+// Dimensions custom reports can be grouped by
+const (
+	CustomDimensionCategory       = "category"
+	CustomDimensionParentCategory = "parentCategory"
+	CustomDimensionAccount        = "account"
+	CustomDimensionAccountType    = "accountType"
+	CustomDimensionCurrency       = "currency"
+	CustomDimensionDay            = "day"
+	CustomDimensionWeek           = "week"
+	CustomDimensionMonth          = "month"
+	CustomDimensionYear           = "year"
+	CustomDimensionWeekday        = "weekday"
+)
+
+// Measures of custom reports, sum and average are in the base currency
+const (
+	CustomMeasureSum   = "sum"
+	CustomMeasureCount = "count"
+	CustomMeasureAvg   = "avg"
+)
+
+// MaxCustomReportGroups limits the number of rows and of columns of a custom report
+const MaxCustomReportGroups = 1000
+
+var customDimensions = map[string]bool{
+	CustomDimensionCategory:       true,
+	CustomDimensionParentCategory: true,
+	CustomDimensionAccount:        true,
+	CustomDimensionAccountType:    true,
+	CustomDimensionCurrency:       true,
+	CustomDimensionDay:            true,
+	CustomDimensionWeek:           true,
+	CustomDimensionMonth:          true,
+	CustomDimensionYear:           true,
+	CustomDimensionWeekday:        true,
+}
+
+// orderedDimensions are shown in their natural order, other dimensions with the largest values first
+var orderedDimensions = map[string]bool{
+	CustomDimensionDay:     true,
+	CustomDimensionWeek:    true,
+	CustomDimensionMonth:   true,
+	CustomDimensionYear:    true,
+	CustomDimensionWeekday: true,
+}
+
+// normalizeCustomReport validates the definition and fills in the default measure. Dates are only
+// required to run the report, a saved definition may get them when it is run.
+func normalizeCustomReport(input dto.CustomReportInputDTO, requireDates bool) (dto.CustomReportInputDTO, error) {
+	if len(input.GroupBy) < 1 || len(input.GroupBy) > 2 {
+		return input, fmt.Errorf("invalid groupBy: one or two dimensions are required")
+	}
+	for _, dimension := range input.GroupBy {
+		if !customDimensions[dimension] {
+			return input, fmt.Errorf("invalid groupBy: unknown dimension %s", dimension)
+		}
+	}
+	if len(input.GroupBy) == 2 && input.GroupBy[0] == input.GroupBy[1] {
+		return input, fmt.Errorf("invalid groupBy: dimensions must be different")
+	}
+
+	if input.Measure == "" {
+		input.Measure = CustomMeasureSum
+	}
+	if input.Measure != CustomMeasureSum && input.Measure != CustomMeasureCount && input.Measure != CustomMeasureAvg {
+		return input, fmt.Errorf("invalid measure: %s. Allowed values are sum, count and avg", input.Measure)
+	}
+
+	filter := input.Filter
+	if filter.Type != "" && filter.Type != "income" && filter.Type != "expense" {
+		return input, fmt.Errorf("invalid type: %s. Allowed values are income and expense", filter.Type)
+	}
+	for i, code := range filter.Currencies {
+		filter.Currencies[i] = strings.ToUpper(code)
+	}
+
+	hasStart := filter.StartDate != nil && !filter.StartDate.IsZero()
+	hasEnd := filter.EndDate != nil && !filter.EndDate.IsZero()
+	if requireDates && (!hasStart || !hasEnd) {
+		return input, fmt.Errorf("invalid filter: start and end dates are required")
+	}
+	if hasStart != hasEnd {
+		return input, fmt.Errorf("invalid filter: set both start and end dates or neither")
+	}
+	if hasStart && filter.EndDate.Before(filter.StartDate.Time) {
+		return input, fmt.Errorf("invalid filter: the end date is before the start date")
+	}
+
+	return input, nil
+}
+
+// customAccumulator collects the transactions of one group
+type customAccumulator struct {
+	sum   decimal.Decimal
+	count int
+}
+
+func (a *customAccumulator) add(amount decimal.Decimal) {
+	a.sum = a.sum.Add(amount)
+	a.count++
+}
+
+func (a *customAccumulator) value(measure string) decimal.Decimal {
+	switch measure {
+	case CustomMeasureCount:
+		return decimal.NewFromInt(int64(a.count))
+	case CustomMeasureAvg:
+		if a.count == 0 {
+			return decimal.Zero
+		}
+		return a.sum.Div(decimal.NewFromInt(int64(a.count))).Round(2)
+	default:
+		return a.sum.Round(2)
+	}
+}
+
+// customGroups keeps the values of a dimension with their labels and totals
+type customGroups struct {
+	dimension string
+	labels    map[string]string
+	totals    map[string]*customAccumulator
+}
+
+func newCustomGroups(dimension string) *customGroups {
+	return &customGroups{dimension: dimension, labels: map[string]string{}, totals: map[string]*customAccumulator{}}
+}
+
+func (g *customGroups) add(key, label string, amount decimal.Decimal) {
+	if _, ok := g.totals[key]; !ok {
+		g.labels[key] = label
+		g.totals[key] = &customAccumulator{}
+	}
+	g.totals[key].add(amount)
+}
+
+// sorted returns the groups in the natural order of the dimension or with the largest values first
+func (g *customGroups) sorted(measure string) []dto.CustomReportGroupDTO {
+	keys := make([]string, 0, len(g.totals))
+	for key := range g.totals {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if orderedDimensions[g.dimension] {
+			return keys[i] < keys[j]
+		}
+		vi, vj := g.totals[keys[i]].value(measure).Abs(), g.totals[keys[j]].value(measure).Abs()
+		if !vi.Equal(vj) {
+			return vi.GreaterThan(vj)
+		}
+		return g.labels[keys[i]] < g.labels[keys[j]]
+	})
+
+	groups := make([]dto.CustomReportGroupDTO, 0, len(keys))
+	for _, key := range keys {
+		groups = append(groups, dto.CustomReportGroupDTO{Key: key, Label: g.labels[key]})
+	}
+	return groups
+}
+
+// customDimensionValue returns the group key and label of the transaction for the dimension.
+// Keys of time dimensions sort in time order.
+func customDimensionValue(dimension string, row reports.CustomReportRow) (string, string) {
+	switch dimension {
+	case CustomDimensionCategory:
+		if row.CategoryID == nil || row.CategoryName == nil {
+			return "0", "Uncategorized"
+		}
+		return strconv.Itoa(*row.CategoryID), *row.CategoryName
+	case CustomDimensionParentCategory:
+		if row.ParentID != nil && row.ParentName != nil {
+			return strconv.Itoa(*row.ParentID), *row.ParentName
+		}
+		return customDimensionValue(CustomDimensionCategory, row)
+	case CustomDimensionAccount:
+		return strconv.Itoa(row.AccountID), row.AccountName
+	case CustomDimensionAccountType:
+		return strconv.Itoa(row.AccountTypeID), row.AccountTypeName
+	case CustomDimensionCurrency:
+		return row.CurrencyCode, row.CurrencyCode
+	case CustomDimensionDay:
+		day := row.DateTime.Format(time.DateOnly)
+		return day, day
+	case CustomDimensionWeek:
+		// ISO weeks start on Monday
+		day := truncateToDay(row.DateTime)
+		monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		year, week := monday.ISOWeek()
+		return monday.Format(time.DateOnly), fmt.Sprintf("%d-W%02d", year, week)
+	case CustomDimensionMonth:
+		month := row.DateTime.Format("2006-01")
+		return month, month
+	case CustomDimensionYear:
+		year := row.DateTime.Format("2006")
+		return year, year
+	case CustomDimensionWeekday:
+		// Monday is 1 and Sunday is 7 so that the week starts on Monday
+		weekday := (int(row.DateTime.Weekday())+6)%7 + 1
+		return strconv.Itoa(weekday), row.DateTime.Weekday().String()
+	}
+	return "", ""
+}
+
+// RunCustomReport aggregates the transactions matching the filter by the group-by dimensions.
+// Amounts are converted to the base currency by transaction date. Without a type filter
+// expenses count as negative amounts, so sums are the net flow of the group.
+func (s *ReportsServiceInstance) RunCustomReport(userID int, input dto.CustomReportInputDTO) (*dto.CustomReportOutputDTO, error) {
+	input, err := normalizeCustomReport(input, true)
+	if err != nil {
+		return nil, err
+	}
+
+	baseCurrency, err := s.reportsRepo.GetUserBaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.reportsRepo.GetCustomReportRows(userID, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rowGroups := newCustomGroups(input.GroupBy[0])
+	var columnGroups *customGroups
+	if len(input.GroupBy) == 2 {
+		columnGroups = newCustomGroups(input.GroupBy[1])
+	}
+	type cellKey struct{ row, column string }
+	cells := map[cellKey]*customAccumulator{}
+	total := &customAccumulator{}
+
+	for _, row := range rows {
+		converted, err := s.exchangeRatesService.CalcAmountFromCurrency(userID, row.DateTime, row.Amount, row.CurrencyCode, baseCurrency)
+		if err != nil {
+			// If conversion fails, use original amount like the other reports
+			converted = row.Amount
+		}
+		if input.Filter.Type == "" && !row.IsIncome {
+			converted = converted.Neg()
+		}
+
+		key := cellKey{}
+		var label string
+		key.row, label = customDimensionValue(input.GroupBy[0], row)
+		rowGroups.add(key.row, label, converted)
+		if columnGroups != nil {
+			key.column, label = customDimensionValue(input.GroupBy[1], row)
+			columnGroups.add(key.column, label, converted)
+		}
+
+		if _, ok := cells[key]; !ok {
+			cells[key] = &customAccumulator{}
+		}
+		cells[key].add(converted)
+		total.add(converted)
+	}
+
+	if len(rowGroups.totals) > MaxCustomReportGroups || (columnGroups != nil && len(columnGroups.totals) > MaxCustomReportGroups) {
+		return nil, fmt.Errorf("invalid report: more than %d groups, narrow the filter or use a coarser dimension", MaxCustomReportGroups)
+	}
+
+	output := &dto.CustomReportOutputDTO{
+		GroupBy:      input.GroupBy,
+		Measure:      input.Measure,
+		CurrencyCode: baseCurrency,
+		Rows:         rowGroups.sorted(input.Measure),
+		Columns:      []dto.CustomReportGroupDTO{},
+		Total:        total.value(input.Measure),
+		Count:        total.count,
+		// Counts are plain numbers in every money format
+		ValueCurrency: baseCurrency,
+	}
+	if input.Measure == CustomMeasureCount {
+		output.ValueCurrency = "-"
+	}
+	columnKeys := []string{""}
+	if columnGroups != nil {
+		output.Columns = columnGroups.sorted(input.Measure)
+		columnKeys = make([]string, 0, len(output.Columns))
+		for _, column := range output.Columns {
+			columnKeys = append(columnKeys, column.Key)
+			output.ColumnTotals = append(output.ColumnTotals, columnGroups.totals[column.Key].value(input.Measure))
+		}
+	}
+
+	output.Values = make([][]*decimal.Decimal, 0, len(output.Rows))
+	output.RowTotals = make([]decimal.Decimal, 0, len(output.Rows))
+	for _, row := range output.Rows {
+		values := make([]*decimal.Decimal, len(columnKeys))
+		for i, column := range columnKeys {
+			if cell, ok := cells[cellKey{row: row.Key, column: column}]; ok {
+				value := cell.value(input.Measure)
+				values[i] = &value
+			}
+		}
+		output.Values = append(output.Values, values)
+		output.RowTotals = append(output.RowTotals, rowGroups.totals[row.Key].value(input.Measure))
+	}
+	if output.ColumnTotals == nil {
+		output.ColumnTotals = []decimal.Decimal{}
+	}
+
+	return output, nil
+}
+
+func (s *ReportsServiceInstance) GetReportDefinitions(userID int) ([]dto.ReportDefinitionDTO, error) {
+	definitions, err := s.reportDefinitionsRepo.GetUserDefinitions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ReportDefinitionDTO, 0, len(definitions))
+	for _, definition := range definitions {
+		definitionDTO, err := toReportDefinitionDTO(definition)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, definitionDTO)
+	}
+
+	return result, nil
+}
+
+func (s *ReportsServiceInstance) GetReportDefinition(userID int, definitionID int) (*dto.ReportDefinitionDTO, error) {
+	definition, err := s.reportDefinitionsRepo.GetDefinitionByID(definitionID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("report definition not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	definitionDTO, err := toReportDefinitionDTO(*definition)
+	if err != nil {
+		return nil, err
+	}
+	return &definitionDTO, nil
+}
+
+func (s *ReportsServiceInstance) CreateReportDefinition(userID int, input dto.SaveReportDefinitionDTO) (*dto.ReportDefinitionDTO, error) {
+	definition, err := newReportDefinition(userID, input)
+	if err != nil {
+		return nil, err
+	}
+	definition.CreatedAt = definition.UpdatedAt
+
+	created, err := s.reportDefinitionsRepo.CreateDefinition(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	definitionDTO, err := toReportDefinitionDTO(*created)
+	if err != nil {
+		return nil, err
+	}
+	return &definitionDTO, nil
+}
+
+func (s *ReportsServiceInstance) UpdateReportDefinition(userID int, definitionID int, input dto.SaveReportDefinitionDTO) (*dto.ReportDefinitionDTO, error) {
+	definition, err := newReportDefinition(userID, input)
+	if err != nil {
+		return nil, err
+	}
+	definition.ID = &definitionID
+
+	if err = s.reportDefinitionsRepo.UpdateDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	return s.GetReportDefinition(userID, definitionID)
+}
+
+func (s *ReportsServiceInstance) DeleteReportDefinition(userID int, definitionID int) error {
+	return s.reportDefinitionsRepo.DeleteDefinition(definitionID, userID)
+}
+
+// RunReportDefinition runs a saved report, the dates replace the saved ones when set
+func (s *ReportsServiceInstance) RunReportDefinition(userID int, definitionID int, startDate, endDate *utils.CustomDate) (*dto.CustomReportOutputDTO, error) {
+	definition, err := s.GetReportDefinition(userID, definitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	input := definition.Definition
+	if startDate != nil {
+		input.Filter.StartDate = startDate
+	}
+	if endDate != nil {
+		input.Filter.EndDate = endDate
+	}
+
+	return s.RunCustomReport(userID, input)
+}
+
+func newReportDefinition(userID int, input dto.SaveReportDefinitionDTO) (models.ReportDefinition, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 200 {
+		return models.ReportDefinition{}, fmt.Errorf("invalid name: it is required and may have up to 200 characters")
+	}
+
+	definition, err := normalizeCustomReport(input.Definition, false)
+	if err != nil {
+		return models.ReportDefinition{}, err
+	}
+
+	definitionJSON, err := json.Marshal(definition)
+	if err != nil {
+		return models.ReportDefinition{}, err
+	}
+
+	return models.ReportDefinition{
+		UserID:     userID,
+		Name:       name,
+		Definition: definitionJSON,
+		UpdatedAt:  time.Now(),
+	}, nil
+}
+
+func toReportDefinitionDTO(definition models.ReportDefinition) (dto.ReportDefinitionDTO, error) {
+	result := dto.ReportDefinitionDTO{
+		Name:      definition.Name,
+		CreatedAt: definition.CreatedAt,
+		UpdatedAt: definition.UpdatedAt,
+	}
+	if definition.ID != nil {
+		result.ID = *definition.ID
+	}
+	if err := json.Unmarshal(definition.Definition, &result.Definition); err != nil {
+		return dto.ReportDefinitionDTO{}, fmt.Errorf("failed to read report definition %d: %w", result.ID, err)
+	}
+	return result, nil
+}
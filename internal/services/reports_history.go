@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ypeskov/budget-go/internal/dto"
+	"ypeskov/budget-go/internal/models"
+	"ypeskov/budget-go/internal/utils"
+)
+
+// MaxBalanceHistoryPoints limits the number of balance reports a history is built from
+const MaxBalanceHistoryPoints = 120
+
+func (s *ReportsServiceInstance) GetBalanceHistory(userID int, startDate, endDate time.Time, period string, accountID *int) (*dto.BalanceHistoryDTO, error) {
+	dates, err := balanceHistoryDates(startDate, endDate, period)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &dto.BalanceHistoryDTO{
+		AccountID: accountID,
+		Name:      "Net worth",
+		Points:    make([]dto.BalanceHistoryPointDTO, 0, len(dates)),
+	}
+
+	input := dto.BalanceReportInputDTO{}
+	if accountID != nil {
+		input.AccountIds = []int{*accountID}
+	}
+
+	for _, date := range dates {
+		input.BalanceDate = utils.CustomDate{Time: date}
+		balances, err := s.GetBalanceReport(userID, input)
+		if err != nil {
+			return nil, err
+		}
+
+		point := dto.BalanceHistoryPointDTO{BalanceDate: input.BalanceDate}
+		if accountID == nil {
+			// Net worth adds up all accounts in the base currency
+			for _, balance := range balances {
+				history.CurrencyCode = balance.BaseCurrencyCode
+				point.Balance = point.Balance.Add(balance.BaseCurrencyBalance)
+			}
+		} else {
+			// A single account is shown in its own currency, so exchange rates don't move the line
+			found := false
+			for _, balance := range balances {
+				if balance.AccountID == *accountID {
+					history.Name = balance.AccountName
+					history.CurrencyCode = balance.CurrencyCode
+					point.Balance = balance.Balance
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("account not found")
+			}
+		}
+		history.Points = append(history.Points, point)
+	}
+
+	return history, nil
+}
+
+// balanceHistoryDates returns the last day of every calendar period between the dates,
+// the last point is the end date itself
+func balanceHistoryDates(startDate, endDate time.Time, period string) ([]time.Time, error) {
+	if !models.ValidatePeriod(period) || strings.EqualFold(period, string(models.PeriodCustom)) {
+		return nil, fmt.Errorf("invalid period: %s. Allowed values are daily, weekly, monthly, quarterly, yearly", period)
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("invalid date range: the end date is before the start date")
+	}
+
+	var dates []time.Time
+	start := PeriodStart(period, startDate, PeriodAlignment{})
+	for {
+		next, err := NextPeriodStart(period, start, PeriodAlignment{})
+		if err != nil {
+			return nil, err
+		}
+		date := next.AddDate(0, 0, -1)
+		if !date.Before(endDate) {
+			dates = append(dates, truncateToDay(endDate))
+			break
+		}
+		dates = append(dates, date)
+		if len(dates) >= MaxBalanceHistoryPoints {
+			return nil, fmt.Errorf("invalid date range: more than %d %s periods, choose a longer period", MaxBalanceHistoryPoints, strings.ToLower(period))
+		}
+		start = next
+	}
+
+	return dates, nil
+}
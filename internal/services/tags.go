@@ -0,0 +1,30 @@
+package services
+
+import "strings"
+
+// maxTagLength matches the size of the tag columns
+const maxTagLength = 50
+
+// NormalizeTags trims and lowercases tags, drops empty ones and duplicates and cuts them to the maximum length.
+// The order of the first occurrences is kept.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len([]rune(tag)) > maxTagLength {
+			tag = strings.TrimSpace(string([]rune(tag)[:maxTagLength]))
+		}
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
@@ -30,6 +30,7 @@ type TransactionsService interface {
 	DeleteTemplates(templateIds []int, userId int) error
 	CreateTransaction(transaction models.Transaction, targetAccountID *int, targetAmount *decimal.Decimal) (*models.Transaction, error)
 	GetExpenseTransactionsForBudget(userId int, categoryIds []int, startDate time.Time, endDate time.Time, transactionIds []int) ([]models.Transaction, error)
+	GetTransactionsTags(transactionIds []int) (map[int][]string, error)
 }
 
 type TransactionsServiceInstance struct {
@@ -89,6 +90,7 @@ func (s *TransactionsServiceInstance) GetTransactionsWithAccounts(userId int,
 
 	for i, transaction := range transactions {
 		amount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(
+			userId,
 			*transaction.DateTime,
 			transaction.Amount,
 			transaction.Currency.Code,
@@ -165,6 +167,8 @@ func (s *TransactionsServiceInstance) CreateTransaction(transaction models.Trans
 		transaction.Notes = &emptyString
 	}
 
+	transaction.Tags = NormalizeTags(transaction.Tags)
+
 	if transaction.IsTransfer {
 		return s.createTransferTransaction(transaction, targetAccountID, targetAmount)
 	} else {
@@ -207,18 +211,15 @@ func (s *TransactionsServiceInstance) createRegularTransaction(transaction model
 		return nil, err
 	}
 
-	// Update only affected budgets (recompute), if this is an expense transaction
-	if !transaction.IsIncome && !transaction.IsTransfer {
-		pairs := []AffectedCategoryDate{}
-		if transaction.CategoryID != nil && transaction.DateTime != nil {
-			pairs = append(pairs, AffectedCategoryDate{CategoryID: *transaction.CategoryID, Date: *transaction.DateTime})
-		}
-		if len(pairs) > 0 {
-			if err := s.sm.BudgetsService.UpdateBudgetCollectedAmountsForCategories(transaction.UserID, pairs); err != nil {
-				logger.Error("Error updating affected budgets after transaction creation", "error", err)
-			}
+	if len(transaction.Tags) > 0 {
+		if err = s.transactionsRepository.SetTransactionTags(*createdTransaction.ID, transaction.Tags); err != nil {
+			logger.Error("Error setting transaction tags", "error", err)
+			return nil, err
 		}
 	}
+	createdTransaction.Tags = transaction.Tags
+
+	s.publishTransactionEvent(TransactionCreated, transaction.UserID, nil, createdTransaction)
 
 	return createdTransaction, nil
 }
@@ -336,6 +337,14 @@ func (s *TransactionsServiceInstance) createTransferTransaction(transaction mode
 		}
 	}
 
+	if len(transaction.Tags) > 0 {
+		if err = s.transactionsRepository.SetTransactionTags(*createdSourceTx.ID, transaction.Tags); err != nil {
+			logger.Error("Error setting transfer tags", "error", err)
+			// Continue anyway, as the transactions are created
+		}
+	}
+	createdSourceTx.Tags = transaction.Tags
+
 	return createdSourceTx, nil
 }
 
@@ -363,6 +372,7 @@ func (s *TransactionsServiceInstance) GetTransactionDetail(transactionId int, us
 		baseCurrencyAmount = *transactionRaw.BaseCurrencyAmount
 	} else {
 		amount, err := s.sm.ExchangeRatesService.CalcAmountFromCurrency(
+			userId,
 			*transactionRaw.DateTime,
 			transactionRaw.Amount,
 			transactionRaw.Currency.Code,
@@ -376,7 +386,17 @@ func (s *TransactionsServiceInstance) GetTransactionDetail(transactionId int, us
 		}
 	}
 
+	tags, err := s.transactionsRepository.GetTransactionsTags([]int{transactionId})
+	if err != nil {
+		logger.Error("Error getting transaction tags", "error", err)
+		return nil, err
+	}
+
 	transactionDetail := convertRawToTransactionDetail(transactionRaw, baseCurrency.Code, baseCurrencyAmount)
+	transactionDetail.Tags = tags[transactionId]
+	if transactionDetail.Tags == nil {
+		transactionDetail.Tags = []string{}
+	}
 	return transactionDetail, nil
 }
 
@@ -469,6 +489,7 @@ func convertRawToTransactionDetail(raw *dto.TransactionDetailRaw, baseCurrencyCo
 			IsDeleted:             raw.Account.IsDeleted,
 			IsArchived:            false,
 			BalanceInBaseCurrency: decimal.Zero,
+			BaseCurrencyCode:      baseCurrencyCode,
 			ArchivedAt:            raw.Account.ArchivedAt,
 		},
 		BaseCurrencyAmount:  baseCurrencyAmount,
@@ -558,6 +579,10 @@ func (s *TransactionsServiceInstance) UpdateTransaction(transactionDTO dto.PutTr
 		return fmt.Errorf("transaction not found")
 	}
 
+	if err = s.loadTags(&existingTransaction.Transaction); err != nil {
+		return err
+	}
+
 	now := time.Now()
 	transaction := models.Transaction{
 		ID:         &transactionDTO.ID,
@@ -570,6 +595,7 @@ func (s *TransactionsServiceInstance) UpdateTransaction(transactionDTO dto.PutTr
 		IsTransfer: transactionDTO.IsTransfer,
 		DateTime:   transactionDTO.DateTime,
 		UpdatedAt:  &now,
+		Tags:       existingTransaction.Tags,
 	}
 
 	// Preserve linked transaction ID if it exists
@@ -579,6 +605,13 @@ func (s *TransactionsServiceInstance) UpdateTransaction(transactionDTO dto.PutTr
 
 	transaction.Notes = transactionDTO.Notes
 
+	// The stored base currency amount holds while the amount, account and date do. Otherwise it is dropped
+	// and the amount is converted with the rates like a new one, so budgets convert the old and the new
+	// version the same way and an edit of the label alone leaves their collected amounts as they are.
+	if sameTransactionValue(&existingTransaction.Transaction, &transaction) {
+		transaction.BaseCurrencyAmount = existingTransaction.BaseCurrencyAmount
+	}
+
 	// Handle account balance updates (including target account changes for transfers)
 	err = s.handleAccountBalanceUpdates(existingTransaction, &transaction, transactionDTO.TargetAccountID)
 	if err != nil {
@@ -601,6 +634,15 @@ func (s *TransactionsServiceInstance) UpdateTransaction(transactionDTO dto.PutTr
 		return err
 	}
 
+	// Tags are replaced only when the request carries them
+	if transactionDTO.Tags != nil {
+		transaction.Tags = NormalizeTags(transactionDTO.Tags)
+		if err = s.transactionsRepository.SetTransactionTags(transactionDTO.ID, transaction.Tags); err != nil {
+			logger.Error("Error setting transaction tags", "error", err)
+			return err
+		}
+	}
+
 	// Handle transfer transactions - update the linked transaction
 	if existingTransaction.IsTransfer && transaction.IsTransfer && existingTransaction.LinkedTransactionID != nil {
 		err = s.updateLinkedTransferTransaction(existingTransaction, &transaction, transactionDTO.TargetAmount, transactionDTO.TargetAccountID)
@@ -610,20 +652,7 @@ func (s *TransactionsServiceInstance) UpdateTransaction(transactionDTO dto.PutTr
 		}
 	}
 
-	// Update only affected budgets (recompute) for expense impact
-	// Consider both old and new values if either side is expense and not transfer
-	var pairs []AffectedCategoryDate
-	if !existingTransaction.IsIncome && !existingTransaction.IsTransfer && existingTransaction.CategoryID != nil && existingTransaction.DateTime != nil {
-		pairs = append(pairs, AffectedCategoryDate{CategoryID: *existingTransaction.CategoryID, Date: *existingTransaction.DateTime})
-	}
-	if !transaction.IsIncome && !transaction.IsTransfer && transaction.CategoryID != nil && transaction.DateTime != nil {
-		pairs = append(pairs, AffectedCategoryDate{CategoryID: *transaction.CategoryID, Date: *transaction.DateTime})
-	}
-	if len(pairs) > 0 {
-		if err := s.sm.BudgetsService.UpdateBudgetCollectedAmountsForCategories(userId, pairs); err != nil {
-			logger.Error("Error updating affected budgets after transaction update", "error", err)
-		}
-	}
+	s.publishTransactionEvent(TransactionUpdated, userId, &existingTransaction.Transaction, &transaction)
 
 	return nil
 }
@@ -641,6 +670,11 @@ func (s *TransactionsServiceInstance) DeleteTransaction(transactionId int, userI
 		return fmt.Errorf("transaction not found")
 	}
 
+	// Tags decide which budgets the transaction counted towards
+	if err = s.loadTags(&existingTransaction.Transaction); err != nil {
+		return err
+	}
+
 	// Handle account balance updates before deletion
 	err = s.handleAccountBalanceOnDelete(existingTransaction)
 	if err != nil {
@@ -654,17 +688,43 @@ func (s *TransactionsServiceInstance) DeleteTransaction(transactionId int, userI
 		return err
 	}
 
-	// Update only affected budgets (recompute) if this was an expense transaction
-	if !existingTransaction.IsIncome && !existingTransaction.IsTransfer && existingTransaction.CategoryID != nil && existingTransaction.DateTime != nil {
-		pairs := []AffectedCategoryDate{{CategoryID: *existingTransaction.CategoryID, Date: *existingTransaction.DateTime}}
-		if err := s.sm.BudgetsService.UpdateBudgetCollectedAmountsForCategories(userId, pairs); err != nil {
-			logger.Error("Error updating affected budgets after transaction deletion", "error", err)
-		}
-	}
+	s.publishTransactionEvent(TransactionDeleted, userId, &existingTransaction.Transaction, nil)
 
 	return nil
 }
 
+// sameTransactionValue reports whether two versions of a transaction have the same amount, account and date
+func sameTransactionValue(before *models.Transaction, after *models.Transaction) bool {
+	if before.AccountID != after.AccountID || !before.Amount.Equal(after.Amount) {
+		return false
+	}
+	if before.DateTime == nil || after.DateTime == nil {
+		return before.DateTime == after.DateTime
+	}
+	return before.DateTime.Equal(*after.DateTime)
+}
+
+// publishTransactionEvent emits the budget effect of a transaction write so affected budgets apply the delta.
+// Failures are only logged, the daily reconciliation repairs any resulting drift.
+func (s *TransactionsServiceInstance) publishTransactionEvent(kind TransactionEventKind, userId int, before *models.Transaction, after *models.Transaction) {
+	transactionID := 0
+	if after != nil && after.ID != nil {
+		transactionID = *after.ID
+	} else if before != nil && before.ID != nil {
+		transactionID = *before.ID
+	}
+
+	event, err := s.sm.BudgetsService.NewTransactionEvent(kind, userId, transactionID, before, after)
+	if err != nil {
+		logger.Error("Error building transaction event", "transactionID", transactionID, "event", kind, "error", err)
+		return
+	}
+
+	if err := s.sm.BudgetsService.ApplyTransactionEvent(event); err != nil {
+		logger.Error("Error applying transaction event to budgets", "transactionID", transactionID, "event", kind, "error", err)
+	}
+}
+
 // handleAccountBalanceUpdates handles balance changes when a transaction is updated
 func (s *TransactionsServiceInstance) handleAccountBalanceUpdates(oldTx *dto.TransactionDetailRaw, newTx *models.Transaction, newTargetAccountID *int) error {
 	// Calculate the balance effect changes
@@ -839,6 +899,8 @@ func (s *TransactionsServiceInstance) updateLinkedTransferTransaction(existingTx
 		linkedAmount = *targetAmount // Use target amount if specified
 	}
 
+	stored := linkedTx.Transaction
+
 	// sync dates of both transactions
 	if updatedSourceTx.DateTime != nil {
 		linkedTx.DateTime = updatedSourceTx.DateTime
@@ -879,6 +941,9 @@ func (s *TransactionsServiceInstance) updateLinkedTransferTransaction(existingTx
 		NewBalance:          &linkedCurrentBalance,    // Current balance after updates
 		UpdatedAt:           &now,
 	}
+	if sameTransactionValue(&stored, &updatedLinkedTx) {
+		updatedLinkedTx.BaseCurrencyAmount = stored.BaseCurrencyAmount
+	}
 
 	// Update the linked transaction
 	err = s.transactionsRepository.UpdateTransaction(updatedLinkedTx)
@@ -897,3 +962,24 @@ func (s *TransactionsServiceInstance) GetExpenseTransactionsForBudget(userId int
 
 	return txList, nil
 }
+
+func (s *TransactionsServiceInstance) GetTransactionsTags(transactionIds []int) (map[int][]string, error) {
+	tags, err := s.transactionsRepository.GetTransactionsTags(transactionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags of %d transactions: %w", len(transactionIds), err)
+	}
+
+	return tags, nil
+}
+
+// loadTags fills the tags of a stored transaction
+func (s *TransactionsServiceInstance) loadTags(transaction *models.Transaction) error {
+	tags, err := s.transactionsRepository.GetTransactionsTags([]int{*transaction.ID})
+	if err != nil {
+		logger.Error("Error getting transaction tags", "error", err)
+		return err
+	}
+
+	transaction.Tags = tags[*transaction.ID]
+	return nil
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ypeskov/budget-go/internal/dto"
+	appErrors "ypeskov/budget-go/internal/errors"
+	"ypeskov/budget-go/internal/logger"
+	"ypeskov/budget-go/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// userRatesCacheExpiration bounds how long the worker and other instances convert with
+	// rates a user has changed since, the instance that saved them reloads them at once
+	userRatesCacheExpiration = 5 * time.Minute
+	// maxRatePathLength limits the conversions chained for a private currency, e.g. an asset
+	// priced in another private currency that is priced in USD, converted to EUR
+	maxRatePathLength = 4
+)
+
+// userRateIndex holds the rates a user entered, indexed for conversions when they are loaded
+type userRateIndex struct {
+	// rates are ordered by date the way the user sees them
+	rates    []models.UserExchangeRate
+	loadedAt time.Time
+
+	// byDay maps a date to the rates of its pairs
+	byDay map[string]map[[2]string]decimal.Decimal
+	// history has the rates of each pair ordered by date, pairs are sorted so paths are found
+	// the same way every time
+	history map[[2]string][]userRatePoint
+	pairs   [][2]string
+	// currencies are the currencies of all the rates
+	currencies []string
+
+	// paths memoizes userRatePath, keyed by userRatePathKey
+	paths sync.Map
+}
+
+// userRatePoint is the rate of a pair on a date
+type userRatePoint struct {
+	date string
+	rate decimal.Decimal
+}
+
+type userRatePathKey struct {
+	day  string
+	from string
+	to   string
+}
+
+// userRatePathResult is a memoized path, it is only valid with the provider rates it was found with
+type userRatePathResult struct {
+	providerDay *rateDay
+	rate        decimal.Decimal
+	date        string
+	ok          bool
+}
+
+func newUserRateIndex(rates []models.UserExchangeRate) *userRateIndex {
+	index := &userRateIndex{
+		rates:    rates,
+		loadedAt: time.Now(),
+		byDay:    make(map[string]map[[2]string]decimal.Decimal),
+		history:  make(map[[2]string][]userRatePoint),
+	}
+
+	currencies := make(map[string]bool)
+	for _, userRate := range rates {
+		day := userRate.RateDate.Format(time.DateOnly)
+		pair := [2]string{userRate.CurrencyFrom, userRate.CurrencyTo}
+
+		if index.byDay[day] == nil {
+			index.byDay[day] = make(map[[2]string]decimal.Decimal)
+		}
+		index.byDay[day][pair] = userRate.Rate
+
+		if _, ok := index.history[pair]; !ok {
+			index.pairs = append(index.pairs, pair)
+		}
+		index.history[pair] = append(index.history[pair], userRatePoint{date: day, rate: userRate.Rate})
+		currencies[userRate.CurrencyFrom] = true
+		currencies[userRate.CurrencyTo] = true
+	}
+
+	for _, points := range index.history {
+		sort.SliceStable(points, func(i, j int) bool { return points[i].date < points[j].date })
+	}
+	sort.Slice(index.pairs, func(i, j int) bool {
+		return index.pairs[i][0]+"/"+index.pairs[i][1] < index.pairs[j][0]+"/"+index.pairs[j][1]
+	})
+	for code := range currencies {
+		index.currencies = append(index.currencies, code)
+	}
+	sort.Strings(index.currencies)
+
+	return index
+}
+
+// onDay returns the rate the user entered for the pair on the day, either way round
+func (index *userRateIndex) onDay(day, currencyFrom, currencyTo string) (decimal.Decimal, bool) {
+	rates, ok := index.byDay[day]
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	if rate, ok := rates[[2]string{currencyFrom, currencyTo}]; ok {
+		return rate, true
+	}
+	if rate, ok := rates[[2]string{currencyTo, currencyFrom}]; ok {
+		return decimal.NewFromInt(1).Div(rate), true
+	}
+	return decimal.Decimal{}, false
+}
+
+// latest returns the last rate of the pair on or before the day
+func (index *userRateIndex) latest(pair [2]string, day string) (userRatePoint, bool) {
+	points := index.history[pair]
+	i := sort.Search(len(points), func(i int) bool { return points[i].date > day })
+	if i == 0 {
+		return userRatePoint{}, false
+	}
+	return points[i-1], true
+}
+
+// rateEdge is a rate from one currency to another, date is the date of the rate
+type rateEdge struct {
+	to   string
+	rate decimal.Decimal
+	date string
+}
+
+func (s *ExchangeRatesServiceInstance) GetUserExchangeRates(userID int) ([]dto.UserExchangeRateDTO, error) {
+	index, err := s.getUserRates(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.UserExchangeRateDTO, 0, len(index.rates))
+	for _, rate := range index.rates {
+		response = append(response, userExchangeRateToDTO(rate))
+	}
+	return response, nil
+}
+
+// SaveUserExchangeRate stores the rate the user entered for a pair and a day, replacing an earlier
+// entry of the pair for the day. The currencies are checked to be the user's by the caller.
+func (s *ExchangeRatesServiceInstance) SaveUserExchangeRate(userID int, input dto.UserExchangeRateInputDTO) (dto.UserExchangeRateDTO, error) {
+	from := strings.ToUpper(strings.TrimSpace(input.From))
+	to := strings.ToUpper(strings.TrimSpace(input.To))
+	if from == "" || to == "" || from == to {
+		return dto.UserExchangeRateDTO{}, fmt.Errorf("invalid currency pair %s/%s", input.From, input.To)
+	}
+	if input.Date.IsZero() {
+		return dto.UserExchangeRateDTO{}, fmt.Errorf("invalid rate date: it is required")
+	}
+	if !input.Rate.IsPositive() {
+		return dto.UserExchangeRateDTO{}, fmt.Errorf("invalid rate %s: it must be positive", input.Rate)
+	}
+
+	now := time.Now()
+	rate, err := s.userExchangeRatesRepository.SaveUserExchangeRate(models.UserExchangeRate{
+		UserID:       userID,
+		RateDate:     calendarDay(input.Date.Time),
+		CurrencyFrom: from,
+		CurrencyTo:   to,
+		Rate:         input.Rate,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		return dto.UserExchangeRateDTO{}, err
+	}
+
+	s.invalidateUserRates(userID)
+	logger.Info("User exchange rate saved", "userID", userID, "pair", from+"/"+to, "date", rate.RateDate.Format(time.DateOnly))
+	return userExchangeRateToDTO(rate), nil
+}
+
+func (s *ExchangeRatesServiceInstance) DeleteUserExchangeRate(id int, userID int) error {
+	deleted, err := s.userExchangeRatesRepository.DeleteUserExchangeRate(id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return appErrors.ErrUserExchangeRateNotFound
+	}
+
+	s.invalidateUserRates(userID)
+	return nil
+}
+
+// getUserRates returns the rates the user entered, loading them when they are not cached or have expired
+func (s *ExchangeRatesServiceInstance) getUserRates(userID int) (*userRateIndex, error) {
+	userExchangeRatesMu.RLock()
+	index, ok := s.userRates[userID]
+	userExchangeRatesMu.RUnlock()
+	if ok && time.Since(index.loadedAt) < userRatesCacheExpiration {
+		return index, nil
+	}
+
+	userExchangeRatesMu.Lock()
+	defer userExchangeRatesMu.Unlock()
+
+	// Another request may have loaded the rates while this one waited
+	if index, ok := s.userRates[userID]; ok && time.Since(index.loadedAt) < userRatesCacheExpiration {
+		return index, nil
+	}
+
+	rates, err := s.userExchangeRatesRepository.GetUserExchangeRates(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	index = newUserRateIndex(rates)
+	s.userRates[userID] = index
+	return index, nil
+}
+
+func (s *ExchangeRatesServiceInstance) invalidateUserRates(userID int) {
+	userExchangeRatesMu.Lock()
+	delete(s.userRates, userID)
+	userExchangeRatesMu.Unlock()
+}
+
+// rateBetween returns the rate between two currencies for the user and the date of the rates used
+func (s *ExchangeRatesServiceInstance) rateBetween(
+	userID int,
+	date time.Time,
+	currencyFrom string,
+	currencyTo string,
+) (decimal.Decimal, string, error) {
+	day := date.Format(time.DateOnly)
+	if currencyFrom == currencyTo {
+		return decimal.NewFromInt(1), day, nil
+	}
+
+	userRates, err := s.getUserRates(userID)
+	if err != nil {
+		return decimal.Decimal{}, "", err
+	}
+
+	// A rate the user entered for the day wins over the provider rates
+	if rate, ok := userRates.onDay(day, currencyFrom, currencyTo); ok {
+		return rate, day, nil
+	}
+
+	rate, rateDate, err := s.providerRate(date, currencyFrom, currencyTo)
+	if err == nil || len(userRates.rates) == 0 {
+		return rate, rateDate, err
+	}
+
+	// Private currencies have only the rates the user entered, they are converted through them
+	if rate, rateDate, ok := s.userRatePath(userRates, date, currencyFrom, currencyTo); ok {
+		return rate, rateDate, nil
+	}
+	return decimal.Decimal{}, "", err
+}
+
+// userRatePath chains the latest user rates on or before the date with the provider rates of the date
+// to get from one currency to the other. The date of the result is the oldest date of the chained rates.
+// Paths are memoized for the day until the user rates or the provider rates of the day change.
+func (s *ExchangeRatesServiceInstance) userRatePath(
+	userRates *userRateIndex,
+	date time.Time,
+	currencyFrom string,
+	currencyTo string,
+) (decimal.Decimal, string, bool) {
+	day := date.Format(time.DateOnly)
+	// Without provider rates the path can only use user rates
+	providerDay, _ := s.rates.dayOn(date)
+
+	key := userRatePathKey{day: day, from: currencyFrom, to: currencyTo}
+	if cached, ok := userRates.paths.Load(key); ok {
+		if result := cached.(userRatePathResult); result.providerDay == providerDay {
+			return result.rate, result.date, result.ok
+		}
+	}
+
+	rate, rateDate, ok := findUserRatePath(userRates, providerDay, day, currencyFrom, currencyTo)
+	userRates.paths.Store(key, userRatePathResult{providerDay: providerDay, rate: rate, date: rateDate, ok: ok})
+	return rate, rateDate, ok
+}
+
+// findUserRatePath searches the latest user rates on or before the day and the provider rates for
+// the chain with the fewest conversions
+func findUserRatePath(
+	userRates *userRateIndex,
+	providerDay *rateDay,
+	day string,
+	currencyFrom string,
+	currencyTo string,
+) (decimal.Decimal, string, bool) {
+	one := decimal.NewFromInt(1)
+
+	edges := make(map[string][]rateEdge)
+	for _, pair := range userRates.pairs {
+		latest, ok := userRates.latest(pair, day)
+		if !ok {
+			continue
+		}
+		edges[pair[0]] = append(edges[pair[0]], rateEdge{to: pair[1], rate: latest.rate, date: latest.date})
+		edges[pair[1]] = append(edges[pair[1]], rateEdge{to: pair[0], rate: one.Div(latest.rate), date: latest.date})
+	}
+
+	// The provider rates connect their currencies through their base currency
+	if providerDay != nil {
+		baseCurrency := providerDay.baseCurrency
+		codes := make([]string, 0, len(providerDay.rates))
+		for code := range providerDay.rates {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			rate := providerDay.rates[code]
+			if code == baseCurrency || !rate.IsPositive() {
+				continue
+			}
+			edges[baseCurrency] = append(edges[baseCurrency], rateEdge{to: code, rate: rate, date: providerDay.date})
+			edges[code] = append(edges[code], rateEdge{to: baseCurrency, rate: one.Div(rate), date: providerDay.date})
+		}
+	}
+
+	// Breadth-first search finds the chain with the fewest conversions
+	type step struct {
+		currency string
+		rate     decimal.Decimal
+		date     string
+		length   int
+	}
+	visited := map[string]bool{currencyFrom: true}
+	queue := []step{{currency: currencyFrom, rate: one, date: day}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.length == maxRatePathLength {
+			continue
+		}
+
+		for _, edge := range edges[current.currency] {
+			if visited[edge.to] {
+				continue
+			}
+			visited[edge.to] = true
+
+			next := step{currency: edge.to, rate: current.rate.Mul(edge.rate), date: current.date, length: current.length + 1}
+			if edge.date < next.date {
+				next.date = edge.date
+			}
+			if edge.to == currencyTo {
+				return next.rate, next.date, true
+			}
+			queue = append(queue, next)
+		}
+	}
+
+	return decimal.Decimal{}, "", false
+}
+
+func userExchangeRateToDTO(rate models.UserExchangeRate) dto.UserExchangeRateDTO {
+	return dto.UserExchangeRateDTO{
+		ID:   rate.ID,
+		Date: rate.RateDate.Format(time.DateOnly),
+		From: rate.CurrencyFrom,
+		To:   rate.CurrencyTo,
+		Rate: rate.Rate,
+	}
+}
@@ -14,12 +14,14 @@ import (
 type UserService interface {
 	GetAllUsers() ([]*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(userID int) (*models.User, error)
 	CreateUser(user *models.User) (*models.User, error)
 	RegisterUser(userDTO *dto.UserRegisterRequestDTO,
 		currenciesService CurrenciesService,
-		activationTokenService ActivationTokenService) (*models.User, error)
+		activationTokenService ActivationTokenService,
+		categoriesService CategoriesService) (*models.User, error)
 	LoginUser(loginDTO *dto.UserLoginDTO) (*models.User, error)
-	LoginOrRegisterOAuth(email, firstName, lastName string) (*models.User, error)
+	LoginOrRegisterOAuth(email, firstName, lastName string, categoriesService CategoriesService) (*models.User, error)
 	ActivateUser(userID int) error
 }
 
@@ -67,6 +69,17 @@ func (us *UserServiceInstance) GetUserByEmail(email string) (*models.User, error
 	return user, nil
 }
 
+func (us *UserServiceInstance) GetUserByID(userID int) (*models.User, error) {
+	logger.Debug("GetUserByID service called")
+	user, err := us.userRepo.GetUserByID(userID)
+	if err != nil {
+		logger.Error("Error getting user by id", "error", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
 // CreateUser creates a new user in the repository
 // and returns the created user or an error if the creation fails.
 // It is used internally by the service and should not be exposed as a public API.
@@ -86,7 +99,8 @@ func (us *UserServiceInstance) CreateUser(user *models.User) (*models.User, erro
 
 func (us *UserServiceInstance) RegisterUser(userDTO *dto.UserRegisterRequestDTO,
 	currenciesService CurrenciesService,
-	activationTokenService ActivationTokenService) (*models.User, error) {
+	activationTokenService ActivationTokenService,
+	categoriesService CategoriesService) (*models.User, error) {
 	logger.Debug("RegisterUser service called")
 
 	// Check if user already exists
@@ -128,6 +142,8 @@ func (us *UserServiceInstance) RegisterUser(userDTO *dto.UserRegisterRequestDTO,
 		return nil, err
 	}
 
+	createDefaultCategories(categoriesService, createdUser.ID, userDTO.Language)
+
 	// Create activation token
 	activationToken, err := activationTokenService.CreateActivationToken(createdUser.ID)
 	if err != nil {
@@ -156,7 +172,7 @@ func (us *UserServiceInstance) RegisterUser(userDTO *dto.UserRegisterRequestDTO,
 	return createdUser, nil
 }
 
-func (us *UserServiceInstance) LoginOrRegisterOAuth(email, firstName, lastName string) (*models.User, error) {
+func (us *UserServiceInstance) LoginOrRegisterOAuth(email, firstName, lastName string, categoriesService CategoriesService) (*models.User, error) {
 	logger.Debug("LoginOrRegisterOAuth service called")
 
 	existingUser, err := us.userRepo.GetUserByEmail(email)
@@ -172,7 +188,14 @@ func (us *UserServiceInstance) LoginOrRegisterOAuth(email, firstName, lastName s
 			IsDeleted:      false,
 		}
 
-		return us.CreateUser(newUser)
+		createdUser, err := us.CreateUser(newUser)
+		if err != nil {
+			return nil, err
+		}
+
+		createDefaultCategories(categoriesService, createdUser.ID, "")
+
+		return createdUser, nil
 	}
 
 	if !existingUser.IsActive {
@@ -222,3 +245,13 @@ func (us *UserServiceInstance) ActivateUser(userID int) error {
 	return us.userRepo.ActivateUser(userID)
 }
 
+// createDefaultCategories gives a new user the default categories of the language.
+// The user is registered anyway when it fails, the defaults can be applied later.
+func createDefaultCategories(categoriesService CategoriesService, userID int, languageCode string) {
+	if categoriesService == nil {
+		return
+	}
+	if _, err := categoriesService.ApplyDefaultCategories(userID, languageCode, false); err != nil {
+		logger.Error("Error creating default categories", "userID", userID, "error", err)
+	}
+}
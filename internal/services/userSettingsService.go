@@ -1,6 +1,9 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"sync"
 	"ypeskov/budget-go/internal/logger"
 	"ypeskov/budget-go/internal/models"
@@ -11,6 +14,8 @@ type UserSettingsService interface {
 	GetBaseCurrency(userId int) (models.Currency, error)
 	UpdateUserSettings(userID int, settingsData map[string]interface{}) (*models.UserSettings, error)
 	GetUserSettings(userID int) (*models.UserSettings, error)
+	// GetDigestUserIDs returns the users who opted in to the email digest of the frequency
+	GetDigestUserIDs(frequency string) ([]int, error)
 }
 
 type UserSettingsServiceInstance struct {
@@ -43,8 +48,24 @@ func (u *UserSettingsServiceInstance) GetBaseCurrency(userId int) (models.Curren
 	return baseCurrency, nil
 }
 
+// UpdateUserSettings merges the settings into the stored ones, so updating one setting keeps the others
 func (u *UserSettingsServiceInstance) UpdateUserSettings(userID int, settingsData map[string]interface{}) (*models.UserSettings, error) {
-	userSettings, err := u.userSettingsRepo.UpsertUserSettings(userID, settingsData)
+	merged := map[string]interface{}{}
+	existing, err := u.userSettingsRepo.GetUserSettings(userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Failed to get user settings", "error", err)
+		return nil, err
+	}
+	if existing != nil {
+		for key, value := range existing.Settings {
+			merged[key] = value
+		}
+	}
+	for key, value := range settingsData {
+		merged[key] = value
+	}
+
+	userSettings, err := u.userSettingsRepo.UpsertUserSettings(userID, merged)
 	if err != nil {
 		logger.Error("Failed to update user settings", "error", err)
 		return nil, err
@@ -62,3 +83,17 @@ func (u *UserSettingsServiceInstance) GetUserSettings(userID int) (*models.UserS
 
 	return userSettings, nil
 }
+
+func (u *UserSettingsServiceInstance) GetDigestUserIDs(frequency string) ([]int, error) {
+	if frequency != models.DigestWeekly && frequency != models.DigestMonthly {
+		return nil, fmt.Errorf("invalid digest frequency: %s", frequency)
+	}
+
+	userIDs, err := u.userSettingsRepo.GetUserIDsBySetting(models.SettingEmailDigest, frequency)
+	if err != nil {
+		logger.Error("Failed to get digest users", "frequency", frequency, "error", err)
+		return nil, err
+	}
+
+	return userIDs, nil
+}
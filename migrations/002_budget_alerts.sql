@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Alert thresholds are stored as comma-separated percentages (e.g. "50,80,100")
+ALTER TABLE budgets ADD COLUMN alert_thresholds TEXT;
+ALTER TABLE budgets ADD COLUMN alert_over_budget BOOLEAN DEFAULT FALSE NOT NULL;
+
+-- Keeps track of alerts that were already sent so each threshold fires once per period
+CREATE TABLE budget_alerts_sent (
+    id SERIAL PRIMARY KEY,
+    budget_id INTEGER NOT NULL,
+    threshold INTEGER NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+ALTER TABLE budget_alerts_sent ADD CONSTRAINT budget_alerts_sent_budget_id_fkey FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE;
+ALTER TABLE budget_alerts_sent ADD CONSTRAINT unique_budget_alert_period UNIQUE (budget_id, threshold, period_start);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS budget_alerts_sent CASCADE;
+ALTER TABLE budgets DROP COLUMN IF EXISTS alert_over_budget;
+ALTER TABLE budgets DROP COLUMN IF EXISTS alert_thresholds;
+
+-- +goose StatementEnd
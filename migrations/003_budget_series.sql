@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Every budget row is a single period of a series. Renewals of repeating budgets share the series_id
+-- of the budget they were created from; the first budget of a series uses its own id.
+ALTER TABLE budgets ADD COLUMN series_id INTEGER;
+
+UPDATE budgets SET series_id = id;
+
+-- Link existing "(copy)" renewals to the budget they were copied from: the renewal starts exactly
+-- where the previous period ended and has the same owner, period type and name without the suffix.
+WITH RECURSIVE chain AS (
+    SELECT b.id, b.id AS series_id
+    FROM budgets b
+    WHERE NOT EXISTS (
+        SELECT 1 FROM budgets prev
+        WHERE prev.user_id = b.user_id
+          AND prev.period = b.period
+          AND prev.end_date = b.start_date
+          AND b.name = prev.name || ' (copy)'
+    )
+    UNION ALL
+    SELECT next.id, chain.series_id
+    FROM chain
+    JOIN budgets cur ON cur.id = chain.id
+    JOIN budgets next ON next.user_id = cur.user_id
+        AND next.period = cur.period
+        AND next.start_date = cur.end_date
+        AND next.name = cur.name || ' (copy)'
+)
+UPDATE budgets SET series_id = chain.series_id
+FROM chain
+WHERE budgets.id = chain.id;
+
+-- Renewals keep the original name now that they are linked through the series
+UPDATE budgets b SET name = root.name
+FROM budgets root
+WHERE root.id = b.series_id AND b.id <> b.series_id;
+
+ALTER TABLE budgets ALTER COLUMN series_id SET NOT NULL;
+CREATE INDEX ix_budgets_series_id ON budgets USING btree (series_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS ix_budgets_series_id;
+ALTER TABLE budgets DROP COLUMN IF EXISTS series_id;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE periodenum ADD VALUE IF NOT EXISTS 'QUARTERLY' AFTER 'MONTHLY';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Period alignment: monthly, quarterly and yearly periods may start on a fixed day of month
+-- (e.g. payday on the 25th) or on the last business day of a month; weekly periods may start
+-- on a chosen weekday (0 = Sunday ... 6 = Saturday).
+ALTER TABLE budgets ADD COLUMN anchor_day INTEGER;
+ALTER TABLE budgets ADD COLUMN anchor_last_business_day BOOLEAN DEFAULT FALSE NOT NULL;
+ALTER TABLE budgets ADD COLUMN week_start_day INTEGER;
+
+ALTER TABLE budgets ADD CONSTRAINT budgets_anchor_day_check CHECK (anchor_day BETWEEN 1 AND 31);
+ALTER TABLE budgets ADD CONSTRAINT budgets_week_start_day_check CHECK (week_start_day BETWEEN 0 AND 6);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE budgets DROP COLUMN IF EXISTS week_start_day;
+ALTER TABLE budgets DROP COLUMN IF EXISTS anchor_last_business_day;
+ALTER TABLE budgets DROP COLUMN IF EXISTS anchor_day;
+
+-- Enum values cannot be dropped; move quarterly budgets to custom so that the value is unused
+UPDATE budgets SET period = 'CUSTOM' WHERE period = 'QUARTERLY';
+-- +goose StatementEnd
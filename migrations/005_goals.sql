@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE goals (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    target_amount NUMERIC NOT NULL,
+    currency_id INTEGER NOT NULL,
+    target_date TIMESTAMPTZ,
+    -- ACCOUNTS: progress is the sum of linked account balances
+    -- CONTRIBUTIONS: progress is the sum of transactions tagged as contributions to the goal
+    progress_source VARCHAR(20) DEFAULT 'ACCOUNTS' NOT NULL,
+    current_amount NUMERIC DEFAULT 0 NOT NULL,
+    required_monthly_contribution NUMERIC,
+    recalculated_at TIMESTAMPTZ,
+    comment VARCHAR,
+    is_archived BOOLEAN DEFAULT FALSE NOT NULL,
+    is_deleted BOOLEAN DEFAULT FALSE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE goal_accounts (
+    goal_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    PRIMARY KEY (goal_id, account_id)
+);
+
+CREATE TABLE goal_contributions (
+    goal_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (goal_id, transaction_id)
+);
+
+ALTER TABLE goals ADD CONSTRAINT goals_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE goals ADD CONSTRAINT goals_currency_id_fkey FOREIGN KEY (currency_id) REFERENCES currencies(id) ON DELETE CASCADE;
+ALTER TABLE goals ADD CONSTRAINT goals_progress_source_check CHECK (progress_source IN ('ACCOUNTS', 'CONTRIBUTIONS'));
+
+ALTER TABLE goal_accounts ADD CONSTRAINT goal_accounts_goal_id_fkey FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE;
+ALTER TABLE goal_accounts ADD CONSTRAINT goal_accounts_account_id_fkey FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+
+ALTER TABLE goal_contributions ADD CONSTRAINT goal_contributions_goal_id_fkey FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE;
+ALTER TABLE goal_contributions ADD CONSTRAINT goal_contributions_transaction_id_fkey FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE;
+
+CREATE INDEX ix_goals_user_id ON goals USING btree (user_id);
+CREATE INDEX ix_goal_contributions_transaction_id ON goal_contributions USING btree (transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS goal_contributions CASCADE;
+DROP TABLE IF EXISTS goal_accounts CASCADE;
+DROP TABLE IF EXISTS goals CASCADE;
+
+-- +goose StatementEnd
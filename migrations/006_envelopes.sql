@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Money assigned to an expense category envelope for a month (in the user's base currency).
+-- Moving money between envelopes lowers the assignment of one envelope and raises the other.
+CREATE TABLE envelope_assignments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    month DATE NOT NULL,
+    amount NUMERIC DEFAULT 0 NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+ALTER TABLE envelope_assignments ADD CONSTRAINT envelope_assignments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE envelope_assignments ADD CONSTRAINT envelope_assignments_category_id_fkey FOREIGN KEY (category_id) REFERENCES user_categories(id) ON DELETE CASCADE;
+ALTER TABLE envelope_assignments ADD CONSTRAINT unique_envelope_assignment_month UNIQUE (user_id, category_id, month);
+ALTER TABLE envelope_assignments ADD CONSTRAINT envelope_assignments_month_check CHECK (EXTRACT(DAY FROM month) = 1);
+
+CREATE INDEX ix_envelope_assignments_user_month ON envelope_assignments (user_id, month);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS envelope_assignments CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Curve used to forecast spending within a budget period:
+-- LINEAR spreads the target evenly, HISTORICAL follows the spend pattern of past periods of the series
+ALTER TABLE budgets ADD COLUMN forecast_curve VARCHAR(20) DEFAULT 'LINEAR' NOT NULL;
+ALTER TABLE budgets ADD CONSTRAINT budgets_forecast_curve_check CHECK (forecast_curve IN ('LINEAR', 'HISTORICAL'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_forecast_curve_check;
+ALTER TABLE budgets DROP COLUMN IF EXISTS forecast_curve;
+
+-- +goose StatementEnd
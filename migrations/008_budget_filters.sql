@@ -0,0 +1,82 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Free-form tags of transactions, stored lowercase
+CREATE TABLE transaction_tags (
+    transaction_id INTEGER NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (transaction_id, tag)
+);
+
+ALTER TABLE transaction_tags ADD CONSTRAINT transaction_tags_transaction_id_fkey FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE;
+CREATE INDEX ix_transaction_tags_tag ON transaction_tags (tag);
+
+-- Categories of a budget. An included category counts together with all its subcategories
+-- (also ones created later), an excluded category is left out even though its parent is included.
+CREATE TABLE budget_categories (
+    budget_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    is_excluded BOOLEAN DEFAULT FALSE NOT NULL,
+    PRIMARY KEY (budget_id, category_id)
+);
+
+ALTER TABLE budget_categories ADD CONSTRAINT budget_categories_budget_id_fkey FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE;
+ALTER TABLE budget_categories ADD CONSTRAINT budget_categories_category_id_fkey FOREIGN KEY (category_id) REFERENCES user_categories(id) ON DELETE CASCADE;
+CREATE INDEX ix_budget_categories_category_id ON budget_categories (category_id);
+
+-- Accounts a budget is limited to, no rows means all accounts count
+CREATE TABLE budget_accounts (
+    budget_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    PRIMARY KEY (budget_id, account_id)
+);
+
+ALTER TABLE budget_accounts ADD CONSTRAINT budget_accounts_budget_id_fkey FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE;
+ALTER TABLE budget_accounts ADD CONSTRAINT budget_accounts_account_id_fkey FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+
+-- Tags a transaction must have (any of the included) or must not have (any of the excluded) to count
+CREATE TABLE budget_tags (
+    budget_id INTEGER NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    is_excluded BOOLEAN DEFAULT FALSE NOT NULL,
+    PRIMARY KEY (budget_id, tag)
+);
+
+ALTER TABLE budget_tags ADD CONSTRAINT budget_tags_budget_id_fkey FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE;
+
+-- Move comma-separated included categories to the relational table
+INSERT INTO budget_categories (budget_id, category_id)
+SELECT DISTINCT b.id, c.category_id
+FROM budgets b
+CROSS JOIN LATERAL unnest(string_to_array(NULLIF(b.included_categories, ''), ',')::int[]) AS c(category_id)
+JOIN user_categories uc ON uc.id = c.category_id;
+
+-- Subcategories now count automatically with their parent, so the ones that were not selected are excluded
+INSERT INTO budget_categories (budget_id, category_id, is_excluded)
+SELECT bc.budget_id, uc.id, TRUE
+FROM budget_categories bc
+JOIN user_categories uc ON uc.parent_id = bc.category_id
+WHERE bc.is_excluded = FALSE
+ON CONFLICT (budget_id, category_id) DO NOTHING;
+
+ALTER TABLE budgets DROP COLUMN included_categories;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE budgets ADD COLUMN included_categories TEXT;
+
+UPDATE budgets b SET included_categories = (
+    SELECT string_agg(bc.category_id::text, ',' ORDER BY bc.category_id)
+    FROM budget_categories bc
+    WHERE bc.budget_id = b.id AND bc.is_excluded = FALSE
+);
+
+DROP TABLE IF EXISTS budget_tags CASCADE;
+DROP TABLE IF EXISTS budget_accounts CASCADE;
+DROP TABLE IF EXISTS budget_categories CASCADE;
+DROP TABLE IF EXISTS transaction_tags CASCADE;
+
+-- +goose StatementEnd
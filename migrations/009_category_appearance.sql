@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Color (#RRGGBB) and icon key of a category. NULL means the subcategory inherits the value of its parent,
+-- a top-level category without a color gets a stable color from the default palette.
+ALTER TABLE user_categories ADD COLUMN color VARCHAR(7);
+ALTER TABLE user_categories ADD COLUMN icon VARCHAR(50);
+ALTER TABLE user_categories ADD CONSTRAINT user_categories_color_check CHECK (color ~ '^#[0-9A-F]{6}$');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE user_categories DROP CONSTRAINT IF EXISTS user_categories_color_check;
+ALTER TABLE user_categories DROP COLUMN IF EXISTS icon;
+ALTER TABLE user_categories DROP COLUMN IF EXISTS color;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Every language has its own default category set, the existing rows are the English one
+ALTER TABLE default_categories ADD COLUMN language_code VARCHAR(50) NOT NULL DEFAULT 'en';
+CREATE INDEX ix_default_categories_language_code ON default_categories USING btree (language_code);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM default_categories WHERE language_code <> 'en';
+DROP INDEX IF EXISTS ix_default_categories_language_code;
+ALTER TABLE default_categories DROP COLUMN IF EXISTS language_code;
+
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Insights found by the nightly spending analysis. The fingerprint identifies the finding,
+-- so running the analysis again does not bring back an insight the user has dismissed.
+CREATE TABLE insights (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    severity VARCHAR(10) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message VARCHAR NOT NULL,
+    fingerprint VARCHAR(255) NOT NULL,
+    category_id INTEGER,
+    transaction_id INTEGER,
+    -- amount of the finding in the base currency of the user
+    amount NUMERIC,
+    details JSONB DEFAULT '{}' NOT NULL,
+    detected_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    is_dismissed BOOLEAN DEFAULT FALSE NOT NULL,
+    dismissed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+ALTER TABLE insights ADD CONSTRAINT insights_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE insights ADD CONSTRAINT insights_category_id_fkey FOREIGN KEY (category_id) REFERENCES user_categories(id) ON DELETE SET NULL;
+ALTER TABLE insights ADD CONSTRAINT insights_transaction_id_fkey FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE SET NULL;
+ALTER TABLE insights ADD CONSTRAINT insights_severity_check CHECK (severity IN ('info', 'warning', 'critical'));
+
+CREATE UNIQUE INDEX ix_insights_user_id_fingerprint ON insights (user_id, fingerprint);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS insights CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Currencies with a user_id are private currencies or assets of that user, like crypto or gold.
+-- Their codes may be longer than ISO codes. decimal_places overrides the minor unit of the code,
+-- NULL keeps the ISO 4217 one.
+ALTER TABLE currencies ALTER COLUMN code TYPE VARCHAR(20);
+ALTER TABLE currencies ADD COLUMN user_id INTEGER;
+ALTER TABLE currencies ADD COLUMN decimal_places SMALLINT;
+ALTER TABLE currencies ADD CONSTRAINT currencies_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE currencies ADD CONSTRAINT currencies_decimal_places_check CHECK (decimal_places BETWEEN 0 AND 18);
+
+CREATE UNIQUE INDEX ix_currencies_user_id_code ON currencies (user_id, code) WHERE user_id IS NOT NULL AND is_deleted = false;
+
+-- Rates entered by a user, they take precedence over the provider rates in the conversions of that user.
+-- rate is the amount of currency_to one unit of currency_from buys on rate_date.
+CREATE TABLE user_exchange_rates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    rate_date DATE NOT NULL,
+    currency_from VARCHAR(20) NOT NULL,
+    currency_to VARCHAR(20) NOT NULL,
+    rate NUMERIC NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+ALTER TABLE user_exchange_rates ADD CONSTRAINT user_exchange_rates_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE user_exchange_rates ADD CONSTRAINT user_exchange_rates_rate_check CHECK (rate > 0);
+ALTER TABLE user_exchange_rates ADD CONSTRAINT user_exchange_rates_pair_check CHECK (currency_from <> currency_to);
+
+CREATE UNIQUE INDEX ix_user_exchange_rates_user_id_pair ON user_exchange_rates (user_id, rate_date, currency_from, currency_to);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS user_exchange_rates CASCADE;
+
+-- Private currencies cannot be kept with 3-letter codes, their accounts are removed with them
+DELETE FROM currencies WHERE user_id IS NOT NULL;
+DROP INDEX IF EXISTS ix_currencies_user_id_code;
+ALTER TABLE currencies DROP CONSTRAINT IF EXISTS currencies_decimal_places_check;
+ALTER TABLE currencies DROP CONSTRAINT IF EXISTS currencies_user_id_fkey;
+ALTER TABLE currencies DROP COLUMN IF EXISTS decimal_places;
+ALTER TABLE currencies DROP COLUMN IF EXISTS user_id;
+ALTER TABLE currencies ALTER COLUMN code TYPE VARCHAR(3);
+
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Past dates the rate providers had no rates of their own for, like weekends and holidays. They answered
+-- with the rates of actual_date, so backfills do not request these dates again.
+CREATE TABLE exchange_rate_gaps (
+    requested_date DATE PRIMARY KEY,
+    actual_date DATE NOT NULL,
+    service_name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS exchange_rate_gaps CASCADE;
+
+-- +goose StatementEnd
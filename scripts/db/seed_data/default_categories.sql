@@ -3,31 +3,57 @@
 DELETE FROM default_categories;
 ALTER SEQUENCE default_categories_id_seq RESTART WITH 1;
 
--- Insert default categories (expenses)
-INSERT INTO default_categories (id, name, parent_id, is_income, created_at, updated_at) VALUES
-(1, 'Life', NULL, false, NOW(), NOW()),
-(2, 'Food', NULL, false, NOW(), NOW()),
-(3, 'Automobile', NULL, false, NOW(), NOW()),
-(4, 'Transport', NULL, false, NOW(), NOW()),
-(5, 'Housing', NULL, false, NOW(), NOW()),
-(6, 'Health', NULL, false, NOW(), NOW()),
-(7, 'Education', NULL, false, NOW(), NOW()),
-(8, 'Entertainment', NULL, false, NOW(), NOW()),
-(9, 'Finances', NULL, false, NOW(), NOW()),
-(10, 'Other', NULL, false, NOW(), NOW()),
+-- Insert English default categories (expenses)
+INSERT INTO default_categories (id, name, parent_id, is_income, language_code, created_at, updated_at) VALUES
+(1, 'Life', NULL, false, 'en', NOW(), NOW()),
+(2, 'Food', NULL, false, 'en', NOW(), NOW()),
+(3, 'Automobile', NULL, false, 'en', NOW(), NOW()),
+(4, 'Transport', NULL, false, 'en', NOW(), NOW()),
+(5, 'Housing', NULL, false, 'en', NOW(), NOW()),
+(6, 'Health', NULL, false, 'en', NOW(), NOW()),
+(7, 'Education', NULL, false, 'en', NOW(), NOW()),
+(8, 'Entertainment', NULL, false, 'en', NOW(), NOW()),
+(9, 'Finances', NULL, false, 'en', NOW(), NOW()),
+(10, 'Other', NULL, false, 'en', NOW(), NOW()),
 -- Subcategories
-(11, 'Parking', 3, false, NOW(), NOW()),
-(12, 'Fuel', 3, false, NOW(), NOW()),
-(13, 'Service', 3, false, NOW(), NOW()),
-(14, 'Taxi', 4, false, NOW(), NOW()),
-(15, 'Meat', 2, false, NOW(), NOW()),
+(11, 'Parking', 3, false, 'en', NOW(), NOW()),
+(12, 'Fuel', 3, false, 'en', NOW(), NOW()),
+(13, 'Service', 3, false, 'en', NOW(), NOW()),
+(14, 'Taxi', 4, false, 'en', NOW(), NOW()),
+(15, 'Meat', 2, false, 'en', NOW(), NOW()),
 -- Income categories
-(16, 'Salary', NULL, true, NOW(), NOW()),
-(17, 'Deposit', NULL, true, NOW(), NOW()),
-(18, 'Present', NULL, true, NOW(), NOW()),
-(19, 'Rent', NULL, true, NOW(), NOW()),
-(20, 'Social', NULL, true, NOW(), NOW()),
-(21, 'Other', NULL, true, NOW(), NOW());
+(16, 'Salary', NULL, true, 'en', NOW(), NOW()),
+(17, 'Deposit', NULL, true, 'en', NOW(), NOW()),
+(18, 'Present', NULL, true, 'en', NOW(), NOW()),
+(19, 'Rent', NULL, true, 'en', NOW(), NOW()),
+(20, 'Social', NULL, true, 'en', NOW(), NOW()),
+(21, 'Other', NULL, true, 'en', NOW(), NOW());
+
+-- Insert Ukrainian default categories (expenses)
+INSERT INTO default_categories (id, name, parent_id, is_income, language_code, created_at, updated_at) VALUES
+(22, 'Побут', NULL, false, 'uk', NOW(), NOW()),
+(23, 'Їжа', NULL, false, 'uk', NOW(), NOW()),
+(24, 'Автомобіль', NULL, false, 'uk', NOW(), NOW()),
+(25, 'Транспорт', NULL, false, 'uk', NOW(), NOW()),
+(26, 'Житло', NULL, false, 'uk', NOW(), NOW()),
+(27, 'Здоров''я', NULL, false, 'uk', NOW(), NOW()),
+(28, 'Освіта', NULL, false, 'uk', NOW(), NOW()),
+(29, 'Розваги', NULL, false, 'uk', NOW(), NOW()),
+(30, 'Фінанси', NULL, false, 'uk', NOW(), NOW()),
+(31, 'Інше', NULL, false, 'uk', NOW(), NOW()),
+-- Subcategories
+(32, 'Паркування', 24, false, 'uk', NOW(), NOW()),
+(33, 'Пальне', 24, false, 'uk', NOW(), NOW()),
+(34, 'Обслуговування', 24, false, 'uk', NOW(), NOW()),
+(35, 'Таксі', 25, false, 'uk', NOW(), NOW()),
+(36, 'М''ясо', 23, false, 'uk', NOW(), NOW()),
+-- Income categories
+(37, 'Зарплата', NULL, true, 'uk', NOW(), NOW()),
+(38, 'Депозит', NULL, true, 'uk', NOW(), NOW()),
+(39, 'Подарунок', NULL, true, 'uk', NOW(), NOW()),
+(40, 'Оренда', NULL, true, 'uk', NOW(), NOW()),
+(41, 'Соціальні виплати', NULL, true, 'uk', NOW(), NOW()),
+(42, 'Інше', NULL, true, 'uk', NOW(), NOW());
 
 -- Update sequence to next available ID
 SELECT setval('default_categories_id_seq', (SELECT MAX(id) FROM default_categories) + 1);
\ No newline at end of file